@@ -1,20 +1,58 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/server"
 	"github.com/bmstu-itstech/itsreg-auth/internal/ports/httpport"
+	"github.com/bmstu-itstech/itsreg-auth/internal/ports/migratecli"
+	"github.com/bmstu-itstech/itsreg-auth/internal/ports/outboxrelay"
+	"github.com/bmstu-itstech/itsreg-auth/internal/ports/webhookdelivery"
 	"github.com/bmstu-itstech/itsreg-auth/internal/service"
 )
 
 func main() {
-	app, cleanup := service.NewApplication()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if service.MigrateOnStartup() {
+		if err := runMigrate([]string{"up"}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	db, cleanup := service.NewDatabase()
 	defer cleanup()
 
+	app, cleanupApp := service.NewApplication(db)
+	defer cleanupApp()
+	limiter := service.NewRateLimiter(db)
+	idempotency := service.NewIdempotency(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go outboxrelay.RunFromEnv(ctx, app.Commands.RelayOutbox)
+	go webhookdelivery.RunFromEnv(ctx, app.Commands.DeliverWebhooks)
+
 	server.RunHTTPServer(func(router chi.Router) http.Handler {
-		return httpport.HandlerFromMux(httpport.NewHTTPServer(app), router)
+		return httpport.NewHTTPHandler(app, router, limiter, idempotency, service.ConcealExistingEmails())
 	})
 }
+
+func runMigrate(args []string) error {
+	m, cleanup := service.NewMigrator()
+	defer cleanup()
+
+	return migratecli.Run(context.Background(), m, args, os.Stdout)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/ports/userimport"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service"
+)
+
+func main() {
+	formatFlag := flag.String("format", "", "input format: jsonl or csv (default: from file extension)")
+	dryRun := flag.Bool("dry-run", false, "validate rows without creating users")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-dry-run] [-format jsonl|csv] <file>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *formatFlag, *dryRun); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(path, formatName string, dryRun bool) error {
+	var format userimport.Format
+	var err error
+	if formatName != "" {
+		format, err = userimport.ParseFormat(formatName)
+	} else {
+		format, err = userimport.FormatFromFilename(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	db, cleanup := service.NewDatabase()
+	defer cleanup()
+
+	app, cleanupApp := service.NewApplication(db)
+	defer cleanupApp()
+
+	report, err := userimport.Run(context.Background(), app.Commands.ImportUser, f, format, dryRun)
+	if err != nil {
+		return fmt.Errorf("%w: stopped after %d imported rows", err, report.Imported)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(report); err != nil {
+		return err
+	}
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", report.Failed, len(report.Rows))
+	}
+
+	return nil
+}
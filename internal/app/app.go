@@ -12,9 +12,42 @@ type Application struct {
 
 type Commands struct {
 	RegisterUser command.RegisterUserHandler
+	ImportUser   command.ImportUserHandler
+
+	UpdateProfile command.UpdateProfileHandler
+	UploadAvatar  command.UploadAvatarHandler
+
+	ChangeUserStatus command.ChangeUserStatusHandler
+	CreateInvitation command.CreateInvitationHandler
+
+	CreatePersonalAccessToken command.CreatePersonalAccessTokenHandler
+	RevokePersonalAccessToken command.RevokePersonalAccessTokenHandler
+
+	RevokeSession      command.RevokeSessionHandler
+	RevokeUserSessions command.RevokeUserSessionsHandler
+
+	RelayOutbox command.RelayOutboxHandler
+
+	CreateWebhookSubscription command.CreateWebhookSubscriptionHandler
+	DeleteWebhookSubscription command.DeleteWebhookSubscriptionHandler
+	RedeliverWebhook          command.RedeliverWebhookHandler
+	DeliverWebhooks           command.DeliverWebhooksHandler
 }
 
 type Queries struct {
 	LoginUser query.LoginUserHandler
 	GetUser   query.GetUserHandler
+	GetAvatar query.GetAvatarHandler
+	ListUsers query.ListUsersHandler
+
+	AuthenticateToken    query.AuthenticateTokenHandler
+	PersonalAccessTokens query.PersonalAccessTokensHandler
+
+	Sessions query.SessionsHandler
+
+	ListAuditEvents query.ListAuditEventsHandler
+	VerifyAuditLog  query.VerifyAuditLogHandler
+
+	ListWebhookSubscriptions query.ListWebhookSubscriptionsHandler
+	ListWebhookDeliveries    query.ListWebhookDeliveriesHandler
 }
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// ChangeUserStatus is made by the admin ChangedBy.
+type ChangeUserStatus struct {
+	UserUUID string
+	// Status is "active", "suspended" or "disabled".
+	Status string
+	// Until is the end of a suspension.
+	Until     time.Time
+	Reason    string
+	ChangedBy string
+	// ExpectedVersion, if set, is the version of the user the change was
+	// based on.
+	ExpectedVersion *int64
+}
+
+type ChangeUserStatusHandler decorator.CommandHandler[ChangeUserStatus]
+
+type changeUserStatusHandler struct {
+	users auth.UsersRepository
+	audit auth.AuditLogRepository
+}
+
+func NewChangeUserStatusHandler(
+	users auth.UsersRepository,
+	audit auth.AuditLogRepository,
+	uow decorator.UnitOfWork,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) ChangeUserStatusHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyTransactionalCommandDecorators[ChangeUserStatus](
+		&changeUserStatusHandler{users: users, audit: audit},
+		uow,
+		logger,
+		metricsClient,
+	)
+}
+
+func (h changeUserStatusHandler) Handle(ctx context.Context, cmd ChangeUserStatus) error {
+	state, err := auth.NewAccountState(cmd.Status)
+	if err != nil {
+		return err
+	}
+
+	status, err := auth.NewAccountStatus(state, cmd.Until, cmd.Reason, cmd.ChangedBy, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = h.users.Update(ctx, cmd.UserUUID, func(_ context.Context, u *auth.User) error {
+		if cmd.ExpectedVersion != nil {
+			if err := u.CheckVersion(*cmd.ExpectedVersion); err != nil {
+				return err
+			}
+		}
+
+		return u.ChangeStatus(status)
+	})
+	if err != nil {
+		return err
+	}
+
+	details := map[string]string{
+		"status": string(status.State),
+		"reason": status.Reason,
+	}
+	if !status.Until.IsZero() {
+		details["until"] = status.Until.UTC().Format(time.RFC3339)
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditUserStatusChanged, cmd.UserUUID, details))
+}
@@ -0,0 +1,78 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+func TestChangeUserStatus_RollsBackOnAuditFailure(t *testing.T) {
+	ctx := context.Background()
+	users, outbox := mocks.NewMockUserRepositoryWithOutbox()
+	audit := &failingAuditLog{AuditLogRepository: mocks.NewMockAuditLogRepository()}
+	h := command.NewChangeUserStatusHandler(
+		users, audit, mocks.NewMockUnitOfWork(users),
+		slogdiscard.NewDiscardLogger(), metrics.NoOp{},
+	)
+
+	user := auth.MustNewUser(gofakeit.UUID(), gofakeit.Email(), "correct horse battery staple", passhash.NewDefaultHasher())
+	require.NoError(t, users.Save(ctx, user))
+
+	cmd := command.ChangeUserStatus{
+		UserUUID:  user.UUID,
+		Status:    string(auth.AccountDisabled),
+		Reason:    "spam",
+		ChangedBy: gofakeit.UUID(),
+	}
+
+	audit.err = errors.New("audit log is down")
+	require.ErrorIs(t, h.Handle(ctx, cmd), audit.err)
+
+	stored, err := users.User(ctx, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, auth.AccountActive, stored.Status.State)
+	require.Equal(t, []auth.EventType{auth.EventUserRegistered}, outboxEventTypes(t, outbox))
+
+	audit.err = nil
+	require.NoError(t, h.Handle(ctx, cmd))
+
+	stored, err = users.User(ctx, user.UUID)
+	require.NoError(t, err)
+	require.Equal(t, auth.AccountDisabled, stored.Status.State)
+	require.Equal(t, []auth.EventType{auth.EventUserRegistered, auth.EventUserStatusChanged}, outboxEventTypes(t, outbox))
+}
+
+type failingAuditLog struct {
+	auth.AuditLogRepository
+	err error
+}
+
+func (r *failingAuditLog) Append(ctx context.Context, e *auth.AuditEvent) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.AuditLogRepository.Append(ctx, e)
+}
+
+// outboxEventTypes peeks at the outbox without relaying it.
+func outboxEventTypes(t *testing.T, outbox auth.OutboxRepository) []auth.EventType {
+	var types []auth.EventType
+	_, err := outbox.RelayOutbox(context.Background(), 1000, func(_ context.Context, messages []*auth.OutboxMessage) []int64 {
+		for _, m := range messages {
+			types = append(types, m.Type)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return types
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type CreateInvitation struct {
+	UUID      string
+	Code      string
+	CreatedBy string
+	Roles     []string
+	MaxUses   int
+	ExpiresAt time.Time
+}
+
+// String hides the code from the logging decorator.
+func (c CreateInvitation) String() string {
+	return fmt.Sprintf(
+		"{UUID:%s CreatedBy:%s Roles:%v MaxUses:%d ExpiresAt:%s}",
+		c.UUID, c.CreatedBy, c.Roles, c.MaxUses, c.ExpiresAt,
+	)
+}
+
+type CreateInvitationHandler decorator.CommandHandler[CreateInvitation]
+
+type createInvitationHandler struct {
+	invitations auth.InvitationsRepository
+	audit       auth.AuditLogRepository
+}
+
+func NewCreateInvitationHandler(
+	invitations auth.InvitationsRepository,
+	audit auth.AuditLogRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) CreateInvitationHandler {
+	if invitations == nil {
+		panic("invitations repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[CreateInvitation](
+		&createInvitationHandler{invitations: invitations, audit: audit},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h createInvitationHandler) Handle(ctx context.Context, cmd CreateInvitation) error {
+	roles := make([]auth.Role, len(cmd.Roles))
+	for i, r := range cmd.Roles {
+		role, err := auth.NewRole(r)
+		if err != nil {
+			return err
+		}
+		roles[i] = role
+	}
+
+	invitation, err := auth.NewInvitation(
+		cmd.UUID,
+		cmd.Code,
+		cmd.CreatedBy,
+		roles,
+		cmd.MaxUses,
+		cmd.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = h.invitations.Save(ctx, invitation); err != nil {
+		return err
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditInvitationCreated, "", map[string]string{
+		"invitation": invitation.UUID,
+		"roles":      joinRoles(invitation.Roles),
+		"maxUses":    strconv.Itoa(invitation.MaxUses),
+	}))
+}
@@ -0,0 +1,101 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type CreatePersonalAccessToken struct {
+	UUID      string
+	UserUUID  string
+	Name      string
+	Secret    string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// String hides the secret from the logging decorator.
+func (c CreatePersonalAccessToken) String() string {
+	return fmt.Sprintf(
+		"{UUID:%s UserUUID:%s Name:%s Scopes:%v ExpiresAt:%s}",
+		c.UUID, c.UserUUID, c.Name, c.Scopes, c.ExpiresAt,
+	)
+}
+
+type CreatePersonalAccessTokenHandler decorator.CommandHandler[CreatePersonalAccessToken]
+
+type createPersonalAccessTokenHandler struct {
+	users  auth.UsersRepository
+	tokens auth.PersonalAccessTokensRepository
+	audit  auth.AuditLogRepository
+}
+
+func NewCreatePersonalAccessTokenHandler(
+	users auth.UsersRepository,
+	tokens auth.PersonalAccessTokensRepository,
+	audit auth.AuditLogRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) CreatePersonalAccessTokenHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	if tokens == nil {
+		panic("personal access tokens repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[CreatePersonalAccessToken](
+		&createPersonalAccessTokenHandler{users: users, tokens: tokens, audit: audit},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h createPersonalAccessTokenHandler) Handle(ctx context.Context, cmd CreatePersonalAccessToken) error {
+	if _, err := h.users.User(ctx, cmd.UserUUID); err != nil {
+		return err
+	}
+
+	scopes := make([]auth.Scope, len(cmd.Scopes))
+	for i, s := range cmd.Scopes {
+		scope, err := auth.NewScope(s)
+		if err != nil {
+			return err
+		}
+		scopes[i] = scope
+	}
+
+	token, err := auth.NewPersonalAccessToken(
+		cmd.UUID,
+		cmd.UserUUID,
+		cmd.Name,
+		cmd.Secret,
+		scopes,
+		cmd.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = h.tokens.Save(ctx, token); err != nil {
+		return err
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditTokenCreated, cmd.UserUUID, map[string]string{
+		"token":  token.UUID,
+		"name":   token.Name,
+		"scopes": strings.Join(cmd.Scopes, ","),
+	}))
+}
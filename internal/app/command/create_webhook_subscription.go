@@ -0,0 +1,84 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type CreateWebhookSubscription struct {
+	UUID       string
+	URL        string
+	EventTypes []string
+	Secret     string
+	CreatedBy  string
+}
+
+// String hides the secret from the logging decorator.
+func (c CreateWebhookSubscription) String() string {
+	return fmt.Sprintf(
+		"{UUID:%s URL:%s EventTypes:%v CreatedBy:%s}",
+		c.UUID, c.URL, c.EventTypes, c.CreatedBy,
+	)
+}
+
+type CreateWebhookSubscriptionHandler decorator.CommandHandler[CreateWebhookSubscription]
+
+type createWebhookSubscriptionHandler struct {
+	webhooks auth.WebhooksRepository
+	audit    auth.AuditLogRepository
+}
+
+func NewCreateWebhookSubscriptionHandler(
+	webhooks auth.WebhooksRepository,
+	audit auth.AuditLogRepository,
+	uow decorator.UnitOfWork,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) CreateWebhookSubscriptionHandler {
+	if webhooks == nil {
+		panic("webhooks repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyTransactionalCommandDecorators[CreateWebhookSubscription](
+		&createWebhookSubscriptionHandler{webhooks: webhooks, audit: audit},
+		uow,
+		logger,
+		metricsClient,
+	)
+}
+
+func (h createWebhookSubscriptionHandler) Handle(ctx context.Context, cmd CreateWebhookSubscription) error {
+	eventTypes := make([]auth.EventType, len(cmd.EventTypes))
+	for i, t := range cmd.EventTypes {
+		eventType, err := auth.NewEventType(t)
+		if err != nil {
+			return err
+		}
+		eventTypes[i] = eventType
+	}
+
+	s, err := auth.NewWebhookSubscription(cmd.UUID, cmd.URL, eventTypes, cmd.Secret, cmd.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	if err = h.webhooks.SaveSubscription(ctx, s); err != nil {
+		return err
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditWebhookCreated, "", map[string]string{
+		"webhook":    s.UUID,
+		"url":        s.URL,
+		"eventTypes": strings.Join(cmd.EventTypes, ","),
+	}))
+}
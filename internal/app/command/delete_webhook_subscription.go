@@ -0,0 +1,56 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// DeleteWebhookSubscription stops the deliveries to the subscription and
+// forgets its delivery log.
+type DeleteWebhookSubscription struct {
+	UUID string
+}
+
+type DeleteWebhookSubscriptionHandler decorator.CommandHandler[DeleteWebhookSubscription]
+
+type deleteWebhookSubscriptionHandler struct {
+	webhooks auth.WebhooksRepository
+	audit    auth.AuditLogRepository
+}
+
+func NewDeleteWebhookSubscriptionHandler(
+	webhooks auth.WebhooksRepository,
+	audit auth.AuditLogRepository,
+	uow decorator.UnitOfWork,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) DeleteWebhookSubscriptionHandler {
+	if webhooks == nil {
+		panic("webhooks repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyTransactionalCommandDecorators[DeleteWebhookSubscription](
+		&deleteWebhookSubscriptionHandler{webhooks: webhooks, audit: audit},
+		uow,
+		logger,
+		metricsClient,
+	)
+}
+
+func (h deleteWebhookSubscriptionHandler) Handle(ctx context.Context, cmd DeleteWebhookSubscription) error {
+	if err := h.webhooks.DeleteSubscription(ctx, cmd.UUID); err != nil {
+		return err
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditWebhookDeleted, "", map[string]string{
+		"webhook": cmd.UUID,
+	}))
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+const DefaultWebhookBatchSize = 20
+
+// webhookDeliveryLease is how long a claimed delivery is hidden from other
+// replicas. It must exceed the timeout of the sender.
+const webhookDeliveryLease = time.Minute
+
+// DeliverWebhooks sends the due webhook deliveries until none are left.
+type DeliverWebhooks struct {
+	BatchSize int
+}
+
+type DeliverWebhooksHandler decorator.CommandHandler[DeliverWebhooks]
+
+type deliverWebhooksHandler struct {
+	webhooks auth.WebhooksRepository
+	sender   WebhookSender
+	policy   auth.WebhookRetryPolicy
+}
+
+func NewDeliverWebhooksHandler(
+	webhooks auth.WebhooksRepository,
+	sender WebhookSender,
+	policy auth.WebhookRetryPolicy,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) DeliverWebhooksHandler {
+	if webhooks == nil {
+		panic("webhooks repository is nil")
+	}
+
+	if sender == nil {
+		panic("webhook sender is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[DeliverWebhooks](
+		&deliverWebhooksHandler{webhooks: webhooks, sender: sender, policy: policy},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h deliverWebhooksHandler) Handle(ctx context.Context, cmd DeliverWebhooks) error {
+	batchSize := cmd.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultWebhookBatchSize
+	}
+
+	for {
+		deliveries, err := h.webhooks.ClaimDueDeliveries(ctx, time.Now(), webhookDeliveryLease, batchSize)
+		if err != nil {
+			return err
+		}
+
+		if err = h.deliver(ctx, deliveries); err != nil {
+			return err
+		}
+
+		if len(deliveries) < batchSize {
+			return nil
+		}
+	}
+}
+
+// deliver sends the deliveries at once.
+func (h deliverWebhooksHandler) deliver(ctx context.Context, deliveries []*auth.WebhookDelivery) error {
+	subscriptions := make(map[string]*auth.WebhookSubscription)
+	for _, d := range deliveries {
+		if _, ok := subscriptions[d.SubscriptionUUID]; ok {
+			continue
+		}
+
+		s, err := h.webhooks.Subscription(ctx, d.SubscriptionUUID)
+		if errors.Is(err, auth.ErrWebhookSubscriptionNotFound) {
+			// Deleted meanwhile, together with the delivery.
+			continue
+		} else if err != nil {
+			return err
+		}
+		subscriptions[s.UUID] = s
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(deliveries))
+	for i, d := range deliveries {
+		s, ok := subscriptions[d.SubscriptionUUID]
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = h.send(ctx, s, d)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (h deliverWebhooksHandler) send(ctx context.Context, s *auth.WebhookSubscription, d *auth.WebhookDelivery) error {
+	statusCode, sendErr := h.sender.Send(ctx, s, d)
+	now := time.Now()
+
+	err := h.webhooks.UpdateDelivery(ctx, d.UUID, func(_ context.Context, d *auth.WebhookDelivery) error {
+		if sendErr != nil {
+			d.Fail(statusCode, sendErr, now, h.policy)
+		} else {
+			d.Succeed(statusCode, now)
+		}
+		return nil
+	})
+	if errors.Is(err, auth.ErrWebhookDeliveryNotFound) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,168 @@
+package command_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+func TestDeliverWebhooks(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{t: t, secret: "whsec_test", status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	webhooks := mocks.NewMockWebhooksRepository()
+	policy := auth.WebhookRetryPolicy{MaxAttempts: 2, BaseDelay: 0, MaxDelay: 0}
+	deliver := command.NewDeliverWebhooksHandler(
+		webhooks, infra.NewHTTPWebhookSender(server.Client()), policy,
+		slogdiscard.NewDiscardLogger(), metrics.NoOp{},
+	)
+	redeliver := command.NewRedeliverWebhookHandler(webhooks, slogdiscard.NewDiscardLogger(), metrics.NoOp{})
+
+	subscription, err := auth.NewWebhookSubscription(
+		gofakeit.UUID(), server.URL, []auth.EventType{auth.EventUserRegistered}, receiver.secret, gofakeit.UUID(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, webhooks.SaveSubscription(ctx, subscription))
+
+	m, err := auth.NewOutboxMessage(auth.UserRegistered{UserUUID: gofakeit.UUID(), Email: gofakeit.Email()})
+	require.NoError(t, err)
+	m.ID = 1
+	delivery := auth.NewWebhookDelivery(gofakeit.UUID(), subscription, m)
+	require.NoError(t, webhooks.SaveDeliveries(ctx, []*auth.WebhookDelivery{delivery}))
+
+	require.NoError(t, deliver.Handle(ctx, command.DeliverWebhooks{}))
+	d := webhookDelivery(t, webhooks, subscription.UUID)
+	require.Equal(t, auth.WebhookDeliveryPending, d.State)
+	require.Equal(t, 1, d.Attempts)
+	require.Equal(t, http.StatusInternalServerError, d.LastStatusCode)
+	require.NotEmpty(t, d.LastError)
+
+	require.NoError(t, deliver.Handle(ctx, command.DeliverWebhooks{}))
+	d = webhookDelivery(t, webhooks, subscription.UUID)
+	require.Equal(t, auth.WebhookDeliveryDead, d.State)
+	require.Equal(t, 2, d.Attempts)
+
+	// Dead deliveries are left alone.
+	require.NoError(t, deliver.Handle(ctx, command.DeliverWebhooks{}))
+	require.Equal(t, 2, receiver.count())
+
+	receiver.setStatus(http.StatusNoContent)
+	err = redeliver.Handle(ctx, command.RedeliverWebhook{SubscriptionUUID: gofakeit.UUID(), DeliveryUUID: d.UUID})
+	require.ErrorIs(t, err, auth.ErrWebhookDeliveryNotFound)
+	require.NoError(t, redeliver.Handle(ctx, command.RedeliverWebhook{
+		SubscriptionUUID: subscription.UUID,
+		DeliveryUUID:     d.UUID,
+	}))
+
+	require.NoError(t, deliver.Handle(ctx, command.DeliverWebhooks{}))
+	d = webhookDelivery(t, webhooks, subscription.UUID)
+	require.Equal(t, auth.WebhookDeliverySucceeded, d.State)
+	require.Equal(t, 1, d.Attempts)
+	require.Equal(t, http.StatusNoContent, d.LastStatusCode)
+	require.Empty(t, d.LastError)
+	require.True(t, d.NextAttemptAt.IsZero())
+	require.Equal(t, 3, receiver.count())
+}
+
+func TestDeliverWebhooks_Backoff(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	webhooks := mocks.NewMockWebhooksRepository()
+	deliver := command.NewDeliverWebhooksHandler(
+		webhooks, infra.NewHTTPWebhookSender(server.Client()), auth.DefaultWebhookRetryPolicy,
+		slogdiscard.NewDiscardLogger(), metrics.NoOp{},
+	)
+
+	subscription, err := auth.NewWebhookSubscription(
+		gofakeit.UUID(), server.URL, []auth.EventType{auth.EventUserDeleted}, "whsec_test", gofakeit.UUID(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, webhooks.SaveSubscription(ctx, subscription))
+
+	m, err := auth.NewOutboxMessage(auth.UserDeleted{UserUUID: gofakeit.UUID()})
+	require.NoError(t, err)
+	require.NoError(t, webhooks.SaveDeliveries(ctx, []*auth.WebhookDelivery{
+		auth.NewWebhookDelivery(gofakeit.UUID(), subscription, m),
+	}))
+
+	before := time.Now()
+	require.NoError(t, deliver.Handle(ctx, command.DeliverWebhooks{}))
+	require.NoError(t, deliver.Handle(ctx, command.DeliverWebhooks{}))
+
+	d := webhookDelivery(t, webhooks, subscription.UUID)
+	require.Equal(t, auth.WebhookDeliveryPending, d.State)
+	require.Equal(t, 1, d.Attempts)
+	require.WithinDuration(t, before.Add(auth.DefaultWebhookRetryPolicy.BaseDelay), d.NextAttemptAt, 5*time.Second)
+}
+
+func webhookDelivery(t *testing.T, webhooks auth.WebhooksRepository, subscriptionUUID string) *auth.WebhookDelivery {
+	t.Helper()
+	deliveries, err := webhooks.Deliveries(context.Background(), subscriptionUUID, "", auth.MaxWebhookDeliveriesLimit)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	return deliveries[0]
+}
+
+// webhookReceiver checks requests the way a subscriber would.
+type webhookReceiver struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	status   int
+	requests int
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	assert.NoError(rcv.t, err)
+
+	err = auth.VerifyWebhookSignature(rcv.secret, r.Header.Get("X-Webhook-Signature"), body, time.Now(), time.Minute)
+	assert.NoError(rcv.t, err)
+
+	var envelope struct {
+		ID   int64  `json:"id"`
+		Type string `json:"type"`
+	}
+	assert.NoError(rcv.t, json.Unmarshal(body, &envelope))
+	assert.Equal(rcv.t, r.Header.Get("X-Event-Type"), envelope.Type)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests++
+	w.WriteHeader(rcv.status)
+}
+
+func (rcv *webhookReceiver) setStatus(status int) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.status = status
+}
+
+func (rcv *webhookReceiver) count() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return rcv.requests
+}
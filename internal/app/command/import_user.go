@@ -0,0 +1,102 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// ImportUser creates a user moved over from another system with its password
+// hash. With DryRun set it only reports whether the user could be imported.
+type ImportUser struct {
+	UUID      string
+	Email     string
+	Passhash  []byte
+	CreatedAt time.Time
+
+	DryRun bool
+}
+
+// String hides the password hash from the logging decorator.
+func (c ImportUser) String() string {
+	return fmt.Sprintf("{UUID:%s Email:%s CreatedAt:%s DryRun:%t}", c.UUID, c.Email, c.CreatedAt, c.DryRun)
+}
+
+type ImportUserHandler decorator.CommandHandler[ImportUser]
+
+type importUserHandler struct {
+	users  auth.UsersRepository
+	audit  auth.AuditLogRepository
+	hasher auth.PasswordHasher
+}
+
+func NewImportUserHandler(
+	users auth.UsersRepository,
+	audit auth.AuditLogRepository,
+	hasher auth.PasswordHasher,
+	uow decorator.UnitOfWork,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) ImportUserHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	if hasher == nil {
+		panic("password hasher is nil")
+	}
+
+	return decorator.ApplyTransactionalCommandDecorators[ImportUser](
+		&importUserHandler{users: users, audit: audit, hasher: hasher},
+		uow,
+		logger,
+		metricsClient,
+	)
+}
+
+func (h importUserHandler) Handle(ctx context.Context, cmd ImportUser) error {
+	user, err := auth.NewImportedUser(cmd.UUID, cmd.Email, cmd.Passhash, cmd.CreatedAt, h.hasher)
+	if err != nil {
+		return err
+	}
+
+	if cmd.DryRun {
+		return h.checkNotExists(ctx, user)
+	}
+
+	if err = h.users.Save(ctx, user); err != nil {
+		return err
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditUserImported, user.UUID, map[string]string{
+		"email": user.Email.String(),
+	}))
+}
+
+func (h importUserHandler) checkNotExists(ctx context.Context, user *auth.User) error {
+	_, err := h.users.User(ctx, user.UUID)
+	if err == nil {
+		return auth.ErrUserAlreadyExists
+	} else if !errors.As(err, &auth.UserNotFound{}) {
+		return err
+	}
+
+	_, err = h.users.UserByEmail(ctx, user.Email)
+	if err == nil {
+		return auth.ErrUserAlreadyExists
+	} else if !errors.As(err, &auth.UserEmailNotFound{}) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+func TestImportUser_RollsBackOnAuditFailure(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepository()
+	audit := &failingAuditLog{AuditLogRepository: mocks.NewMockAuditLogRepository(), err: errors.New("audit log is down")}
+	hasher := passhash.NewDefaultHasher()
+	h := command.NewImportUserHandler(
+		users, audit, hasher, mocks.NewMockUnitOfWork(users),
+		slogdiscard.NewDiscardLogger(), metrics.NoOp{},
+	)
+
+	hash, err := hasher.Hash("correct horse battery staple")
+	require.NoError(t, err)
+
+	cmd := command.ImportUser{
+		UUID:      gofakeit.UUID(),
+		Email:     gofakeit.Email(),
+		Passhash:  hash,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+	require.ErrorIs(t, h.Handle(ctx, cmd), audit.err)
+
+	_, err = users.User(ctx, cmd.UUID)
+	require.ErrorAs(t, err, &auth.UserNotFound{})
+
+	audit.err = nil
+	require.NoError(t, h.Handle(ctx, cmd))
+
+	_, err = users.User(ctx, cmd.UUID)
+	require.NoError(t, err)
+}
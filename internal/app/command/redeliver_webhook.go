@@ -0,0 +1,51 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// RedeliverWebhook sends a delivery of the subscription again, whatever its
+// state, for example once the receiver of a dead delivery is fixed.
+type RedeliverWebhook struct {
+	SubscriptionUUID string
+	DeliveryUUID     string
+}
+
+type RedeliverWebhookHandler decorator.CommandHandler[RedeliverWebhook]
+
+type redeliverWebhookHandler struct {
+	webhooks auth.WebhooksRepository
+}
+
+func NewRedeliverWebhookHandler(
+	webhooks auth.WebhooksRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) RedeliverWebhookHandler {
+	if webhooks == nil {
+		panic("webhooks repository is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[RedeliverWebhook](
+		&redeliverWebhookHandler{webhooks: webhooks},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h redeliverWebhookHandler) Handle(ctx context.Context, cmd RedeliverWebhook) error {
+	return h.webhooks.UpdateDelivery(ctx, cmd.DeliveryUUID, func(_ context.Context, d *auth.WebhookDelivery) error {
+		if d.SubscriptionUUID != cmd.SubscriptionUUID {
+			return auth.ErrWebhookDeliveryNotFound
+		}
+
+		d.Redeliver(time.Now())
+		return nil
+	})
+}
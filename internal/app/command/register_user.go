@@ -2,9 +2,14 @@ package command
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/executor"
 	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
 )
 
@@ -12,16 +17,48 @@ type RegisterUser struct {
 	UUID     string
 	Email    string
 	Password string
+
+	// InvitationCode is optional unless the registration policy is
+	// invite-only.
+	InvitationCode string
+}
+
+// String hides the password and the invitation code from the logging
+// decorator.
+func (c RegisterUser) String() string {
+	return fmt.Sprintf("{UUID:%s Email:%s}", c.UUID, c.Email)
 }
 
 type RegisterUserHandler decorator.CommandHandler[RegisterUser]
 
 type registerUserHandler struct {
-	users auth.UsersRepository
+	users       auth.UsersRepository
+	invitations auth.InvitationsRepository
+	audit       auth.AuditLogRepository
+	uow         decorator.UnitOfWork
+	mailer      Mailer
+	hasher      auth.PasswordHasher
+	hashing     *executor.Executor
+	logger      *slog.Logger
+
+	passwordPolicy        auth.PasswordPolicy
+	registrationPolicy    auth.RegistrationPolicy
+	concealExistingEmails bool
 }
 
+// NewRegisterUserHandler with concealExistingEmails set succeeds for taken
+// emails and mails their owner instead.
 func NewRegisterUserHandler(
 	users auth.UsersRepository,
+	invitations auth.InvitationsRepository,
+	audit auth.AuditLogRepository,
+	uow decorator.UnitOfWork,
+	mailer Mailer,
+	hasher auth.PasswordHasher,
+	hashing *executor.Executor,
+	passwordPolicy auth.PasswordPolicy,
+	registrationPolicy auth.RegistrationPolicy,
+	concealExistingEmails bool,
 
 	logger *slog.Logger,
 	metricsClient decorator.MetricsClient,
@@ -30,18 +67,154 @@ func NewRegisterUserHandler(
 		panic("users repository is nil")
 	}
 
+	if invitations == nil {
+		panic("invitations repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	if uow == nil {
+		panic("unit of work is nil")
+	}
+
+	if mailer == nil {
+		panic("mailer is nil")
+	}
+
+	if hasher == nil {
+		panic("password hasher is nil")
+	}
+
+	if hashing == nil {
+		panic("hashing executor is nil")
+	}
+
 	return decorator.ApplyCommandDecorators[RegisterUser](
-		&registerUserHandler{users: users},
+		&registerUserHandler{
+			users:                 users,
+			invitations:           invitations,
+			audit:                 audit,
+			uow:                   uow,
+			mailer:                mailer,
+			hasher:                hasher,
+			hashing:               hashing,
+			logger:                logger,
+			passwordPolicy:        passwordPolicy,
+			registrationPolicy:    registrationPolicy,
+			concealExistingEmails: concealExistingEmails,
+		},
 		logger,
 		metricsClient,
 	)
 }
 
 func (h registerUserHandler) Handle(ctx context.Context, cmd RegisterUser) error {
-	user, err := auth.NewUser(cmd.UUID, cmd.Email, cmd.Password)
+	email, err := auth.NewEmail(cmd.Email)
+	if err != nil {
+		return err
+	}
+
+	var invitation *auth.Invitation
+	if cmd.InvitationCode != "" {
+		invitation, err = h.invitations.InvitationByCodeHash(ctx, auth.HashInvitationCode(cmd.InvitationCode))
+		if errors.Is(err, auth.ErrInvitationNotFound) {
+			return auth.RegistrationForbiddenError{Code: auth.RegistrationForbiddenInvitationInvalid}
+		} else if err != nil {
+			return err
+		}
+		err = h.registrationPolicy.CheckInvited(email)
+	} else {
+		err = h.registrationPolicy.Check(email)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = h.passwordPolicy.Validate(cmd.Password, cmd.Email); err != nil {
+		return err
+	}
+
+	user, err := executor.Call(ctx, h.hashing, func() (*auth.User, error) {
+		return auth.NewUser(cmd.UUID, cmd.Email, cmd.Password, h.hasher)
+	})
 	if err != nil {
 		return err
 	}
 
-	return h.users.Save(ctx, user)
+	err = h.uow.Do(ctx, func(ctx context.Context) error {
+		return h.save(ctx, user, invitation)
+	})
+	if errors.Is(err, auth.ErrUserAlreadyExists) && h.concealExistingEmails {
+		go h.notifyExistingOwner(context.WithoutCancel(ctx), user.Email)
+		return nil
+	}
+
+	return err
+}
+
+func (h registerUserHandler) save(ctx context.Context, user *auth.User, invitation *auth.Invitation) error {
+	details := map[string]string{"email": user.Email.String()}
+
+	var err error
+	if invitation != nil {
+		details["invitation"] = invitation.UUID
+		err = h.saveInvited(ctx, user, invitation.UUID)
+	} else {
+		err = h.users.Save(ctx, user)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditUserRegistered, user.UUID, details)); err != nil {
+		return err
+	}
+
+	if invitation != nil && len(user.Roles) > 0 {
+		return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditUserRolesGranted, user.UUID, map[string]string{
+			"roles":      joinRoles(user.Roles),
+			"invitation": invitation.UUID,
+		}))
+	}
+
+	return nil
+}
+
+func joinRoles(roles []auth.Role) string {
+	s := make([]string, len(roles))
+	for i, r := range roles {
+		s[i] = string(r)
+	}
+	return strings.Join(s, ",")
+}
+
+func (h registerUserHandler) saveInvited(ctx context.Context, user *auth.User, invitationUUID string) error {
+	return h.invitations.Update(ctx, invitationUUID, func(ctx context.Context, i *auth.Invitation) error {
+		if err := i.Use(time.Now()); err != nil {
+			return err
+		}
+
+		for _, role := range i.Roles {
+			user.GrantRole(role)
+		}
+
+		return h.users.Save(ctx, user)
+	})
+}
+
+// notifyExistingOwner mails nothing if the UUID, not the email, is taken.
+func (h registerUserHandler) notifyExistingOwner(ctx context.Context, email auth.Email) {
+	_, err := h.users.UserByEmail(ctx, email)
+	if errors.As(err, &auth.UserEmailNotFound{}) {
+		return
+	}
+
+	if err == nil {
+		err = h.mailer.SendAccountExists(ctx, email.String())
+	}
+	if err != nil {
+		h.logger.Error("Failed to notify the owner of an existing account", "error", err.Error())
+	}
 }
@@ -0,0 +1,242 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/executor"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+func TestRegisterUser_ConcealExistingEmails(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepository()
+	invitations := mocks.NewMockInvitationsRepository()
+	audit := mocks.NewMockAuditLogRepository()
+	mailer := &recordingMailer{}
+	h := command.NewRegisterUserHandler(
+		users, invitations, audit, mocks.NewMockUnitOfWork(users, invitations, audit), mailer,
+		passhash.NewDefaultHasher(), executor.New("test", 1, 1, metrics.NoOp{}),
+		auth.DefaultPasswordPolicy, auth.DefaultRegistrationPolicy, true,
+		slogdiscard.NewDiscardLogger(), metrics.NoOp{},
+	)
+
+	uuid := gofakeit.UUID()
+	email := gofakeit.Email()
+	require.NoError(t, h.Handle(ctx, command.RegisterUser{UUID: uuid, Email: email, Password: "correct horse battery staple"}))
+	require.Empty(t, mailer.Sent())
+
+	err := h.Handle(ctx, command.RegisterUser{UUID: gofakeit.UUID(), Email: email, Password: "correct horse battery staple"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(mailer.Sent()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{email}, mailer.Sent())
+
+	other := gofakeit.Email()
+	err = h.Handle(ctx, command.RegisterUser{UUID: uuid, Email: other, Password: "correct horse battery staple"})
+	require.NoError(t, err)
+
+	_, err = users.UserByEmail(ctx, auth.MustNewEmail(other))
+	require.ErrorAs(t, err, &auth.UserEmailNotFound{})
+	require.Never(t, func() bool {
+		return len(mailer.Sent()) > 1
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestRegisterUser_AllowedDomains(t *testing.T) {
+	ctx := context.Background()
+	policy, err := auth.NewRegistrationPolicy(auth.RegistrationDomains, []string{"bmstu.ru", "student.bmstu.ru"})
+	require.NoError(t, err)
+	h := newRegisterUserHandler(mocks.NewMockUserRepository(), mocks.NewMockInvitationsRepository(), policy)
+
+	err = h.Handle(ctx, command.RegisterUser{UUID: gofakeit.UUID(), Email: "ivan@BMSTU.ru", Password: "correct horse battery staple"})
+	require.NoError(t, err)
+
+	err = h.Handle(ctx, command.RegisterUser{UUID: gofakeit.UUID(), Email: "ivan@student.bmstu.ru", Password: "correct horse battery staple"})
+	require.NoError(t, err)
+
+	err = h.Handle(ctx, command.RegisterUser{UUID: gofakeit.UUID(), Email: "ivan@mail.bmstu.ru", Password: "correct horse battery staple"})
+	requireRegistrationForbidden(t, err, auth.RegistrationForbiddenDomainNotAllowed)
+}
+
+func TestRegisterUser_Invitation(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepository()
+	invitations := mocks.NewMockInvitationsRepository()
+	policy, err := auth.NewRegistrationPolicy(auth.RegistrationInvite, nil)
+	require.NoError(t, err)
+	h := newRegisterUserHandler(users, invitations, policy)
+
+	code, err := auth.NewInvitationCode()
+	require.NoError(t, err)
+	invitation, err := auth.NewInvitation(gofakeit.UUID(), code, gofakeit.UUID(), []auth.Role{auth.RoleAdmin}, 1, time.Time{})
+	require.NoError(t, err)
+	require.NoError(t, invitations.Save(ctx, invitation))
+
+	err = h.Handle(ctx, command.RegisterUser{UUID: gofakeit.UUID(), Email: gofakeit.Email(), Password: "correct horse battery staple"})
+	requireRegistrationForbidden(t, err, auth.RegistrationForbiddenInvitationRequired)
+
+	err = h.Handle(ctx, command.RegisterUser{
+		UUID: gofakeit.UUID(), Email: gofakeit.Email(), Password: "correct horse battery staple",
+		InvitationCode: "itsreg_inv_unknown",
+	})
+	requireRegistrationForbidden(t, err, auth.RegistrationForbiddenInvitationInvalid)
+
+	uuid := gofakeit.UUID()
+	err = h.Handle(ctx, command.RegisterUser{
+		UUID: uuid, Email: gofakeit.Email(), Password: "correct horse battery staple",
+		InvitationCode: code,
+	})
+	require.NoError(t, err)
+
+	user, err := users.User(ctx, uuid)
+	require.NoError(t, err)
+	require.True(t, user.HasRole(auth.RoleAdmin))
+
+	err = h.Handle(ctx, command.RegisterUser{
+		UUID: gofakeit.UUID(), Email: gofakeit.Email(), Password: "correct horse battery staple",
+		InvitationCode: code,
+	})
+	requireRegistrationForbidden(t, err, auth.RegistrationForbiddenInvitationUsedUp)
+}
+
+func TestRegisterUser_InvitationKeepsAllowedDomains(t *testing.T) {
+	ctx := context.Background()
+	invitations := mocks.NewMockInvitationsRepository()
+	policy, err := auth.NewRegistrationPolicy(auth.RegistrationDomains, []string{"bmstu.ru"})
+	require.NoError(t, err)
+	h := newRegisterUserHandler(mocks.NewMockUserRepository(), invitations, policy)
+
+	code, err := auth.NewInvitationCode()
+	require.NoError(t, err)
+	invitation, err := auth.NewInvitation(gofakeit.UUID(), code, gofakeit.UUID(), nil, 1, time.Time{})
+	require.NoError(t, err)
+	require.NoError(t, invitations.Save(ctx, invitation))
+
+	err = h.Handle(ctx, command.RegisterUser{
+		UUID: gofakeit.UUID(), Email: "ivan@example.com", Password: "correct horse battery staple",
+		InvitationCode: code,
+	})
+	requireRegistrationForbidden(t, err, auth.RegistrationForbiddenDomainNotAllowed)
+
+	err = h.Handle(ctx, command.RegisterUser{
+		UUID: gofakeit.UUID(), Email: "ivan@bmstu.ru", Password: "correct horse battery staple",
+		InvitationCode: code,
+	})
+	require.NoError(t, err)
+}
+
+func TestRegisterUser_InvitationNotUsedOnFailure(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepository()
+	invitations := mocks.NewMockInvitationsRepository()
+	policy, err := auth.NewRegistrationPolicy(auth.RegistrationInvite, nil)
+	require.NoError(t, err)
+	h := newRegisterUserHandler(users, invitations, policy)
+
+	code, err := auth.NewInvitationCode()
+	require.NoError(t, err)
+	invitation, err := auth.NewInvitation(gofakeit.UUID(), code, gofakeit.UUID(), nil, 1, time.Time{})
+	require.NoError(t, err)
+	require.NoError(t, invitations.Save(ctx, invitation))
+
+	existing := auth.MustNewUser(gofakeit.UUID(), gofakeit.Email(), "correct horse battery staple", passhash.NewDefaultHasher())
+	require.NoError(t, users.Save(ctx, existing))
+
+	err = h.Handle(ctx, command.RegisterUser{
+		UUID: existing.UUID, Email: gofakeit.Email(), Password: "correct horse battery staple",
+		InvitationCode: code,
+	})
+	require.ErrorIs(t, err, auth.ErrUserAlreadyExists)
+
+	saved, err := invitations.InvitationByCodeHash(ctx, auth.HashInvitationCode(code))
+	require.NoError(t, err)
+	require.Zero(t, saved.Uses)
+}
+
+func TestRegisterUser_RolledBackIfAuditFails(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepository()
+	invitations := mocks.NewMockInvitationsRepository()
+	errAuditFailed := errors.New("audit log is unavailable")
+	policy, err := auth.NewRegistrationPolicy(auth.RegistrationInvite, nil)
+	require.NoError(t, err)
+	h := command.NewRegisterUserHandler(
+		users, invitations, &failingAuditLog{err: errAuditFailed}, mocks.NewMockUnitOfWork(users, invitations), &recordingMailer{},
+		passhash.NewHasher(passhash.Bcrypt{Cost: 4}), executor.New("test", 1, 1, metrics.NoOp{}),
+		auth.DefaultPasswordPolicy, policy, false,
+		slogdiscard.NewDiscardLogger(), metrics.NoOp{},
+	)
+
+	code, err := auth.NewInvitationCode()
+	require.NoError(t, err)
+	invitation, err := auth.NewInvitation(gofakeit.UUID(), code, gofakeit.UUID(), nil, 1, time.Time{})
+	require.NoError(t, err)
+	require.NoError(t, invitations.Save(ctx, invitation))
+
+	uuid := gofakeit.UUID()
+	err = h.Handle(ctx, command.RegisterUser{
+		UUID: uuid, Email: gofakeit.Email(), Password: "correct horse battery staple",
+		InvitationCode: code,
+	})
+	require.ErrorIs(t, err, errAuditFailed)
+
+	_, err = users.User(ctx, uuid)
+	require.ErrorAs(t, err, &auth.UserNotFound{})
+
+	saved, err := invitations.InvitationByCodeHash(ctx, auth.HashInvitationCode(code))
+	require.NoError(t, err)
+	require.Zero(t, saved.Uses)
+}
+
+func newRegisterUserHandler(
+	users auth.UsersRepository,
+	invitations auth.InvitationsRepository,
+	policy auth.RegistrationPolicy,
+) command.RegisterUserHandler {
+	audit := mocks.NewMockAuditLogRepository()
+	return command.NewRegisterUserHandler(
+		users, invitations, audit, mocks.NewMockUnitOfWork(users, invitations, audit), &recordingMailer{},
+		passhash.NewHasher(passhash.Bcrypt{Cost: 4}), executor.New("test", 1, 1, metrics.NoOp{}),
+		auth.DefaultPasswordPolicy, policy, false,
+		slogdiscard.NewDiscardLogger(), metrics.NoOp{},
+	)
+}
+
+func requireRegistrationForbidden(t *testing.T, err error, code string) {
+	var forbiddenErr auth.RegistrationForbiddenError
+	require.ErrorAs(t, err, &forbiddenErr)
+	require.Equal(t, code, forbiddenErr.Code)
+}
+
+type recordingMailer struct {
+	sync.Mutex
+	sent []string
+}
+
+func (m *recordingMailer) SendAccountExists(_ context.Context, email string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+func (m *recordingMailer) Sent() []string {
+	m.Lock()
+	defer m.Unlock()
+
+	return append([]string(nil), m.sent...)
+}
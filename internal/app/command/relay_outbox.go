@@ -0,0 +1,126 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+const DefaultRelayBatchSize = 100
+
+// RelayOutbox publishes the outbox until it is empty or a message fails.
+type RelayOutbox struct {
+	BatchSize int
+}
+
+type RelayOutboxHandler decorator.CommandHandler[RelayOutbox]
+
+type relayOutboxHandler struct {
+	outbox    auth.OutboxRepository
+	webhooks  auth.WebhooksRepository
+	publisher Publisher
+	logger    *slog.Logger
+}
+
+func NewRelayOutboxHandler(
+	outbox auth.OutboxRepository,
+	webhooks auth.WebhooksRepository,
+	publisher Publisher,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) RelayOutboxHandler {
+	if outbox == nil {
+		panic("outbox repository is nil")
+	}
+
+	if webhooks == nil {
+		panic("webhooks repository is nil")
+	}
+
+	if publisher == nil {
+		panic("publisher is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[RelayOutbox](
+		&relayOutboxHandler{outbox: outbox, webhooks: webhooks, publisher: publisher, logger: logger},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h relayOutboxHandler) Handle(ctx context.Context, cmd RelayOutbox) error {
+	batchSize := cmd.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultRelayBatchSize
+	}
+
+	for {
+		published, err := h.outbox.RelayOutbox(ctx, batchSize, h.publish)
+		if err != nil {
+			return err
+		}
+
+		if published < batchSize {
+			return nil
+		}
+	}
+}
+
+// publish keeps the order of events of a user: once a message of a user
+// fails, the later messages of that user wait for the next relay.
+func (h relayOutboxHandler) publish(ctx context.Context, messages []*auth.OutboxMessage) []int64 {
+	subscriptions, err := h.webhooks.Subscriptions(ctx)
+	if err != nil {
+		h.logger.WarnContext(ctx, "Unable to load webhook subscriptions", "error", err.Error())
+		return nil
+	}
+
+	published := make([]int64, 0, len(messages))
+	failed := make(map[string]bool)
+	for _, m := range messages {
+		if failed[m.AggregateUUID] {
+			continue
+		}
+
+		err = h.publisher.Publish(ctx, m)
+		if err == nil {
+			err = h.queueWebhooks(ctx, subscriptions, m)
+		}
+		if err != nil {
+			h.logger.WarnContext(
+				ctx, "Unable to publish event",
+				"id", m.ID, "type", string(m.Type), "error", err.Error(),
+			)
+			failed[m.AggregateUUID] = true
+			continue
+		}
+
+		published = append(published, m.ID)
+	}
+
+	return published
+}
+
+func (h relayOutboxHandler) queueWebhooks(
+	ctx context.Context,
+	subscriptions []*auth.WebhookSubscription,
+	m *auth.OutboxMessage,
+) error {
+	var deliveries []*auth.WebhookDelivery
+	for _, s := range subscriptions {
+		if s.Subscribed(m.Type) {
+			deliveries = append(deliveries, auth.NewWebhookDelivery(uuid.NewString(), s, m))
+		}
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return h.webhooks.SaveDeliveries(ctx, deliveries)
+}
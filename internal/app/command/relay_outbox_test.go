@@ -0,0 +1,84 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+func TestRelayOutbox(t *testing.T) {
+	ctx := context.Background()
+	users, outbox := mocks.NewMockUserRepositoryWithOutbox()
+	webhooks := mocks.NewMockWebhooksRepository()
+	publisher := mocks.NewFakePublisher()
+	h := command.NewRelayOutboxHandler(outbox, webhooks, publisher, slogdiscard.NewDiscardLogger(), metrics.NoOp{})
+
+	subscription, err := auth.NewWebhookSubscription(
+		gofakeit.UUID(),
+		"https://example.com/hooks",
+		[]auth.EventType{auth.EventUserDeleted},
+		"whsec_test",
+		gofakeit.UUID(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, webhooks.SaveSubscription(ctx, subscription))
+
+	failing := auth.MustNewUser(gofakeit.UUID(), gofakeit.Email(), "correct horse battery staple", passhash.NewDefaultHasher())
+	failing.GrantRole(auth.RoleAdmin)
+	require.NoError(t, users.Save(ctx, failing))
+
+	other := auth.MustNewUser(gofakeit.UUID(), gofakeit.Email(), "correct horse battery staple", passhash.NewDefaultHasher())
+	require.NoError(t, users.Save(ctx, other))
+	require.NoError(t, users.Delete(ctx, other.UUID))
+
+	publisher.Fail = func(m *auth.OutboxMessage) error {
+		if m.AggregateUUID == failing.UUID && m.Type == auth.EventUserRegistered {
+			return errors.New("broker is down")
+		}
+		return nil
+	}
+
+	require.NoError(t, h.Handle(ctx, command.RelayOutbox{BatchSize: 1}))
+	require.NoError(t, h.Handle(ctx, command.RelayOutbox{}))
+	require.Equal(t, []string{
+		other.UUID + " " + string(auth.EventUserRegistered),
+		other.UUID + " " + string(auth.EventUserDeleted),
+	}, publishedEvents(publisher))
+
+	publisher.Fail = nil
+	require.NoError(t, h.Handle(ctx, command.RelayOutbox{}))
+	require.Equal(t, []string{
+		other.UUID + " " + string(auth.EventUserRegistered),
+		other.UUID + " " + string(auth.EventUserDeleted),
+		failing.UUID + " " + string(auth.EventUserRegistered),
+		failing.UUID + " " + string(auth.EventUserRoleGranted),
+	}, publishedEvents(publisher))
+
+	require.NoError(t, h.Handle(ctx, command.RelayOutbox{}))
+	require.Len(t, publisher.Messages(), 4)
+
+	deliveries, err := webhooks.Deliveries(ctx, subscription.UUID, "", auth.MaxWebhookDeliveriesLimit)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, other.UUID, deliveries[0].Event.AggregateUUID)
+	require.Equal(t, auth.EventUserDeleted, deliveries[0].Event.Type)
+	require.Equal(t, auth.WebhookDeliveryPending, deliveries[0].State)
+}
+
+func publishedEvents(p *mocks.FakePublisher) []string {
+	var events []string
+	for _, m := range p.Messages() {
+		events = append(events, m.AggregateUUID+" "+string(m.Type))
+	}
+	return events
+}
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type RevokePersonalAccessToken struct {
+	UserUUID  string
+	TokenUUID string
+}
+
+type RevokePersonalAccessTokenHandler decorator.CommandHandler[RevokePersonalAccessToken]
+
+type revokePersonalAccessTokenHandler struct {
+	tokens auth.PersonalAccessTokensRepository
+	audit  auth.AuditLogRepository
+}
+
+func NewRevokePersonalAccessTokenHandler(
+	tokens auth.PersonalAccessTokensRepository,
+	audit auth.AuditLogRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) RevokePersonalAccessTokenHandler {
+	if tokens == nil {
+		panic("personal access tokens repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[RevokePersonalAccessToken](
+		&revokePersonalAccessTokenHandler{tokens: tokens, audit: audit},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h revokePersonalAccessTokenHandler) Handle(ctx context.Context, cmd RevokePersonalAccessToken) error {
+	token, err := h.tokens.PersonalAccessToken(ctx, cmd.TokenUUID)
+	if err != nil {
+		return err
+	}
+
+	// Someone else's token is reported as missing.
+	if token.UserUUID != cmd.UserUUID {
+		return auth.PersonalAccessTokenNotFound{TokenUUID: cmd.TokenUUID}
+	}
+
+	if err = h.tokens.Delete(ctx, cmd.TokenUUID); err != nil {
+		return err
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditTokenRevoked, cmd.UserUUID, map[string]string{
+		"token": cmd.TokenUUID,
+	}))
+}
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type RevokeSession struct {
+	UserUUID    string
+	SessionUUID string
+}
+
+type RevokeSessionHandler decorator.CommandHandler[RevokeSession]
+
+type revokeSessionHandler struct {
+	sessions auth.SessionsRepository
+	audit    auth.AuditLogRepository
+}
+
+func NewRevokeSessionHandler(
+	sessions auth.SessionsRepository,
+	audit auth.AuditLogRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) RevokeSessionHandler {
+	if sessions == nil {
+		panic("sessions repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[RevokeSession](
+		&revokeSessionHandler{sessions: sessions, audit: audit},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h revokeSessionHandler) Handle(ctx context.Context, cmd RevokeSession) error {
+	session, err := h.sessions.Session(ctx, cmd.SessionUUID)
+	if err != nil {
+		return err
+	}
+
+	if session.UserUUID != cmd.UserUUID {
+		return auth.SessionNotFound{SessionUUID: cmd.SessionUUID}
+	}
+
+	if err = h.sessions.Delete(ctx, cmd.SessionUUID); err != nil {
+		return err
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditSessionRevoked, cmd.UserUUID, map[string]string{
+		"session": cmd.SessionUUID,
+	}))
+}
@@ -0,0 +1,67 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// RevokeUserSessions logs the user out everywhere except ExceptSessionUUID.
+type RevokeUserSessions struct {
+	UserUUID          string
+	ExceptSessionUUID string
+}
+
+type RevokeUserSessionsHandler decorator.CommandHandler[RevokeUserSessions]
+
+type revokeUserSessionsHandler struct {
+	users    auth.UsersRepository
+	sessions auth.SessionsRepository
+	audit    auth.AuditLogRepository
+}
+
+func NewRevokeUserSessionsHandler(
+	users auth.UsersRepository,
+	sessions auth.SessionsRepository,
+	audit auth.AuditLogRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) RevokeUserSessionsHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	if sessions == nil {
+		panic("sessions repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[RevokeUserSessions](
+		&revokeUserSessionsHandler{users: users, sessions: sessions, audit: audit},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h revokeUserSessionsHandler) Handle(ctx context.Context, cmd RevokeUserSessions) error {
+	if _, err := h.users.User(ctx, cmd.UserUUID); err != nil {
+		return err
+	}
+
+	if err := h.sessions.DeleteUserSessions(ctx, cmd.UserUUID, cmd.ExceptSessionUUID); err != nil {
+		return err
+	}
+
+	var details map[string]string
+	if cmd.ExceptSessionUUID != "" {
+		details = map[string]string{"exceptSession": cmd.ExceptSessionUUID}
+	}
+
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditUserSessionsRevoked, cmd.UserUUID, details))
+}
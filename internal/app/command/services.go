@@ -0,0 +1,27 @@
+package command
+
+import (
+	"context"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// Mailer sends notifications to users. Implementations must not block on
+// delivery.
+type Mailer interface {
+	// SendAccountExists tells the owner of email that someone tried to
+	// register another account with it.
+	SendAccountExists(ctx context.Context, email string) error
+}
+
+// Publisher delivers outbox messages to other services. Publish returns
+// once the message is accepted.
+type Publisher interface {
+	Publish(ctx context.Context, m *auth.OutboxMessage) error
+}
+
+// WebhookSender returns the status code of the response, or zero if there
+// was none, and an error unless the status is 2xx.
+type WebhookSender interface {
+	Send(ctx context.Context, s *auth.WebhookSubscription, d *auth.WebhookDelivery) (int, error)
+}
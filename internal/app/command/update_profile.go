@@ -0,0 +1,54 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// UpdateProfile changes the profile fields set in Update and leaves the
+// others as they are.
+type UpdateProfile struct {
+	UserUUID string
+	Update   auth.ProfileUpdate
+	// ExpectedVersion, if set, is the version of the user the change was
+	// based on.
+	ExpectedVersion *int64
+}
+
+type UpdateProfileHandler decorator.CommandHandler[UpdateProfile]
+
+type updateProfileHandler struct {
+	users auth.UsersRepository
+}
+
+func NewUpdateProfileHandler(
+	users auth.UsersRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) UpdateProfileHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[UpdateProfile](
+		&updateProfileHandler{users: users},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h updateProfileHandler) Handle(ctx context.Context, cmd UpdateProfile) error {
+	return h.users.Update(ctx, cmd.UserUUID, func(_ context.Context, u *auth.User) error {
+		if cmd.ExpectedVersion != nil {
+			if err := u.CheckVersion(*cmd.ExpectedVersion); err != nil {
+				return err
+			}
+		}
+
+		return u.UpdateProfile(cmd.Update)
+	})
+}
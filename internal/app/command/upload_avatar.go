@@ -0,0 +1,83 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// UploadAvatar replaces the avatar of the user with Image, which is
+// re-encoded to the canonical size and format.
+type UploadAvatar struct {
+	UserUUID string
+	Image    []byte
+}
+
+type UploadAvatarHandler decorator.CommandHandler[UploadAvatar]
+
+type uploadAvatarHandler struct {
+	users   auth.UsersRepository
+	storage auth.BlobStorage
+	logger  *slog.Logger
+}
+
+func NewUploadAvatarHandler(
+	users auth.UsersRepository,
+	storage auth.BlobStorage,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) UploadAvatarHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	if storage == nil {
+		panic("blob storage is nil")
+	}
+
+	return decorator.ApplyCommandDecorators[UploadAvatar](
+		&uploadAvatarHandler{users: users, storage: storage, logger: logger},
+		logger,
+		metricsClient,
+	)
+}
+
+// Handle may leave an orphaned blob, but never a dangling reference.
+func (h uploadAvatarHandler) Handle(ctx context.Context, cmd UploadAvatar) error {
+	avatar, err := auth.NewAvatar(cmd.UserUUID, cmd.Image)
+	if err != nil {
+		return err
+	}
+
+	if err = h.storage.Put(ctx, avatar.Key, avatar.Data, auth.AvatarContentType); err != nil {
+		return err
+	}
+
+	var previous string
+	err = h.users.Update(ctx, cmd.UserUUID, func(_ context.Context, u *auth.User) error {
+		previous = u.SetAvatar(avatar.Key)
+		return nil
+	})
+	if err != nil {
+		h.deleteBlob(ctx, avatar.Key)
+		return err
+	}
+
+	// The same image uploaded again gets the same key.
+	if previous != "" && previous != avatar.Key {
+		h.deleteBlob(ctx, previous)
+	}
+
+	return nil
+}
+
+func (h uploadAvatarHandler) deleteBlob(ctx context.Context, key string) {
+	err := h.storage.Delete(context.WithoutCancel(ctx), key)
+	if err != nil && !errors.Is(err, auth.ErrBlobNotFound) {
+		h.logger.WarnContext(ctx, "Unable to delete avatar", "key", key, "error", err.Error())
+	}
+}
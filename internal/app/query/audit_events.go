@@ -0,0 +1,113 @@
+package query
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// ListAuditEvents selects a page of the audit log, newest first. Zero fields
+// are not filtered on.
+type ListAuditEvents struct {
+	ActorUUID  string
+	TargetUUID string
+	Action     string
+	From       time.Time
+	To         time.Time
+
+	Cursor string
+	Limit  int
+}
+
+type AuditEvent struct {
+	Seq        int64
+	Action     string
+	ActorUUID  string
+	TargetUUID string
+	IP         string
+	UserAgent  string
+	RequestID  string
+	Details    map[string]string
+	CreatedAt  time.Time
+	// Hash is hex encoded.
+	Hash string
+}
+
+type AuditEventsPage struct {
+	Events     []AuditEvent
+	NextCursor string
+}
+
+type ListAuditEventsHandler decorator.QueryHandler[ListAuditEvents, AuditEventsPage]
+
+type listAuditEventsHandler struct {
+	audit auth.AuditLogRepository
+}
+
+func NewListAuditEventsHandler(
+	audit auth.AuditLogRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) ListAuditEventsHandler {
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[ListAuditEvents, AuditEventsPage](
+		listAuditEventsHandler{audit: audit},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h listAuditEventsHandler) Handle(ctx context.Context, query ListAuditEvents) (AuditEventsPage, error) {
+	l, err := auth.NewListAuditEvents(
+		auth.AuditFilter{
+			ActorUUID:  query.ActorUUID,
+			TargetUUID: query.TargetUUID,
+			Action:     auth.AuditAction(query.Action),
+			From:       query.From,
+			To:         query.To,
+		},
+		query.Cursor,
+		query.Limit,
+	)
+	if err != nil {
+		return AuditEventsPage{}, err
+	}
+
+	page, err := h.audit.ListAuditEvents(ctx, l)
+	if err != nil {
+		return AuditEventsPage{}, err
+	}
+
+	res := AuditEventsPage{
+		Events:     make([]AuditEvent, len(page.Events)),
+		NextCursor: page.NextCursor,
+	}
+	for i, e := range page.Events {
+		res.Events[i] = mapAuditEventFromDomain(e)
+	}
+
+	return res, nil
+}
+
+func mapAuditEventFromDomain(e *auth.AuditEvent) AuditEvent {
+	return AuditEvent{
+		Seq:        e.Seq,
+		Action:     string(e.Action),
+		ActorUUID:  e.ActorUUID,
+		TargetUUID: e.TargetUUID,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Details:    e.Details,
+		CreatedAt:  e.CreatedAt,
+		Hash:       hex.EncodeToString(e.Hash),
+	}
+}
@@ -0,0 +1,161 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/jwtauth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type AuthenticateToken struct {
+	Token string
+	IP    string
+}
+
+// String hides the token from the logging decorator.
+func (q AuthenticateToken) String() string {
+	return fmt.Sprintf("{IP:%s}", q.IP)
+}
+
+type AuthenticateTokenHandler decorator.QueryHandler[AuthenticateToken, Principal]
+
+type authenticateTokenHandler struct {
+	users    auth.UsersRepository
+	sessions auth.SessionsRepository
+	tokens   auth.PersonalAccessTokensRepository
+}
+
+func NewAuthenticateTokenHandler(
+	users auth.UsersRepository,
+	sessions auth.SessionsRepository,
+	tokens auth.PersonalAccessTokensRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) AuthenticateTokenHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	if sessions == nil {
+		panic("sessions repository is nil")
+	}
+
+	if tokens == nil {
+		panic("personal access tokens repository is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[AuthenticateToken, Principal](
+		authenticateTokenHandler{users: users, sessions: sessions, tokens: tokens},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h authenticateTokenHandler) Handle(ctx context.Context, query AuthenticateToken) (Principal, error) {
+	var principal Principal
+	var err error
+	if auth.IsPersonalAccessToken(query.Token) {
+		principal, err = h.authenticatePersonalAccessToken(ctx, query)
+	} else {
+		principal, err = h.authenticateAccessToken(ctx, query)
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+
+	user, err := h.users.User(ctx, principal.UserUUID)
+	if errors.As(err, &auth.UserNotFound{}) {
+		return Principal{}, auth.ErrInvalidToken
+	} else if err != nil {
+		return Principal{}, err
+	}
+
+	if err = user.CheckActive(time.Now()); err != nil {
+		return Principal{}, err
+	}
+
+	principal.Roles = mapRolesFromDomain(user.Roles)
+
+	return principal, nil
+}
+
+func (h authenticateTokenHandler) authenticateAccessToken(
+	ctx context.Context,
+	query AuthenticateToken,
+) (Principal, error) {
+	payload, err := jwtauth.ParseAccessToken(query.Token)
+	if err != nil {
+		return Principal{}, auth.ErrInvalidToken
+	}
+
+	// Tokens issued before sessions were introduced are accepted until they
+	// expire, but can not be revoked.
+	if payload.SessionUUID == "" {
+		return Principal{UserUUID: payload.UserUUID}, nil
+	}
+
+	session, err := h.sessions.Session(ctx, payload.SessionUUID)
+	if errors.As(err, &auth.SessionNotFound{}) {
+		return Principal{}, auth.ErrInvalidToken
+	} else if err != nil {
+		return Principal{}, err
+	}
+
+	if session.UserUUID != payload.UserUUID {
+		return Principal{}, auth.ErrInvalidToken
+	}
+
+	now := time.Now()
+	if session.NeedsTouch(now) {
+		err = h.sessions.Update(ctx, session.UUID, func(ctx context.Context, s *auth.Session) error {
+			s.Touch(query.IP, now)
+			return nil
+		})
+		if errors.As(err, &auth.SessionNotFound{}) {
+			return Principal{}, auth.ErrInvalidToken
+		} else if err != nil {
+			return Principal{}, err
+		}
+	}
+
+	return Principal{
+		UserUUID:    payload.UserUUID,
+		SessionUUID: session.UUID,
+	}, nil
+}
+
+func (h authenticateTokenHandler) authenticatePersonalAccessToken(
+	ctx context.Context,
+	query AuthenticateToken,
+) (Principal, error) {
+	token, err := h.tokens.PersonalAccessTokenByHash(ctx, auth.HashPersonalAccessToken(query.Token))
+	if err != nil {
+		return Principal{}, err
+	}
+
+	now := time.Now()
+	if token.IsExpired(now) {
+		return Principal{}, auth.ErrTokenExpired
+	}
+
+	err = h.tokens.Update(ctx, token.UUID, func(ctx context.Context, t *auth.PersonalAccessToken) error {
+		t.MarkUsed(query.IP, now)
+		return nil
+	})
+	if err != nil {
+		return Principal{}, err
+	}
+
+	p := mapPersonalAccessTokenFromDomain(token)
+	return Principal{
+		UserUUID:                token.UserUUID,
+		PersonalAccessTokenUUID: token.UUID,
+		Scopes:                  p.Scopes,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type GetAvatar struct {
+	UserUUID string
+}
+
+type GetAvatarHandler decorator.QueryHandler[GetAvatar, Avatar]
+
+type getAvatarHandler struct {
+	users   auth.UsersRepository
+	storage auth.BlobStorage
+}
+
+func NewGetAvatarHandler(
+	users auth.UsersRepository,
+	storage auth.BlobStorage,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) GetAvatarHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	if storage == nil {
+		panic("blob storage is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[GetAvatar, Avatar](
+		getAvatarHandler{users: users, storage: storage},
+		logger,
+		metricsClient,
+	)
+}
+
+// Handle returns auth.ErrAvatarNotFound if the user has not uploaded an
+// avatar.
+func (h getAvatarHandler) Handle(ctx context.Context, query GetAvatar) (Avatar, error) {
+	user, err := h.users.User(ctx, query.UserUUID)
+	if err != nil {
+		return Avatar{}, err
+	}
+
+	if user.AvatarKey == "" {
+		return Avatar{}, auth.ErrAvatarNotFound
+	}
+
+	data, err := h.storage.Get(ctx, user.AvatarKey)
+	if errors.Is(err, auth.ErrBlobNotFound) {
+		return Avatar{}, auth.ErrAvatarNotFound
+	} else if err != nil {
+		return Avatar{}, err
+	}
+
+	return Avatar{
+		Key:         user.AvatarKey,
+		Data:        data,
+		ContentType: auth.AvatarContentType,
+	}, nil
+}
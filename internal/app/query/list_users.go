@@ -0,0 +1,95 @@
+package query
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// ListUsers selects a page of users for admins. Zero fields are not
+// filtered on.
+type ListUsers struct {
+	EmailContains string
+	Role          string
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	// Status is "active", "suspended" or "disabled".
+	Status string
+
+	// Sort is "createdAt" by default or "updatedAt".
+	Sort       string
+	Descending bool
+
+	Cursor    string
+	Limit     int
+	WithTotal bool
+}
+
+type UsersPage struct {
+	Users      []User
+	NextCursor string
+	// Total is -1 unless it is requested.
+	Total int
+}
+
+type ListUsersHandler decorator.QueryHandler[ListUsers, UsersPage]
+
+type listUsersHandler struct {
+	users auth.UsersRepository
+}
+
+func NewListUsersHandler(
+	users auth.UsersRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) ListUsersHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[ListUsers, UsersPage](
+		listUsersHandler{users: users},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h listUsersHandler) Handle(ctx context.Context, query ListUsers) (UsersPage, error) {
+	l, err := auth.NewListUsers(
+		auth.UsersFilter{
+			EmailContains: query.EmailContains,
+			Role:          auth.Role(query.Role),
+			CreatedFrom:   query.CreatedFrom,
+			CreatedTo:     query.CreatedTo,
+			Status:        auth.AccountState(query.Status),
+		},
+		auth.UsersSort(query.Sort),
+		query.Descending,
+		query.Cursor,
+		query.Limit,
+		query.WithTotal,
+	)
+	if err != nil {
+		return UsersPage{}, err
+	}
+
+	page, err := h.users.ListUsers(ctx, l)
+	if err != nil {
+		return UsersPage{}, err
+	}
+
+	users := make([]User, len(page.Users))
+	for i, u := range page.Users {
+		users[i] = mapUserFromDomain(u)
+	}
+
+	return UsersPage{
+		Users:      users,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}, nil
+}
@@ -0,0 +1,89 @@
+package query_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+func TestListUsers(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepository()
+	h := query.NewListUsersHandler(users, slogdiscard.NewDiscardLogger(), metrics.NoOp{})
+
+	hasher := passhash.NewDefaultHasher()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		domain := "example.com"
+		if i%2 == 0 {
+			domain = "bmstu.ru"
+		}
+
+		u := auth.MustNewUser(
+			fmt.Sprintf("0190a5c2-7b3e-7d4f-9a1b-00000000000%d", i),
+			fmt.Sprintf("user%d@%s", i, domain),
+			"password", hasher,
+		)
+		u.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		if i == 4 {
+			u.GrantRole(auth.RoleAdmin)
+		}
+		require.NoError(t, users.Save(ctx, u))
+	}
+
+	var (
+		listed []string
+		cursor string
+	)
+	for {
+		page, err := h.Handle(ctx, query.ListUsers{Descending: true, Cursor: cursor, Limit: 2, WithTotal: true})
+		require.NoError(t, err)
+		require.Equal(t, 5, page.Total)
+
+		for _, u := range page.Users {
+			listed = append(listed, u.Email)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	require.Equal(t, []string{
+		"user4@bmstu.ru", "user3@example.com", "user2@bmstu.ru", "user1@example.com", "user0@bmstu.ru",
+	}, listed)
+
+	page, err := h.Handle(ctx, query.ListUsers{EmailContains: "@BMSTU", CreatedFrom: start.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, page.Users, 2)
+	require.Equal(t, -1, page.Total)
+	require.Empty(t, page.NextCursor)
+
+	page, err = h.Handle(ctx, query.ListUsers{Role: string(auth.RoleAdmin)})
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	require.Equal(t, "user4@bmstu.ru", page.Users[0].Email)
+
+	first, err := h.Handle(ctx, query.ListUsers{Limit: 1})
+	require.NoError(t, err)
+
+	_, err = h.Handle(ctx, query.ListUsers{Cursor: first.NextCursor, Sort: string(auth.UsersByUpdatedAt)})
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = h.Handle(ctx, query.ListUsers{Cursor: "garbage"})
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = h.Handle(ctx, query.ListUsers{Limit: auth.MaxUsersPageLimit + 1})
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+}
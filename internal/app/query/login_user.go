@@ -4,24 +4,48 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"maps"
+	"strconv"
+	"time"
 
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/executor"
 	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
 )
 
 type LoginUser struct {
 	Email    string
 	Password string
+
+	SessionUUID string
+	UserAgent   string
+	IP          string
 }
 
 type LoginUserHandler decorator.QueryHandler[LoginUser, User]
 
 type loginUserHandler struct {
-	users auth.UsersRepository
+	users    auth.UsersRepository
+	sessions auth.SessionsRepository
+	attempts auth.LoginAttemptsRepository
+	audit    auth.AuditLogRepository
+	hasher   auth.PasswordHasher
+	hashing  *executor.Executor
+
+	// dummyPasshash is compared against when there is no user.
+	dummyPasshash []byte
+
+	accountPolicy auth.LockoutPolicy
+	ipPolicy      auth.LockoutPolicy
 }
 
 func NewLoginUserHandler(
 	users auth.UsersRepository,
+	sessions auth.SessionsRepository,
+	attempts auth.LoginAttemptsRepository,
+	audit auth.AuditLogRepository,
+	hasher auth.PasswordHasher,
+	hashing *executor.Executor,
 
 	logger *slog.Logger,
 	metricsClient decorator.MetricsClient,
@@ -30,24 +54,230 @@ func NewLoginUserHandler(
 		panic("users repository is nil")
 	}
 
+	if sessions == nil {
+		panic("sessions repository is nil")
+	}
+
+	if attempts == nil {
+		panic("login attempts repository is nil")
+	}
+
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	if hasher == nil {
+		panic("password hasher is nil")
+	}
+
+	if hashing == nil {
+		panic("hashing executor is nil")
+	}
+
+	dummyPasshash, err := hasher.Hash("dummy password")
+	if err != nil {
+		panic(err)
+	}
+
 	return decorator.ApplyQueryDecorators[LoginUser, User](
-		loginUserHandler{users: users},
+		loginUserHandler{
+			users:         users,
+			sessions:      sessions,
+			attempts:      attempts,
+			audit:         audit,
+			hasher:        hasher,
+			hashing:       hashing,
+			dummyPasshash: dummyPasshash,
+			accountPolicy: auth.DefaultAccountLockoutPolicy,
+			ipPolicy:      auth.DefaultIPLockoutPolicy,
+		},
 		logger,
 		metricsClient,
 	)
 }
 
 func (h loginUserHandler) Handle(ctx context.Context, query LoginUser) (User, error) {
-	user, err := h.users.UserByEmail(ctx, query.Email)
-	if errors.As(err, &auth.UserEmailNotFound{}) {
+	// No account can have a malformed email, and telling so leaks nothing.
+	email, err := auth.NewEmail(query.Email)
+	if err != nil {
 		return User{}, auth.ErrInvalidCredentials
+	}
+
+	accountKey := auth.LoginAttemptsKeyForEmail(email)
+	// Clients of unknown IP would otherwise share one counter.
+	ipKey := ""
+	if query.IP != "" {
+		ipKey = auth.LoginAttemptsKeyForIP(query.IP)
+	}
+
+	if err := h.checkLockout(ctx, accountKey, ipKey); err != nil {
+		return User{}, err
+	}
+
+	user, err := h.users.UserByEmail(ctx, email)
+	if errors.As(err, &auth.UserEmailNotFound{}) {
+		err = h.hashing.Do(ctx, func() error {
+			_ = h.hasher.Compare(h.dummyPasshash, query.Password)
+			return nil
+		})
+		if err != nil {
+			return User{}, err
+		}
+		return User{}, h.fail(ctx, accountKey, ipKey, "", email, "unknown_email")
+	} else if err != nil {
+		return User{}, err
+	}
+
+	err = h.hashing.Do(ctx, func() error {
+		return user.PasswordMatch(h.hasher, query.Password)
+	})
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		return User{}, h.fail(ctx, accountKey, ipKey, user.UUID, email, "invalid_password")
 	} else if err != nil {
 		return User{}, err
 	}
 
-	if err = user.PasswordMatch(query.Password); err != nil {
+	if user.NeedsRehash(h.hasher) {
+		passhash, err := executor.Call(ctx, h.hashing, func() ([]byte, error) {
+			return h.hasher.Hash(query.Password)
+		})
+		if err != nil {
+			return User{}, err
+		}
+
+		err = h.users.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+			u.RehashPassword(user.Passhash, passhash)
+			return nil
+		})
+		if err != nil {
+			return User{}, err
+		}
+	}
+
+	if err = h.attempts.Delete(ctx, accountKey); err != nil {
+		return User{}, err
+	}
+
+	// Only those who know the password learn that the account is blocked.
+	var inactiveErr auth.AccountInactiveError
+	if err = user.CheckActive(time.Now()); errors.As(err, &inactiveErr) {
+		if recordErr := h.recordFailure(ctx, user.UUID, email, inactiveErr.Code(), nil); recordErr != nil {
+			return User{}, recordErr
+		}
+		return User{}, err
+	} else if err != nil {
+		return User{}, err
+	}
+
+	session, err := auth.NewSession(query.SessionUUID, user.UUID, query.UserAgent, query.IP)
+	if err != nil {
+		return User{}, err
+	}
+
+	if err = h.sessions.Save(ctx, session); err != nil {
+		return User{}, err
+	}
+
+	event := auth.NewAuditEvent(ctx, auth.AuditLoginSucceeded, user.UUID, map[string]string{
+		"session": session.UUID,
+	})
+	event.ActorUUID = user.UUID
+	if err = h.audit.Append(ctx, event); err != nil {
 		return User{}, err
 	}
 
 	return mapUserFromDomain(user), nil
 }
+
+// fail audits only the first failure of a window and those locking the email
+// out.
+func (h loginUserHandler) fail(
+	ctx context.Context,
+	accountKey, ipKey string,
+	targetUUID string,
+	email auth.Email,
+	reason string,
+) error {
+	failures, err := h.registerFailure(ctx, accountKey, ipKey)
+	if err != nil {
+		return err
+	}
+
+	if failures == 1 || failures >= h.accountPolicy.Threshold {
+		err = h.recordFailure(ctx, targetUUID, email, reason, map[string]string{"failures": strconv.Itoa(failures)})
+		if err != nil {
+			return err
+		}
+	}
+
+	return auth.ErrInvalidCredentials
+}
+
+// recordFailure takes an empty targetUUID if no user has the email.
+func (h loginUserHandler) recordFailure(
+	ctx context.Context,
+	targetUUID string,
+	email auth.Email,
+	reason string,
+	details map[string]string,
+) error {
+	d := map[string]string{
+		"email":  email.Normalized(),
+		"reason": reason,
+	}
+	maps.Copy(d, details)
+	return h.audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditLoginFailed, targetUUID, d))
+}
+
+func (h loginUserHandler) checkLockout(ctx context.Context, keys ...string) error {
+	now := time.Now()
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		a, err := h.attempts.LoginAttempts(ctx, key)
+		if err != nil {
+			return err
+		}
+		retryAfter = max(retryAfter, a.RetryAfter(now))
+	}
+
+	if retryAfter > 0 {
+		return auth.TooManyLoginAttemptsError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// registerFailure returns the failures of the account. The IP counter is
+// never reset on success.
+func (h loginUserHandler) registerFailure(ctx context.Context, accountKey, ipKey string) (int, error) {
+	now := time.Now()
+
+	var failures int
+	err := h.attempts.Update(ctx, accountKey, func(_ context.Context, a *auth.LoginAttempts) error {
+		h.accountPolicy.RegisterFailure(a, now)
+		failures = a.Failures
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if ipKey == "" {
+		return failures, nil
+	}
+
+	err = h.attempts.Update(ctx, ipKey, func(_ context.Context, a *auth.LoginAttempts) error {
+		h.ipPolicy.RegisterFailure(a, now)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return failures, nil
+}
@@ -0,0 +1,255 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/executor"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+// TestLoginUser_Timing checks that a failed login takes as long for an
+// unknown email as for a registered one, so the response time does not reveal
+// which emails are registered.
+func TestLoginUser_Timing(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping timing test in short mode")
+	}
+
+	const (
+		samples   = 30
+		tolerance = 0.2
+	)
+
+	ctx := context.Background()
+	users := mocks.NewMockUserRepository()
+	hasher := passhash.NewDefaultHasher()
+	h := newLoginUserHandler(users, hasher)
+
+	// Every attempt uses its own account and IP, so the lockout does not kick in.
+	emails := make([]string, samples)
+	for i := range emails {
+		emails[i] = gofakeit.Email()
+		require.NoError(t, users.Save(ctx, auth.MustNewUser(gofakeit.UUID(), emails[i], "password", hasher)))
+	}
+
+	login := func(i int, email string) time.Duration {
+		start := time.Now()
+		_, err := h.Handle(ctx, query.LoginUser{
+			Email:       email,
+			Password:    "wrong password",
+			SessionUUID: gofakeit.UUID(),
+			IP:          fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+		})
+		elapsed := time.Since(start)
+		require.True(t, errors.Is(err, auth.ErrInvalidCredentials), err)
+		return elapsed
+	}
+
+	existing := make([]time.Duration, samples)
+	unknown := make([]time.Duration, samples)
+	for i := range samples {
+		existing[i] = login(2*i, emails[i])
+		unknown[i] = login(2*i+1, "unknown-"+emails[i])
+	}
+
+	existingMedian := median(existing)
+	unknownMedian := median(unknown)
+	diff := float64(existingMedian-unknownMedian) / float64(max(existingMedian, unknownMedian))
+	require.Less(t, diff, tolerance, "existing: %s, unknown: %s", existingMedian, unknownMedian)
+	require.Greater(t, diff, -tolerance, "existing: %s, unknown: %s", existingMedian, unknownMedian)
+}
+
+func TestLoginUser_RehashesOutdatedPassword(t *testing.T) {
+	ctx := context.Background()
+	users := mocks.NewMockUserRepository()
+	hasher := passhash.NewDefaultHasher()
+	h := newLoginUserHandler(users, hasher)
+
+	user := auth.MustNewUser(gofakeit.UUID(), gofakeit.Email(), "password", passhash.NewHasher(passhash.Bcrypt{Cost: 4}))
+	require.NoError(t, users.Save(ctx, user))
+	require.True(t, user.NeedsRehash(hasher))
+
+	_, err := h.Handle(ctx, query.LoginUser{
+		Email:       user.Email.String(),
+		Password:    "password",
+		SessionUUID: gofakeit.UUID(),
+		IP:          gofakeit.IPv4Address(),
+	})
+	require.NoError(t, err)
+
+	saved, err := users.User(ctx, user.UUID)
+	require.NoError(t, err)
+	require.False(t, saved.NeedsRehash(hasher))
+	require.NoError(t, saved.PasswordMatch(hasher, "password"))
+}
+
+func TestLoginUser_AccountStatus(t *testing.T) {
+	ctx := context.Background()
+	hasher := passhash.NewHasher(passhash.Bcrypt{Cost: 4})
+	admin := gofakeit.UUID()
+
+	tests := []struct {
+		name   string
+		status auth.AccountStatus
+		// wantState is empty if the login succeeds.
+		wantState auth.AccountState
+	}{
+		{
+			name:   "active",
+			status: auth.AccountStatus{State: auth.AccountActive},
+		},
+		{
+			name: "suspended",
+			status: auth.AccountStatus{
+				State: auth.AccountSuspended, Until: time.Now().Add(time.Hour), Reason: "spam", ChangedBy: admin,
+			},
+			wantState: auth.AccountSuspended,
+		},
+		{
+			name: "suspension lifted",
+			status: auth.AccountStatus{
+				State: auth.AccountSuspended, Until: time.Now().Add(-time.Second), Reason: "spam", ChangedBy: admin,
+			},
+		},
+		{
+			name:      "disabled",
+			status:    auth.AccountStatus{State: auth.AccountDisabled, Reason: "left", ChangedBy: admin},
+			wantState: auth.AccountDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewMockUserRepository()
+			h := newLoginUserHandler(users, hasher)
+
+			user := auth.MustNewUser(gofakeit.UUID(), gofakeit.Email(), "password", hasher)
+			require.NoError(t, user.ChangeStatus(tt.status))
+			require.NoError(t, users.Save(ctx, user))
+
+			_, err := h.Handle(ctx, query.LoginUser{
+				Email:       user.Email.String(),
+				Password:    "password",
+				SessionUUID: gofakeit.UUID(),
+				IP:          gofakeit.IPv4Address(),
+			})
+			if tt.wantState == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var inactiveErr auth.AccountInactiveError
+			require.ErrorAs(t, err, &inactiveErr)
+			require.Equal(t, tt.wantState, inactiveErr.State)
+
+			// The password is checked first, so a wrong one tells nothing.
+			_, err = h.Handle(ctx, query.LoginUser{
+				Email:       user.Email.String(),
+				Password:    "wrong password",
+				SessionUUID: gofakeit.UUID(),
+				IP:          gofakeit.IPv4Address(),
+			})
+			require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		})
+	}
+}
+
+func TestLoginUser_UnknownIP(t *testing.T) {
+	ctx := context.Background()
+	attempts := infra.NewMemoryLoginAttemptsRepository()
+	h := query.NewLoginUserHandler(
+		mocks.NewMockUserRepository(),
+		mocks.NewMockSessionsRepository(),
+		attempts,
+		mocks.NewMockAuditLogRepository(),
+		passhash.NewHasher(passhash.Bcrypt{Cost: 4}),
+		executor.New("test", 1, 1, metrics.NoOp{}),
+		slogdiscard.NewDiscardLogger(),
+		metrics.NoOp{},
+	)
+
+	// Clients of unknown IP do not lock each other out.
+	for range auth.DefaultIPLockoutPolicy.Threshold + 1 {
+		_, err := h.Handle(ctx, query.LoginUser{
+			Email:       gofakeit.Email(),
+			Password:    "wrong password",
+			SessionUUID: gofakeit.UUID(),
+		})
+		require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	}
+
+	a, err := attempts.LoginAttempts(ctx, auth.LoginAttemptsKeyForIP(""))
+	require.NoError(t, err)
+	require.Zero(t, a.Failures)
+}
+
+func TestLoginUser_AggregatesAuditedFailures(t *testing.T) {
+	ctx := context.Background()
+	audit := mocks.NewMockAuditLogRepository()
+	h := query.NewLoginUserHandler(
+		mocks.NewMockUserRepository(),
+		mocks.NewMockSessionsRepository(),
+		infra.NewMemoryLoginAttemptsRepository(),
+		audit,
+		passhash.NewHasher(passhash.Bcrypt{Cost: 4}),
+		executor.New("test", 1, 1, metrics.NoOp{}),
+		slogdiscard.NewDiscardLogger(),
+		metrics.NoOp{},
+	)
+
+	email := gofakeit.Email()
+	for range auth.DefaultAccountLockoutPolicy.Threshold {
+		_, err := h.Handle(ctx, query.LoginUser{
+			Email:       email,
+			Password:    "wrong password",
+			SessionUUID: gofakeit.UUID(),
+			IP:          gofakeit.IPv4Address(),
+		})
+		require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	}
+
+	l, err := auth.NewListAuditEvents(auth.AuditFilter{Action: auth.AuditLoginFailed}, "", 0)
+	require.NoError(t, err)
+	page, err := audit.ListAuditEvents(ctx, l)
+	require.NoError(t, err)
+
+	failures := make([]string, len(page.Events))
+	for i, e := range page.Events {
+		failures[i] = e.Details["failures"]
+	}
+	require.Equal(t, []string{strconv.Itoa(auth.DefaultAccountLockoutPolicy.Threshold), "1"}, failures)
+}
+
+func newLoginUserHandler(users auth.UsersRepository, hasher auth.PasswordHasher) query.LoginUserHandler {
+	return query.NewLoginUserHandler(
+		users,
+		mocks.NewMockSessionsRepository(),
+		infra.NewMemoryLoginAttemptsRepository(),
+		mocks.NewMockAuditLogRepository(),
+		hasher,
+		executor.New("test", 1, 1, metrics.NoOp{}),
+		slogdiscard.NewDiscardLogger(),
+		metrics.NoOp{},
+	)
+}
+
+func median(ds []time.Duration) time.Duration {
+	sorted := slices.Clone(ds)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
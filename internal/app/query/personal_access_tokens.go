@@ -0,0 +1,50 @@
+package query
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type PersonalAccessTokens struct {
+	UserUUID string
+}
+
+type PersonalAccessTokensHandler decorator.QueryHandler[PersonalAccessTokens, []PersonalAccessToken]
+
+type personalAccessTokensHandler struct {
+	tokens auth.PersonalAccessTokensRepository
+}
+
+func NewPersonalAccessTokensHandler(
+	tokens auth.PersonalAccessTokensRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) PersonalAccessTokensHandler {
+	if tokens == nil {
+		panic("personal access tokens repository is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[PersonalAccessTokens, []PersonalAccessToken](
+		personalAccessTokensHandler{tokens: tokens},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h personalAccessTokensHandler) Handle(ctx context.Context, query PersonalAccessTokens) ([]PersonalAccessToken, error) {
+	tokens, err := h.tokens.UserPersonalAccessTokens(ctx, query.UserUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]PersonalAccessToken, len(tokens))
+	for i, t := range tokens {
+		res[i] = mapPersonalAccessTokenFromDomain(t)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,60 @@
+package query
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type Sessions struct {
+	UserUUID string
+}
+
+type SessionsHandler decorator.QueryHandler[Sessions, []Session]
+
+type sessionsHandler struct {
+	users    auth.UsersRepository
+	sessions auth.SessionsRepository
+}
+
+func NewSessionsHandler(
+	users auth.UsersRepository,
+	sessions auth.SessionsRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) SessionsHandler {
+	if users == nil {
+		panic("users repository is nil")
+	}
+
+	if sessions == nil {
+		panic("sessions repository is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[Sessions, []Session](
+		sessionsHandler{users: users, sessions: sessions},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h sessionsHandler) Handle(ctx context.Context, query Sessions) ([]Session, error) {
+	if _, err := h.users.User(ctx, query.UserUUID); err != nil {
+		return nil, err
+	}
+
+	sessions, err := h.sessions.UserSessions(ctx, query.UserUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Session, len(sessions))
+	for i, s := range sessions {
+		res[i] = mapSessionFromDomain(s)
+	}
+
+	return res, nil
+}
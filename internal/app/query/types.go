@@ -1,6 +1,7 @@
 package query
 
 import (
+	"slices"
 	"time"
 
 	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
@@ -9,17 +10,157 @@ import (
 type Empty struct{}
 
 type User struct {
-	UUID      string
-	Email     string
+	UUID    string
+	Email   string
+	Roles   []string
+	Profile Profile
+	// AvatarKey is empty if the user has not uploaded an avatar.
+	AvatarKey string
+	Status    AccountStatus
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int64
 }
 
 func mapUserFromDomain(u *auth.User) User {
 	return User{
 		UUID:      u.UUID,
-		Email:     u.Email,
+		Email:     u.Email.String(),
+		Roles:     mapRolesFromDomain(u.Roles),
+		Profile:   Profile(u.Profile),
+		AvatarKey: u.AvatarKey,
+		Status:    mapAccountStatusFromDomain(u.Status, time.Now()),
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
+		Version:   u.Version,
 	}
 }
+
+// Profile mirrors auth.Profile. Empty fields are unset.
+type Profile struct {
+	DisplayName string
+	LastName    string
+	FirstName   string
+	Patronymic  string
+	Group       string
+	Faculty     string
+	Locale      string
+	Timezone    string
+	AvatarURL   string
+}
+
+// AccountStatus is the status in effect when the user is read.
+type AccountStatus struct {
+	// State is "active", "suspended" or "disabled".
+	State string
+	// Until is set for suspensions only.
+	Until time.Time
+
+	Reason    string
+	ChangedBy string
+	ChangedAt time.Time
+}
+
+func mapAccountStatusFromDomain(s auth.AccountStatus, now time.Time) AccountStatus {
+	res := AccountStatus{
+		State:     string(s.Effective(now)),
+		Reason:    s.Reason,
+		ChangedBy: s.ChangedBy,
+		ChangedAt: s.ChangedAt,
+	}
+	if res.State == string(auth.AccountSuspended) {
+		res.Until = s.Until
+	}
+	return res
+}
+
+func mapRolesFromDomain(roles []auth.Role) []string {
+	res := make([]string, len(roles))
+	for i, r := range roles {
+		res[i] = string(r)
+	}
+	return res
+}
+
+type Avatar struct {
+	Key         string
+	Data        []byte
+	ContentType string
+}
+
+type Session struct {
+	UUID       string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+func mapSessionFromDomain(s *auth.Session) Session {
+	return Session{
+		UUID:       s.UUID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+	}
+}
+
+type PersonalAccessToken struct {
+	UUID       string
+	Name       string
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	LastUsedIP string
+	CreatedAt  time.Time
+}
+
+func mapPersonalAccessTokenFromDomain(t *auth.PersonalAccessToken) PersonalAccessToken {
+	scopes := make([]string, len(t.Scopes))
+	for i, s := range t.Scopes {
+		scopes[i] = string(s)
+	}
+
+	return PersonalAccessToken{
+		UUID:       t.UUID,
+		Name:       t.Name,
+		Scopes:     scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		LastUsedIP: t.LastUsedIP,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// Principal is the authenticated owner of a token.
+type Principal struct {
+	UserUUID string
+	Roles    []string
+
+	// SessionUUID is set if the principal was authenticated by an access
+	// token issued on login.
+	SessionUUID string
+
+	// PersonalAccessTokenUUID is set if the principal was authenticated
+	// by a personal access token.
+	PersonalAccessTokenUUID string
+	Scopes                  []string
+}
+
+func (p Principal) IsAdmin() bool {
+	return slices.Contains(p.Roles, string(auth.RoleAdmin))
+}
+
+func (p Principal) IsPersonalAccessToken() bool {
+	return p.PersonalAccessTokenUUID != ""
+}
+
+// HasScope reports whether the principal is allowed to act within scope.
+// Access tokens issued on login are not restricted by scopes.
+func (p Principal) HasScope(scope string) bool {
+	if !p.IsPersonalAccessToken() {
+		return true
+	}
+	return slices.Contains(p.Scopes, scope)
+}
@@ -0,0 +1,109 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// verifyAuditLogBatch is the number of events read at once.
+const verifyAuditLogBatch = 1000
+
+type VerifyAuditLog struct{}
+
+type AuditLogVerification struct {
+	// Checked is the number of events that match their chains.
+	Checked int64
+	// BrokenAt is the number of the first event of a chain that does not, or
+	// 0 if the whole log is intact.
+	BrokenAt int64
+}
+
+type VerifyAuditLogHandler decorator.QueryHandler[VerifyAuditLog, AuditLogVerification]
+
+type verifyAuditLogHandler struct {
+	audit auth.AuditLogRepository
+}
+
+func NewVerifyAuditLogHandler(
+	audit auth.AuditLogRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) VerifyAuditLogHandler {
+	if audit == nil {
+		panic("audit log repository is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[VerifyAuditLog, AuditLogVerification](
+		verifyAuditLogHandler{audit: audit},
+		logger,
+		metricsClient,
+	)
+}
+
+// Handle walks the chains one by one. Events appended meanwhile are checked
+// too, unless their chain is already walked.
+func (h verifyAuditLogHandler) Handle(ctx context.Context, _ VerifyAuditLog) (AuditLogVerification, error) {
+	var res AuditLogVerification
+	if err := h.verifyChain(ctx, "", &res); err != nil || res.BrokenAt != 0 {
+		return res, err
+	}
+
+	var after string
+	for {
+		keys, err := h.audit.AuditChainKeys(ctx, after, verifyAuditLogBatch)
+		if err != nil {
+			return AuditLogVerification{}, err
+		}
+
+		for _, key := range keys {
+			if err = h.verifyChain(ctx, key, &res); err != nil || res.BrokenAt != 0 {
+				return res, err
+			}
+		}
+
+		if len(keys) < verifyAuditLogBatch {
+			return res, nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+func (h verifyAuditLogHandler) verifyChain(ctx context.Context, chain string, res *AuditLogVerification) error {
+	var prev *auth.AuditEvent
+	for {
+		var after int64
+		if prev != nil {
+			after = prev.Seq
+		}
+
+		events, err := h.audit.AuditChainEvents(ctx, chain, after, verifyAuditLogBatch)
+		if err != nil {
+			return err
+		}
+
+		var brokenErr auth.AuditChainBrokenError
+		if err = auth.VerifyAuditChain(prev, events); errors.As(err, &brokenErr) {
+			for _, e := range events {
+				if e.Seq == brokenErr.Seq {
+					break
+				}
+				res.Checked++
+			}
+			res.BrokenAt = brokenErr.Seq
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		res.Checked += int64(len(events))
+		if len(events) < verifyAuditLogBatch {
+			return nil
+		}
+		prev = events[len(events)-1]
+	}
+}
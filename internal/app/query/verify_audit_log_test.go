@@ -0,0 +1,55 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+func TestVerifyAuditLog(t *testing.T) {
+	ctx := context.Background()
+	audit := &tamperedAuditLog{AuditLogRepository: mocks.NewMockAuditLogRepository()}
+	h := query.NewVerifyAuditLogHandler(audit, slogdiscard.NewDiscardLogger(), metrics.NoOp{})
+
+	for _, target := range []string{"", gofakeit.UUID(), gofakeit.UUID(), ""} {
+		require.NoError(t, audit.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditLoginFailed, target, nil)))
+	}
+
+	res, err := h.Handle(ctx, query.VerifyAuditLog{})
+	require.NoError(t, err)
+	require.Equal(t, query.AuditLogVerification{Checked: 4}, res)
+
+	audit.tamperedSeq = 4
+	res, err = h.Handle(ctx, query.VerifyAuditLog{})
+	require.NoError(t, err)
+	require.Equal(t, query.AuditLogVerification{Checked: 1, BrokenAt: 4}, res)
+}
+
+// tamperedAuditLog edits the event tamperedSeq when it is read.
+type tamperedAuditLog struct {
+	auth.AuditLogRepository
+	tamperedSeq int64
+}
+
+func (r *tamperedAuditLog) AuditChainEvents(
+	ctx context.Context,
+	chain string,
+	seq int64,
+	limit int,
+) ([]*auth.AuditEvent, error) {
+	events, err := r.AuditLogRepository.AuditChainEvents(ctx, chain, seq, limit)
+	for _, e := range events {
+		if e.Seq == r.tamperedSeq {
+			e.IP = "192.0.2.1"
+		}
+	}
+	return events, err
+}
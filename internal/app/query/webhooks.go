@@ -0,0 +1,169 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// WebhookSubscription leaves out the secret, which is shown only on creation.
+type WebhookSubscription struct {
+	UUID       string
+	URL        string
+	EventTypes []string
+	CreatedBy  string
+	CreatedAt  time.Time
+}
+
+type ListWebhookSubscriptions struct{}
+
+type ListWebhookSubscriptionsHandler decorator.QueryHandler[ListWebhookSubscriptions, []WebhookSubscription]
+
+type listWebhookSubscriptionsHandler struct {
+	webhooks auth.WebhooksRepository
+}
+
+func NewListWebhookSubscriptionsHandler(
+	webhooks auth.WebhooksRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) ListWebhookSubscriptionsHandler {
+	if webhooks == nil {
+		panic("webhooks repository is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[ListWebhookSubscriptions, []WebhookSubscription](
+		listWebhookSubscriptionsHandler{webhooks: webhooks},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h listWebhookSubscriptionsHandler) Handle(
+	ctx context.Context,
+	_ ListWebhookSubscriptions,
+) ([]WebhookSubscription, error) {
+	subscriptions, err := h.webhooks.Subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]WebhookSubscription, len(subscriptions))
+	for i, s := range subscriptions {
+		eventTypes := make([]string, len(s.EventTypes))
+		for j, t := range s.EventTypes {
+			eventTypes[j] = string(t)
+		}
+
+		res[i] = WebhookSubscription{
+			UUID:       s.UUID,
+			URL:        s.URL,
+			EventTypes: eventTypes,
+			CreatedBy:  s.CreatedBy,
+			CreatedAt:  s.CreatedAt,
+		}
+	}
+
+	return res, nil
+}
+
+// ListWebhookDeliveries returns the latest deliveries of a subscription,
+// newest first. An empty State matches every delivery.
+type ListWebhookDeliveries struct {
+	SubscriptionUUID string
+	State            string
+	Limit            int
+}
+
+type WebhookDelivery struct {
+	UUID      string
+	EventID   int64
+	EventType string
+	State     string
+	Attempts  int
+	// NextAttemptAt is zero unless the delivery is pending.
+	NextAttemptAt  time.Time
+	LastAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+}
+
+type ListWebhookDeliveriesHandler decorator.QueryHandler[ListWebhookDeliveries, []WebhookDelivery]
+
+type listWebhookDeliveriesHandler struct {
+	webhooks auth.WebhooksRepository
+}
+
+func NewListWebhookDeliveriesHandler(
+	webhooks auth.WebhooksRepository,
+
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) ListWebhookDeliveriesHandler {
+	if webhooks == nil {
+		panic("webhooks repository is nil")
+	}
+
+	return decorator.ApplyQueryDecorators[ListWebhookDeliveries, []WebhookDelivery](
+		listWebhookDeliveriesHandler{webhooks: webhooks},
+		logger,
+		metricsClient,
+	)
+}
+
+func (h listWebhookDeliveriesHandler) Handle(
+	ctx context.Context,
+	query ListWebhookDeliveries,
+) ([]WebhookDelivery, error) {
+	var state auth.WebhookDeliveryState
+	if query.State != "" {
+		var err error
+		if state, err = auth.NewWebhookDeliveryState(query.State); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = auth.DefaultWebhookDeliveriesLimit
+	}
+	if limit < 1 || limit > auth.MaxWebhookDeliveriesLimit {
+		return nil, commonerrs.NewInvalidInputError(
+			fmt.Sprintf("expected limit between 1 and %d", auth.MaxWebhookDeliveriesLimit),
+		)
+	}
+
+	if _, err := h.webhooks.Subscription(ctx, query.SubscriptionUUID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := h.webhooks.Deliveries(ctx, query.SubscriptionUUID, state, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		res[i] = WebhookDelivery{
+			UUID:           d.UUID,
+			EventID:        d.Event.ID,
+			EventType:      string(d.Event.Type),
+			State:          string(d.State),
+			Attempts:       d.Attempts,
+			NextAttemptAt:  d.NextAttemptAt,
+			LastAttemptAt:  d.LastAttemptAt,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			CreatedAt:      d.CreatedAt,
+		}
+	}
+
+	return res, nil
+}
@@ -1,15 +1,37 @@
 package commonerrs
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type InvalidInputError struct {
+	Message    string
+	Violations []Violation
+}
+
+// Violation is a single failed validation rule.
+type Violation struct {
+	Rule    string
 	Message string
 }
 
 func (e InvalidInputError) Error() string {
-	return fmt.Sprintf("invalid input: %s", e.Message)
+	if len(e.Violations) == 0 {
+		return fmt.Sprintf("invalid input: %s", e.Message)
+	}
+
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return fmt.Sprintf("invalid input: %s: %s", e.Message, strings.Join(messages, "; "))
 }
 
 func NewInvalidInputError(message string) error {
 	return InvalidInputError{Message: message}
 }
+
+func NewInvalidInputErrorWithViolations(message string, violations []Violation) error {
+	return InvalidInputError{Message: message, Violations: violations}
+}
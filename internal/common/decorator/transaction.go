@@ -0,0 +1,44 @@
+package decorator
+
+import (
+	"context"
+	"log/slog"
+)
+
+// UnitOfWork keeps the changes fn makes through the repositories with its
+// context only if fn returns nil.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ApplyTransactionalCommandDecorators runs the handler in a unit of work.
+func ApplyTransactionalCommandDecorators[H any](
+	handler CommandHandler[H],
+	uow UnitOfWork,
+	logger *slog.Logger,
+	metricsClient MetricsClient,
+) CommandHandler[H] {
+	if uow == nil {
+		panic("unit of work is nil")
+	}
+
+	return ApplyCommandDecorators[H](
+		commandTransactionDecorator[H]{
+			base: handler,
+			uow:  uow,
+		},
+		logger,
+		metricsClient,
+	)
+}
+
+type commandTransactionDecorator[C any] struct {
+	base CommandHandler[C]
+	uow  UnitOfWork
+}
+
+func (d commandTransactionDecorator[C]) Handle(ctx context.Context, cmd C) error {
+	return d.uow.Do(ctx, func(ctx context.Context) error {
+		return d.base.Handle(ctx, cmd)
+	})
+}
@@ -0,0 +1,91 @@
+// Package executor runs CPU-heavy work, such as password hashing, with a
+// fixed concurrency limit.
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+)
+
+var ErrQueueFull = errors.New("executor queue is full")
+
+type Executor struct {
+	name string
+
+	// running limits the work running at once, admitted additionally limits
+	// the work waiting for a running slot.
+	running  chan struct{}
+	admitted chan struct{}
+
+	metricsClient decorator.MetricsClient
+}
+
+// New reports metrics under "executors.<name>".
+func New(name string, concurrency int, queueSize int, metricsClient decorator.MetricsClient) *Executor {
+	if concurrency <= 0 {
+		panic("executor concurrency must be positive")
+	}
+
+	if queueSize < 0 {
+		panic("executor queue size must not be negative")
+	}
+
+	if metricsClient == nil {
+		panic("metrics client is nil")
+	}
+
+	return &Executor{
+		name:          name,
+		running:       make(chan struct{}, concurrency),
+		admitted:      make(chan struct{}, concurrency+queueSize),
+		metricsClient: metricsClient,
+	}
+}
+
+// Do runs fn on the calling goroutine once a slot is free. It fails with
+// ErrQueueFull if the queue is full.
+func (e *Executor) Do(ctx context.Context, fn func() error) error {
+	select {
+	case e.admitted <- struct{}{}:
+	default:
+		e.metricsClient.Inc(e.metric("rejected"), 1)
+		return ErrQueueFull
+	}
+	defer func() { <-e.admitted }()
+
+	start := time.Now()
+	e.metricsClient.Inc(e.metric("queue_depth"), 1)
+
+	select {
+	case e.running <- struct{}{}:
+	case <-ctx.Done():
+		e.metricsClient.Inc(e.metric("queue_depth"), -1)
+		e.metricsClient.Inc(e.metric("canceled"), 1)
+		return ctx.Err()
+	}
+	defer func() { <-e.running }()
+
+	e.metricsClient.Inc(e.metric("queue_depth"), -1)
+	e.metricsClient.Inc(e.metric("wait_ms"), int(time.Since(start).Milliseconds()))
+
+	return fn()
+}
+
+// Call is Do for functions returning a result.
+func Call[T any](ctx context.Context, e *Executor, fn func() (T, error)) (T, error) {
+	var res T
+	err := e.Do(ctx, func() error {
+		var err error
+		res, err = fn()
+		return err
+	})
+	return res, err
+}
+
+func (e *Executor) metric(name string) string {
+	return fmt.Sprintf("executors.%s.%s", e.name, name)
+}
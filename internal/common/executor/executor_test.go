@@ -0,0 +1,123 @@
+package executor_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/executor"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+)
+
+func TestExecutor_LimitsConcurrency(t *testing.T) {
+	e := executor.New("test", 2, 10, metrics.NoOp{})
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := e.Do(context.Background(), func() error {
+				n := running.Add(1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestExecutor_RejectsWhenQueueIsFull(t *testing.T) {
+	m := &gaugeMetrics{}
+	e := executor.New("test", 1, 1, m)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = e.Do(context.Background(), func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	queued := make(chan error)
+	go func() {
+		queued <- e.Do(context.Background(), func() error { return nil })
+	}()
+	require.Eventually(t, func() bool {
+		return m.value("executors.test.queue_depth") == 1
+	}, time.Second, time.Millisecond)
+
+	require.ErrorIs(t, e.Do(context.Background(), func() error { return nil }), executor.ErrQueueFull)
+	require.Equal(t, 1, m.value("executors.test.rejected"))
+
+	close(release)
+	require.NoError(t, <-queued)
+	require.Equal(t, 0, m.value("executors.test.queue_depth"))
+}
+
+func TestExecutor_CancelsWaiting(t *testing.T) {
+	e := executor.New("test", 1, 1, metrics.NoOp{})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = e.Do(context.Background(), func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	called := false
+	err := e.Do(ctx, func() error {
+		called = true
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.False(t, called)
+}
+
+type gaugeMetrics struct {
+	sync.Mutex
+	m map[string]int
+}
+
+func (g *gaugeMetrics) Inc(key string, value int) {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.m == nil {
+		g.m = make(map[string]int)
+	}
+	g.m[key] += value
+}
+
+func (g *gaugeMetrics) value(key string) int {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.m[key]
+}
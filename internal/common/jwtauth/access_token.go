@@ -9,22 +9,51 @@ import (
 
 type accessTokenClaims struct {
 	jwt.RegisteredClaims
-	UserUUID string `json:"user_uuid"`
+	UserUUID    string `json:"user_uuid"`
+	SessionUUID string `json:"session_uuid,omitempty"`
+	*ProfileClaims
+}
+
+// ProfileClaims are the standard OpenID Connect claims of the user profile.
+// Empty claims are omitted.
+type ProfileClaims struct {
+	Name              string `json:"name,omitempty"`
+	GivenName         string `json:"given_name,omitempty"`
+	FamilyName        string `json:"family_name,omitempty"`
+	MiddleName        string `json:"middle_name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Locale            string `json:"locale,omitempty"`
+	Zoneinfo          string `json:"zoneinfo,omitempty"`
+	Picture           string `json:"picture,omitempty"`
 }
 
 type AccessTokenPayload struct {
 	UserUUID string
+
+	// SessionUUID is empty for tokens issued before sessions were introduced.
+	SessionUUID string
+
+	// Profile is set if the token carries profile claims.
+	Profile *ProfileClaims
 }
 
+// NewAccessToken includes the profile claims only if JWT_PROFILE_CLAIMS is
+// true.
 func NewAccessToken(
 	userUUID string,
+	sessionUUID string,
+	profile ProfileClaims,
 	ttl time.Duration,
 ) (string, error) {
 	claims := accessTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 		},
-		UserUUID: userUUID,
+		UserUUID:    userUUID,
+		SessionUUID: sessionUUID,
+	}
+	if withProfileClaims && profile != (ProfileClaims{}) {
+		claims.ProfileClaims = &profile
 	}
 
 	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
@@ -33,9 +62,9 @@ func NewAccessToken(
 func ParseAccessToken(token string) (AccessTokenPayload, error) {
 	parsed, err := jwt.ParseWithClaims(token, &accessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithExpirationRequired())
 	if err != nil {
-		return AccessTokenPayload{}, nil
+		return AccessTokenPayload{}, err
 	}
 
 	claims, ok := parsed.Claims.(*accessTokenClaims)
@@ -44,6 +73,8 @@ func ParseAccessToken(token string) (AccessTokenPayload, error) {
 	}
 
 	return AccessTokenPayload{
-		UserUUID: claims.UserUUID,
+		UserUUID:    claims.UserUUID,
+		SessionUUID: claims.SessionUUID,
+		Profile:     claims.ProfileClaims,
 	}, nil
 }
@@ -4,4 +4,6 @@ import "os"
 
 var (
 	secret = os.Getenv("JWT_SECRET")
+
+	withProfileClaims = os.Getenv("JWT_PROFILE_CLAIMS") == "true"
 )
@@ -0,0 +1,437 @@
+// Package migrate applies SQL migrations named "<version>_<name>.up.sql" and
+// "<version>_<name>.down.sql" to Postgres or SQLite.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+)
+
+// lockKey is the advisory lock held by the running migrator.
+const lockKey = 0x6d696772617465
+
+var fileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the hex SHA-256 of Up.
+	Checksum string
+}
+
+// Load reads the migrations in the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			migration.Up = string(b)
+			sum := sha256.Sum256(b)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	return migrations, nil
+}
+
+type State string
+
+const (
+	StateApplied State = "applied"
+	StatePending State = "pending"
+	// StateEdited is an applied migration whose up file has changed since.
+	StateEdited State = "edited"
+	// StateMissing is an applied migration which is no longer known.
+	StateMissing State = "missing"
+)
+
+type Status struct {
+	Version   int
+	Name      string
+	State     State
+	AppliedAt time.Time
+}
+
+// ErrEditedMigration is returned by Up if an applied migration has been
+// changed.
+var ErrEditedMigration = errors.New("applied migration was edited")
+
+// Step is done in Go after the up file of migration Version, in its
+// transaction, for changes SQL can not make.
+type Step struct {
+	Version int
+	Up      func(ctx context.Context, tx *sqlx.Tx) error
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+	steps      map[int]Step
+	// sqlite tells the database is SQLite rather than Postgres.
+	sqlite bool
+}
+
+func New(db *sqlx.DB, fsys fs.FS, steps ...Step) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]Step, len(steps))
+	for _, step := range steps {
+		known := slices.ContainsFunc(migrations, func(mig Migration) bool {
+			return mig.Version == step.Version
+		})
+		if !known {
+			return nil, fmt.Errorf("step of unknown migration %d", step.Version)
+		}
+		byVersion[step.Version] = step
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		steps:      byVersion,
+		sqlite:     db.DriverName() == "sqlite3",
+	}, nil
+}
+
+// Up applies the pending migrations in order, each in its own transaction.
+// It returns the applied versions.
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	var applied []int
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		statuses, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		states := make(map[int]State, len(statuses))
+		for _, s := range statuses {
+			if s.State == StateEdited {
+				return fmt.Errorf("%w: %d_%s", ErrEditedMigration, s.Version, s.Name)
+			}
+			states[s.Version] = s.State
+		}
+
+		for _, mig := range m.migrations {
+			if states[mig.Version] != StatePending {
+				continue
+			}
+
+			if err = m.apply(ctx, conn, mig); err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig.Version)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first. It returns
+// the reverted versions.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	if steps < 1 {
+		return nil, errors.New("expected at least one step")
+	}
+
+	var reverted []int
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(versions) - 1; i >= 0 && len(reverted) < steps; i-- {
+			j := slices.IndexFunc(m.migrations, func(mig Migration) bool {
+				return mig.Version == versions[i]
+			})
+			if j < 0 {
+				return fmt.Errorf("migration %d is applied, but unknown", versions[i])
+			}
+
+			mig := m.migrations[j]
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+			}
+
+			if err = m.revert(ctx, conn, mig); err != nil {
+				return fmt.Errorf("revert migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig.Version)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists the known and the applied migrations by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		var err error
+		statuses, err = m.status(ctx, conn)
+		return err
+	})
+
+	return statuses, err
+}
+
+// locked runs fn on a connection holding the advisory lock, if any.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sqlx.Conn) error) (err error) {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.sqlite {
+		if err = m.prepare(ctx, conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	}
+
+	if _, err = pgutils.Exec(ctx, conn, `SELECT pg_advisory_lock($1)`, int64(lockKey)); err != nil {
+		return err
+	}
+	defer func() {
+		_, unlockErr := pgutils.Exec(context.Background(), conn, `SELECT pg_advisory_unlock($1)`, int64(lockKey))
+		err = errors.Join(err, unlockErr)
+	}()
+
+	if err = m.prepare(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// prepare takes the version of golang-migrate as applied.
+func (m *Migrator) prepare(ctx context.Context, conn *sqlx.Conn) error {
+	query := `SELECT to_regclass('schema_versions') IS NOT NULL`
+	if m.sqlite {
+		query = `SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'schema_versions'`
+	}
+
+	var exists bool
+	err := pgutils.Get(ctx, conn, &exists, query)
+	if err != nil || exists {
+		return err
+	}
+
+	return pgutils.RunTx(ctx, conn, func(tx *sqlx.Tx) error {
+		_, err := pgutils.Exec(
+			ctx, tx,
+			`CREATE TABLE schema_versions (
+				version    INTEGER     PRIMARY KEY,
+				name       VARCHAR(64) NOT NULL,
+				checksum   VARCHAR(64) NOT NULL,
+				applied_at TIMESTAMP   NOT NULL
+			)`,
+		)
+		if err != nil || m.sqlite {
+			return err
+		}
+
+		var legacy bool
+		err = pgutils.Get(ctx, tx, &legacy, `SELECT to_regclass('schema_migrations') IS NOT NULL`)
+		if err != nil || !legacy {
+			return err
+		}
+
+		var row struct {
+			Version int  `db:"version"`
+			Dirty   bool `db:"dirty"`
+		}
+		err = pgutils.Get(ctx, tx, &row, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if row.Dirty {
+			return fmt.Errorf("golang-migrate left version %d dirty, fix it by hand first", row.Version)
+		}
+
+		// The checksums are unknown; the current files are trusted.
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`INSERT INTO
+				schema_versions (version, name, checksum, applied_at)
+			 SELECT
+				version, '', '', NOW() AT TIME ZONE 'UTC'
+			 FROM
+				generate_series(1, $1) AS version`,
+			row.Version,
+		)
+		return err
+	})
+}
+
+type appliedRow struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+func (m *Migrator) status(ctx context.Context, conn *sqlx.Conn) ([]Status, error) {
+	var rows []appliedRow
+	err := pgutils.Select(
+		ctx, conn, &rows,
+		`SELECT
+			version, name, checksum, applied_at
+		 FROM
+			schema_versions
+		 ORDER BY
+			version`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]appliedRow, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	var statuses []Status
+	for _, mig := range m.migrations {
+		row, ok := applied[mig.Version]
+		if !ok {
+			statuses = append(statuses, Status{Version: mig.Version, Name: mig.Name, State: StatePending})
+			continue
+		}
+		delete(applied, mig.Version)
+
+		state := StateApplied
+		// Migrations adopted from golang-migrate have no checksum.
+		if row.Checksum != "" && row.Checksum != mig.Checksum {
+			state = StateEdited
+		}
+		statuses = append(statuses, Status{
+			Version:   mig.Version,
+			Name:      mig.Name,
+			State:     state,
+			AppliedAt: row.AppliedAt.Local(),
+		})
+	}
+
+	for _, row := range applied {
+		statuses = append(statuses, Status{
+			Version:   row.Version,
+			Name:      row.Name,
+			State:     StateMissing,
+			AppliedAt: row.AppliedAt.Local(),
+		})
+	}
+
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return a.Version - b.Version
+	})
+
+	return statuses, nil
+}
+
+func appliedVersions(ctx context.Context, conn *sqlx.Conn) ([]int, error) {
+	var versions []int
+	err := pgutils.Select(ctx, conn, &versions, `SELECT version FROM schema_versions ORDER BY version`)
+	return versions, err
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, mig Migration) error {
+	return pgutils.RunTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return err
+		}
+
+		if step, ok := m.steps[mig.Version]; ok {
+			if err := step.Up(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		_, err := pgutils.Exec(
+			ctx, tx,
+			tx.Rebind(`INSERT INTO
+				schema_versions (version, name, checksum, applied_at)
+			 VALUES
+				(?, ?, ?, ?)`),
+			mig.Version, mig.Name, mig.Checksum, time.Now().UTC(),
+		)
+		return err
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sqlx.Conn, mig Migration) error {
+	return pgutils.RunTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return err
+		}
+
+		_, err := pgutils.Exec(ctx, tx, tx.Rebind(`DELETE FROM schema_versions WHERE version = ?`), mig.Version)
+		return err
+	})
+}
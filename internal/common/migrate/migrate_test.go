@@ -0,0 +1,253 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/migrate"
+	"github.com/bmstu-itstech/itsreg-auth/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+		"002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INT);")},
+		"README.md":           {Data: []byte("not a migration")},
+	}
+
+	ms, err := migrate.Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, ms, 2)
+	require.Equal(t, 1, ms[0].Version)
+	require.Equal(t, "first", ms[0].Name)
+	require.Empty(t, ms[0].Down)
+	require.Equal(t, 2, ms[1].Version)
+	require.Equal(t, "DROP TABLE b;", ms[1].Down)
+	require.Len(t, ms[1].Checksum, 64)
+	require.NotEqual(t, ms[0].Checksum, ms[1].Checksum)
+
+	_, err = migrate.Load(fstest.MapFS{"001_first.down.sql": {Data: []byte("DROP TABLE a;")}})
+	require.Error(t, err)
+
+	_, err = migrate.Load(fstest.MapFS{
+		"001_first.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+		"001_other.up.sql": {Data: []byte("CREATE TABLE b (id INT);")},
+	})
+	require.Error(t, err)
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	for name, fsys := range map[string]fs.FS{"postgres": migrations.FS, "sqlite": migrations.SQLiteFS} {
+		ms, err := migrate.Load(fsys)
+		require.NoError(t, err, name)
+		require.NotEmpty(t, ms, name)
+
+		for i, m := range ms {
+			require.Equal(t, i+1, m.Version, "%s migrations are numbered without gaps", name)
+			require.NotEmpty(t, m.Down, "%s migration %d_%s has a down file", name, m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigrator_SQLite(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	m, err := migrate.New(db, migrations.SQLiteFS)
+	require.NoError(t, err)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3, 4}, applied)
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 4)
+	require.Equal(t, migrate.StateApplied, statuses[0].State)
+	require.False(t, statuses[0].AppliedAt.IsZero())
+
+	reverted, err := m.Down(ctx, 4)
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 3, 2, 1}, reverted)
+
+	var tables int
+	require.NoError(t, db.Get(&tables, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'users'`))
+	require.Zero(t, tables)
+}
+
+func TestMigrator_Step(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INT);")},
+		"001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+		"002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+
+	_, err := migrate.New(db, fsys, migrate.Step{Version: 3})
+	require.Error(t, err)
+
+	errStep := errors.New("step failed")
+	m, err := migrate.New(db, fsys, migrate.Step{
+		Version: 2,
+		Up: func(ctx context.Context, tx *sqlx.Tx) error {
+			var tables int
+			require.NoError(t, tx.GetContext(ctx, &tables, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'b'`))
+			require.Equal(t, 1, tables, "the step runs after the up file")
+			return errStep
+		},
+	})
+	require.NoError(t, err)
+
+	// A failed step rolls back its migration.
+	applied, err := m.Up(ctx)
+	require.ErrorIs(t, err, errStep)
+	require.Equal(t, []int{1}, applied)
+
+	var tables int
+	require.NoError(t, db.Get(&tables, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'b'`))
+	require.Zero(t, tables)
+
+	m, err = migrate.New(db, fsys, migrate.Step{
+		Version: 2,
+		Up: func(ctx context.Context, tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO b VALUES (1)`)
+			return err
+		},
+	})
+	require.NoError(t, err)
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, applied)
+
+	var rows int
+	require.NoError(t, db.Get(&rows, `SELECT COUNT(*) FROM b`))
+	require.Equal(t, 1, rows)
+}
+
+func TestMigrator(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	// The migrations go to a schema of their own, so the tables of the
+	// service are left alone.
+	schema := fmt.Sprintf("migrate_test_%d", gofakeit.Uint32())
+	admin := sqlx.MustConnect("postgres", os.Getenv("DATABASE_URI"))
+	_, err := admin.Exec(`CREATE SCHEMA ` + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		require.NoError(t, err)
+		require.NoError(t, admin.Close())
+	})
+
+	u, err := url.Parse(os.Getenv("DATABASE_URI"))
+	require.NoError(t, err)
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	db := sqlx.MustConnect("postgres", u.String())
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INT);")},
+		"001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INT); INSERT INTO b VALUES (1);")},
+		"002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+
+	m, err := migrate.New(db, fsys)
+	require.NoError(t, err)
+
+	// Replicas started at once apply every migration once.
+	var wg sync.WaitGroup
+	applied := make([][]int, 3)
+	for i := range applied {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			applied[i], err = m.Up(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	var all []int
+	for _, a := range applied {
+		all = append(all, a...)
+	}
+	require.ElementsMatch(t, []int{1, 2}, all)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	for _, s := range statuses {
+		require.Equal(t, migrate.StateApplied, s.State)
+		require.False(t, s.AppliedAt.IsZero())
+	}
+
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, reverted)
+
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, migrate.StatePending, statuses[1].State)
+
+	applied[0], err = m.Up(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, applied[0])
+
+	// An edited migration is reported and stops further migrations.
+	fsys["002_second.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id BIGINT);")}
+	fsys["003_third.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE c (id INT);")}
+	m, err = migrate.New(db, fsys)
+	require.NoError(t, err)
+
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, migrate.StateEdited, statuses[1].State)
+	require.Equal(t, migrate.StatePending, statuses[2].State)
+
+	_, err = m.Up(ctx)
+	require.ErrorIs(t, err, migrate.ErrEditedMigration)
+
+	var exists bool
+	require.NoError(t, db.Get(&exists, `SELECT to_regclass('c') IS NOT NULL`))
+	require.False(t, exists)
+}
@@ -0,0 +1,164 @@
+package passhash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Bounds of the parameters of hashes to compare.
+const (
+	maxArgon2idMemory      = 256 * 1024
+	maxArgon2idIterations  = 16
+	maxArgon2idParallelism = 16
+	minArgon2idSaltLength  = 8
+	minArgon2idKeyLength   = 16
+	maxArgon2idKeyLength   = 64
+)
+
+// Argon2id encodes hashes in the PHC string format:
+// "$argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>".
+type Argon2id struct {
+	// Memory in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2id follows the OWASP recommendation of 19 MiB and 2 iterations.
+var DefaultArgon2id = Argon2id{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+type argon2idHash struct {
+	params Argon2id
+	salt   []byte
+	key    []byte
+}
+
+// Hash refuses parameters out of the bounds of Compare, whose hashes could
+// never be checked.
+func (a Argon2id) Hash(password []byte) ([]byte, error) {
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey(password, salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return []byte(fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func (a Argon2id) Compare(hash []byte, password []byte) error {
+	h, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	p := h.params
+	key := argon2.IDKey(password, h.salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, h.key) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return nil
+}
+
+func (a Argon2id) Identifies(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(argon2idPrefix))
+}
+
+func (a Argon2id) Validate(hash []byte) error {
+	_, err := decodeArgon2id(hash)
+	return err
+}
+
+func (a Argon2id) Outdated(hash []byte) bool {
+	h, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	p := h.params
+	return p.Memory != a.Memory ||
+		p.Iterations != a.Iterations ||
+		p.Parallelism != a.Parallelism ||
+		p.SaltLength != a.SaltLength ||
+		p.KeyLength != a.KeyLength
+}
+
+func decodeArgon2id(hash []byte) (argon2idHash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return argon2idHash{}, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2idHash{}, fmt.Errorf("%w: unsupported argon2id version %q", ErrUnknownFormat, parts[2])
+	}
+
+	var h argon2idHash
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.params.Memory, &h.params.Iterations, &h.params.Parallelism)
+	if err != nil {
+		return argon2idHash{}, fmt.Errorf("%w: invalid argon2id parameters %q", ErrUnknownFormat, parts[3])
+	}
+
+	h.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2idHash{}, fmt.Errorf("%w: invalid argon2id salt", ErrUnknownFormat)
+	}
+
+	h.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return argon2idHash{}, fmt.Errorf("%w: invalid argon2id key", ErrUnknownFormat)
+	}
+
+	h.params.SaltLength = uint32(len(h.salt))
+	h.params.KeyLength = uint32(len(h.key))
+
+	if err = h.params.validate(); err != nil {
+		return argon2idHash{}, fmt.Errorf("%w: %w", ErrUnknownFormat, err)
+	}
+
+	return h, nil
+}
+
+func (a Argon2id) validate() error {
+	switch {
+	case a.Iterations < 1 || a.Iterations > maxArgon2idIterations:
+		return fmt.Errorf("argon2id iterations must be within 1 and %d", maxArgon2idIterations)
+	case a.Parallelism < 1 || a.Parallelism > maxArgon2idParallelism:
+		return fmt.Errorf("argon2id parallelism must be within 1 and %d", maxArgon2idParallelism)
+	case a.Memory < 8*uint32(a.Parallelism) || a.Memory > maxArgon2idMemory:
+		return fmt.Errorf("argon2id memory must be within 8 KiB per thread and %d KiB", maxArgon2idMemory)
+	case a.SaltLength < minArgon2idSaltLength:
+		return fmt.Errorf("argon2id salt must be at least %d bytes", minArgon2idSaltLength)
+	case a.KeyLength < minArgon2idKeyLength || a.KeyLength > maxArgon2idKeyLength:
+		return fmt.Errorf("argon2id key must be within %d and %d bytes", minArgon2idKeyLength, maxArgon2idKeyLength)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package passhash
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt keeps the modular crypt format bcrypt has always been stored in,
+// for example "$2a$10$...".
+type Bcrypt struct {
+	Cost int
+}
+
+var DefaultBcrypt = Bcrypt{Cost: bcrypt.DefaultCost}
+
+const (
+	bcryptHashLength = 60
+	// maxBcryptCost keeps a hash from taking unreasonably long to compare.
+	maxBcryptCost = 16
+)
+
+func (b Bcrypt) Hash(password []byte) ([]byte, error) {
+	if b.Cost > maxBcryptCost {
+		return nil, fmt.Errorf("bcrypt cost must be at most %d", maxBcryptCost)
+	}
+
+	return bcrypt.GenerateFromPassword(password, b.Cost)
+}
+
+func (b Bcrypt) Compare(hash []byte, password []byte) error {
+	if err := b.Validate(hash); err != nil {
+		return err
+	}
+
+	err := bcrypt.CompareHashAndPassword(hash, password)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedPassword
+	}
+	return err
+}
+
+func (b Bcrypt) Identifies(hash []byte) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if bytes.HasPrefix(hash, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b Bcrypt) Validate(hash []byte) error {
+	if !b.Identifies(hash) || len(hash) != bcryptHashLength {
+		return ErrUnknownFormat
+	}
+
+	cost, err := bcrypt.Cost(hash)
+	if err != nil || cost > maxBcryptCost {
+		return fmt.Errorf("%w: bcrypt cost must be at most %d", ErrUnknownFormat, maxBcryptCost)
+	}
+
+	return nil
+}
+
+func (b Bcrypt) Outdated(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != b.Cost
+}
@@ -0,0 +1,82 @@
+// Package passhash hashes passwords with one preferred algorithm and verifies
+// hashes made by any supported one.
+package passhash
+
+import (
+	"errors"
+)
+
+var (
+	ErrMismatchedPassword = errors.New("password does not match the hash")
+	ErrUnknownFormat      = errors.New("unknown password hash format")
+)
+
+// Algorithm produces self-describing encoded hashes, which carry the
+// algorithm, its parameters and the salt.
+type Algorithm interface {
+	Hash(password []byte) ([]byte, error)
+	// Compare returns ErrMismatchedPassword if the password does not match.
+	Compare(hash []byte, password []byte) error
+	// Identifies reports whether the hash was made by this algorithm.
+	Identifies(hash []byte) bool
+	// Validate decodes the whole hash and returns ErrUnknownFormat if it is
+	// malformed or its parameters are out of the bounds Compare accepts.
+	Validate(hash []byte) error
+	// Outdated reports whether the hash was made with other parameters than
+	// the algorithm is configured with.
+	Outdated(hash []byte) bool
+}
+
+type Hasher struct {
+	preferred  Algorithm
+	algorithms []Algorithm
+}
+
+// NewHasher hashes new passwords with preferred and verifies hashes made by
+// preferred or any of the others.
+func NewHasher(preferred Algorithm, others ...Algorithm) *Hasher {
+	if preferred == nil {
+		panic("preferred algorithm is nil")
+	}
+
+	return &Hasher{
+		preferred:  preferred,
+		algorithms: append([]Algorithm{preferred}, others...),
+	}
+}
+
+// NewDefaultHasher prefers argon2id and still verifies bcrypt and Django
+// PBKDF2-SHA256 hashes.
+func NewDefaultHasher() *Hasher {
+	return NewHasher(DefaultArgon2id, DefaultBcrypt, DefaultPBKDF2SHA256)
+}
+
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	return h.preferred.Hash([]byte(password))
+}
+
+// Supports reports whether Compare can check the hash, which is fully
+// decoded.
+func (h *Hasher) Supports(hash []byte) bool {
+	for _, a := range h.algorithms {
+		if a.Identifies(hash) {
+			return a.Validate(hash) == nil
+		}
+	}
+	return false
+}
+
+func (h *Hasher) Compare(hash []byte, password string) error {
+	for _, a := range h.algorithms {
+		if a.Identifies(hash) {
+			return a.Compare(hash, []byte(password))
+		}
+	}
+	return ErrUnknownFormat
+}
+
+// NeedsRehash reports whether the hash should be replaced with one made by
+// the preferred algorithm with its current parameters.
+func (h *Hasher) NeedsRehash(hash []byte) bool {
+	return !h.preferred.Identifies(hash) || h.preferred.Outdated(hash)
+}
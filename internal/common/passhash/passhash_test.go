@@ -0,0 +1,102 @@
+package passhash_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+)
+
+func TestHasher(t *testing.T) {
+	weakArgon2id := passhash.DefaultArgon2id
+	weakArgon2id.Iterations = 1
+
+	tests := []struct {
+		name      string
+		preferred passhash.Algorithm
+		prefix    string
+	}{
+		{name: "argon2id", preferred: passhash.DefaultArgon2id, prefix: "$argon2id$v=19$m=19456,t=2,p=1$"},
+		{name: "bcrypt", preferred: passhash.Bcrypt{Cost: 4}, prefix: "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := passhash.NewHasher(tt.preferred, passhash.DefaultBcrypt, weakArgon2id)
+
+			hash, err := h.Hash("password")
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(string(hash), tt.prefix), string(hash))
+
+			require.NoError(t, h.Compare(hash, "password"))
+			require.ErrorIs(t, h.Compare(hash, "another"), passhash.ErrMismatchedPassword)
+			require.False(t, h.NeedsRehash(hash))
+		})
+	}
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	weakArgon2id := passhash.DefaultArgon2id
+	weakArgon2id.Iterations = 1
+
+	h := passhash.NewDefaultHasher()
+
+	bcryptHash, err := passhash.Bcrypt{Cost: 4}.Hash([]byte("password"))
+	require.NoError(t, err)
+	require.NoError(t, h.Compare(bcryptHash, "password"))
+	require.True(t, h.NeedsRehash(bcryptHash))
+
+	weakHash, err := weakArgon2id.Hash([]byte("password"))
+	require.NoError(t, err)
+	require.NoError(t, h.Compare(weakHash, "password"))
+	require.True(t, h.NeedsRehash(weakHash))
+}
+
+func TestHasher_UnknownFormat(t *testing.T) {
+	h := passhash.NewDefaultHasher()
+	require.ErrorIs(t, h.Compare([]byte("plaintext"), "plaintext"), passhash.ErrUnknownFormat)
+	require.ErrorIs(t, h.Compare([]byte("$argon2id$v=19$broken"), "password"), passhash.ErrUnknownFormat)
+}
+
+func TestHasher_DjangoPBKDF2SHA256(t *testing.T) {
+	h := passhash.NewDefaultHasher()
+
+	// Made by Django's PBKDF2PasswordHasher with 1000 iterations.
+	hash := []byte("pbkdf2_sha256$1000$seasalt$YIWkt6M1JFXrHg5s0jZjBSc7C2Cz6QvchSJ0h8Y+i7c=")
+	require.True(t, h.Supports(hash))
+	require.NoError(t, h.Compare(hash, "password"))
+	require.ErrorIs(t, h.Compare(hash, "another"), passhash.ErrMismatchedPassword)
+	require.True(t, h.NeedsRehash(hash))
+
+	own, err := passhash.PBKDF2SHA256{Iterations: 1000, SaltLength: 12}.Hash([]byte("password"))
+	require.NoError(t, err)
+	require.NoError(t, h.Compare(own, "password"))
+}
+
+func TestHasher_Bounds(t *testing.T) {
+	h := passhash.NewDefaultHasher()
+
+	for _, hash := range []string{
+		// argon2.IDKey panics with no iterations or threads.
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=0$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=4194304,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0$",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0",
+		"pbkdf2_sha256$2000000000$seasalt$YIWkt6M1JFXrHg5s0jZjBSc7C2Cz6QvchSJ0h8Y+i7c=",
+		"pbkdf2_sha256$1000$$YIWkt6M1JFXrHg5s0jZjBSc7C2Cz6QvchSJ0h8Y+i7c=",
+		"pbkdf2_sha256$1000$seasalt$not base64",
+		"$2a$31$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+		"$2a$10$truncated",
+	} {
+		require.False(t, h.Supports([]byte(hash)), hash)
+		require.ErrorIs(t, h.Compare([]byte(hash), "password"), passhash.ErrUnknownFormat, hash)
+	}
+
+	weak := passhash.DefaultArgon2id
+	weak.Iterations = 0
+	_, err := weak.Hash([]byte("password"))
+	require.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package passhash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const pbkdf2SHA256Prefix = "pbkdf2_sha256$"
+
+// PBKDF2SHA256 reads and writes the Django format:
+// "pbkdf2_sha256$<iterations>$<salt>$<base64 key>".
+type PBKDF2SHA256 struct {
+	Iterations int
+	SaltLength int
+}
+
+// DefaultPBKDF2SHA256 matches Django 5.0.
+var DefaultPBKDF2SHA256 = PBKDF2SHA256{
+	Iterations: 720000,
+	SaltLength: 22,
+}
+
+const (
+	pbkdf2KeyLength = sha256.Size
+	pbkdf2SaltChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// maxPBKDF2Iterations leaves room for the growing Django defaults, but
+	// keeps a hash from taking unreasonably long to compare.
+	maxPBKDF2Iterations = 10_000_000
+	maxPBKDF2KeyLength  = 64
+)
+
+func (p PBKDF2SHA256) Hash(password []byte) ([]byte, error) {
+	// Django salts are alphanumeric strings, not raw bytes.
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	for i, b := range salt {
+		salt[i] = pbkdf2SaltChars[int(b)%len(pbkdf2SaltChars)]
+	}
+
+	key := pbkdf2.Key(password, salt, p.Iterations, pbkdf2KeyLength, sha256.New)
+
+	return []byte(fmt.Sprintf(
+		"%s%d$%s$%s",
+		pbkdf2SHA256Prefix, p.Iterations, salt, base64.StdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func (p PBKDF2SHA256) Compare(hash []byte, password []byte) error {
+	iterations, salt, key, err := decodePBKDF2SHA256(hash)
+	if err != nil {
+		return err
+	}
+
+	actual := pbkdf2.Key(password, salt, iterations, len(key), sha256.New)
+	if subtle.ConstantTimeCompare(actual, key) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return nil
+}
+
+func (p PBKDF2SHA256) Identifies(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(pbkdf2SHA256Prefix))
+}
+
+func (p PBKDF2SHA256) Validate(hash []byte) error {
+	_, _, _, err := decodePBKDF2SHA256(hash)
+	return err
+}
+
+func (p PBKDF2SHA256) Outdated(hash []byte) bool {
+	iterations, _, _, err := decodePBKDF2SHA256(hash)
+	return err != nil || iterations != p.Iterations
+}
+
+func decodePBKDF2SHA256(hash []byte) (int, []byte, []byte, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2_sha256" {
+		return 0, nil, nil, ErrUnknownFormat
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 || iterations > maxPBKDF2Iterations {
+		return 0, nil, nil, fmt.Errorf("%w: invalid pbkdf2_sha256 iterations %q", ErrUnknownFormat, parts[1])
+	}
+
+	if parts[2] == "" {
+		return 0, nil, nil, fmt.Errorf("%w: empty pbkdf2_sha256 salt", ErrUnknownFormat)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 || len(key) > maxPBKDF2KeyLength {
+		return 0, nil, nil, fmt.Errorf("%w: invalid pbkdf2_sha256 key", ErrUnknownFormat)
+	}
+
+	return iterations, []byte(parts[2]), key, nil
+}
@@ -48,7 +48,43 @@ func setMiddlewares(router *chi.Mux, log *slog.Logger) {
 		middleware.SetHeader("X-Content-Type-Options", "nosniff"),
 		middleware.SetHeader("X-Frame-Options", "deny"),
 	)
-	router.Use(middleware.NoCache)
+	router.Use(noCacheByDefault)
+}
+
+// noCacheByDefault is middleware.NoCache keeping the conditional request
+// headers.
+func noCacheByDefault(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&noCacheResponseWriter{ResponseWriter: w}, r)
+	})
+}
+
+type noCacheResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *noCacheResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", "no-cache, no-store, no-transform, must-revalidate, private, max-age=0")
+			w.Header().Set("Expires", "Thu, 01 Jan 1970 00:00:00 UTC")
+			w.Header().Set("Pragma", "no-cache")
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *noCacheResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *noCacheResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 func addCorsMiddleware(router *chi.Mux) {
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// replayedHeaders are recorded with the response. Others, such as rate limit
+// headers, describe the retry rather than the original request.
+var replayedHeaders = []string{"Content-Type", "Content-Location", "Location"}
+
+// IdempotentResponse is the outcome of the first request made with a key.
+// Until the request completes only Fingerprint is set.
+type IdempotentResponse struct {
+	Fingerprint []byte
+	Completed   bool
+
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+var ErrIdempotencyKeyTaken = errors.New("idempotency key is taken")
+
+var (
+	ErrIdempotencyKeyTooLong    = errors.New("idempotency key is too long")
+	ErrIdempotentBodyUnreadable = errors.New("unable to read request body")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key is already used for another request")
+	ErrIdempotentRequestPending = errors.New("request with this idempotency key is in progress")
+)
+
+// IdempotencyStore keeps responses until they expire. Implementations shared
+// by several replicas must apply Begin atomically.
+type IdempotencyStore interface {
+	// Begin reserves key for a new request. If the key is reserved and not
+	// expired, it returns ErrIdempotencyKeyTaken with the existing response.
+	Begin(
+		ctx context.Context,
+		key string,
+		fingerprint []byte,
+		expiresAt time.Time,
+		now time.Time,
+	) (IdempotentResponse, error)
+	Complete(ctx context.Context, key string, res IdempotentResponse) error
+	// Release forgets the key.
+	Release(ctx context.Context, key string) error
+}
+
+type Idempotency struct {
+	store IdempotencyStore
+	ttl   time.Duration
+	log   *slog.Logger
+}
+
+func NewIdempotency(store IdempotencyStore, ttl time.Duration) *Idempotency {
+	if store == nil {
+		panic("idempotency store is nil")
+	}
+
+	return &Idempotency{
+		store: store,
+		ttl:   ttl,
+		log:   logs.DefaultLogger(),
+	}
+}
+
+// NewIdempotencyFromEnv reads the TTL from IDEMPOTENCY_KEY_TTL.
+func NewIdempotencyFromEnv(store IdempotencyStore) *Idempotency {
+	ttl := DefaultIdempotencyTTL
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL"); v != "" {
+		var err error
+		ttl, err = time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			panic("IDEMPOTENCY_KEY_TTL must be a positive duration")
+		}
+	}
+
+	return NewIdempotency(store, ttl)
+}
+
+// Middleware replays the response to the first POST or PATCH request made
+// with the same Idempotency-Key header, route and user. Responses with a 5xx
+// status or 429 are not recorded. It must run after routing and
+// authentication.
+func (i *Idempotency) Middleware(
+	user func(r *http.Request) (string, bool),
+	respond ErrorResponder,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				respond(w, r, ErrIdempotencyKeyTooLong, http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestBytes))
+			if err != nil {
+				respond(w, r, ErrIdempotentBodyUnreadable, http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+
+			scopedKey := r.Method + " " + route + "|" + idempotencyIdentity(r, user) + "|" + key
+			fingerprint := sha256.Sum256(body)
+
+			now := time.Now()
+			existing, err := i.store.Begin(r.Context(), scopedKey, fingerprint[:], now.Add(i.ttl), now)
+			if errors.Is(err, ErrIdempotencyKeyTaken) {
+				replayIdempotentResponse(w, r, existing, fingerprint[:], respond)
+				return
+			} else if err != nil {
+				i.log.Error("Unable to reserve idempotency key", "error", err.Error())
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// The request context may be canceled by now, but the outcome
+			// still has to be stored.
+			ctx := context.WithoutCancel(r.Context())
+
+			defer func() {
+				if p := recover(); p != nil {
+					if err := i.store.Release(ctx, scopedKey); err != nil {
+						i.log.Error("Unable to release idempotency key", "error", err.Error())
+					}
+					panic(p)
+				}
+			}()
+
+			rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.statusCode >= http.StatusInternalServerError || rec.statusCode == http.StatusTooManyRequests {
+				err = i.store.Release(ctx, scopedKey)
+			} else {
+				err = i.store.Complete(ctx, scopedKey, IdempotentResponse{
+					Fingerprint: fingerprint[:],
+					Completed:   true,
+					StatusCode:  rec.statusCode,
+					Header:      recordedHeader(rec.Header()),
+					Body:        rec.body.Bytes(),
+				})
+			}
+			if err != nil {
+				i.log.Error("Unable to store idempotent response", "error", err.Error())
+			}
+		})
+	}
+}
+
+func replayIdempotentResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	res IdempotentResponse,
+	fingerprint []byte,
+	respond ErrorResponder,
+) {
+	if !bytes.Equal(res.Fingerprint, fingerprint) {
+		respond(w, r, ErrIdempotencyKeyReused, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !res.Completed {
+		respond(w, r, ErrIdempotentRequestPending, http.StatusConflict)
+		return
+	}
+
+	for name, values := range res.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(res.StatusCode)
+	_, _ = w.Write(res.Body)
+}
+
+func idempotencyIdentity(r *http.Request, user func(r *http.Request) (string, bool)) string {
+	if user != nil {
+		if uuid, ok := user(r); ok {
+			return "user:" + uuid
+		}
+	}
+	return "anonymous"
+}
+
+func recordedHeader(h http.Header) http.Header {
+	res := make(http.Header)
+	for _, name := range replayedHeaders {
+		if values := h.Values(name); len(values) > 0 {
+			res[name] = values
+		}
+	}
+	return res
+}
+
+// responseRecorder copies the response it passes through.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.statusCode = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
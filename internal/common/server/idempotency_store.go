@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+)
+
+// memoryIdempotencyStore keeps responses in process memory. It is only
+// suitable for a single replica.
+type memoryIdempotencyStore struct {
+	sync.Mutex
+	m map[string]memoryIdempotentResponse
+}
+
+type memoryIdempotentResponse struct {
+	IdempotentResponse
+	ExpiresAt time.Time
+}
+
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{
+		m: make(map[string]memoryIdempotentResponse),
+	}
+}
+
+func (s *memoryIdempotencyStore) Begin(
+	ctx context.Context,
+	key string,
+	fingerprint []byte,
+	expiresAt time.Time,
+	now time.Time,
+) (IdempotentResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for k, res := range s.m {
+		if !now.Before(res.ExpiresAt) {
+			delete(s.m, k)
+		}
+	}
+
+	if res, ok := s.m[key]; ok {
+		return res.IdempotentResponse, ErrIdempotencyKeyTaken
+	}
+
+	s.m[key] = memoryIdempotentResponse{
+		IdempotentResponse: IdempotentResponse{Fingerprint: fingerprint},
+		ExpiresAt:          expiresAt,
+	}
+
+	return IdempotentResponse{}, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, key string, res IdempotentResponse) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if existing, ok := s.m[key]; ok {
+		existing.IdempotentResponse = res
+		s.m[key] = existing
+	}
+
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.m, key)
+
+	return nil
+}
+
+type pgIdempotencyStore struct {
+	db *sqlx.DB
+}
+
+func NewPgIdempotencyStore(db *sqlx.DB) IdempotencyStore {
+	return &pgIdempotencyStore{
+		db: db,
+	}
+}
+
+// Begin removes expired keys first.
+func (s *pgIdempotencyStore) Begin(
+	ctx context.Context,
+	key string,
+	fingerprint []byte,
+	expiresAt time.Time,
+	now time.Time,
+) (res IdempotentResponse, err error) {
+	err = pgutils.RunTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		_, err := pgutils.Exec(
+			ctx, tx,
+			`DELETE FROM
+				idempotency_keys
+			 WHERE
+				expires_at <= $1`,
+			now.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		r, err := pgutils.Exec(
+			ctx, tx,
+			`INSERT INTO
+				idempotency_keys (key, fingerprint, completed, status_code, header, body, expires_at)
+			 VALUES
+				($1, $2, FALSE, 0, '{}', '', $3)
+			 ON CONFLICT (key) DO NOTHING`,
+			key, fingerprint, expiresAt.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		aff, err := r.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if aff == 1 {
+			return nil
+		}
+
+		var row idempotentResponseRow
+		err = pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				fingerprint, completed, status_code, header, body
+			 FROM
+				idempotency_keys
+			 WHERE
+				key = $1`,
+			key,
+		)
+		if err != nil {
+			return err
+		}
+
+		res, err = mapIdempotentResponseFromRow(row)
+		if err != nil {
+			return err
+		}
+
+		return ErrIdempotencyKeyTaken
+	})
+
+	return res, err
+}
+
+func (s *pgIdempotencyStore) Complete(ctx context.Context, key string, res IdempotentResponse) error {
+	row, err := mapIdempotentResponseToRow(res)
+	if err != nil {
+		return err
+	}
+
+	_, err = pgutils.Exec(
+		ctx, s.db,
+		`UPDATE
+			idempotency_keys
+		 SET
+			completed = $2,
+			status_code = $3,
+			header = $4,
+			body = $5
+		 WHERE
+			key = $1`,
+		key, row.Completed, row.StatusCode, row.Header, row.Body,
+	)
+	return err
+}
+
+func (s *pgIdempotencyStore) Release(ctx context.Context, key string) error {
+	_, err := pgutils.Exec(
+		ctx, s.db,
+		`DELETE FROM
+			idempotency_keys
+		 WHERE
+			key = $1`,
+		key,
+	)
+	return err
+}
+
+type idempotentResponseRow struct {
+	Fingerprint []byte `db:"fingerprint"`
+	Completed   bool   `db:"completed"`
+	StatusCode  int    `db:"status_code"`
+	Header      []byte `db:"header"`
+	Body        []byte `db:"body"`
+}
+
+func mapIdempotentResponseFromRow(row idempotentResponseRow) (IdempotentResponse, error) {
+	var header http.Header
+	if err := json.Unmarshal(row.Header, &header); err != nil {
+		return IdempotentResponse{}, err
+	}
+
+	return IdempotentResponse{
+		Fingerprint: row.Fingerprint,
+		Completed:   row.Completed,
+		StatusCode:  row.StatusCode,
+		Header:      header,
+		Body:        row.Body,
+	}, nil
+}
+
+func mapIdempotentResponseToRow(res IdempotentResponse) (idempotentResponseRow, error) {
+	header, err := json.Marshal(res.Header)
+	if err != nil {
+		return idempotentResponseRow{}, err
+	}
+
+	return idempotentResponseRow{
+		Fingerprint: res.Fingerprint,
+		Completed:   res.Completed,
+		StatusCode:  res.StatusCode,
+		Header:      header,
+		Body:        res.Body,
+	}, nil
+}
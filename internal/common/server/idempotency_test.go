@@ -0,0 +1,115 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/server"
+)
+
+func TestIdempotency(t *testing.T) {
+	idempotency := server.NewIdempotency(server.NewMemoryIdempotencyStore(), time.Hour)
+
+	calls := 0
+	status := http.StatusCreated
+	router := chi.NewRouter()
+	router.With(idempotency.Middleware(nil, respondError)).Post("/register", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Location", fmt.Sprintf("/users/%d", calls))
+		w.WriteHeader(status)
+		_, _ = fmt.Fprintf(w, `{"uuid":"%d"}`, calls)
+	})
+
+	do := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(server.IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("key-1", `{"email":"a@b.ru"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, `{"uuid":"1"}`, rec.Body.String())
+	require.Empty(t, rec.Header().Get(server.IdempotentReplayedHeader))
+
+	rec = do("key-1", `{"email":"a@b.ru"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, `{"uuid":"1"}`, rec.Body.String())
+	require.Equal(t, "/users/1", rec.Header().Get("Content-Location"))
+	require.Equal(t, "true", rec.Header().Get(server.IdempotentReplayedHeader))
+	require.Equal(t, 1, calls)
+
+	rec = do("key-1", `{"email":"c@d.ru"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, 1, calls)
+
+	rec = do("", `{"email":"a@b.ru"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, 2, calls)
+
+	// Server errors are not recorded, so the request can be retried.
+	status = http.StatusInternalServerError
+	rec = do("key-2", `{"email":"a@b.ru"}`)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	status = http.StatusCreated
+	rec = do("key-2", `{"email":"a@b.ru"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Empty(t, rec.Header().Get(server.IdempotentReplayedHeader))
+	require.Equal(t, 4, calls)
+}
+
+func TestIdempotency_Panic(t *testing.T) {
+	idempotency := server.NewIdempotency(server.NewMemoryIdempotencyStore(), time.Hour)
+
+	calls := 0
+	handler := idempotency.Middleware(nil, respondError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{}`))
+		req.Header.Set(server.IdempotencyKeyHeader, "key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Panics(t, func() { do() })
+
+	// The key is released, so the retry is handled rather than refused as
+	// in progress.
+	rec := do()
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotency_Expiry(t *testing.T) {
+	store := server.NewMemoryIdempotencyStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := store.Begin(ctx, "key", []byte("a"), now.Add(time.Minute), now)
+	require.NoError(t, err)
+
+	_, err = store.Begin(ctx, "key", []byte("a"), now.Add(time.Minute), now)
+	require.ErrorIs(t, err, server.ErrIdempotencyKeyTaken)
+
+	_, err = store.Begin(ctx, "key", []byte("b"), now.Add(2*time.Minute), now.Add(time.Minute))
+	require.NoError(t, err)
+}
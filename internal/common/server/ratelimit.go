@@ -0,0 +1,385 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs"
+)
+
+type RateLimitScope string
+
+const (
+	// RateLimitScopeRoute shares one bucket between all clients.
+	RateLimitScopeRoute RateLimitScope = "route"
+	RateLimitScopeIP    RateLimitScope = "ip"
+	// RateLimitScopeUser falls back to the client IP for anonymous requests.
+	RateLimitScopeUser RateLimitScope = "user"
+)
+
+const anyRateLimitMatch = "*"
+
+// RateLimit is a token bucket holding up to Limit tokens and refilled with
+// Limit tokens every Period.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets until they are full again.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+	// Peek tells whether Take would allow a request, without taking a token.
+	Peek(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// ErrorResponder writes err with the status code in the error format of the
+// API.
+type ErrorResponder func(w http.ResponseWriter, r *http.Request, err error, code int)
+
+var ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+type tokenBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+func (l RateLimit) take(b *tokenBucket, now time.Time) RateLimitResult {
+	l.refill(b, now)
+
+	allowed := b.Tokens >= 1
+	if allowed {
+		b.Tokens--
+	}
+
+	return l.result(b, allowed)
+}
+
+// peek refills b without taking a token.
+func (l RateLimit) peek(b *tokenBucket, now time.Time) RateLimitResult {
+	l.refill(b, now)
+	return l.result(b, b.Tokens >= 1)
+}
+
+func (l RateLimit) refill(b *tokenBucket, now time.Time) {
+	capacity := float64(l.Limit)
+	rate := capacity / l.Period.Seconds()
+
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = min(capacity, b.Tokens+elapsed*rate)
+	}
+	if now.After(b.UpdatedAt) {
+		b.UpdatedAt = now
+	}
+}
+
+func (l RateLimit) result(b *tokenBucket, allowed bool) RateLimitResult {
+	capacity := float64(l.Limit)
+	rate := capacity / l.Period.Seconds()
+
+	res := RateLimitResult{Allowed: allowed}
+	if !allowed {
+		res.RetryAfter = secondsToDuration((1 - b.Tokens) / rate)
+	}
+
+	res.Remaining = int(b.Tokens)
+	res.Reset = secondsToDuration((capacity - b.Tokens) / rate)
+
+	return res
+}
+
+func (l RateLimit) fullAt(b tokenBucket) time.Time {
+	rate := float64(l.Limit) / l.Period.Seconds()
+	return b.UpdatedAt.Add(secondsToDuration((float64(l.Limit) - b.Tokens) / rate))
+}
+
+// tighter reports whether r is to be shown to the client instead of other.
+func (r RateLimitResult) tighter(other RateLimitResult) bool {
+	if r.Allowed != other.Allowed {
+		return !r.Allowed
+	}
+	if !r.Allowed {
+		return r.RetryAfter > other.RetryAfter
+	}
+	return r.Remaining < other.Remaining
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// RateLimitRule limits requests matching Method and the chi route pattern
+// Route. Both accept "*".
+type RateLimitRule struct {
+	Method string
+	Route  string
+	Scope  RateLimitScope
+	RateLimit
+}
+
+func (r RateLimitRule) String() string {
+	return fmt.Sprintf("%s %s %s %d/%s", r.Method, r.Route, r.Scope, r.Limit, r.Period)
+}
+
+func (r RateLimitRule) matches(method, route string) bool {
+	return (r.Method == anyRateLimitMatch || strings.EqualFold(r.Method, method)) &&
+		(r.Route == anyRateLimitMatch || r.Route == route)
+}
+
+// ParseRateLimitRules parses rules separated by ";". Every rule has the form
+// "<method> <route> <scope> <limit>/<period>", for example
+// "POST /api/register ip 5/1m".
+func ParseRateLimitRules(s string) ([]RateLimitRule, error) {
+	var rules []RateLimitRule
+	for _, spec := range strings.Split(s, ";") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		rule, err := parseRateLimitRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit rule %q: %w", spec, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseRateLimitRule(spec string) (RateLimitRule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 4 {
+		return RateLimitRule{}, fmt.Errorf("expected 4 fields, got %d", len(fields))
+	}
+
+	scope := RateLimitScope(fields[2])
+	switch scope {
+	case RateLimitScopeRoute, RateLimitScopeIP, RateLimitScopeUser:
+	default:
+		return RateLimitRule{}, fmt.Errorf("unknown scope %q", scope)
+	}
+
+	limit, err := ParseRateLimit(fields[3])
+	if err != nil {
+		return RateLimitRule{}, err
+	}
+
+	return RateLimitRule{
+		Method:    strings.ToUpper(fields[0]),
+		Route:     fields[1],
+		Scope:     scope,
+		RateLimit: limit,
+	}, nil
+}
+
+// ParseRateLimit parses "<limit>/<period>", for example "10/1m".
+func ParseRateLimit(s string) (RateLimit, error) {
+	limitStr, periodStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("expected <limit>/<period>, got %q", s)
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return RateLimit{}, fmt.Errorf("limit must be a positive integer, got %q", limitStr)
+	}
+
+	// Allow "10/m" as a shorthand for "10/1m".
+	if periodStr != "" && (periodStr[0] < '0' || periodStr[0] > '9') {
+		periodStr = "1" + periodStr
+	}
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("period must be a positive duration, got %q", periodStr)
+	}
+
+	return RateLimit{Limit: limit, Period: period}, nil
+}
+
+var DefaultAuthFailuresRateLimit = RateLimit{Limit: 30, Period: time.Minute}
+
+type RateLimiter struct {
+	store RateLimitStore
+	rules []RateLimitRule
+	// authFailures is disabled if zero.
+	authFailures RateLimit
+	log          *slog.Logger
+}
+
+func NewRateLimiter(store RateLimitStore, rules []RateLimitRule, authFailures RateLimit) *RateLimiter {
+	if store == nil {
+		panic("rate limit store is nil")
+	}
+
+	return &RateLimiter{
+		store:        store,
+		rules:        rules,
+		authFailures: authFailures,
+		log:          logs.DefaultLogger(),
+	}
+}
+
+// NewRateLimiterFromEnv reads RATE_LIMITS and RATE_LIMIT_AUTH_FAILURES,
+// where "off" disables the limit.
+func NewRateLimiterFromEnv(store RateLimitStore) *RateLimiter {
+	rules, err := ParseRateLimitRules(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		panic(err)
+	}
+
+	authFailures := DefaultAuthFailuresRateLimit
+	switch v := os.Getenv("RATE_LIMIT_AUTH_FAILURES"); v {
+	case "":
+	case "off":
+		authFailures = RateLimit{}
+	default:
+		authFailures, err = ParseRateLimit(v)
+		if err != nil {
+			panic(fmt.Errorf("invalid RATE_LIMIT_AUTH_FAILURES: %w", err))
+		}
+	}
+
+	return NewRateLimiter(store, rules, authFailures)
+}
+
+// Middleware must run after routing and authentication. A store failure lets
+// the request through.
+func (l *RateLimiter) Middleware(
+	user func(r *http.Request) (string, bool),
+	respond ErrorResponder,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+
+			var (
+				tightest     RateLimitResult
+				tightestRule RateLimitRule
+				found        bool
+			)
+			now := time.Now()
+			for _, rule := range l.rules {
+				if !rule.matches(r.Method, route) {
+					continue
+				}
+
+				key := rule.String() + "|" + rateLimitIdentity(r, rule.Scope, user)
+				res, err := l.store.Take(r.Context(), key, rule.RateLimit, now)
+				if err != nil {
+					l.log.Error("Unable to apply rate limit", "rule", rule.String(), "error", err.Error())
+					continue
+				}
+
+				if !found || res.tighter(tightest) {
+					tightest, tightestRule, found = res, rule, true
+				}
+			}
+
+			if !found {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setRateLimitHeaders(w, tightestRule, tightest)
+
+			if !tightest.Allowed {
+				w.Header().Set("Retry-After", durationToSeconds(tightest.RetryAfter))
+				respond(w, r, ErrRateLimitExceeded, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthFailuresMiddleware refuses the requests of a client IP once too many
+// of them were answered with 401. It must run before authentication.
+func (l *RateLimiter) AuthFailuresMiddleware(respond ErrorResponder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l.authFailures.Limit == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "auth-failures|" + rateLimitIdentity(r, RateLimitScopeIP, nil)
+
+			res, err := l.store.Peek(r.Context(), key, l.authFailures, time.Now())
+			if err != nil {
+				l.log.Error("Unable to apply rate limit of authentication failures", "error", err.Error())
+			} else if !res.Allowed {
+				w.Header().Set("Retry-After", durationToSeconds(res.RetryAfter))
+				respond(w, r, ErrRateLimitExceeded, http.StatusTooManyRequests)
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if ww.Status() != http.StatusUnauthorized {
+				return
+			}
+
+			_, err = l.store.Take(context.WithoutCancel(r.Context()), key, l.authFailures, time.Now())
+			if err != nil {
+				l.log.Error("Unable to count authentication failure", "error", err.Error())
+			}
+		})
+	}
+}
+
+func rateLimitIdentity(r *http.Request, scope RateLimitScope, user func(r *http.Request) (string, bool)) string {
+	switch scope {
+	case RateLimitScopeRoute:
+		return ""
+	case RateLimitScopeUser:
+		if user != nil {
+			if uuid, ok := user(r); ok {
+				return "user:" + uuid
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, rule RateLimitRule, res RateLimitResult) {
+	h := w.Header()
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rule.Limit, int(rule.Period.Seconds())))
+	h.Set("RateLimit-Limit", strconv.Itoa(rule.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", durationToSeconds(res.Reset))
+}
+
+func durationToSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+)
+
+// rateLimitSweepInterval is how often the memory store forgets full buckets.
+const rateLimitSweepInterval = time.Minute
+
+// memoryRateLimitStore keeps buckets in process memory. It is only suitable
+// for a single replica.
+type memoryRateLimitStore struct {
+	sync.Mutex
+	m         map[string]memoryTokenBucket
+	nextSweep time.Time
+}
+
+type memoryTokenBucket struct {
+	tokenBucket
+	FullAt time.Time
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		m: make(map[string]memoryTokenBucket),
+	}
+}
+
+func (s *memoryRateLimitStore) Take(
+	ctx context.Context,
+	key string,
+	limit RateLimit,
+	now time.Time,
+) (RateLimitResult, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.sweep(now)
+
+	b := s.m[key]
+	res := limit.take(&b.tokenBucket, now)
+	b.FullAt = limit.fullAt(b.tokenBucket)
+	s.m[key] = b
+
+	return res, nil
+}
+
+func (s *memoryRateLimitStore) Peek(
+	ctx context.Context,
+	key string,
+	limit RateLimit,
+	now time.Time,
+) (RateLimitResult, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	b := s.m[key]
+	return limit.peek(&b.tokenBucket, now), nil
+}
+
+// sweep forgets the full buckets, which are the same as missing ones.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(rateLimitSweepInterval)
+
+	for key, b := range s.m {
+		if !now.Before(b.FullAt) {
+			delete(s.m, key)
+		}
+	}
+}
+
+type pgRateLimitStore struct {
+	db *sqlx.DB
+}
+
+func NewPgRateLimitStore(db *sqlx.DB) RateLimitStore {
+	return &pgRateLimitStore{
+		db: db,
+	}
+}
+
+// Take locks the bucket row. It removes full buckets first.
+func (s *pgRateLimitStore) Take(
+	ctx context.Context,
+	key string,
+	limit RateLimit,
+	now time.Time,
+) (res RateLimitResult, err error) {
+	_, err = pgutils.Exec(
+		ctx, s.db,
+		`DELETE FROM
+			rate_limit_buckets
+		 WHERE
+			full_at <= $1`,
+		now.UTC(),
+	)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	err = pgutils.RunTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var row tokenBucketRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`INSERT INTO
+				rate_limit_buckets (key, tokens, updated_at, full_at)
+			 VALUES
+				($1, $2, $3, $3)
+			 ON CONFLICT (key) DO UPDATE SET
+				key = EXCLUDED.key
+			 RETURNING
+				tokens, updated_at`,
+			key, float64(limit.Limit), now.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+
+		b := tokenBucket{
+			Tokens:    row.Tokens,
+			UpdatedAt: row.UpdatedAt.Local(),
+		}
+		res = limit.take(&b, now)
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				rate_limit_buckets
+			 SET
+				tokens = $2,
+				updated_at = $3,
+				full_at = $4
+			 WHERE
+				key = $1`,
+			key, b.Tokens, b.UpdatedAt.UTC(), limit.fullAt(b).UTC(),
+		)
+		return err
+	})
+
+	return res, err
+}
+
+func (s *pgRateLimitStore) Peek(
+	ctx context.Context,
+	key string,
+	limit RateLimit,
+	now time.Time,
+) (RateLimitResult, error) {
+	var row tokenBucketRow
+	err := pgutils.Get(
+		ctx, s.db, &row,
+		`SELECT
+			tokens, updated_at
+		 FROM
+			rate_limit_buckets
+		 WHERE
+			key = $1`,
+		key,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return limit.peek(&tokenBucket{}, now), nil
+	} else if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	b := tokenBucket{
+		Tokens:    row.Tokens,
+		UpdatedAt: row.UpdatedAt.Local(),
+	}
+	return limit.peek(&b, now), nil
+}
+
+type tokenBucketRow struct {
+	Tokens    float64   `db:"tokens"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimitStore_Sweep(t *testing.T) {
+	s := NewMemoryRateLimitStore().(*memoryRateLimitStore)
+	ctx := context.Background()
+	limit := RateLimit{Limit: 2, Period: time.Minute}
+	now := time.Now()
+
+	_, err := s.Take(ctx, "a", limit, now)
+	require.NoError(t, err)
+	_, err = s.Take(ctx, "b", limit, now.Add(rateLimitSweepInterval))
+	require.NoError(t, err)
+
+	// "a" was refilled within the period and is forgotten, "b" is not full
+	// yet.
+	require.NotContains(t, s.m, "a")
+	require.Contains(t, s.m, "b")
+
+	_, err = s.Take(ctx, "c", limit, now.Add(3*rateLimitSweepInterval))
+	require.NoError(t, err)
+	require.NotContains(t, s.m, "b")
+	require.Contains(t, s.m, "c")
+}
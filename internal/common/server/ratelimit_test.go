@@ -0,0 +1,123 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/server"
+)
+
+func TestParseRateLimitRules(t *testing.T) {
+	rules, err := server.ParseRateLimitRules("POST /api/register ip 5/1m; * * user 100/h;")
+	require.NoError(t, err)
+	require.Equal(t, []server.RateLimitRule{
+		{
+			Method:    "POST",
+			Route:     "/api/register",
+			Scope:     server.RateLimitScopeIP,
+			RateLimit: server.RateLimit{Limit: 5, Period: time.Minute},
+		},
+		{
+			Method:    "*",
+			Route:     "*",
+			Scope:     server.RateLimitScopeUser,
+			RateLimit: server.RateLimit{Limit: 100, Period: time.Hour},
+		},
+	}, rules)
+
+	for _, spec := range []string{
+		"POST /api/register 5/1m",
+		"POST /api/register host 5/1m",
+		"POST /api/register ip 0/1m",
+		"POST /api/register ip 5",
+		"POST /api/register ip 5/",
+	} {
+		_, err = server.ParseRateLimitRules(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	rules, err := server.ParseRateLimitRules("POST /register ip 2/1h; * * route 3/1h")
+	require.NoError(t, err)
+	limiter := server.NewRateLimiter(server.NewMemoryRateLimitStore(), rules, server.RateLimit{})
+
+	router := chi.NewRouter()
+	router.With(limiter.Middleware(nil, respondError)).Post("/register", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	do := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/register", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("10.0.0.1:1234")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "2;w=3600", rec.Header().Get("RateLimit-Policy"))
+
+	rec = do("10.0.0.1:1234")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = do("10.0.0.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("Retry-After"))
+	require.Contains(t, rec.Body.String(), server.ErrRateLimitExceeded.Error())
+
+	// Rejected requests still count against the route limit.
+	rec = do("10.0.0.2:1234")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "3", rec.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimiter_AuthFailures(t *testing.T) {
+	limiter := server.NewRateLimiter(server.NewMemoryRateLimitStore(), nil, server.RateLimit{Limit: 2, Period: time.Hour})
+
+	handler := limiter.AuthFailuresMiddleware(respondError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(remoteAddr string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Authenticated requests are not counted.
+	for range 3 {
+		require.Equal(t, http.StatusOK, do("10.0.0.1:1234", "valid").Code)
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do("10.0.0.1:1234", "invalid").Code)
+	require.Equal(t, http.StatusUnauthorized, do("10.0.0.1:1234", "invalid").Code)
+
+	// Once the failures are used up, the IP is refused even with a valid
+	// token, so tokens can not be guessed.
+	rec := do("10.0.0.1:1234", "valid")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusOK, do("10.0.0.2:1234", "valid").Code)
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, err error, code int) {
+	http.Error(w, err.Error(), code)
+}
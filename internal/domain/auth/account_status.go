@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+type AccountState string
+
+const (
+	AccountActive    AccountState = "active"
+	AccountSuspended AccountState = "suspended"
+	AccountDisabled  AccountState = "disabled"
+)
+
+var knownAccountStates = []AccountState{AccountActive, AccountSuspended, AccountDisabled}
+
+func NewAccountState(s string) (AccountState, error) {
+	state := AccountState(s)
+	if !slices.Contains(knownAccountStates, state) {
+		return "", commonerrs.NewInvalidInputError(fmt.Sprintf("unknown account status %q", s))
+	}
+	return state, nil
+}
+
+const maxStatusReasonLength = 512
+
+// AccountStatus tells whether the user may log in. It is read through
+// Effective.
+type AccountStatus struct {
+	State AccountState
+	// Until is set for suspensions only.
+	Until time.Time
+
+	// Reason, ChangedBy and ChangedAt are empty until an admin changes the
+	// status for the first time.
+	Reason    string
+	ChangedBy string
+	ChangedAt time.Time
+}
+
+// Effective returns the state at the moment now.
+func (s AccountStatus) Effective(now time.Time) AccountState {
+	switch s.State {
+	case AccountDisabled:
+		return AccountDisabled
+	case AccountSuspended:
+		if now.Before(s.Until) {
+			return AccountSuspended
+		}
+	}
+	return AccountActive
+}
+
+// NewAccountStatus validates a status set by the admin changedBy. A reason is
+// always required, also for lifting a suspension.
+func NewAccountStatus(
+	state AccountState,
+	until time.Time,
+	reason string,
+	changedBy string,
+	now time.Time,
+) (AccountStatus, error) {
+	if !slices.Contains(knownAccountStates, state) {
+		return AccountStatus{}, commonerrs.NewInvalidInputError(fmt.Sprintf("unknown account status %q", state))
+	}
+
+	if state == AccountSuspended && !until.After(now) {
+		return AccountStatus{}, commonerrs.NewInvalidInputError("expected suspension end in the future")
+	}
+	if state != AccountSuspended && !until.IsZero() {
+		return AccountStatus{}, commonerrs.NewInvalidInputError("only suspensions have an end")
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return AccountStatus{}, commonerrs.NewInvalidInputError("expected not empty reason")
+	}
+	if utf8.RuneCountInString(reason) > maxStatusReasonLength {
+		return AccountStatus{}, commonerrs.NewInvalidInputError(
+			fmt.Sprintf("reason must be at most %d characters long", maxStatusReasonLength),
+		)
+	}
+
+	if changedBy == "" {
+		return AccountStatus{}, commonerrs.NewInvalidInputError("expected not empty changedBy")
+	}
+
+	return AccountStatus{
+		State:     state,
+		Until:     until,
+		Reason:    reason,
+		ChangedBy: changedBy,
+		ChangedAt: now,
+	}, nil
+}
+
+// Codes of AccountInactiveError, which clients may rely on.
+const (
+	AccountInactiveSuspended = "account_suspended"
+	AccountInactiveDisabled  = "account_disabled"
+)
+
+// AccountInactiveError is returned when a suspended or disabled user logs in
+// or uses a token.
+type AccountInactiveError struct {
+	State AccountState
+	// Until is when a suspension lifts.
+	Until time.Time
+}
+
+func (e AccountInactiveError) Error() string {
+	if e.State == AccountSuspended {
+		return fmt.Sprintf("account is suspended until %s", e.Until.UTC().Format(time.RFC3339))
+	}
+	return "account is disabled"
+}
+
+func (e AccountInactiveError) Code() string {
+	if e.State == AccountSuspended {
+		return AccountInactiveSuspended
+	}
+	return AccountInactiveDisabled
+}
@@ -0,0 +1,92 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+const testAdminUUID = "0190a5c2-7b3e-7d4f-9a1b-000000000001"
+
+func TestNewAccountStatus(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		state   auth.AccountState
+		until   time.Time
+		reason  string
+		wantErr bool
+	}{
+		{name: "active", state: auth.AccountActive, reason: "appealed"},
+		{name: "suspended", state: auth.AccountSuspended, until: now.Add(time.Hour), reason: "spam"},
+		{name: "disabled", state: auth.AccountDisabled, reason: "left the university"},
+		{name: "unknown state", state: "banned", reason: "spam", wantErr: true},
+		{name: "no reason", state: auth.AccountDisabled, reason: "  ", wantErr: true},
+		{name: "suspended without end", state: auth.AccountSuspended, reason: "spam", wantErr: true},
+		{name: "suspended in the past", state: auth.AccountSuspended, until: now.Add(-time.Hour), reason: "spam", wantErr: true},
+		{name: "disabled with end", state: auth.AccountDisabled, until: now.Add(time.Hour), reason: "spam", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := auth.NewAccountStatus(tt.state, tt.until, tt.reason, testAdminUUID, now)
+			if tt.wantErr {
+				require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.state, s.Effective(now))
+			require.Equal(t, testAdminUUID, s.ChangedBy)
+		})
+	}
+}
+
+func TestAccountStatus_SuspensionLifts(t *testing.T) {
+	now := time.Now()
+	s, err := auth.NewAccountStatus(auth.AccountSuspended, now.Add(time.Hour), "spam", testAdminUUID, now)
+	require.NoError(t, err)
+
+	require.Equal(t, auth.AccountSuspended, s.Effective(now.Add(time.Hour-time.Nanosecond)))
+	require.Equal(t, auth.AccountActive, s.Effective(now.Add(time.Hour)))
+}
+
+func TestUser_CheckActive(t *testing.T) {
+	now := time.Now()
+	user := auth.MustNewUser(
+		"0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b", "test@test.com", "password", passhash.NewHasher(passhash.Bcrypt{Cost: 4}),
+	)
+	require.NoError(t, user.CheckActive(now))
+
+	s, err := auth.NewAccountStatus(auth.AccountSuspended, now.Add(time.Hour), "spam", testAdminUUID, now)
+	require.NoError(t, err)
+	require.NoError(t, user.ChangeStatus(s))
+
+	var inactiveErr auth.AccountInactiveError
+	require.ErrorAs(t, user.CheckActive(now), &inactiveErr)
+	require.Equal(t, auth.AccountInactiveSuspended, inactiveErr.Code())
+	require.True(t, inactiveErr.Until.Equal(now.Add(time.Hour)))
+	require.NoError(t, user.CheckActive(now.Add(time.Hour)))
+
+	s, err = auth.NewAccountStatus(auth.AccountDisabled, time.Time{}, "left", testAdminUUID, now)
+	require.NoError(t, err)
+	require.NoError(t, user.ChangeStatus(s))
+	require.ErrorAs(t, user.CheckActive(now.Add(24*time.Hour)), &inactiveErr)
+	require.Equal(t, auth.AccountInactiveDisabled, inactiveErr.Code())
+}
+
+func TestUser_ChangeStatus_Own(t *testing.T) {
+	user := auth.MustNewUser(
+		"0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b", "test@test.com", "password", passhash.NewHasher(passhash.Bcrypt{Cost: 4}),
+	)
+
+	s, err := auth.NewAccountStatus(auth.AccountDisabled, time.Time{}, "oops", user.UUID, time.Now())
+	require.NoError(t, err)
+	require.ErrorAs(t, user.ChangeStatus(s), &commonerrs.InvalidInputError{})
+	require.Equal(t, auth.AccountActive, user.Status.State)
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+type AuditAction string
+
+const (
+	AuditLoginSucceeded AuditAction = "login.succeeded"
+	AuditLoginFailed    AuditAction = "login.failed"
+
+	AuditUserRegistered    AuditAction = "user.registered"
+	AuditUserImported      AuditAction = "user.imported"
+	AuditUserRolesGranted  AuditAction = "user.roles_granted"
+	AuditUserStatusChanged AuditAction = "user.status_changed"
+
+	AuditSessionRevoked      AuditAction = "session.revoked"
+	AuditUserSessionsRevoked AuditAction = "user.sessions_revoked"
+
+	AuditTokenCreated AuditAction = "token.created"
+	AuditTokenRevoked AuditAction = "token.revoked"
+
+	AuditInvitationCreated AuditAction = "invitation.created"
+
+	AuditWebhookCreated AuditAction = "webhook.created"
+	AuditWebhookDeleted AuditAction = "webhook.deleted"
+)
+
+var knownAuditActions = []AuditAction{
+	AuditLoginSucceeded,
+	AuditLoginFailed,
+	AuditUserRegistered,
+	AuditUserImported,
+	AuditUserRolesGranted,
+	AuditUserStatusChanged,
+	AuditSessionRevoked,
+	AuditUserSessionsRevoked,
+	AuditTokenCreated,
+	AuditTokenRevoked,
+	AuditInvitationCreated,
+	AuditWebhookCreated,
+	AuditWebhookDeleted,
+}
+
+func NewAuditAction(s string) (AuditAction, error) {
+	action := AuditAction(s)
+	if !slices.Contains(knownAuditActions, action) {
+		return "", commonerrs.NewInvalidInputError(fmt.Sprintf("unknown audit action %q", s))
+	}
+	return action, nil
+}
+
+// RequestInfo describes the request that causes audit events.
+type RequestInfo struct {
+	// ActorUUID is the authenticated user, empty for anonymous requests.
+	ActorUUID string
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+type requestInfoCtxKey struct{}
+
+func ContextWithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoCtxKey{}, info)
+}
+
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoCtxKey{}).(RequestInfo)
+	return info
+}
+
+// AuditEvent is an entry of the append-only audit log. Events are chained per
+// target by the hash of the previous event.
+type AuditEvent struct {
+	// Seq orders the events. Numbers may be skipped.
+	Seq    int64
+	Action AuditAction
+
+	ActorUUID  string
+	TargetUUID string
+	IP         string
+	UserAgent  string
+	RequestID  string
+	Details    map[string]string
+
+	CreatedAt time.Time
+
+	PrevHash []byte
+	Hash     []byte
+}
+
+const (
+	maxAuditUserAgent = 512
+	maxAuditRequestID = 128
+	maxAuditDetail    = 1024
+)
+
+// NewAuditEvent takes the actor and the client from the request info in ctx.
+func NewAuditEvent(
+	ctx context.Context,
+	action AuditAction,
+	targetUUID string,
+	details map[string]string,
+) *AuditEvent {
+	info := RequestInfoFromContext(ctx)
+
+	var clean map[string]string
+	if len(details) > 0 {
+		clean = make(map[string]string, len(details))
+		for k, v := range details {
+			clean[k] = clip(v, maxAuditDetail)
+		}
+	}
+
+	return &AuditEvent{
+		Action:     action,
+		ActorUUID:  info.ActorUUID,
+		TargetUUID: targetUUID,
+		IP:         info.IP,
+		UserAgent:  clip(info.UserAgent, maxAuditUserAgent),
+		RequestID:  clip(info.RequestID, maxAuditRequestID),
+		Details:    clean,
+		// Postgres keeps microseconds, and the hash must survive a round trip.
+		CreatedAt: time.Now().Truncate(time.Microsecond),
+	}
+}
+
+// clip cuts s to at most n bytes of valid UTF-8 without NUL characters.
+func clip(s string, n int) string {
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.ReplaceAll(strings.ToValidUTF8(s, ""), "\x00", "")
+}
+
+// ChainKey names the chain of the event. Events without a target share the
+// chain keyed "".
+func (e *AuditEvent) ChainKey() string {
+	return e.TargetUUID
+}
+
+// Chain numbers the event seq and places it after prev, the last event of its
+// chain, or nil if the event starts the chain.
+func (e *AuditEvent) Chain(seq int64, prev *AuditEvent) {
+	e.Seq = seq
+	if prev == nil {
+		e.PrevHash = make([]byte, sha256.Size)
+	} else {
+		e.PrevHash = prev.Hash
+	}
+	e.Hash = e.ComputeHash()
+}
+
+// ComputeHash hashes every field except Hash itself.
+func (e *AuditEvent) ComputeHash() []byte {
+	h := sha256.New()
+
+	var buf [binary.MaxVarintLen64]byte
+	write := func(b []byte) {
+		h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(b)))])
+		h.Write(b)
+	}
+	writeString := func(s string) {
+		write([]byte(s))
+	}
+
+	writeString(strconv.FormatInt(e.Seq, 10))
+	writeString(string(e.Action))
+	writeString(e.ActorUUID)
+	writeString(e.TargetUUID)
+	writeString(e.IP)
+	writeString(e.UserAgent)
+	writeString(e.RequestID)
+
+	keys := make([]string, 0, len(e.Details))
+	for k := range e.Details {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	writeString(strconv.Itoa(len(keys)))
+	for _, k := range keys {
+		writeString(k)
+		writeString(e.Details[k])
+	}
+
+	writeString(strconv.FormatInt(e.CreatedAt.UnixMicro(), 10))
+	write(e.PrevHash)
+
+	return h.Sum(nil)
+}
+
+type AuditChainBrokenError struct {
+	Seq int64
+}
+
+func (e AuditChainBrokenError) Error() string {
+	return fmt.Sprintf("audit log chain is broken at event %d", e.Seq)
+}
+
+// VerifyAuditChain checks consecutive events of one chain following prev,
+// which is nil if the events start the chain.
+func VerifyAuditChain(prev *AuditEvent, events []*AuditEvent) error {
+	for _, e := range events {
+		minSeq, wantPrevHash := int64(1), make([]byte, sha256.Size)
+		if prev != nil {
+			minSeq, wantPrevHash = prev.Seq+1, prev.Hash
+		}
+
+		if e.Seq < minSeq ||
+			!bytes.Equal(e.PrevHash, wantPrevHash) ||
+			!bytes.Equal(e.Hash, e.ComputeHash()) {
+			return AuditChainBrokenError{Seq: e.Seq}
+		}
+
+		prev = e
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+const (
+	DefaultAuditPageLimit = 50
+	MaxAuditPageLimit     = 200
+)
+
+// AuditFilter matches events meeting every set condition.
+type AuditFilter struct {
+	ActorUUID  string
+	TargetUUID string
+	Action     AuditAction
+	// From is inclusive and To is exclusive.
+	From time.Time
+	To   time.Time
+}
+
+// ListAuditEvents selects a page of events, newest first.
+type ListAuditEvents struct {
+	Filter AuditFilter
+
+	// Cursor continues the listing after the page it was returned with.
+	Cursor string
+	Limit  int
+}
+
+func NewListAuditEvents(filter AuditFilter, cursor string, limit int) (ListAuditEvents, error) {
+	if filter.Action != "" {
+		if _, err := NewAuditAction(string(filter.Action)); err != nil {
+			return ListAuditEvents{}, err
+		}
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return ListAuditEvents{}, commonerrs.NewInvalidInputError("expected time range start before its end")
+	}
+
+	if limit == 0 {
+		limit = DefaultAuditPageLimit
+	}
+	if limit < 1 || limit > MaxAuditPageLimit {
+		return ListAuditEvents{}, commonerrs.NewInvalidInputError("expected limit between 1 and 200")
+	}
+
+	l := ListAuditEvents{
+		Filter: filter,
+		Cursor: cursor,
+		Limit:  limit,
+	}
+
+	if _, err := l.BeforeSeq(); err != nil {
+		return ListAuditEvents{}, err
+	}
+
+	return l, nil
+}
+
+// BeforeSeq returns the number the next page of events is below, or 0 for the
+// first page.
+func (l ListAuditEvents) BeforeSeq() (int64, error) {
+	if l.Cursor == "" {
+		return 0, nil
+	}
+
+	seq, err := strconv.ParseInt(l.Cursor, 10, 64)
+	if err != nil || seq < 1 {
+		return 0, commonerrs.NewInvalidInputError("invalid cursor")
+	}
+
+	return seq, nil
+}
+
+// NextCursor returns the cursor of the page ending with last.
+func (l ListAuditEvents) NextCursor(last *AuditEvent) string {
+	return strconv.FormatInt(last.Seq, 10)
+}
+
+// Matches reports whether the event passes the filter. Repositories that can
+// not filter in the query use it.
+func (f AuditFilter) Matches(e *AuditEvent) bool {
+	if f.ActorUUID != "" && e.ActorUUID != f.ActorUUID {
+		return false
+	}
+
+	if f.TargetUUID != "" && e.TargetUUID != f.TargetUUID {
+		return false
+	}
+
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+
+	if !f.From.IsZero() && e.CreatedAt.Before(f.From) {
+		return false
+	}
+
+	if !f.To.IsZero() && !e.CreatedAt.Before(f.To) {
+		return false
+	}
+
+	return true
+}
+
+type AuditEventsPage struct {
+	Events []*AuditEvent
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+type AuditLogRepository interface {
+	// Append chains the event after the last one of its chain and saves it.
+	// Events are never changed or deleted.
+	Append(ctx context.Context, e *AuditEvent) error
+	ListAuditEvents(ctx context.Context, l ListAuditEvents) (AuditEventsPage, error)
+	// AuditChainKeys returns up to limit keys of the chains following after in
+	// order, which leaves out the chain keyed "".
+	AuditChainKeys(ctx context.Context, after string, limit int) ([]string, error)
+	// AuditChainEvents returns up to limit events of the chain following the
+	// event seq in order.
+	AuditChainEvents(ctx context.Context, chain string, seq int64, limit int) ([]*AuditEvent, error)
+}
@@ -0,0 +1,109 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestNewAuditEvent(t *testing.T) {
+	ctx := auth.ContextWithRequestInfo(context.Background(), auth.RequestInfo{
+		ActorUUID: testAdminUUID,
+		IP:        "192.0.2.1",
+		UserAgent: strings.Repeat("a", 1000),
+		RequestID: "req\x00-1",
+	})
+
+	e := auth.NewAuditEvent(ctx, auth.AuditUserStatusChanged, "target", map[string]string{"reason": "bad\xffbyte"})
+	require.Equal(t, testAdminUUID, e.ActorUUID)
+	require.Equal(t, "192.0.2.1", e.IP)
+	require.Len(t, e.UserAgent, 512)
+	require.Equal(t, "req-1", e.RequestID)
+	require.Equal(t, "badbyte", e.Details["reason"])
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	ctx := context.Background()
+
+	newChain := func() []*auth.AuditEvent {
+		var events []*auth.AuditEvent
+		var prev *auth.AuditEvent
+		for i, session := range []string{"a", "b", "c"} {
+			e := auth.NewAuditEvent(ctx, auth.AuditSessionRevoked, "target", map[string]string{"session": session})
+			// Other chains take the skipped numbers.
+			e.Chain(int64(2*i+1), prev)
+			events = append(events, e)
+			prev = e
+		}
+		return events
+	}
+
+	tests := []struct {
+		name       string
+		tamper     func(events []*auth.AuditEvent) []*auth.AuditEvent
+		wantBroken int64
+	}{
+		{
+			name:   "intact",
+			tamper: func(events []*auth.AuditEvent) []*auth.AuditEvent { return events },
+		},
+		{
+			name: "edited field",
+			tamper: func(events []*auth.AuditEvent) []*auth.AuditEvent {
+				events[1].TargetUUID = "x"
+				return events
+			},
+			wantBroken: 3,
+		},
+		{
+			name: "edited details",
+			tamper: func(events []*auth.AuditEvent) []*auth.AuditEvent {
+				events[2].Details["session"] = "x"
+				return events
+			},
+			wantBroken: 5,
+		},
+		{
+			name: "removed event",
+			tamper: func(events []*auth.AuditEvent) []*auth.AuditEvent {
+				return append(events[:1], events[2:]...)
+			},
+			wantBroken: 5,
+		},
+		{
+			name: "reordered events",
+			tamper: func(events []*auth.AuditEvent) []*auth.AuditEvent {
+				events[1], events[2] = events[2], events[1]
+				return events
+			},
+			wantBroken: 5,
+		},
+		{
+			name: "rehashed event",
+			tamper: func(events []*auth.AuditEvent) []*auth.AuditEvent {
+				events[0].TargetUUID = "x"
+				events[0].Hash = events[0].ComputeHash()
+				return events
+			},
+			wantBroken: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := auth.VerifyAuditChain(nil, tt.tamper(newChain()))
+			if tt.wantBroken == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var brokenErr auth.AuditChainBrokenError
+			require.ErrorAs(t, err, &brokenErr)
+			require.Equal(t, tt.wantBroken, brokenErr.Seq)
+		})
+	}
+}
@@ -0,0 +1,515 @@
+// Package authtest tests every implementation of the auth repositories.
+package authtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// TestUsersRepository calls newRepo once by each test.
+func TestUsersRepository(t *testing.T, newRepo func(t *testing.T) auth.UsersRepository) {
+	t.Parallel()
+
+	t.Run("should save user", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+		user := newUser()
+
+		err := r.Save(ctx, user)
+		require.NoError(t, err)
+
+		saved, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+
+		requireEqualUsers(t, user, saved)
+	})
+
+	t.Run("should return error if user uuid already exists", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user1 := newUser()
+		err := r.Save(ctx, user1)
+		require.NoError(t, err)
+
+		user2 := newUser()
+		user2.UUID = user1.UUID
+		err = r.Save(ctx, user2)
+		require.ErrorIs(t, err, auth.ErrUserAlreadyExists)
+	})
+
+	t.Run("should return error if user email already exists", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user1 := newUser()
+		err := r.Save(ctx, user1)
+		require.NoError(t, err)
+
+		user2 := newUser()
+		user2.Email = user1.Email
+		err = r.Save(ctx, user2)
+		require.ErrorIs(t, err, auth.ErrUserAlreadyExists)
+	})
+
+	t.Run("should return error if user email differs only in case", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user1 := newUser()
+		err := r.Save(ctx, user1)
+		require.NoError(t, err)
+
+		user2 := newUser()
+		user2.Email = auth.MustNewEmail(strings.ToUpper(user1.Email.String()))
+		err = r.Save(ctx, user2)
+		require.ErrorIs(t, err, auth.ErrUserAlreadyExists)
+	})
+
+	t.Run("should find user by email in another case", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user := newUser()
+		err := r.Save(ctx, user)
+		require.NoError(t, err)
+
+		found, err := r.UserByEmail(ctx, auth.MustNewEmail(strings.ToUpper(user.Email.String())))
+		require.NoError(t, err)
+		require.Equal(t, user.UUID, found.UUID)
+		require.Equal(t, user.Email.String(), found.Email.String())
+	})
+
+	t.Run("should return error if user not found by uuid", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		fakeUUID := gofakeit.UUID()
+		_, err := r.User(ctx, fakeUUID)
+		require.EqualError(t, err, fmt.Sprintf("user with UUID %s not found", fakeUUID))
+	})
+
+	t.Run("should return error if user not found by email", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		fakeEmail := gofakeit.Email()
+		_, err := r.UserByEmail(ctx, auth.MustNewEmail(fakeEmail))
+		require.EqualError(t, err, fmt.Sprintf("user with email %s not found", fakeEmail))
+	})
+
+	t.Run("should return error if user email is taken on update", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user1 := newUser()
+		require.NoError(t, r.Save(ctx, user1))
+		user2 := newUser()
+		require.NoError(t, r.Save(ctx, user2))
+
+		err := r.Update(ctx, user2.UUID, func(_ context.Context, u *auth.User) error {
+			u.Email = auth.MustNewEmail(strings.ToUpper(user1.Email.String()))
+			return nil
+		})
+		require.ErrorIs(t, err, auth.ErrUserAlreadyExists)
+
+		stored, err := r.User(ctx, user2.UUID)
+		require.NoError(t, err)
+		require.Equal(t, user2.Email, stored.Email)
+	})
+
+	t.Run("should save one of concurrent users with the same email", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		email := gofakeit.Email()
+		const saves = 10
+		var wg sync.WaitGroup
+		errs := make(chan error, saves)
+		for range saves {
+			user := newUser()
+			user.Email = auth.MustNewEmail(email)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- r.Save(ctx, user)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		saved := 0
+		for err := range errs {
+			if err == nil {
+				saved++
+			} else {
+				require.ErrorIs(t, err, auth.ErrUserAlreadyExists)
+			}
+		}
+		require.Equal(t, 1, saved)
+	})
+
+	t.Run("should keep user if update fails", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user := newUser()
+		user.GrantRole(auth.RoleAdmin)
+		require.NoError(t, r.Save(ctx, user))
+
+		errFailed := errors.New("failed")
+		err := r.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+			u.Roles[0] = "changed"
+			u.Profile.Group = "changed"
+			return errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+
+		stored, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Equal(t, []auth.Role{auth.RoleAdmin}, stored.Roles)
+		require.Empty(t, stored.Profile.Group)
+		require.Equal(t, user.Version, stored.Version)
+	})
+
+	t.Run("should not change user through a returned one", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user := newUser()
+		user.GrantRole(auth.RoleAdmin)
+		require.NoError(t, r.Save(ctx, user))
+		user.Roles[0] = "changed"
+
+		got, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+		got.Roles[0] = "changed"
+		got.Passhash[0]++
+
+		stored, err := r.UserByEmail(ctx, user.Email)
+		require.NoError(t, err)
+		require.Equal(t, []auth.Role{auth.RoleAdmin}, stored.Roles)
+		require.NotEqual(t, got.Passhash, stored.Passhash)
+	})
+
+	t.Run("should update user", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user := newUser()
+		err := r.Save(ctx, user)
+		require.NoError(t, err)
+
+		time.Sleep(time.Millisecond)
+
+		err = r.Update(ctx, user.UUID, func(ctx context.Context, u *auth.User) error {
+			u.UpdatedAt = time.Now()
+			return nil
+		})
+		require.NoError(t, err)
+
+		updated, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+
+		require.Greater(t, updated.UpdatedAt.Sub(user.UpdatedAt), time.Millisecond)
+	})
+
+	t.Run("should not lose concurrent updates", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user := newUser()
+		require.NoError(t, r.Save(ctx, user))
+
+		const updates = 50
+		var wg sync.WaitGroup
+		errs := make(chan error, updates)
+		for range updates {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- r.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+					n, _ := strconv.Atoi(u.Profile.Group)
+					u.Profile.Group = strconv.Itoa(n + 1)
+					return nil
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		updated, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Equal(t, strconv.Itoa(updates), updated.Profile.Group)
+		require.Equal(t, user.Version+updates, updated.Version)
+	})
+
+	t.Run("should refuse update based on a stale read", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user := newUser()
+		require.NoError(t, r.Save(ctx, user))
+
+		first, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+
+		second, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+
+		err = r.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+			if err := u.CheckVersion(first.Version); err != nil {
+				return err
+			}
+			u.Profile.Group = "first"
+			return nil
+		})
+		require.NoError(t, err)
+
+		err = r.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+			if err := u.CheckVersion(second.Version); err != nil {
+				return err
+			}
+			u.Profile.Group = "second"
+			return nil
+		})
+		require.ErrorIs(t, err, auth.ErrConcurrentModification)
+
+		updated, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Equal(t, "first", updated.Profile.Group)
+		require.Equal(t, first.Version+1, updated.Version)
+	})
+
+	t.Run("should update user profile", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user := newUser()
+		err := r.Save(ctx, user)
+		require.NoError(t, err)
+
+		lastName := "Иванов"
+		err = r.Update(ctx, user.UUID, func(ctx context.Context, u *auth.User) error {
+			return u.UpdateProfile(auth.ProfileUpdate{LastName: &lastName})
+		})
+		require.NoError(t, err)
+
+		updated, err := r.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Equal(t, auth.Profile{LastName: lastName}, updated.Profile)
+	})
+
+	t.Run("should list users page by page", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		// Other tests save users concurrently.
+		marker := strings.ToLower(gofakeit.LetterN(12))
+		saved := make([]string, 3)
+		for i := range saved {
+			user := newUser()
+			user.Email = auth.MustNewEmail(fmt.Sprintf("%s.%d@example.com", marker, i))
+			user.CreatedAt = user.CreatedAt.Add(time.Duration(i) * time.Second)
+			require.NoError(t, r.Save(ctx, user))
+			saved[i] = user.UUID
+		}
+
+		var listed []string
+		l, err := auth.NewListUsers(auth.UsersFilter{EmailContains: marker}, auth.UsersByCreatedAt, false, "", 2, true)
+		require.NoError(t, err)
+		for {
+			page, err := r.ListUsers(ctx, l)
+			require.NoError(t, err)
+			require.Equal(t, 3, page.Total)
+
+			for _, u := range page.Users {
+				listed = append(listed, u.UUID)
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			l.Cursor = page.NextCursor
+		}
+
+		require.Equal(t, saved, listed)
+	})
+
+	t.Run("should filter users by effective status", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		marker := strings.ToLower(gofakeit.LetterN(12))
+		now := time.Now()
+		admin := gofakeit.UUID()
+		statuses := []auth.AccountStatus{
+			{State: auth.AccountActive},
+			{State: auth.AccountSuspended, Until: now.Add(time.Hour), Reason: "spam", ChangedBy: admin, ChangedAt: now},
+			{State: auth.AccountSuspended, Until: now.Add(-time.Hour), Reason: "spam", ChangedBy: admin, ChangedAt: now},
+			{State: auth.AccountDisabled, Reason: "left", ChangedBy: admin, ChangedAt: now},
+		}
+		saved := make([]string, len(statuses))
+		for i, status := range statuses {
+			user := newUser()
+			user.Email = auth.MustNewEmail(fmt.Sprintf("%s.%d@example.com", marker, i))
+			user.CreatedAt = user.CreatedAt.Add(time.Duration(i) * time.Second)
+			require.NoError(t, user.ChangeStatus(status))
+			require.NoError(t, r.Save(ctx, user))
+			saved[i] = user.UUID
+		}
+
+		stored, err := r.User(ctx, saved[1])
+		require.NoError(t, err)
+		require.Equal(t, auth.AccountSuspended, stored.Status.State)
+		require.Equal(t, "spam", stored.Status.Reason)
+		require.Equal(t, admin, stored.Status.ChangedBy)
+		require.WithinDuration(t, now.Add(time.Hour), stored.Status.Until, time.Millisecond)
+
+		for state, want := range map[auth.AccountState][]string{
+			auth.AccountActive:    {saved[0], saved[2]},
+			auth.AccountSuspended: {saved[1]},
+			auth.AccountDisabled:  {saved[3]},
+		} {
+			l, err := auth.NewListUsers(
+				auth.UsersFilter{EmailContains: marker, Status: state}, auth.UsersByCreatedAt, false, "", 10, false,
+			)
+			require.NoError(t, err)
+
+			page, err := r.ListUsers(ctx, l)
+			require.NoError(t, err)
+
+			listed := make([]string, len(page.Users))
+			for i, u := range page.Users {
+				listed[i] = u.UUID
+			}
+			require.Equal(t, want, listed, state)
+		}
+	})
+
+	t.Run("should return error on update if user not found", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		fakeUUID := gofakeit.UUID()
+		err := r.Update(ctx, fakeUUID, func(ctx context.Context, u *auth.User) error {
+			return nil
+		})
+		require.EqualError(t, err, fmt.Sprintf("user with UUID %s not found", fakeUUID))
+	})
+
+	t.Run("should delete user", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		user := newUser()
+		err := r.Save(ctx, user)
+		require.NoError(t, err)
+
+		err = r.Delete(ctx, user.UUID)
+		require.NoError(t, err)
+
+		_, err = r.User(ctx, user.UUID)
+		require.EqualError(t, err, fmt.Sprintf("user with UUID %s not found", user.UUID))
+
+		_, err = r.UserByEmail(ctx, user.Email)
+		require.ErrorAs(t, err, &auth.UserEmailNotFound{})
+
+		err = r.Delete(ctx, user.UUID)
+		require.ErrorAs(t, err, &auth.UserNotFound{})
+	})
+
+	t.Run("should return error if user for delete not found", func(t *testing.T) {
+		t.Parallel()
+		r := newRepo(t)
+
+		ctx := context.Background()
+
+		fakeUUID := gofakeit.UUID()
+		err := r.Delete(ctx, fakeUUID)
+		require.EqualError(t, err, fmt.Sprintf("user with UUID %s not found", fakeUUID))
+	})
+}
+
+func newUser() *auth.User {
+	return auth.MustNewUser(
+		gofakeit.UUID(),
+		gofakeit.Email(),
+		gofakeit.Password(true, true, true, true, false, 8),
+		passhash.NewDefaultHasher(),
+	)
+}
+
+func requireEqualUsers(t *testing.T, expected *auth.User, actual *auth.User) {
+	require.True(
+		t, equalUsers(*expected, *actual),
+		fmt.Sprintf(
+			"expected and actual users are different\nexpected: %+v\nactual: %+v",
+			expected, actual,
+		),
+	)
+}
+
+func equalUsers(a auth.User, b auth.User) bool {
+	return a.UUID == b.UUID &&
+		a.Email == b.Email &&
+		bytes.Compare(a.Passhash, b.Passhash) == 0 &&
+		a.CreatedAt.Sub(b.CreatedAt) < time.Microsecond &&
+		a.UpdatedAt.Sub(b.UpdatedAt) < time.Microsecond
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+const (
+	// AvatarSize is the width and height avatars are scaled to.
+	AvatarSize        = 256
+	AvatarContentType = "image/png"
+
+	// MaxAvatarUploadBytes limits uploads before they are decoded.
+	MaxAvatarUploadBytes = 5 << 20
+	// maxAvatarUploadSide guards against images that are small when
+	// compressed but huge when decoded.
+	maxAvatarUploadSide = 8192
+)
+
+var ErrAvatarNotFound = errors.New("avatar not found")
+
+// Avatar is an uploaded image re-encoded to the canonical size and format.
+// Its key changes with the content.
+type Avatar struct {
+	Key  string
+	Data []byte
+}
+
+// NewAvatar accepts PNG, JPEG, GIF and WebP images. The image is cropped to
+// the central square and scaled to AvatarSize.
+func NewAvatar(userUUID string, upload []byte) (Avatar, error) {
+	if userUUID == "" {
+		return Avatar{}, commonerrs.NewInvalidInputError("expected not empty user uuid")
+	}
+
+	if len(upload) > MaxAvatarUploadBytes {
+		return Avatar{}, commonerrs.NewInvalidInputError(
+			fmt.Sprintf("expected image of at most %d bytes", MaxAvatarUploadBytes),
+		)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(upload))
+	if err != nil {
+		return Avatar{}, commonerrs.NewInvalidInputError("expected PNG, JPEG, GIF or WebP image")
+	}
+
+	if cfg.Width < 1 || cfg.Height < 1 || cfg.Width > maxAvatarUploadSide || cfg.Height > maxAvatarUploadSide {
+		return Avatar{}, commonerrs.NewInvalidInputError(
+			fmt.Sprintf("expected image of at most %dx%d pixels", maxAvatarUploadSide, maxAvatarUploadSide),
+		)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(upload))
+	if err != nil {
+		return Avatar{}, commonerrs.NewInvalidInputError("unable to decode image")
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, centralSquare(src.Bounds()), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, dst); err != nil {
+		return Avatar{}, err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	return Avatar{
+		Key:  fmt.Sprintf("avatars/%s/%s.png", userUUID, hex.EncodeToString(sum[:8])),
+		Data: buf.Bytes(),
+	}, nil
+}
+
+func centralSquare(r image.Rectangle) image.Rectangle {
+	side := min(r.Dx(), r.Dy())
+	x := r.Min.X + (r.Dx()-side)/2
+	y := r.Min.Y + (r.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
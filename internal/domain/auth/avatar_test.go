@@ -0,0 +1,56 @@
+package auth_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestNewAvatar(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 200; y++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var upload bytes.Buffer
+	require.NoError(t, jpeg.Encode(&upload, src, nil))
+
+	avatar, err := auth.NewAvatar("0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b", upload.Bytes())
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(avatar.Key, "avatars/0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b/"))
+	require.True(t, strings.HasSuffix(avatar.Key, ".png"))
+
+	decoded, err := png.Decode(bytes.NewReader(avatar.Data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, auth.AvatarSize, auth.AvatarSize), decoded.Bounds())
+
+	again, err := auth.NewAvatar("0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b", upload.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, avatar.Key, again.Key)
+}
+
+func TestNewAvatar_Invalid(t *testing.T) {
+	userUUID := "0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b"
+
+	_, err := auth.NewAvatar(userUUID, []byte("<svg></svg>"))
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	var huge bytes.Buffer
+	require.NoError(t, png.Encode(&huge, image.NewGray(image.Rect(0, 0, 10000, 1))))
+	_, err = auth.NewAvatar(userUUID, huge.Bytes())
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = auth.NewAvatar(userUUID, make([]byte, auth.MaxAvatarUploadBytes+1))
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+}
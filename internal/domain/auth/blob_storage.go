@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStorage keeps immutable binary objects, such as avatars, by key. Keys
+// are slash separated paths of letters, digits, hyphens, underscores and dots.
+type BlobStorage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns ErrBlobNotFound if there is no blob with the key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete succeeds if there is no blob with the key.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+const (
+	emailMaxLength          = 254
+	emailLocalPartMaxLength = 64
+)
+
+// Email is an email address together with its normalized form, which
+// identifies the account.
+type Email struct {
+	address    string
+	normalized string
+}
+
+// NewEmail validates the address. Internationalized local parts and domains
+// are allowed; quoted local parts, comments and IP literals are not.
+func NewEmail(address string) (Email, error) {
+	address = norm.NFC.String(strings.TrimSpace(address))
+	if address == "" {
+		return Email{}, commonerrs.NewInvalidInputError("expected not empty email")
+	}
+
+	if utf8.RuneCountInString(address) > emailMaxLength {
+		return Email{}, commonerrs.NewInvalidInputError("email is too long")
+	}
+
+	at := strings.LastIndexByte(address, '@')
+	if at < 0 {
+		return Email{}, commonerrs.NewInvalidInputError("email must contain @")
+	}
+	local, domain := address[:at], address[at+1:]
+
+	if !isValidEmailLocalPart(local) {
+		return Email{}, commonerrs.NewInvalidInputError("invalid email local part")
+	}
+
+	unicodeDomain, err := normalizeEmailDomain(domain)
+	if err != nil {
+		return Email{}, commonerrs.NewInvalidInputError("invalid email domain")
+	}
+
+	return Email{
+		address:    address,
+		normalized: norm.NFC.String(strings.ToLower(local)) + "@" + unicodeDomain,
+	}, nil
+}
+
+func MustNewEmail(address string) Email {
+	email, err := NewEmail(address)
+	if err != nil {
+		panic(err)
+	}
+	return email
+}
+
+// NewEmailFromDB restores an email without validation: stored addresses
+// were accepted by earlier, more lenient versions.
+func NewEmailFromDB(address string, normalized string) Email {
+	return Email{
+		address:    address,
+		normalized: normalized,
+	}
+}
+
+// String returns the address as entered by the user.
+func (e Email) String() string {
+	return e.address
+}
+
+// Normalized returns the form used to compare emails.
+func (e Email) Normalized() string {
+	return e.normalized
+}
+
+// Domain returns the normalized domain.
+func (e Email) Domain() string {
+	return e.normalized[strings.LastIndexByte(e.normalized, '@')+1:]
+}
+
+func (e Email) Equal(other Email) bool {
+	return e.normalized == other.normalized
+}
+
+func (e Email) IsZero() bool {
+	return e.normalized == ""
+}
+
+func isValidEmailLocalPart(local string) bool {
+	if local == "" || utf8.RuneCountInString(local) > emailLocalPartMaxLength {
+		return false
+	}
+
+	if local[0] == '.' || local[len(local)-1] == '.' || strings.Contains(local, "..") {
+		return false
+	}
+
+	for _, r := range local {
+		if !isEmailLocalPartRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isEmailLocalPartRune accepts atext of RFC 5322 extended with non-ASCII
+// letters, marks and digits by RFC 6531.
+func isEmailLocalPartRune(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' ||
+			'A' <= r && r <= 'Z' ||
+			'0' <= r && r <= '9' ||
+			strings.ContainsRune(".!#$%&'*+-/=?^_`{|}~", r)
+	}
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
+// normalizeEmailDomain returns the domain in lower case Unicode form, so
+// "xn--e1afmkfd.xn--p1ai" and "ПРИМЕР.рф" are the same domain.
+func normalizeEmailDomain(domain string) (string, error) {
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.Contains(ascii, ".") || strings.HasSuffix(ascii, ".") {
+		return "", errors.New("expected fully qualified domain")
+	}
+
+	return idna.Lookup.ToUnicode(ascii)
+}
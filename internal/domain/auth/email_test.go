@@ -0,0 +1,74 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestNewEmail(t *testing.T) {
+	tests := []struct {
+		address    string
+		normalized string
+	}{
+		{"ivan@bmstu.ru", "ivan@bmstu.ru"},
+		{"  Ivan@BMSTU.ru ", "ivan@bmstu.ru"},
+		{"first.last+tag@sub.example.com", "first.last+tag@sub.example.com"},
+		{"user@ПРИМЕР.рф", "user@пример.рф"},
+		{"user@xn--e1afmkfd.xn--p1ai", "user@пример.рф"},
+		{"Иван@почта.рф", "иван@почта.рф"},
+		// "é" composed and decomposed.
+		{"josé@example.com", "josé@example.com"},
+		{"josé@example.com", "josé@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			email, err := auth.NewEmail(tt.address)
+			require.NoError(t, err)
+			require.Equal(t, tt.normalized, email.Normalized())
+			require.Equal(t, strings.TrimSpace(email.String()), email.String())
+		})
+	}
+}
+
+func TestNewEmail_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"ivan",
+		"ivan@",
+		"@bmstu.ru",
+		"ivan@bmstu",
+		"ivan@bmstu.ru.",
+		".ivan@bmstu.ru",
+		"ivan.@bmstu.ru",
+		"iv..an@bmstu.ru",
+		"iv an@bmstu.ru",
+		"Ivan <ivan@bmstu.ru>",
+		"ivan@bm_stu.ru",
+		"ivan@-bmstu.ru",
+		"ivan@[127.0.0.1]",
+		strings.Repeat("a", 65) + "@bmstu.ru",
+	}
+
+	for _, address := range tests {
+		t.Run(address, func(t *testing.T) {
+			_, err := auth.NewEmail(address)
+			require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+		})
+	}
+}
+
+func TestEmail_Equal(t *testing.T) {
+	a := auth.MustNewEmail("Ivan@BMSTU.ru")
+	b := auth.MustNewEmail("ivan@bmstu.ru")
+	require.True(t, a.Equal(b))
+	require.Equal(t, "Ivan@BMSTU.ru", a.String())
+
+	c := auth.MustNewEmail("ivan2@bmstu.ru")
+	require.False(t, a.Equal(c))
+}
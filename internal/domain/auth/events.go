@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+type EventType string
+
+const (
+	EventUserRegistered     EventType = "user.registered"
+	EventUserRoleGranted    EventType = "user.role_granted"
+	EventUserEmailChanged   EventType = "user.email_changed"
+	EventUserProfileUpdated EventType = "user.profile_updated"
+	EventUserStatusChanged  EventType = "user.status_changed"
+	EventUserDeleted        EventType = "user.deleted"
+)
+
+var knownEventTypes = []EventType{
+	EventUserRegistered,
+	EventUserRoleGranted,
+	EventUserEmailChanged,
+	EventUserProfileUpdated,
+	EventUserStatusChanged,
+	EventUserDeleted,
+}
+
+func NewEventType(s string) (EventType, error) {
+	t := EventType(s)
+	if !slices.Contains(knownEventTypes, t) {
+		return "", commonerrs.NewInvalidInputError(fmt.Sprintf("unknown event type %q", s))
+	}
+	return t, nil
+}
+
+// Event is a change of a user other services may be interested in. Events are
+// raised by User methods and stored to the outbox together with the change.
+type Event interface {
+	EventType() EventType
+	// AggregateUUID is the user the event is about. Events of a user are
+	// published in the order they were raised.
+	AggregateUUID() string
+}
+
+type UserRegistered struct {
+	UserUUID string `json:"userUuid"`
+	Email    string `json:"email"`
+	// Imported is true for users moved over from another system.
+	Imported bool `json:"imported"`
+}
+
+func (e UserRegistered) EventType() EventType  { return EventUserRegistered }
+func (e UserRegistered) AggregateUUID() string { return e.UserUUID }
+
+type UserRoleGranted struct {
+	UserUUID string `json:"userUuid"`
+	Role     string `json:"role"`
+}
+
+func (e UserRoleGranted) EventType() EventType  { return EventUserRoleGranted }
+func (e UserRoleGranted) AggregateUUID() string { return e.UserUUID }
+
+type UserEmailChanged struct {
+	UserUUID string `json:"userUuid"`
+	Email    string `json:"email"`
+}
+
+func (e UserEmailChanged) EventType() EventType  { return EventUserEmailChanged }
+func (e UserEmailChanged) AggregateUUID() string { return e.UserUUID }
+
+type UserProfileUpdated struct {
+	UserUUID string `json:"userUuid"`
+}
+
+func (e UserProfileUpdated) EventType() EventType  { return EventUserProfileUpdated }
+func (e UserProfileUpdated) AggregateUUID() string { return e.UserUUID }
+
+type UserStatusChanged struct {
+	UserUUID string     `json:"userUuid"`
+	Status   string     `json:"status"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+func (e UserStatusChanged) EventType() EventType  { return EventUserStatusChanged }
+func (e UserStatusChanged) AggregateUUID() string { return e.UserUUID }
+
+// UserDeleted is raised by UsersRepository.Delete.
+type UserDeleted struct {
+	UserUUID string `json:"userUuid"`
+}
+
+func (e UserDeleted) EventType() EventType  { return EventUserDeleted }
+func (e UserDeleted) AggregateUUID() string { return e.UserUUID }
+
+// OutboxMessage is an event waiting in the outbox to be published at least
+// once.
+type OutboxMessage struct {
+	// ID grows in the order the messages were stored.
+	ID            int64
+	Type          EventType
+	AggregateUUID string
+	// Payload is the event encoded as JSON.
+	Payload    json.RawMessage
+	OccurredAt time.Time
+}
+
+func NewOutboxMessage(e Event) (*OutboxMessage, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxMessage{
+		Type:          e.EventType(),
+		AggregateUUID: e.AggregateUUID(),
+		Payload:       payload,
+		OccurredAt:    time.Now(),
+	}, nil
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+const (
+	invitationCodePrefix = "itsreg_inv_"
+	invitationCodeBytes  = 16
+)
+
+// Invitation lets up to MaxUses users register while registration is
+// restricted. Users registered with it get its Roles.
+type Invitation struct {
+	UUID      string
+	CodeHash  []byte
+	CreatedBy string
+
+	Roles   []Role
+	MaxUses int
+	Uses    int
+
+	// ExpiresAt is zero for invitations that never expire.
+	ExpiresAt time.Time
+
+	CreatedAt time.Time
+}
+
+// NewInvitationCode generates a plaintext code. It is shown to the admin once
+// and only its hash is ever stored.
+func NewInvitationCode() (string, error) {
+	b := make([]byte, invitationCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return invitationCodePrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func HashInvitationCode(code string) []byte {
+	h := sha256.Sum256([]byte(strings.TrimSpace(code)))
+	return h[:]
+}
+
+func NewInvitation(
+	uuid string,
+	code string,
+	createdBy string,
+	roles []Role,
+	maxUses int,
+	expiresAt time.Time,
+) (*Invitation, error) {
+	if uuid == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	if !strings.HasPrefix(code, invitationCodePrefix) {
+		return nil, commonerrs.NewInvalidInputError("expected invitation code")
+	}
+
+	if createdBy == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty creator uuid")
+	}
+
+	if maxUses < 1 {
+		return nil, commonerrs.NewInvalidInputError("expected positive max uses")
+	}
+
+	for i, r := range roles {
+		if _, err := NewRole(string(r)); err != nil {
+			return nil, err
+		}
+		if slices.Contains(roles[:i], r) {
+			return nil, commonerrs.NewInvalidInputError(fmt.Sprintf("duplicate role %q", r))
+		}
+	}
+
+	now := time.Now()
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return nil, commonerrs.NewInvalidInputError("expected expiration time in the future")
+	}
+
+	return &Invitation{
+		UUID:      uuid,
+		CodeHash:  HashInvitationCode(code),
+		CreatedBy: createdBy,
+		Roles:     slices.Clone(roles),
+		MaxUses:   maxUses,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}, nil
+}
+
+func NewInvitationFromDB(
+	uuid string,
+	codeHash []byte,
+	createdBy string,
+	roles []Role,
+	maxUses int,
+	uses int,
+	expiresAt time.Time,
+	createdAt time.Time,
+) (*Invitation, error) {
+	if uuid == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	if len(codeHash) == 0 {
+		return nil, commonerrs.NewInvalidInputError("expected not empty code hash")
+	}
+
+	if createdAt.IsZero() {
+		return nil, commonerrs.NewInvalidInputError("expected not empty createdAt")
+	}
+
+	return &Invitation{
+		UUID:      uuid,
+		CodeHash:  codeHash,
+		CreatedBy: createdBy,
+		Roles:     roles,
+		MaxUses:   maxUses,
+		Uses:      uses,
+		ExpiresAt: expiresAt,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+func (i *Invitation) IsExpired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && !now.Before(i.ExpiresAt)
+}
+
+// Use counts a registration against the invitation.
+func (i *Invitation) Use(now time.Time) error {
+	if i.IsExpired(now) {
+		return RegistrationForbiddenError{Code: RegistrationForbiddenInvitationExpired}
+	}
+
+	if i.Uses >= i.MaxUses {
+		return RegistrationForbiddenError{Code: RegistrationForbiddenInvitationUsedUp}
+	}
+
+	i.Uses++
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestNewInvitation(t *testing.T) {
+	code, err := auth.NewInvitationCode()
+	require.NoError(t, err)
+
+	invitation, err := auth.NewInvitation("1234", code, "5678", []auth.Role{auth.RoleAdmin}, 3, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, auth.HashInvitationCode(code), invitation.CodeHash)
+	require.Zero(t, invitation.Uses)
+
+	_, err = auth.NewInvitation("1234", code, "5678", nil, 0, time.Time{})
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = auth.NewInvitation("1234", code, "5678", []auth.Role{"unknown"}, 1, time.Time{})
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = auth.NewInvitation("1234", code, "5678", nil, 1, time.Now().Add(-time.Minute))
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = auth.NewInvitation("1234", "not a code", "5678", nil, 1, time.Time{})
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+}
+
+func TestInvitation_Use(t *testing.T) {
+	code, err := auth.NewInvitationCode()
+	require.NoError(t, err)
+
+	now := time.Now()
+	invitation, err := auth.NewInvitation("1234", code, "5678", nil, 2, now.Add(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, invitation.Use(now))
+	require.NoError(t, invitation.Use(now))
+	require.Equal(t, 2, invitation.Uses)
+
+	var forbiddenErr auth.RegistrationForbiddenError
+	require.ErrorAs(t, invitation.Use(now), &forbiddenErr)
+	require.Equal(t, auth.RegistrationForbiddenInvitationUsedUp, forbiddenErr.Code)
+
+	invitation.MaxUses = 10
+	require.ErrorAs(t, invitation.Use(now.Add(time.Hour)), &forbiddenErr)
+	require.Equal(t, auth.RegistrationForbiddenInvitationExpired, forbiddenErr.Code)
+}
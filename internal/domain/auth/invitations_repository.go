@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvitationAlreadyExists = errors.New("invitation already exists")
+)
+
+type InvitationsRepository interface {
+	Save(ctx context.Context, i *Invitation) error
+	InvitationByCodeHash(ctx context.Context, hash []byte) (*Invitation, error)
+	// Update locks the invitation until updateFn returns and discards the
+	// update if updateFn returns an error.
+	Update(
+		ctx context.Context,
+		uuid string,
+		updateFn func(ctx context.Context, i *Invitation) error,
+	) error
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// LoginAttempts counts consecutive failed logins for a single key, which is
+// either an account or a client IP.
+type LoginAttempts struct {
+	Key string
+
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+	// ExpiresAt is when the attempts no longer matter and can be forgotten.
+	ExpiresAt time.Time
+}
+
+// LoginAttemptsKeyForEmail hashes the email.
+func LoginAttemptsKeyForEmail(email Email) string {
+	return "email:" + loginAttemptsKeyHash(email.Normalized())
+}
+
+// LoginAttemptsKeyForIP hashes the IP, which may come from a client header.
+func LoginAttemptsKeyForIP(ip string) string {
+	return "ip:" + loginAttemptsKeyHash(ip)
+}
+
+func loginAttemptsKeyHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// LockoutPolicy locks a key out for BaseDelay after Threshold failures within
+// Window, doubling up to MaxDelay.
+type LockoutPolicy struct {
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Window    time.Duration
+}
+
+var (
+	DefaultAccountLockoutPolicy = LockoutPolicy{
+		Threshold: 5,
+		BaseDelay: time.Second * 30,
+		MaxDelay:  time.Minute * 30,
+		Window:    time.Hour,
+	}
+
+	DefaultIPLockoutPolicy = LockoutPolicy{
+		Threshold: 20,
+		BaseDelay: time.Second * 10,
+		MaxDelay:  time.Minute * 15,
+		Window:    time.Hour,
+	}
+)
+
+func (a *LoginAttempts) IsLocked(now time.Time) bool {
+	return now.Before(a.LockedUntil)
+}
+
+func (a *LoginAttempts) RetryAfter(now time.Time) time.Duration {
+	if !a.IsLocked(now) {
+		return 0
+	}
+	return a.LockedUntil.Sub(now)
+}
+
+func (a *LoginAttempts) IsExpired(now time.Time) bool {
+	return !now.Before(a.ExpiresAt)
+}
+
+func (p LockoutPolicy) RegisterFailure(a *LoginAttempts, now time.Time) {
+	if now.Sub(a.LastFailureAt) > p.Window {
+		a.Failures = 0
+	}
+
+	a.Failures++
+	a.LastFailureAt = now
+
+	if a.Failures >= p.Threshold {
+		delay := p.MaxDelay
+		if exp := a.Failures - p.Threshold; exp < 32 {
+			delay = min(p.BaseDelay<<exp, p.MaxDelay)
+		}
+		a.LockedUntil = now.Add(delay)
+	}
+
+	// The failure is forgotten after Window, and the lockout once it ends.
+	a.ExpiresAt = now.Add(p.Window)
+	if a.LockedUntil.After(a.ExpiresAt) {
+		a.ExpiresAt = a.LockedUntil
+	}
+}
+
+type TooManyLoginAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e TooManyLoginAttemptsError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
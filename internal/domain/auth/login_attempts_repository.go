@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"context"
+)
+
+// LoginAttemptsRepository stores failed login counters until they expire.
+// Implementations shared by several replicas must apply Update atomically.
+type LoginAttemptsRepository interface {
+	// LoginAttempts returns empty attempts if the key has no failures or
+	// they expired.
+	LoginAttempts(ctx context.Context, key string) (*LoginAttempts, error)
+
+	// Update creates the attempts for the key if they do not exist yet or
+	// expired.
+	Update(
+		ctx context.Context,
+		key string,
+		updateFn func(ctx context.Context, a *LoginAttempts) error,
+	) error
+	Delete(ctx context.Context, key string) error
+}
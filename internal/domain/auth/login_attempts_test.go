@@ -0,0 +1,76 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestLockoutPolicy_RegisterFailure(t *testing.T) {
+	policy := auth.LockoutPolicy{
+		Threshold: 3,
+		BaseDelay: time.Second,
+		MaxDelay:  5 * time.Second,
+		Window:    time.Hour,
+	}
+
+	now := time.Now()
+	a := &auth.LoginAttempts{Key: auth.LoginAttemptsKeyForEmail(auth.MustNewEmail("test@test.com"))}
+
+	policy.RegisterFailure(a, now)
+	policy.RegisterFailure(a, now)
+	require.False(t, a.IsLocked(now))
+
+	policy.RegisterFailure(a, now)
+	require.True(t, a.IsLocked(now))
+	require.Equal(t, time.Second, a.RetryAfter(now))
+
+	policy.RegisterFailure(a, now)
+	require.Equal(t, 2*time.Second, a.RetryAfter(now))
+
+	policy.RegisterFailure(a, now)
+	require.Equal(t, 4*time.Second, a.RetryAfter(now))
+
+	policy.RegisterFailure(a, now)
+	require.Equal(t, 5*time.Second, a.RetryAfter(now))
+
+	require.Equal(t, now.Add(policy.Window), a.ExpiresAt)
+	require.False(t, a.IsExpired(now))
+	require.True(t, a.IsExpired(now.Add(policy.Window)))
+
+	later := now.Add(2 * time.Hour)
+	policy.RegisterFailure(a, later)
+	require.Equal(t, 1, a.Failures)
+	require.False(t, a.IsLocked(later))
+}
+
+func TestLockoutPolicy_RegisterFailure_ExpiresAfterLockout(t *testing.T) {
+	policy := auth.LockoutPolicy{
+		Threshold: 1,
+		BaseDelay: 2 * time.Hour,
+		MaxDelay:  2 * time.Hour,
+		Window:    time.Hour,
+	}
+
+	now := time.Now()
+	a := &auth.LoginAttempts{}
+	policy.RegisterFailure(a, now)
+	require.Equal(t, a.LockedUntil, a.ExpiresAt)
+}
+
+func TestLoginAttemptsKey(t *testing.T) {
+	long := strings.Repeat("я", 60) + "@" + strings.Repeat("д", 60) + ".рф"
+	key := auth.LoginAttemptsKeyForEmail(auth.MustNewEmail(long))
+	require.Len(t, key, len("email:")+64)
+	require.NotContains(t, key, "я")
+
+	require.Equal(t,
+		auth.LoginAttemptsKeyForEmail(auth.MustNewEmail("Test@Test.com")),
+		auth.LoginAttemptsKeyForEmail(auth.MustNewEmail("test@test.com")),
+	)
+	require.NotEqual(t, auth.LoginAttemptsKeyForIP("10.0.0.1"), auth.LoginAttemptsKeyForIP("10.0.0.2"))
+}
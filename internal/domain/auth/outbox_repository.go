@@ -0,0 +1,15 @@
+package auth
+
+import "context"
+
+// RelayFn publishes messages and returns the IDs of the published ones.
+type RelayFn func(ctx context.Context, messages []*OutboxMessage) (published []int64)
+
+// OutboxRepository gives out the events stored by UsersRepository.
+type OutboxRepository interface {
+	// RelayOutbox passes up to limit messages, oldest first, to relayFn and
+	// removes the published ones. Only one relay runs at a time: concurrent
+	// calls return without calling relayFn. It returns the number of
+	// published messages.
+	RelayOutbox(ctx context.Context, limit int, relayFn RelayFn) (int, error)
+}
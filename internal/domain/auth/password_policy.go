@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+// BcryptMaxPasswordBytes is the length after which bcrypt silently ignores
+// the rest of the password.
+const BcryptMaxPasswordBytes = 72
+
+const (
+	PasswordRuleMinLength   = "min_length"
+	PasswordRuleMaxLength   = "max_length"
+	PasswordRuleCharClasses = "char_classes"
+	PasswordRuleBannedWord  = "banned_word"
+	PasswordRuleBreached    = "breached"
+)
+
+// minBannedEmailLocalPartLen keeps short local parts such as "a" from banning
+// most passwords.
+const minBannedEmailLocalPartLen = 3
+
+// PasswordPolicy decides which passwords users may choose. MinLength counts
+// characters, MaxBytes counts bytes.
+type PasswordPolicy struct {
+	MinLength      int
+	MaxBytes       int
+	MinCharClasses int
+	BannedWords    []string
+	CheckBreached  bool
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:      8,
+	MaxBytes:       BcryptMaxPasswordBytes,
+	MinCharClasses: 2,
+	CheckBreached:  true,
+}
+
+// Validate returns an InvalidInputError listing every rule the password
+// breaks.
+func (p PasswordPolicy) Validate(password string, email string) error {
+	var violations []commonerrs.Violation
+	violate := func(rule string, format string, args ...any) {
+		violations = append(violations, commonerrs.Violation{
+			Rule:    rule,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violate(PasswordRuleMinLength, "password must be at least %d characters long", p.MinLength)
+	}
+
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violate(PasswordRuleMaxLength, "password must be at most %d bytes long", p.MaxBytes)
+	}
+
+	if classes := passwordCharClasses(password); classes < p.MinCharClasses {
+		violate(
+			PasswordRuleCharClasses,
+			"password must use at least %d of lowercase letters, uppercase letters, digits and symbols",
+			p.MinCharClasses,
+		)
+	}
+
+	if word, ok := p.bannedWord(password, email); ok {
+		violate(PasswordRuleBannedWord, "password must not contain %q", word)
+	}
+
+	if p.CheckBreached && IsBreachedPassword(password) {
+		violate(PasswordRuleBreached, "password is known from data breaches")
+	}
+
+	if len(violations) > 0 {
+		return commonerrs.NewInvalidInputErrorWithViolations("password does not meet the policy", violations)
+	}
+
+	return nil
+}
+
+func (p PasswordPolicy) bannedWord(password string, email string) (string, bool) {
+	lower := strings.ToLower(password)
+
+	words := p.BannedWords
+	if local, _, ok := strings.Cut(email, "@"); ok && len(local) >= minBannedEmailLocalPartLen {
+		words = append(words[:len(words):len(words)], local)
+	}
+
+	for _, word := range words {
+		if word != "" && strings.Contains(lower, strings.ToLower(word)) {
+			return word, true
+		}
+	}
+
+	return "", false
+}
+
+func passwordCharClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	return classes
+}
+
+// breachedPasswords has SHA-1 hashes of breached passwords, one
+// "<5-char prefix>:<suffix>" per line.
+//
+//go:embed breached_passwords.txt
+var breachedPasswords []byte
+
+const breachedPrefixLen = 5
+
+var breachedPasswordRanges = sync.OnceValue(func() map[string][]string {
+	ranges := make(map[string][]string)
+
+	s := bufio.NewScanner(bytes.NewReader(breachedPasswords))
+	for s.Scan() {
+		prefix, suffix, ok := strings.Cut(strings.TrimSpace(s.Text()), ":")
+		if !ok {
+			continue
+		}
+		ranges[prefix] = append(ranges[prefix], suffix)
+	}
+
+	return ranges
+})
+
+// IsBreachedPassword looks the password up in the bundled breached password
+// list.
+func IsBreachedPassword(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	for _, suffix := range breachedPasswordRanges()[hash[:breachedPrefixLen]] {
+		if suffix == hash[breachedPrefixLen:] {
+			return true
+		}
+	}
+
+	return false
+}
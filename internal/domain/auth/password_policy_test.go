@@ -0,0 +1,77 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := auth.DefaultPasswordPolicy
+	policy.BannedWords = []string{"itsreg"}
+
+	tests := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{
+			name:     "strong password",
+			password: "correct horse battery staple",
+		},
+		{
+			name:     "too short",
+			password: "1",
+			rules:    []string{auth.PasswordRuleMinLength, auth.PasswordRuleCharClasses},
+		},
+		{
+			name:     "too long for bcrypt",
+			password: strings.Repeat("aB", auth.BcryptMaxPasswordBytes),
+			rules:    []string{auth.PasswordRuleMaxLength},
+		},
+		{
+			name:     "banned word",
+			password: "MyItsRegPassword",
+			rules:    []string{auth.PasswordRuleBannedWord},
+		},
+		{
+			name:     "email local part",
+			password: "JohnDoe-1990",
+			rules:    []string{auth.PasswordRuleBannedWord},
+		},
+		{
+			name:     "breached",
+			password: "P@ssw0rd",
+			rules:    []string{auth.PasswordRuleBreached},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, "johndoe@example.com")
+			if len(tt.rules) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var invalidInputErr commonerrs.InvalidInputError
+			require.ErrorAs(t, err, &invalidInputErr)
+
+			rules := make([]string, len(invalidInputErr.Violations))
+			for i, v := range invalidInputErr.Violations {
+				rules[i] = v.Rule
+			}
+			require.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestIsBreachedPassword(t *testing.T) {
+	require.True(t, auth.IsBreachedPassword("123456"))
+	require.True(t, auth.IsBreachedPassword("password"))
+	require.False(t, auth.IsBreachedPassword("correct horse battery staple"))
+}
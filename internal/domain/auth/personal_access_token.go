@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+)
+
+var knownScopes = []Scope{ScopeRead, ScopeWrite}
+
+func NewScope(s string) (Scope, error) {
+	scope := Scope(s)
+	if !slices.Contains(knownScopes, scope) {
+		return "", commonerrs.NewInvalidInputError(fmt.Sprintf("unknown scope %q", s))
+	}
+	return scope, nil
+}
+
+const (
+	personalAccessTokenPrefix      = "itsreg_pat_"
+	personalAccessTokenSecretBytes = 32
+	maxPersonalAccessTokenName     = 64
+)
+
+type PersonalAccessToken struct {
+	UUID     string
+	UserUUID string
+
+	Name      string
+	Scopes    []Scope
+	TokenHash []byte
+
+	// ExpiresAt is zero for tokens that never expire.
+	ExpiresAt time.Time
+
+	// LastUsedAt is zero until the token is used for the first time.
+	LastUsedAt time.Time
+	LastUsedIP string
+
+	CreatedAt time.Time
+}
+
+// NewPersonalAccessTokenSecret generates a plaintext token. It is shown to
+// the user once and only its hash is ever stored.
+func NewPersonalAccessTokenSecret() (string, error) {
+	b := make([]byte, personalAccessTokenSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return personalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+func HashPersonalAccessToken(token string) []byte {
+	h := sha256.Sum256([]byte(token))
+	return h[:]
+}
+
+func NewPersonalAccessToken(
+	uuid string,
+	userUUID string,
+	name string,
+	secret string,
+	scopes []Scope,
+	expiresAt time.Time,
+) (*PersonalAccessToken, error) {
+	if uuid == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	if userUUID == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty user uuid")
+	}
+
+	if name == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty token name")
+	}
+
+	if len(name) > maxPersonalAccessTokenName {
+		return nil, commonerrs.NewInvalidInputError(
+			fmt.Sprintf("expected token name not longer than %d", maxPersonalAccessTokenName),
+		)
+	}
+
+	if !IsPersonalAccessToken(secret) {
+		return nil, commonerrs.NewInvalidInputError("expected personal access token secret")
+	}
+
+	if len(scopes) == 0 {
+		return nil, commonerrs.NewInvalidInputError("expected at least one scope")
+	}
+
+	for i, s := range scopes {
+		if _, err := NewScope(string(s)); err != nil {
+			return nil, err
+		}
+		if slices.Contains(scopes[:i], s) {
+			return nil, commonerrs.NewInvalidInputError(fmt.Sprintf("duplicate scope %q", s))
+		}
+	}
+
+	now := time.Now()
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return nil, commonerrs.NewInvalidInputError("expected expiration time in the future")
+	}
+
+	return &PersonalAccessToken{
+		UUID:      uuid,
+		UserUUID:  userUUID,
+		Name:      name,
+		Scopes:    slices.Clone(scopes),
+		TokenHash: HashPersonalAccessToken(secret),
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}, nil
+}
+
+func NewPersonalAccessTokenFromDB(
+	uuid string,
+	userUUID string,
+	name string,
+	scopes []Scope,
+	tokenHash []byte,
+	expiresAt time.Time,
+	lastUsedAt time.Time,
+	lastUsedIP string,
+	createdAt time.Time,
+) (*PersonalAccessToken, error) {
+	if uuid == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	if userUUID == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty user uuid")
+	}
+
+	if len(tokenHash) == 0 {
+		return nil, commonerrs.NewInvalidInputError("expected not empty token hash")
+	}
+
+	if createdAt.IsZero() {
+		return nil, commonerrs.NewInvalidInputError("expected not empty createdAt")
+	}
+
+	return &PersonalAccessToken{
+		UUID:       uuid,
+		UserUUID:   userUUID,
+		Name:       name,
+		Scopes:     scopes,
+		TokenHash:  tokenHash,
+		ExpiresAt:  expiresAt,
+		LastUsedAt: lastUsedAt,
+		LastUsedIP: lastUsedIP,
+		CreatedAt:  createdAt,
+	}, nil
+}
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+func (t *PersonalAccessToken) IsExpired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+func (t *PersonalAccessToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+func (t *PersonalAccessToken) MarkUsed(ip string, at time.Time) {
+	t.LastUsedAt = at
+	t.LastUsedIP = ip
+}
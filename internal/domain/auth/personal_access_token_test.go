@@ -0,0 +1,56 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestNewPersonalAccessToken(t *testing.T) {
+	secret, err := auth.NewPersonalAccessTokenSecret()
+	require.NoError(t, err)
+	require.True(t, auth.IsPersonalAccessToken(secret))
+
+	token, err := auth.NewPersonalAccessToken(
+		"1234", "5678", "ci", secret, []auth.Scope{auth.ScopeRead}, time.Time{},
+	)
+	require.NoError(t, err)
+	require.Equal(t, auth.HashPersonalAccessToken(secret), token.TokenHash)
+	require.NotContains(t, string(token.TokenHash), secret)
+	require.True(t, token.HasScope(auth.ScopeRead))
+	require.False(t, token.HasScope(auth.ScopeWrite))
+	require.False(t, token.IsExpired(time.Now().Add(100*365*24*time.Hour)))
+
+	_, err = auth.NewPersonalAccessToken(
+		"1234", "5678", "ci", secret, []auth.Scope{"admin"}, time.Time{},
+	)
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = auth.NewPersonalAccessToken(
+		"1234", "5678", "ci", secret, []auth.Scope{auth.ScopeRead}, time.Now().Add(-time.Minute),
+	)
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = auth.NewPersonalAccessToken(
+		"1234", "5678", "ci", "not-a-token", []auth.Scope{auth.ScopeRead}, time.Time{},
+	)
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+}
+
+func TestPersonalAccessToken_IsExpired(t *testing.T) {
+	secret, err := auth.NewPersonalAccessTokenSecret()
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour)
+	token, err := auth.NewPersonalAccessToken(
+		"1234", "5678", "ci", secret, []auth.Scope{auth.ScopeRead, auth.ScopeWrite}, expiresAt,
+	)
+	require.NoError(t, err)
+
+	require.False(t, token.IsExpired(expiresAt.Add(-time.Second)))
+	require.True(t, token.IsExpired(expiresAt))
+}
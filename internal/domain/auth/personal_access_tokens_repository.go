@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type PersonalAccessTokenNotFound struct {
+	TokenUUID string
+}
+
+func (e PersonalAccessTokenNotFound) Error() string {
+	return fmt.Sprintf("personal access token with UUID %s not found", e.TokenUUID)
+}
+
+var ErrPersonalAccessTokenAlreadyExists = errors.New("personal access token already exists")
+
+type PersonalAccessTokensRepository interface {
+	Save(ctx context.Context, t *PersonalAccessToken) error
+	PersonalAccessToken(ctx context.Context, uuid string) (*PersonalAccessToken, error)
+	PersonalAccessTokenByHash(ctx context.Context, hash []byte) (*PersonalAccessToken, error)
+	UserPersonalAccessTokens(ctx context.Context, userUUID string) ([]*PersonalAccessToken, error)
+	Update(
+		ctx context.Context,
+		uuid string,
+		updateFn func(ctx context.Context, t *PersonalAccessToken) error,
+	) error
+	Delete(ctx context.Context, uuid string) error
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+
+	_ "time/tzdata"
+)
+
+// Profile holds optional user attributes. Empty fields are unset.
+type Profile struct {
+	DisplayName string
+
+	// LastName, FirstName and Patronymic are the full name in Russian.
+	LastName   string
+	FirstName  string
+	Patronymic string
+
+	// Group is the study group, such as "ИУ7-64Б".
+	Group   string
+	Faculty string
+
+	// Locale is a BCP 47 language tag.
+	Locale string
+	// Timezone is an IANA time zone name.
+	Timezone string
+
+	AvatarURL string
+}
+
+// ProfileUpdate changes the fields that are not nil. A field set to an empty
+// string is cleared.
+type ProfileUpdate struct {
+	DisplayName *string
+	LastName    *string
+	FirstName   *string
+	Patronymic  *string
+	Group       *string
+	Faculty     *string
+	Locale      *string
+	Timezone    *string
+	AvatarURL   *string
+}
+
+const (
+	ProfileFieldDisplayName = "displayName"
+	ProfileFieldLastName    = "lastName"
+	ProfileFieldFirstName   = "firstName"
+	ProfileFieldPatronymic  = "patronymic"
+	ProfileFieldGroup       = "group"
+	ProfileFieldFaculty     = "faculty"
+	ProfileFieldLocale      = "locale"
+	ProfileFieldTimezone    = "timezone"
+	ProfileFieldAvatarURL   = "avatarUrl"
+)
+
+const (
+	maxProfileNameLength    = 64
+	maxProfileGroupLength   = 32
+	maxProfileFacultyLength = 128
+	maxAvatarURLLength      = 2048
+)
+
+// Apply returns the profile with the update applied and normalized. Every
+// invalid field is reported as a violation named after the field.
+func (p Profile) Apply(update ProfileUpdate) (Profile, error) {
+	res := p
+	var violations []commonerrs.Violation
+
+	set := func(field string, dst *string, v *string, normalize func(string) (string, error)) {
+		if v == nil {
+			return
+		}
+
+		s := strings.TrimSpace(*v)
+		if s == "" {
+			*dst = ""
+			return
+		}
+
+		normalized, err := normalize(s)
+		if err != nil {
+			violations = append(violations, commonerrs.Violation{Rule: field, Message: err.Error()})
+			return
+		}
+		*dst = normalized
+	}
+
+	set(ProfileFieldDisplayName, &res.DisplayName, update.DisplayName, normalizeDisplayName)
+	set(ProfileFieldLastName, &res.LastName, update.LastName, normalizeRussianName)
+	set(ProfileFieldFirstName, &res.FirstName, update.FirstName, normalizeRussianName)
+	set(ProfileFieldPatronymic, &res.Patronymic, update.Patronymic, normalizeRussianName)
+	set(ProfileFieldGroup, &res.Group, update.Group, normalizeGroup)
+	set(ProfileFieldFaculty, &res.Faculty, update.Faculty, normalizeFaculty)
+	set(ProfileFieldLocale, &res.Locale, update.Locale, normalizeLocale)
+	set(ProfileFieldTimezone, &res.Timezone, update.Timezone, normalizeTimezone)
+	set(ProfileFieldAvatarURL, &res.AvatarURL, update.AvatarURL, normalizeAvatarURL)
+
+	if len(violations) > 0 {
+		return Profile{}, commonerrs.NewInvalidInputErrorWithViolations("invalid profile", violations)
+	}
+
+	return res, nil
+}
+
+func normalizeDisplayName(s string) (string, error) {
+	if utf8.RuneCountInString(s) > maxProfileNameLength {
+		return "", fmt.Errorf("must be at most %d characters long", maxProfileNameLength)
+	}
+
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return "", fmt.Errorf("must not contain control characters")
+		}
+	}
+
+	return s, nil
+}
+
+// normalizeRussianName accepts Cyrillic words joined by single hyphens or
+// spaces, as in "Салтыков-Щедрин".
+func normalizeRussianName(s string) (string, error) {
+	if utf8.RuneCountInString(s) > maxProfileNameLength {
+		return "", fmt.Errorf("must be at most %d characters long", maxProfileNameLength)
+	}
+
+	prevSeparator := true
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			prevSeparator = false
+		case (r == '-' || r == ' ') && !prevSeparator:
+			prevSeparator = true
+		default:
+			return "", fmt.Errorf("must be written in Cyrillic letters")
+		}
+	}
+
+	if prevSeparator {
+		return "", fmt.Errorf("must not end with a separator")
+	}
+
+	return s, nil
+}
+
+func normalizeGroup(s string) (string, error) {
+	if utf8.RuneCountInString(s) > maxProfileGroupLength {
+		return "", fmt.Errorf("must be at most %d characters long", maxProfileGroupLength)
+	}
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '.' {
+			return "", fmt.Errorf("must contain only letters, digits, hyphens and dots")
+		}
+	}
+
+	return strings.ToUpper(s), nil
+}
+
+func normalizeFaculty(s string) (string, error) {
+	if utf8.RuneCountInString(s) > maxProfileFacultyLength {
+		return "", fmt.Errorf("must be at most %d characters long", maxProfileFacultyLength)
+	}
+
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return "", fmt.Errorf("must not contain control characters")
+		}
+	}
+
+	return s, nil
+}
+
+func normalizeLocale(s string) (string, error) {
+	tag, err := language.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("must be a BCP 47 language tag")
+	}
+	return tag.String(), nil
+}
+
+func normalizeTimezone(s string) (string, error) {
+	// "Local" and "UTC" are accepted by LoadLocation but only the latter is
+	// an IANA name.
+	if s == "Local" {
+		return "", fmt.Errorf("must be an IANA time zone name")
+	}
+
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return "", fmt.Errorf("must be an IANA time zone name")
+	}
+	return loc.String(), nil
+}
+
+func normalizeAvatarURL(s string) (string, error) {
+	if len(s) > maxAvatarURLLength {
+		return "", fmt.Errorf("must be at most %d characters long", maxAvatarURLLength)
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return "", fmt.Errorf("must be an https URL")
+	}
+
+	return u.String(), nil
+}
@@ -0,0 +1,69 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestProfile_Apply(t *testing.T) {
+	p, err := auth.Profile{}.Apply(auth.ProfileUpdate{
+		LastName:   ptr("Салтыков-Щедрин"),
+		FirstName:  ptr(" Михаил "),
+		Patronymic: ptr("Евграфович"),
+		Group:      ptr("иу7-64б"),
+		Locale:     ptr("ru-ru"),
+		Timezone:   ptr("Europe/Moscow"),
+		AvatarURL:  ptr("https://example.com/avatar.png"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, auth.Profile{
+		LastName:   "Салтыков-Щедрин",
+		FirstName:  "Михаил",
+		Patronymic: "Евграфович",
+		Group:      "ИУ7-64Б",
+		Locale:     "ru-RU",
+		Timezone:   "Europe/Moscow",
+		AvatarURL:  "https://example.com/avatar.png",
+	}, p)
+
+	p, err = p.Apply(auth.ProfileUpdate{Group: ptr(""), DisplayName: ptr("misha")})
+	require.NoError(t, err)
+	require.Empty(t, p.Group)
+	require.Equal(t, "misha", p.DisplayName)
+	require.Equal(t, "Михаил", p.FirstName)
+}
+
+func TestProfile_Apply_Invalid(t *testing.T) {
+	p := auth.Profile{FirstName: "Иван"}
+
+	_, err := p.Apply(auth.ProfileUpdate{
+		FirstName: ptr("Ivan"),
+		LastName:  ptr("Иванов-"),
+		Locale:    ptr("not a locale"),
+		Timezone:  ptr("Local"),
+		AvatarURL: ptr("http://example.com/avatar.png"),
+	})
+
+	var invalidErr commonerrs.InvalidInputError
+	require.ErrorAs(t, err, &invalidErr)
+
+	rules := make([]string, len(invalidErr.Violations))
+	for i, v := range invalidErr.Violations {
+		rules[i] = v.Rule
+	}
+	require.ElementsMatch(t, []string{
+		auth.ProfileFieldFirstName,
+		auth.ProfileFieldLastName,
+		auth.ProfileFieldLocale,
+		auth.ProfileFieldTimezone,
+		auth.ProfileFieldAvatarURL,
+	}, rules)
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+type RegistrationMode string
+
+const (
+	// RegistrationOpen lets anyone register.
+	RegistrationOpen RegistrationMode = "open"
+	// RegistrationDomains lets only emails of the allowed domains register.
+	RegistrationDomains RegistrationMode = "domains"
+	// RegistrationInvite requires an invitation code.
+	RegistrationInvite RegistrationMode = "invite"
+)
+
+var knownRegistrationModes = []RegistrationMode{RegistrationOpen, RegistrationDomains, RegistrationInvite}
+
+func NewRegistrationMode(s string) (RegistrationMode, error) {
+	mode := RegistrationMode(s)
+	if !slices.Contains(knownRegistrationModes, mode) {
+		return "", commonerrs.NewInvalidInputError(fmt.Sprintf("unknown registration mode %q", s))
+	}
+	return mode, nil
+}
+
+// Codes of RegistrationForbiddenError, which clients may rely on.
+const (
+	RegistrationForbiddenDomainNotAllowed   = "email_domain_not_allowed"
+	RegistrationForbiddenInvitationRequired = "invitation_required"
+	RegistrationForbiddenInvitationInvalid  = "invitation_invalid"
+	RegistrationForbiddenInvitationExpired  = "invitation_expired"
+	RegistrationForbiddenInvitationUsedUp   = "invitation_used_up"
+)
+
+type RegistrationForbiddenError struct {
+	Code string
+}
+
+func (e RegistrationForbiddenError) Error() string {
+	switch e.Code {
+	case RegistrationForbiddenDomainNotAllowed:
+		return "registration is not allowed for this email domain"
+	case RegistrationForbiddenInvitationRequired:
+		return "registration requires an invitation"
+	case RegistrationForbiddenInvitationInvalid:
+		return "invalid invitation code"
+	case RegistrationForbiddenInvitationExpired:
+		return "invitation has expired"
+	case RegistrationForbiddenInvitationUsedUp:
+		return "invitation has been used up"
+	default:
+		return "registration is forbidden"
+	}
+}
+
+// RegistrationPolicy decides who may register. A valid invitation lifts the
+// invite requirement but not the allowed domains.
+type RegistrationPolicy struct {
+	Mode RegistrationMode
+	// AllowedDomains are matched exactly and ignored in the open mode.
+	AllowedDomains []string
+}
+
+var DefaultRegistrationPolicy = RegistrationPolicy{Mode: RegistrationOpen}
+
+func NewRegistrationPolicy(mode RegistrationMode, allowedDomains []string) (RegistrationPolicy, error) {
+	if mode == RegistrationDomains && len(allowedDomains) == 0 {
+		return RegistrationPolicy{}, commonerrs.NewInvalidInputError("expected at least one allowed domain")
+	}
+
+	domains := make([]string, len(allowedDomains))
+	for i, d := range allowedDomains {
+		normalized, err := normalizeEmailDomain(strings.TrimSpace(d))
+		if err != nil {
+			return RegistrationPolicy{}, commonerrs.NewInvalidInputError(fmt.Sprintf("invalid domain %q", d))
+		}
+		domains[i] = normalized
+	}
+
+	return RegistrationPolicy{
+		Mode:           mode,
+		AllowedDomains: domains,
+	}, nil
+}
+
+// Check returns RegistrationForbiddenError if the email may not register
+// without an invitation.
+func (p RegistrationPolicy) Check(email Email) error {
+	switch p.Mode {
+	case RegistrationDomains:
+		return p.checkDomain(email)
+	case RegistrationInvite:
+		return RegistrationForbiddenError{Code: RegistrationForbiddenInvitationRequired}
+	default:
+		return nil
+	}
+}
+
+// CheckInvited returns RegistrationForbiddenError if the email may not
+// register with a valid invitation.
+func (p RegistrationPolicy) CheckInvited(email Email) error {
+	if p.Mode == RegistrationOpen {
+		return nil
+	}
+	return p.checkDomain(email)
+}
+
+func (p RegistrationPolicy) checkDomain(email Email) error {
+	if len(p.AllowedDomains) > 0 && !slices.Contains(p.AllowedDomains, email.Domain()) {
+		return RegistrationForbiddenError{Code: RegistrationForbiddenDomainNotAllowed}
+	}
+	return nil
+}
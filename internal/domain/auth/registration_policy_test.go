@@ -0,0 +1,59 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestRegistrationPolicy_Check(t *testing.T) {
+	require.NoError(t, auth.DefaultRegistrationPolicy.Check(auth.MustNewEmail("ivan@example.com")))
+
+	policy, err := auth.NewRegistrationPolicy(auth.RegistrationDomains, []string{"BMSTU.ru", " student.bmstu.ru"})
+	require.NoError(t, err)
+
+	require.NoError(t, policy.Check(auth.MustNewEmail("ivan@bmstu.ru")))
+	require.NoError(t, policy.Check(auth.MustNewEmail("ivan@Student.BMSTU.ru")))
+
+	var forbiddenErr auth.RegistrationForbiddenError
+	require.ErrorAs(t, policy.Check(auth.MustNewEmail("ivan@mail.bmstu.ru")), &forbiddenErr)
+	require.Equal(t, auth.RegistrationForbiddenDomainNotAllowed, forbiddenErr.Code)
+	require.ErrorAs(t, policy.Check(auth.MustNewEmail("ivan@bmstu.ru.evil.com")), &forbiddenErr)
+
+	policy, err = auth.NewRegistrationPolicy(auth.RegistrationInvite, nil)
+	require.NoError(t, err)
+	require.ErrorAs(t, policy.Check(auth.MustNewEmail("ivan@bmstu.ru")), &forbiddenErr)
+	require.Equal(t, auth.RegistrationForbiddenInvitationRequired, forbiddenErr.Code)
+}
+
+func TestRegistrationPolicy_CheckInvited(t *testing.T) {
+	require.NoError(t, auth.DefaultRegistrationPolicy.CheckInvited(auth.MustNewEmail("ivan@example.com")))
+
+	policy, err := auth.NewRegistrationPolicy(auth.RegistrationInvite, nil)
+	require.NoError(t, err)
+	require.NoError(t, policy.CheckInvited(auth.MustNewEmail("ivan@example.com")))
+
+	var forbiddenErr auth.RegistrationForbiddenError
+	for _, mode := range []auth.RegistrationMode{auth.RegistrationDomains, auth.RegistrationInvite} {
+		policy, err = auth.NewRegistrationPolicy(mode, []string{"bmstu.ru"})
+		require.NoError(t, err)
+
+		require.NoError(t, policy.CheckInvited(auth.MustNewEmail("ivan@bmstu.ru")))
+		require.ErrorAs(t, policy.CheckInvited(auth.MustNewEmail("ivan@example.com")), &forbiddenErr)
+		require.Equal(t, auth.RegistrationForbiddenDomainNotAllowed, forbiddenErr.Code)
+	}
+}
+
+func TestNewRegistrationPolicy_Invalid(t *testing.T) {
+	_, err := auth.NewRegistrationPolicy(auth.RegistrationDomains, nil)
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = auth.NewRegistrationPolicy(auth.RegistrationDomains, []string{"not a domain"})
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+
+	_, err = auth.NewRegistrationMode("closed")
+	require.ErrorAs(t, err, &commonerrs.InvalidInputError{})
+}
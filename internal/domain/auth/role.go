@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+type Role string
+
+const (
+	RoleAdmin Role = "admin"
+)
+
+var knownRoles = []Role{RoleAdmin}
+
+func NewRole(s string) (Role, error) {
+	role := Role(s)
+	if !slices.Contains(knownRoles, role) {
+		return "", commonerrs.NewInvalidInputError(fmt.Sprintf("unknown role %q", s))
+	}
+	return role, nil
+}
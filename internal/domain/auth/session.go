@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+const (
+	maxSessionUserAgent = 512
+
+	// sessionTouchInterval limits how often LastSeenAt is persisted.
+	sessionTouchInterval = time.Minute
+)
+
+// Session is created on every successful login. Access tokens are bound to
+// a session and stop being accepted once the session is revoked.
+type Session struct {
+	UUID     string
+	UserUUID string
+
+	UserAgent string
+	IP        string
+
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+func NewSession(
+	uuid string,
+	userUUID string,
+	userAgent string,
+	ip string,
+) (*Session, error) {
+	if uuid == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	if userUUID == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty user uuid")
+	}
+
+	if len(userAgent) > maxSessionUserAgent {
+		userAgent = userAgent[:maxSessionUserAgent]
+	}
+
+	now := time.Now()
+	return &Session{
+		UUID:       uuid,
+		UserUUID:   userUUID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}, nil
+}
+
+func NewSessionFromDB(
+	uuid string,
+	userUUID string,
+	userAgent string,
+	ip string,
+	createdAt time.Time,
+	lastSeenAt time.Time,
+) (*Session, error) {
+	if uuid == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	if userUUID == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty user uuid")
+	}
+
+	if createdAt.IsZero() {
+		return nil, commonerrs.NewInvalidInputError("expected not empty createdAt")
+	}
+
+	if lastSeenAt.IsZero() {
+		return nil, commonerrs.NewInvalidInputError("expected not empty lastSeenAt")
+	}
+
+	return &Session{
+		UUID:       uuid,
+		UserUUID:   userUUID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  createdAt,
+		LastSeenAt: lastSeenAt,
+	}, nil
+}
+
+func (s *Session) NeedsTouch(now time.Time) bool {
+	return now.Sub(s.LastSeenAt) >= sessionTouchInterval
+}
+
+func (s *Session) Touch(ip string, at time.Time) {
+	s.LastSeenAt = at
+	if ip != "" {
+		s.IP = ip
+	}
+}
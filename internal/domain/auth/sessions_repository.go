@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type SessionNotFound struct {
+	SessionUUID string
+}
+
+func (e SessionNotFound) Error() string {
+	return fmt.Sprintf("session with UUID %s not found", e.SessionUUID)
+}
+
+var ErrSessionAlreadyExists = errors.New("session already exists")
+
+type SessionsRepository interface {
+	Save(ctx context.Context, s *Session) error
+	Session(ctx context.Context, uuid string) (*Session, error)
+	UserSessions(ctx context.Context, userUUID string) ([]*Session, error)
+	Update(
+		ctx context.Context,
+		uuid string,
+		updateFn func(ctx context.Context, s *Session) error,
+	) error
+	Delete(ctx context.Context, uuid string) error
+
+	// DeleteUserSessions deletes all sessions of the user except the one
+	// with exceptUUID. Empty exceptUUID deletes every session.
+	DeleteUserSessions(ctx context.Context, userUUID string, exceptUUID string) error
+}
@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"bytes"
 	"errors"
+	"slices"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
 )
@@ -12,68 +14,148 @@ import (
 type User struct {
 	UUID string
 
-	Email    string
+	Email    Email
 	Passhash []byte
 
+	Roles []Role
+
+	Profile Profile
+	// AvatarKey is the blob storage key of the uploaded avatar, if any.
+	AvatarKey string
+
+	Status AccountStatus
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// Version counts the stored updates of the user.
+	Version int64
+
+	events []Event
+}
+
+// PasswordHasher makes and checks self-describing password hashes.
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	// Supports reports whether Compare can check the hash.
+	Supports(passhash []byte) bool
+	// Compare returns an error if the password does not match the hash.
+	Compare(passhash []byte, password string) error
+	// NeedsRehash reports whether the hash is made by another algorithm or
+	// with other parameters than Hash would use now.
+	NeedsRehash(passhash []byte) bool
 }
 
 func NewUser(
 	uuid string,
 	email string,
 	password string,
+	hasher PasswordHasher,
 ) (*User, error) {
-	if uuid == "" {
-		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	if err := validateUserUUID(uuid); err != nil {
+		return nil, err
 	}
 
-	if email == "" {
-		return nil, commonerrs.NewInvalidInputError("expected not empty email")
+	e, err := NewEmail(email)
+	if err != nil {
+		return nil, err
 	}
 
 	if password == "" {
 		return nil, commonerrs.NewInvalidInputError("expected not empty password")
 	}
 
-	passhash, err := createPasshash(password)
+	passhash, err := hasher.Hash(password)
 	if err != nil {
 		return nil, err
 	}
 
-	return &User{
+	u := &User{
 		UUID:      uuid,
-		Email:     email,
+		Email:     e,
 		Passhash:  passhash,
+		Status:    AccountStatus{State: AccountActive},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-	}, nil
+	}
+	u.record(UserRegistered{UserUUID: uuid, Email: e.String()})
+
+	return u, nil
 }
 
 func MustNewUser(
 	uuid string,
 	email string,
 	password string,
+	hasher PasswordHasher,
 ) *User {
-	user, err := NewUser(uuid, email, password)
+	user, err := NewUser(uuid, email, password, hasher)
 	if err != nil {
 		panic(err)
 	}
 	return user
 }
 
-func NewUserFromDB(
+// NewImportedUser creates a user moved over from another system with its
+// password hash. A zero createdAt means now.
+func NewImportedUser(
 	uuid string,
 	email string,
 	passhash []byte,
 	createdAt time.Time,
+	hasher PasswordHasher,
+) (*User, error) {
+	if err := validateUserUUID(uuid); err != nil {
+		return nil, err
+	}
+
+	e, err := NewEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(passhash) == 0 {
+		return nil, commonerrs.NewInvalidInputError("expected not empty password hash")
+	}
+
+	if !hasher.Supports(passhash) {
+		return nil, commonerrs.NewInvalidInputError("unsupported password hash format")
+	}
+
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	u := &User{
+		UUID:      uuid,
+		Email:     e,
+		Passhash:  passhash,
+		Status:    AccountStatus{State: AccountActive},
+		CreatedAt: createdAt,
+		UpdatedAt: time.Now(),
+	}
+	u.record(UserRegistered{UserUUID: uuid, Email: e.String(), Imported: true})
+
+	return u, nil
+}
+
+func NewUserFromDB(
+	uuid string,
+	email Email,
+	passhash []byte,
+	roles []Role,
+	profile Profile,
+	avatarKey string,
+	status AccountStatus,
+	createdAt time.Time,
 	updatedAt time.Time,
+	version int64,
 ) (*User, error) {
 	if uuid == "" {
 		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
 	}
 
-	if email == "" {
+	if email.IsZero() {
 		return nil, commonerrs.NewInvalidInputError("expected not empty email")
 	}
 
@@ -81,6 +163,10 @@ func NewUserFromDB(
 		return nil, commonerrs.NewInvalidInputError("expected not empty password")
 	}
 
+	if _, err := NewAccountState(string(status.State)); err != nil {
+		return nil, err
+	}
+
 	if createdAt.IsZero() {
 		return nil, commonerrs.NewInvalidInputError("expected not empty createdAt")
 	}
@@ -93,20 +179,152 @@ func NewUserFromDB(
 		UUID:      uuid,
 		Email:     email,
 		Passhash:  passhash,
+		Roles:     roles,
+		Profile:   profile,
+		AvatarKey: avatarKey,
+		Status:    status,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
+		Version:   version,
 	}, nil
 }
 
+// validateUserUUID accepts only the canonical lower case form.
+func validateUserUUID(s string) error {
+	if s == "" {
+		return commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	u, err := uuid.Parse(s)
+	if err != nil || u.String() != s || u == uuid.Nil {
+		return commonerrs.NewInvalidInputError("expected uuid in canonical form")
+	}
+
+	return nil
+}
+
 var ErrInvalidCredentials = errors.New("invalid credentials")
 
-func (u *User) PasswordMatch(password string) error {
-	if err := bcrypt.CompareHashAndPassword(u.Passhash, []byte(password)); err != nil {
+func (u *User) PasswordMatch(hasher PasswordHasher, password string) error {
+	if err := hasher.Compare(u.Passhash, password); err != nil {
 		return ErrInvalidCredentials
 	}
 	return nil
 }
 
-func createPasshash(password string) ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (u *User) NeedsRehash(hasher PasswordHasher) bool {
+	return hasher.NeedsRehash(u.Passhash)
+}
+
+// RehashPassword replaces the hash with passhash unless it is no longer
+// outdated.
+func (u *User) RehashPassword(outdated []byte, passhash []byte) {
+	if !bytes.Equal(u.Passhash, outdated) {
+		return
+	}
+
+	u.Passhash = passhash
+	u.UpdatedAt = time.Now()
+}
+
+func (u *User) HasRole(role Role) bool {
+	return slices.Contains(u.Roles, role)
+}
+
+func (u *User) GrantRole(role Role) {
+	if u.HasRole(role) {
+		return
+	}
+	u.Roles = append(u.Roles, role)
+	u.UpdatedAt = time.Now()
+	u.record(UserRoleGranted{UserUUID: u.UUID, Role: string(role)})
+}
+
+func (u *User) ChangeEmail(email string) error {
+	e, err := NewEmail(email)
+	if err != nil {
+		return err
+	}
+
+	if e.String() == u.Email.String() {
+		return nil
+	}
+
+	u.Email = e
+	u.UpdatedAt = time.Now()
+	u.record(UserEmailChanged{UserUUID: u.UUID, Email: e.String()})
+
+	return nil
+}
+
+func (u *User) UpdateProfile(update ProfileUpdate) error {
+	profile, err := u.Profile.Apply(update)
+	if err != nil {
+		return err
+	}
+
+	u.Profile = profile
+	u.UpdatedAt = time.Now()
+	u.record(UserProfileUpdated{UserUUID: u.UUID})
+
+	return nil
+}
+
+// SetAvatar returns the key of the replaced avatar.
+func (u *User) SetAvatar(key string) (previous string) {
+	previous = u.AvatarKey
+	u.AvatarKey = key
+	u.UpdatedAt = time.Now()
+	return previous
+}
+
+// CheckVersion returns ErrConcurrentModification unless the user is at version expected.
+func (u *User) CheckVersion(expected int64) error {
+	if u.Version != expected {
+		return ErrConcurrentModification
+	}
+
+	return nil
+}
+
+// CheckActive returns AccountInactiveError unless the user may log in at the
+// moment now.
+func (u *User) CheckActive(now time.Time) error {
+	switch u.Status.Effective(now) {
+	case AccountSuspended:
+		return AccountInactiveError{State: AccountSuspended, Until: u.Status.Until}
+	case AccountDisabled:
+		return AccountInactiveError{State: AccountDisabled}
+	default:
+		return nil
+	}
+}
+
+func (u *User) ChangeStatus(status AccountStatus) error {
+	if status.ChangedBy == u.UUID {
+		return commonerrs.NewInvalidInputError("users can not change their own status")
+	}
+
+	u.Status = status
+	u.UpdatedAt = time.Now()
+
+	event := UserStatusChanged{UserUUID: u.UUID, Status: string(status.State)}
+	if !status.Until.IsZero() {
+		until := status.Until.UTC()
+		event.Until = &until
+	}
+	u.record(event)
+
+	return nil
+}
+
+func (u *User) record(e Event) {
+	u.events = append(u.events, e)
+}
+
+// PopEvents returns the events raised since the last call.
+func (u *User) PopEvents() []Event {
+	events := u.events
+	u.events = nil
+	return events
 }
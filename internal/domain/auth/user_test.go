@@ -2,15 +2,110 @@ package auth_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
 	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
 )
 
 func TestUser_MatchPassword(t *testing.T) {
+	hasher := passhash.NewDefaultHasher()
 	password := "qwerty"
-	user := auth.MustNewUser("1234", "test@test.com", password)
-	require.NoError(t, user.PasswordMatch(password))
-	require.ErrorIs(t, user.PasswordMatch("another"), auth.ErrInvalidCredentials)
+	user := auth.MustNewUser("0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b", "test@test.com", password, hasher)
+	require.NoError(t, user.PasswordMatch(hasher, password))
+	require.ErrorIs(t, user.PasswordMatch(hasher, "another"), auth.ErrInvalidCredentials)
+}
+
+func TestUser_RehashPassword(t *testing.T) {
+	password := "qwerty"
+	user := auth.MustNewUser("0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b", "test@test.com", password, passhash.NewHasher(passhash.DefaultBcrypt))
+
+	hasher := passhash.NewDefaultHasher()
+	require.NoError(t, user.PasswordMatch(hasher, password))
+	require.True(t, user.NeedsRehash(hasher))
+
+	outdated := user.Passhash
+	passhash, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	user.RehashPassword(outdated, passhash)
+	require.False(t, user.NeedsRehash(hasher))
+	require.NoError(t, user.PasswordMatch(hasher, password))
+
+	another, err := hasher.Hash("another")
+	require.NoError(t, err)
+	user.RehashPassword(outdated, another)
+	require.NoError(t, user.PasswordMatch(hasher, password))
+}
+
+func TestNewImportedUser(t *testing.T) {
+	hasher := passhash.NewDefaultHasher()
+
+	user, err := auth.NewImportedUser(
+		"0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b", "test@test.com",
+		[]byte("pbkdf2_sha256$1000$seasalt$YIWkt6M1JFXrHg5s0jZjBSc7C2Cz6QvchSJ0h8Y+i7c="),
+		time.Time{}, hasher,
+	)
+	require.NoError(t, err)
+	require.False(t, user.CreatedAt.IsZero())
+	require.NoError(t, user.PasswordMatch(hasher, "password"))
+	require.True(t, user.NeedsRehash(hasher))
+
+	for _, hash := range []string{
+		"md5$salt$hash",
+		"pbkdf2_sha256$1000$seasalt",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+	} {
+		_, err = auth.NewImportedUser("0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b", "test@test.com", []byte(hash), time.Time{}, hasher)
+		require.ErrorAs(t, err, &commonerrs.InvalidInputError{}, hash)
+	}
+}
+
+func TestNewUser_InvalidUUID(t *testing.T) {
+	hasher := passhash.NewHasher(passhash.Bcrypt{Cost: 4})
+
+	for _, uuid := range []string{
+		"",
+		"1234",
+		"0190A5C2-7B3E-7D4F-9A1B-2C3D4E5F6A7B",
+		"{0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b}",
+		"urn:uuid:0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b",
+		"0190a5c27b3e7d4f9a1b2c3d4e5f6a7b",
+		"00000000-0000-0000-0000-000000000000",
+	} {
+		_, err := auth.NewUser(uuid, "test@test.com", "qwerty", hasher)
+		require.ErrorAs(t, err, &commonerrs.InvalidInputError{}, uuid)
+	}
+}
+
+func TestUser_Events(t *testing.T) {
+	uuid := "0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b"
+	user := auth.MustNewUser(uuid, "test@test.com", "qwerty", passhash.NewDefaultHasher())
+	user.GrantRole(auth.RoleAdmin)
+	user.GrantRole(auth.RoleAdmin)
+	require.NoError(t, user.ChangeEmail("other@test.com"))
+	require.NoError(t, user.ChangeEmail("other@test.com"))
+	require.Error(t, user.ChangeEmail("not an email"))
+
+	require.Equal(t, []auth.Event{
+		auth.UserRegistered{UserUUID: uuid, Email: "test@test.com"},
+		auth.UserRoleGranted{UserUUID: uuid, Role: string(auth.RoleAdmin)},
+		auth.UserEmailChanged{UserUUID: uuid, Email: "other@test.com"},
+	}, user.PopEvents())
+	require.Empty(t, user.PopEvents())
+
+	until := time.Now().Add(time.Hour)
+	status, err := auth.NewAccountStatus(auth.AccountSuspended, until, "spam", testAdminUUID, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, user.ChangeStatus(status))
+
+	events := user.PopEvents()
+	require.Len(t, events, 1)
+	changed, ok := events[0].(auth.UserStatusChanged)
+	require.True(t, ok)
+	require.Equal(t, string(auth.AccountSuspended), changed.Status)
+	require.True(t, until.Equal(*changed.Until))
 }
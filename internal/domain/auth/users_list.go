@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+type UsersSort string
+
+const (
+	UsersByCreatedAt UsersSort = "createdAt"
+	UsersByUpdatedAt UsersSort = "updatedAt"
+)
+
+const (
+	DefaultUsersPageLimit = 50
+	MaxUsersPageLimit     = 200
+)
+
+// UsersFilter matches users meeting every set condition.
+type UsersFilter struct {
+	// EmailContains is matched against the normalized email.
+	EmailContains string
+	Role          Role
+	// CreatedFrom is inclusive and CreatedTo is exclusive.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	// Status is matched against the effective status at Now.
+	Status AccountState
+	Now    time.Time
+}
+
+// ListUsers selects a page of users, continued by keyset.
+type ListUsers struct {
+	Filter     UsersFilter
+	Sort       UsersSort
+	Descending bool
+
+	// Cursor continues the listing after the page it was returned with.
+	Cursor string
+	Limit  int
+
+	WithTotal bool
+}
+
+func NewListUsers(
+	filter UsersFilter,
+	sort UsersSort,
+	descending bool,
+	cursor string,
+	limit int,
+	withTotal bool,
+) (ListUsers, error) {
+	if sort == "" {
+		sort = UsersByCreatedAt
+	}
+	if sort != UsersByCreatedAt && sort != UsersByUpdatedAt {
+		return ListUsers{}, commonerrs.NewInvalidInputError("expected sort by createdAt or updatedAt")
+	}
+
+	if limit == 0 {
+		limit = DefaultUsersPageLimit
+	}
+	if limit < 1 || limit > MaxUsersPageLimit {
+		return ListUsers{}, commonerrs.NewInvalidInputError("expected limit between 1 and 200")
+	}
+
+	if filter.Role != "" {
+		if _, err := NewRole(string(filter.Role)); err != nil {
+			return ListUsers{}, err
+		}
+	}
+
+	if filter.Status != "" {
+		if _, err := NewAccountState(string(filter.Status)); err != nil {
+			return ListUsers{}, err
+		}
+	}
+	if filter.Now.IsZero() {
+		filter.Now = time.Now()
+	}
+
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return ListUsers{}, commonerrs.NewInvalidInputError("expected created range start before its end")
+	}
+
+	filter.EmailContains = NormalizeEmailSubstring(filter.EmailContains)
+
+	l := ListUsers{
+		Filter:     filter,
+		Sort:       sort,
+		Descending: descending,
+		Cursor:     cursor,
+		Limit:      limit,
+		WithTotal:  withTotal,
+	}
+
+	if _, err := l.DecodeCursor(); err != nil {
+		return ListUsers{}, err
+	}
+
+	return l, nil
+}
+
+func NormalizeEmailSubstring(s string) string {
+	return norm.NFC.String(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// UsersCursor is the position of the last user of a page.
+type UsersCursor struct {
+	Sort       UsersSort `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	Time       time.Time `json:"t"`
+	UUID       string    `json:"u"`
+}
+
+// SortKey returns the time the users are sorted by.
+func (l ListUsers) SortKey(u *User) time.Time {
+	if l.Sort == UsersByUpdatedAt {
+		return u.UpdatedAt
+	}
+	return u.CreatedAt
+}
+
+// NextCursor returns the cursor of the page ending with last.
+func (l ListUsers) NextCursor(last *User) string {
+	b, _ := json.Marshal(UsersCursor{
+		Sort:       l.Sort,
+		Descending: l.Descending,
+		Time:       l.SortKey(last).UTC(),
+		UUID:       last.UUID,
+	})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor returns nil for the first page.
+func (l ListUsers) DecodeCursor() (*UsersCursor, error) {
+	if l.Cursor == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(l.Cursor)
+	if err != nil {
+		return nil, commonerrs.NewInvalidInputError("invalid cursor")
+	}
+
+	var c UsersCursor
+	if err = json.Unmarshal(b, &c); err != nil || c.UUID == "" || c.Time.IsZero() {
+		return nil, commonerrs.NewInvalidInputError("invalid cursor")
+	}
+
+	if c.Sort != l.Sort || c.Descending != l.Descending {
+		return nil, commonerrs.NewInvalidInputError("cursor is made for another sort order")
+	}
+
+	return &c, nil
+}
+
+// Matches reports whether the user passes the filter.
+func (f UsersFilter) Matches(u *User) bool {
+	if f.EmailContains != "" && !strings.Contains(u.Email.Normalized(), f.EmailContains) {
+		return false
+	}
+
+	if f.Role != "" && !u.HasRole(f.Role) {
+		return false
+	}
+
+	if !f.CreatedFrom.IsZero() && u.CreatedAt.Before(f.CreatedFrom) {
+		return false
+	}
+
+	if !f.CreatedTo.IsZero() && !u.CreatedAt.Before(f.CreatedTo) {
+		return false
+	}
+
+	if f.Status != "" && u.Status.Effective(f.Now) != f.Status {
+		return false
+	}
+
+	return true
+}
+
+// After reports whether the user goes after the cursor in the sort order.
+func (l ListUsers) After(u *User, c *UsersCursor) bool {
+	if c == nil {
+		return true
+	}
+
+	t := l.SortKey(u)
+	if l.Descending {
+		return t.Before(c.Time) || t.Equal(c.Time) && u.UUID < c.UUID
+	}
+	return t.After(c.Time) || t.Equal(c.Time) && u.UUID > c.UUID
+}
+
+// Less orders users by the sort key with the UUID breaking ties.
+func (l ListUsers) Less(a *User, b *User) bool {
+	ta, tb := l.SortKey(a), l.SortKey(b)
+	if !ta.Equal(tb) {
+		return ta.Before(tb) != l.Descending
+	}
+	return (a.UUID < b.UUID) != l.Descending
+}
+
+type UsersPage struct {
+	Users []*User
+	// NextCursor is empty on the last page.
+	NextCursor string
+	// Total is -1 unless it is requested.
+	Total int
+}
@@ -24,10 +24,15 @@ func (e UserEmailNotFound) Error() string {
 
 var ErrUserAlreadyExists = errors.New("user already exists")
 
+// ErrConcurrentModification is returned if the user was changed by someone
+// else after the caller read it.
+var ErrConcurrentModification = errors.New("user was modified concurrently")
+
 type UsersRepository interface {
 	Save(ctx context.Context, u *User) error
 	User(ctx context.Context, uuid string) (*User, error)
-	UserByEmail(ctx context.Context, email string) (*User, error)
+	UserByEmail(ctx context.Context, email Email) (*User, error)
+	ListUsers(ctx context.Context, l ListUsers) (UsersPage, error)
 	Update(
 		ctx context.Context,
 		uuid string,
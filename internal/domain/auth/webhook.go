@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	webhookSecretBytes  = 32
+	maxWebhookURL       = 2048
+	maxWebhookError     = 512
+)
+
+// WebhookSubscription asks for events of EventTypes to be posted to URL.
+type WebhookSubscription struct {
+	UUID       string
+	URL        string
+	EventTypes []EventType
+	Secret     string
+	CreatedBy  string
+	CreatedAt  time.Time
+}
+
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func NewWebhookSubscription(
+	uuid string,
+	rawURL string,
+	eventTypes []EventType,
+	secret string,
+	createdBy string,
+) (*WebhookSubscription, error) {
+	if uuid == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(rawURL) > maxWebhookURL {
+		return nil, commonerrs.NewInvalidInputError("expected absolute http or https webhook URL")
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, commonerrs.NewInvalidInputError("expected at least one event type")
+	}
+
+	for i, t := range eventTypes {
+		if _, err = NewEventType(string(t)); err != nil {
+			return nil, err
+		}
+		if slices.Contains(eventTypes[:i], t) {
+			return nil, commonerrs.NewInvalidInputError(fmt.Sprintf("duplicate event type %q", t))
+		}
+	}
+
+	if !strings.HasPrefix(secret, webhookSecretPrefix) {
+		return nil, commonerrs.NewInvalidInputError("expected webhook secret")
+	}
+
+	if createdBy == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty creator uuid")
+	}
+
+	return &WebhookSubscription{
+		UUID:       uuid,
+		URL:        rawURL,
+		EventTypes: slices.Clone(eventTypes),
+		Secret:     secret,
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+func NewWebhookSubscriptionFromDB(
+	uuid string,
+	rawURL string,
+	eventTypes []EventType,
+	secret string,
+	createdBy string,
+	createdAt time.Time,
+) (*WebhookSubscription, error) {
+	if uuid == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty uuid")
+	}
+
+	if rawURL == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty URL")
+	}
+
+	if secret == "" {
+		return nil, commonerrs.NewInvalidInputError("expected not empty secret")
+	}
+
+	if createdAt.IsZero() {
+		return nil, commonerrs.NewInvalidInputError("expected not empty createdAt")
+	}
+
+	return &WebhookSubscription{
+		UUID:       uuid,
+		URL:        rawURL,
+		EventTypes: eventTypes,
+		Secret:     secret,
+		CreatedBy:  createdBy,
+		CreatedAt:  createdAt,
+	}, nil
+}
+
+func (s *WebhookSubscription) Subscribed(t EventType) bool {
+	return slices.Contains(s.EventTypes, t)
+}
+
+type WebhookDeliveryState string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryState = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryState = "succeeded"
+	// WebhookDeliveryDead is only retried if an admin redelivers it.
+	WebhookDeliveryDead WebhookDeliveryState = "dead"
+)
+
+func NewWebhookDeliveryState(s string) (WebhookDeliveryState, error) {
+	switch state := WebhookDeliveryState(s); state {
+	case WebhookDeliveryPending, WebhookDeliverySucceeded, WebhookDeliveryDead:
+		return state, nil
+	default:
+		return "", commonerrs.NewInvalidInputError(fmt.Sprintf("unknown webhook delivery state %q", s))
+	}
+}
+
+// WebhookRetryPolicy waits BaseDelay * 2^(n-1) before the n-th retry, up to
+// MaxDelay.
+type WebhookRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultWebhookRetryPolicy gives up after about a day.
+var DefaultWebhookRetryPolicy = WebhookRetryPolicy{
+	MaxAttempts: 10,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    6 * time.Hour,
+}
+
+// Delay is the wait after the given number of failed attempts.
+func (p WebhookRetryPolicy) Delay(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+type WebhookDelivery struct {
+	UUID             string
+	SubscriptionUUID string
+	Event            OutboxMessage
+
+	State    WebhookDeliveryState
+	Attempts int
+	// NextAttemptAt is zero unless the delivery is pending.
+	NextAttemptAt time.Time
+
+	LastAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+
+	CreatedAt time.Time
+}
+
+// NewWebhookDelivery is due at once.
+func NewWebhookDelivery(uuid string, s *WebhookSubscription, m *OutboxMessage) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		UUID:             uuid,
+		SubscriptionUUID: s.UUID,
+		Event:            *m,
+		State:            WebhookDeliveryPending,
+		NextAttemptAt:    now,
+		CreatedAt:        now,
+	}
+}
+
+func (d *WebhookDelivery) Succeed(statusCode int, now time.Time) {
+	d.Attempts++
+	d.State = WebhookDeliverySucceeded
+	d.NextAttemptAt = time.Time{}
+	d.LastAttemptAt = now
+	d.LastStatusCode = statusCode
+	d.LastError = ""
+}
+
+// Fail schedules the next attempt or gives up after policy.MaxAttempts.
+// statusCode is zero if there was no response.
+func (d *WebhookDelivery) Fail(statusCode int, err error, now time.Time, policy WebhookRetryPolicy) {
+	d.Attempts++
+	d.LastAttemptAt = now
+	d.LastStatusCode = statusCode
+	d.LastError = clip(err.Error(), maxWebhookError)
+
+	if d.Attempts >= policy.MaxAttempts {
+		d.State = WebhookDeliveryDead
+		d.NextAttemptAt = time.Time{}
+		return
+	}
+
+	d.State = WebhookDeliveryPending
+	d.NextAttemptAt = now.Add(policy.Delay(d.Attempts))
+}
+
+// Redeliver sends the delivery again at once, with a fresh set of attempts.
+func (d *WebhookDelivery) Redeliver(now time.Time) {
+	d.State = WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+}
+
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// SignWebhook returns the value of the signature header,
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(webhookMAC(secret, t, body))
+}
+
+// VerifyWebhookSignature rejects signatures made more than tolerance away
+// from now.
+func VerifyWebhookSignature(
+	secret string,
+	header string,
+	body []byte,
+	now time.Time,
+	tolerance time.Duration,
+) error {
+	var t string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+
+	if now.Sub(time.Unix(unix, 0)).Abs() > tolerance {
+		return ErrInvalidWebhookSignature
+	}
+
+	want := webhookMAC(secret, t, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+
+	return ErrInvalidWebhookSignature
+}
+
+func webhookMAC(secret string, t string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,110 @@
+package auth_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	secret, err := auth.NewWebhookSecret()
+	require.NoError(t, err)
+
+	body := []byte(`{"id":1}`)
+	now := time.Now()
+	header := auth.SignWebhook(secret, now, body)
+
+	require.NoError(t, auth.VerifyWebhookSignature(secret, header, body, now, time.Minute))
+	require.NoError(t, auth.VerifyWebhookSignature(secret, header, body, now.Add(30*time.Second), time.Minute))
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+	}{
+		{name: "other secret", secret: "whsec_other", header: header, body: body, now: now},
+		{name: "tampered body", secret: secret, header: header, body: []byte(`{"id":2}`), now: now},
+		{name: "replayed", secret: secret, header: header, body: body, now: now.Add(2 * time.Minute)},
+		{name: "no timestamp", secret: secret, header: "v1=00", body: body, now: now},
+		{name: "empty", secret: secret, header: "", body: body, now: now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := auth.VerifyWebhookSignature(tt.secret, tt.header, tt.body, tt.now, time.Minute)
+			require.ErrorIs(t, err, auth.ErrInvalidWebhookSignature)
+		})
+	}
+}
+
+func TestWebhookRetryPolicy_Delay(t *testing.T) {
+	p := auth.WebhookRetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	require.Equal(t, time.Second, p.Delay(1))
+	require.Equal(t, 2*time.Second, p.Delay(2))
+	require.Equal(t, 8*time.Second, p.Delay(4))
+	require.Equal(t, 10*time.Second, p.Delay(5))
+	require.Equal(t, 10*time.Second, p.Delay(100))
+}
+
+func TestWebhookDelivery_Fail(t *testing.T) {
+	s, err := auth.NewWebhookSubscription(
+		"sub", "https://example.com/hooks", []auth.EventType{auth.EventUserRegistered}, "whsec_test", "admin",
+	)
+	require.NoError(t, err)
+
+	m, err := auth.NewOutboxMessage(auth.UserRegistered{UserUUID: "user", Email: "user@example.com"})
+	require.NoError(t, err)
+
+	p := auth.WebhookRetryPolicy{MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	d := auth.NewWebhookDelivery("delivery", s, m)
+	now := time.Now()
+
+	d.Fail(0, errors.New("connection refused"), now, p)
+	require.Equal(t, auth.WebhookDeliveryPending, d.State)
+	require.Equal(t, now.Add(time.Minute), d.NextAttemptAt)
+	require.Equal(t, "connection refused", d.LastError)
+
+	d.Fail(500, errors.New("status 500"), now, p)
+	require.Equal(t, auth.WebhookDeliveryDead, d.State)
+	require.Equal(t, 2, d.Attempts)
+	require.True(t, d.NextAttemptAt.IsZero())
+
+	d.Redeliver(now)
+	require.Equal(t, auth.WebhookDeliveryPending, d.State)
+	require.Zero(t, d.Attempts)
+}
+
+func TestNewWebhookSubscription(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		eventTypes []auth.EventType
+		secret     string
+	}{
+		{name: "relative url", url: "/hooks", eventTypes: []auth.EventType{auth.EventUserDeleted}, secret: "whsec_x"},
+		{name: "ftp url", url: "ftp://example.com", eventTypes: []auth.EventType{auth.EventUserDeleted}, secret: "whsec_x"},
+		{name: "no event types", url: "https://example.com", secret: "whsec_x"},
+		{name: "unknown event type", url: "https://example.com", eventTypes: []auth.EventType{"user.x"}, secret: "whsec_x"},
+		{
+			name:       "duplicate event type",
+			url:        "https://example.com",
+			eventTypes: []auth.EventType{auth.EventUserDeleted, auth.EventUserDeleted},
+			secret:     "whsec_x",
+		},
+		{name: "bad secret", url: "https://example.com", eventTypes: []auth.EventType{auth.EventUserDeleted}, secret: "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.NewWebhookSubscription("sub", tt.url, tt.eventTypes, tt.secret, "admin")
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrWebhookSubscriptionNotFound      = errors.New("webhook subscription not found")
+	ErrWebhookSubscriptionAlreadyExists = errors.New("webhook subscription already exists")
+	ErrWebhookDeliveryNotFound          = errors.New("webhook delivery not found")
+)
+
+const (
+	DefaultWebhookDeliveriesLimit = 50
+	MaxWebhookDeliveriesLimit     = 200
+)
+
+type WebhooksRepository interface {
+	SaveSubscription(ctx context.Context, s *WebhookSubscription) error
+	Subscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+	Subscription(ctx context.Context, uuid string) (*WebhookSubscription, error)
+	// DeleteSubscription deletes its deliveries too.
+	DeleteSubscription(ctx context.Context, uuid string) error
+
+	// SaveDeliveries skips events a subscription already has a delivery for.
+	SaveDeliveries(ctx context.Context, deliveries []*WebhookDelivery) error
+	// ClaimDueDeliveries returns up to limit pending deliveries due at now,
+	// oldest first, and postpones them by lease.
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*WebhookDelivery, error)
+	UpdateDelivery(
+		ctx context.Context,
+		uuid string,
+		updateFn func(ctx context.Context, d *WebhookDelivery) error,
+	) error
+	// Deliveries returns the latest deliveries of the subscription, newest
+	// first. An empty state matches every delivery.
+	Deliveries(
+		ctx context.Context,
+		subscriptionUUID string,
+		state WebhookDeliveryState,
+		limit int,
+	) ([]*WebhookDelivery, error)
+}
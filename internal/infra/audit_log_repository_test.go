@@ -0,0 +1,141 @@
+package infra_test
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestPgAuditLogRepository(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	r := infra.NewPgAuditLogRepository(db)
+	testAuditLogRepository(t, r)
+
+	t.Run("should forbid changing events", func(t *testing.T) {
+		_, err := db.Exec(`UPDATE audit_log SET target_uuid = 'x' WHERE seq = 1`)
+		require.Error(t, err)
+
+		_, err = db.Exec(`DELETE FROM audit_log`)
+		require.Error(t, err)
+	})
+}
+
+func TestSQLiteAuditLogRepository(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	r := infra.NewSQLiteAuditLogRepository(db)
+	testAuditLogRepository(t, r)
+
+	t.Run("should forbid changing events", func(t *testing.T) {
+		_, err := db.Exec(`UPDATE audit_log SET target_uuid = 'x' WHERE seq = 1`)
+		require.Error(t, err)
+
+		_, err = db.Exec(`DELETE FROM audit_log`)
+		require.Error(t, err)
+	})
+}
+
+func testAuditLogRepository(t *testing.T, r auth.AuditLogRepository) {
+	t.Run("should append chained events", func(t *testing.T) {
+		ctx := context.Background()
+		target := gofakeit.UUID()
+
+		var events []*auth.AuditEvent
+		for range 3 {
+			e := auth.NewAuditEvent(ctx, auth.AuditSessionRevoked, target, map[string]string{
+				"session": gofakeit.UUID(),
+			})
+			require.NoError(t, r.Append(ctx, e))
+			events = append(events, e)
+		}
+		require.Less(t, events[0].Seq, events[1].Seq)
+
+		stored, err := r.AuditChainEvents(ctx, target, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, stored, 3)
+		require.Equal(t, events[0].Hash, stored[0].Hash)
+		require.Equal(t, events[2].Hash, stored[2].Hash)
+		require.NoError(t, auth.VerifyAuditChain(nil, stored))
+
+		stored, err = r.AuditChainEvents(ctx, target, events[0].Seq, 1)
+		require.NoError(t, err)
+		require.Len(t, stored, 1)
+		require.Equal(t, events[1].Hash, stored[0].Hash)
+	})
+
+	t.Run("should chain events per target", func(t *testing.T) {
+		ctx := context.Background()
+		targets := []string{gofakeit.UUID(), gofakeit.UUID()}
+
+		for range 2 {
+			for _, target := range targets {
+				require.NoError(t, r.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditTokenCreated, target, nil)))
+			}
+		}
+
+		for _, target := range targets {
+			stored, err := r.AuditChainEvents(ctx, target, 0, 10)
+			require.NoError(t, err)
+			require.Len(t, stored, 2)
+			require.NoError(t, auth.VerifyAuditChain(nil, stored))
+		}
+
+		slices.Sort(targets)
+		keys, err := r.AuditChainKeys(ctx, targets[0], 1)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.LessOrEqual(t, keys[0], targets[1])
+
+		keys, err = r.AuditChainKeys(ctx, targets[1], 1000)
+		require.NoError(t, err)
+		require.NotContains(t, keys, targets[1])
+		require.NotContains(t, keys, "")
+	})
+
+	t.Run("should list events by filter with pagination", func(t *testing.T) {
+		ctx := context.Background()
+		target := gofakeit.UUID()
+
+		for range 3 {
+			require.NoError(t, r.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditTokenCreated, target, nil)))
+		}
+		require.NoError(t, r.Append(ctx, auth.NewAuditEvent(ctx, auth.AuditTokenRevoked, target, nil)))
+
+		filter := auth.AuditFilter{TargetUUID: target, Action: auth.AuditTokenCreated}
+		l, err := auth.NewListAuditEvents(filter, "", 2)
+		require.NoError(t, err)
+
+		page, err := r.ListAuditEvents(ctx, l)
+		require.NoError(t, err)
+		require.Len(t, page.Events, 2)
+		require.NotEmpty(t, page.NextCursor)
+		require.Greater(t, page.Events[0].Seq, page.Events[1].Seq)
+
+		l, err = auth.NewListAuditEvents(filter, page.NextCursor, 2)
+		require.NoError(t, err)
+
+		page, err = r.ListAuditEvents(ctx, l)
+		require.NoError(t, err)
+		require.Len(t, page.Events, 1)
+		require.Empty(t, page.NextCursor)
+		require.Equal(t, auth.AuditTokenCreated, page.Events[0].Action)
+	})
+}
@@ -0,0 +1,175 @@
+package infra_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestFSBlobStorage(t *testing.T) {
+	s, err := infra.NewFSBlobStorage(t.TempDir())
+	require.NoError(t, err)
+
+	testBlobStorage(t, s)
+
+	err = s.Put(context.Background(), "../escape", []byte("data"), "text/plain")
+	require.Error(t, err)
+}
+
+func TestS3BlobStorage(t *testing.T) {
+	srv := httptest.NewServer(newFakeS3("avatars-bucket"))
+	t.Cleanup(srv.Close)
+
+	s, err := infra.NewS3BlobStorage(infra.S3Config{
+		Endpoint:        srv.URL,
+		Bucket:          "avatars-bucket",
+		AccessKeyID:     "test",
+		SecretAccessKey: "test",
+	}, srv.Client())
+	require.NoError(t, err)
+
+	testBlobStorage(t, s)
+}
+
+func testBlobStorage(t *testing.T, s auth.BlobStorage) {
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, "avatars/missing.png")
+	require.ErrorIs(t, err, auth.ErrBlobNotFound)
+
+	err = s.Put(ctx, "avatars/user/1.png", []byte("first"), "image/png")
+	require.NoError(t, err)
+
+	data, err := s.Get(ctx, "avatars/user/1.png")
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), data)
+
+	err = s.Put(ctx, "avatars/user/1.png", []byte("second"), "image/png")
+	require.NoError(t, err)
+
+	data, err = s.Get(ctx, "avatars/user/1.png")
+	require.NoError(t, err)
+	require.Equal(t, []byte("second"), data)
+
+	err = s.Delete(ctx, "avatars/user/1.png")
+	require.NoError(t, err)
+
+	_, err = s.Get(ctx, "avatars/user/1.png")
+	require.ErrorIs(t, err, auth.ErrBlobNotFound)
+
+	err = s.Delete(ctx, "avatars/user/1.png")
+	require.NoError(t, err)
+}
+
+// fakeS3 stands in for an S3 compatible storage. It checks that requests
+// are signed and that the body matches its hash or, if it is sent in signed
+// chunks, its decoded length.
+type fakeS3 struct {
+	sync.Mutex
+	bucket  string
+	objects map[string][]byte
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{
+		bucket:  bucket,
+		objects: make(map[string][]byte),
+	}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+s.bucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch hash := r.Header.Get("X-Amz-Content-Sha256"); hash {
+	case "UNSIGNED-PAYLOAD":
+	case "STREAMING-AWS4-HMAC-SHA256-PAYLOAD":
+		body, err = decodeS3Chunks(body)
+		if err != nil || strconv.Itoa(len(body)) != r.Header.Get("X-Amz-Decoded-Content-Length") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	default:
+		sum := sha256.Sum256(body)
+		if hash != hex.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = body
+	case http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		sum := sha256.Sum256(data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeS3Chunks joins the data of chunks, each sent as
+// <hex size>;chunk-signature=<signature>\r\n<data>\r\n up to an empty one.
+func decodeS3Chunks(body []byte) ([]byte, error) {
+	var data []byte
+	for {
+		header, rest, ok := bytes.Cut(body, []byte("\r\n"))
+		if !ok {
+			return nil, errors.New("malformed chunk header")
+		}
+
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+		size, err := strconv.ParseInt(string(sizeHex), 16, 64)
+		if err != nil || int64(len(rest)) < size+2 {
+			return nil, errors.New("malformed chunk")
+		}
+
+		if size == 0 {
+			return data, nil
+		}
+		data = append(data, rest[:size]...)
+		body = rest[size+2:]
+	}
+}
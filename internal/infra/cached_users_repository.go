@@ -0,0 +1,150 @@
+package infra
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// UserCacheInvalidator drops a user from the cache once a change of the user
+// is known to be committed.
+type UserCacheInvalidator interface {
+	InvalidateUser(ctx context.Context, uuid string)
+	InvalidateAllUsers(ctx context.Context)
+}
+
+// cachedUserRepository reads users by UUID through cache.
+type cachedUserRepository struct {
+	auth.UsersRepository
+
+	cache         UserCache
+	changes       UserChanges
+	metricsClient decorator.MetricsClient
+	loads         singleflight.Group
+	// generation grows with every invalidation.
+	generation atomic.Uint64
+}
+
+// NewCachedUserRepository returns the cached repository and its invalidator,
+// which the listener of changes calls for the users changed by any replica.
+func NewCachedUserRepository(
+	users auth.UsersRepository,
+	cache UserCache,
+	changes UserChanges,
+	metricsClient decorator.MetricsClient,
+) (auth.UsersRepository, UserCacheInvalidator) {
+	if users == nil {
+		panic("users repository is nil")
+	}
+	if cache == nil {
+		panic("user cache is nil")
+	}
+	if changes == nil {
+		panic("user changes is nil")
+	}
+	if metricsClient == nil {
+		panic("metrics client is nil")
+	}
+
+	r := &cachedUserRepository{
+		UsersRepository: users,
+		cache:           cache,
+		changes:         changes,
+		metricsClient:   metricsClient,
+	}
+	return r, r
+}
+
+// User skips the cache within a unit of work.
+func (r *cachedUserRepository) User(ctx context.Context, uuid string) (*auth.User, error) {
+	if ctx.Value(txContextKey{}) != nil {
+		return r.UsersRepository.User(ctx, uuid)
+	}
+
+	if u, ok := r.cache.User(ctx, uuid); ok {
+		r.metricsClient.Inc("cache.users.hit", 1)
+		return u, nil
+	}
+	r.metricsClient.Inc("cache.users.miss", 1)
+
+	v, err, _ := r.loads.Do(uuid, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		generation := r.generation.Load()
+
+		u, err := r.UsersRepository.User(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.generation.Load() == generation {
+			r.cache.Set(ctx, u)
+		}
+		return u, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u := cloneUser(*v.(*auth.User))
+	return &u, nil
+}
+
+func (r *cachedUserRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.User) error,
+) error {
+	defer r.InvalidateUser(ctx, uuid)
+	return r.UsersRepository.Update(ctx, uuid, func(ctx context.Context, u *auth.User) error {
+		if err := updateFn(ctx, u); err != nil {
+			return err
+		}
+		return r.changes.Announce(ctx, uuid)
+	})
+}
+
+func (r *cachedUserRepository) Delete(ctx context.Context, uuid string) error {
+	defer r.InvalidateUser(ctx, uuid)
+	if err := r.UsersRepository.Delete(ctx, uuid); err != nil {
+		return err
+	}
+	return r.changes.Announce(ctx, uuid)
+}
+
+func (r *cachedUserRepository) InvalidateUser(ctx context.Context, uuid string) {
+	r.generation.Add(1)
+	r.loads.Forget(uuid)
+	r.cache.Delete(ctx, uuid)
+}
+
+func (r *cachedUserRepository) InvalidateAllUsers(ctx context.Context) {
+	r.generation.Add(1)
+	r.cache.Clear(ctx)
+}
+
+// userCacheInvalidatingPublisher invalidates the users the events are about
+// before they are published.
+type userCacheInvalidatingPublisher struct {
+	publisher   command.Publisher
+	invalidator UserCacheInvalidator
+}
+
+func NewUserCacheInvalidatingPublisher(
+	publisher command.Publisher,
+	invalidator UserCacheInvalidator,
+) command.Publisher {
+	return &userCacheInvalidatingPublisher{
+		publisher:   publisher,
+		invalidator: invalidator,
+	}
+}
+
+func (p *userCacheInvalidatingPublisher) Publish(ctx context.Context, m *auth.OutboxMessage) error {
+	p.invalidator.InvalidateUser(ctx, m.AggregateUUID)
+	return p.publisher.Publish(ctx, m)
+}
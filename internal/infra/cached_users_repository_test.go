@@ -0,0 +1,301 @@
+package infra_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth/authtest"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+func TestCachedUsersRepository(t *testing.T) {
+	authtest.TestUsersRepository(t, func(*testing.T) auth.UsersRepository {
+		users, _ := infra.NewCachedUserRepository(
+			mocks.NewMockUserRepository(),
+			infra.NewMemoryUserCache(infra.DefaultUserCacheSize, infra.DefaultUserCacheTTL),
+			infra.NewLocalUserChanges(),
+			&countingMetrics{},
+		)
+		return users
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		authtest.TestUsersRepository(t, func(t *testing.T) auth.UsersRepository {
+			users, _ := infra.NewCachedUserRepository(
+				infra.NewSQLiteUserRepository(newSQLiteDB(t)),
+				infra.NewMemoryUserCache(infra.DefaultUserCacheSize, infra.DefaultUserCacheTTL),
+				infra.NewLocalUserChanges(),
+				&countingMetrics{},
+			)
+			return users
+		})
+	})
+
+	t.Run("should coalesce concurrent misses and report hits", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		inner := &blockingUsers{UsersRepository: mocks.NewMockUserRepository(), release: make(chan struct{})}
+		metrics := &countingMetrics{}
+		users, _ := infra.NewCachedUserRepository(inner, infra.NewMemoryUserCache(10, time.Minute), infra.NewLocalUserChanges(), metrics)
+
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		const lookups = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, lookups)
+		for range lookups {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := users.User(ctx, user.UUID)
+				errs <- err
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(inner.release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		require.EqualValues(t, 1, inner.lookups.Load())
+		require.Equal(t, lookups, metrics.value("cache.users.hit")+metrics.value("cache.users.miss"))
+
+		_, err := users.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, inner.lookups.Load())
+	})
+
+	t.Run("should invalidate user on update and on relayed events", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		inner := mocks.NewMockUserRepository()
+		users, invalidator := infra.NewCachedUserRepository(
+			inner, infra.NewMemoryUserCache(10, time.Minute), infra.NewLocalUserChanges(), &countingMetrics{},
+		)
+		publisher := infra.NewUserCacheInvalidatingPublisher(mocks.NewFakePublisher(), invalidator)
+
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+		_, err := users.User(ctx, user.UUID)
+		require.NoError(t, err)
+
+		err = users.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+			u.GrantRole(auth.RoleAdmin)
+			return nil
+		})
+		require.NoError(t, err)
+
+		cached, err := users.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.True(t, cached.HasRole(auth.RoleAdmin))
+
+		// Another replica changes the user, which only its event tells.
+		var events []auth.Event
+		err = inner.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+			require.NoError(t, u.ChangeStatus(auth.AccountStatus{State: auth.AccountDisabled, ChangedAt: time.Now()}))
+			events = u.PopEvents()
+			return nil
+		})
+		require.NoError(t, err)
+
+		cached, err = users.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Equal(t, auth.AccountActive, cached.Status.State)
+
+		m, err := auth.NewOutboxMessage(events[0])
+		require.NoError(t, err)
+		require.NoError(t, publisher.Publish(ctx, m))
+
+		cached, err = users.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Equal(t, auth.AccountDisabled, cached.Status.State)
+
+		require.NoError(t, users.Delete(ctx, user.UUID))
+		_, err = users.User(ctx, user.UUID)
+		require.ErrorAs(t, err, &auth.UserNotFound{})
+	})
+
+	t.Run("should invalidate user on every replica", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		inner := mocks.NewMockUserRepository()
+		changes := &broadcastUserChanges{}
+		first, firstInvalidator := infra.NewCachedUserRepository(
+			inner, infra.NewMemoryUserCache(10, time.Minute), changes, &countingMetrics{},
+		)
+		second, secondInvalidator := infra.NewCachedUserRepository(
+			inner, infra.NewMemoryUserCache(10, time.Minute), changes, &countingMetrics{},
+		)
+		changes.invalidators = []infra.UserCacheInvalidator{firstInvalidator, secondInvalidator}
+
+		user := fakeUser()
+		require.NoError(t, first.Save(ctx, user))
+		_, err := second.User(ctx, user.UUID)
+		require.NoError(t, err)
+
+		err = first.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+			return u.ChangeStatus(auth.AccountStatus{State: auth.AccountDisabled, ChangedAt: time.Now()})
+		})
+		require.NoError(t, err)
+
+		cached, err := second.User(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Equal(t, auth.AccountDisabled, cached.Status.State)
+
+		require.NoError(t, first.Delete(ctx, user.UUID))
+		_, err = second.User(ctx, user.UUID)
+		require.ErrorAs(t, err, &auth.UserNotFound{})
+	})
+}
+
+func TestPgUserChanges(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	invalidator := &recordingInvalidator{users: make(chan string, 1)}
+	stop, err := infra.ListenPgUserChanges(url, invalidator, slogdiscard.NewDiscardLogger())
+	require.NoError(t, err)
+	t.Cleanup(stop)
+
+	uuid := gofakeit.UUID()
+	require.NoError(t, infra.NewPgUserChanges(db).Announce(context.Background(), uuid))
+
+	select {
+	case got := <-invalidator.users:
+		require.Equal(t, uuid, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("user change was not announced")
+	}
+}
+
+func TestMemoryUserCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should drop the least recently used user", func(t *testing.T) {
+		c := infra.NewMemoryUserCache(2, time.Minute)
+
+		u1, u2, u3 := fakeUser(), fakeUser(), fakeUser()
+		c.Set(ctx, u1)
+		c.Set(ctx, u2)
+		_, ok := c.User(ctx, u1.UUID)
+		require.True(t, ok)
+
+		c.Set(ctx, u3)
+		_, ok = c.User(ctx, u2.UUID)
+		require.False(t, ok)
+		_, ok = c.User(ctx, u1.UUID)
+		require.True(t, ok)
+		_, ok = c.User(ctx, u3.UUID)
+		require.True(t, ok)
+	})
+
+	t.Run("should expire users", func(t *testing.T) {
+		c := infra.NewMemoryUserCache(2, 10*time.Millisecond)
+
+		u := fakeUser()
+		c.Set(ctx, u)
+		_, ok := c.User(ctx, u.UUID)
+		require.True(t, ok)
+
+		time.Sleep(20 * time.Millisecond)
+		_, ok = c.User(ctx, u.UUID)
+		require.False(t, ok)
+	})
+
+	t.Run("should clear users", func(t *testing.T) {
+		c := infra.NewMemoryUserCache(2, time.Minute)
+
+		u := fakeUser()
+		c.Set(ctx, u)
+		c.Clear(ctx)
+		_, ok := c.User(ctx, u.UUID)
+		require.False(t, ok)
+
+		c.Set(ctx, u)
+		_, ok = c.User(ctx, u.UUID)
+		require.True(t, ok)
+	})
+}
+
+// broadcastUserChanges announces the changes to the invalidators of every
+// replica at once.
+type broadcastUserChanges struct {
+	invalidators []infra.UserCacheInvalidator
+}
+
+func (c *broadcastUserChanges) Announce(ctx context.Context, uuid string) error {
+	for _, invalidator := range c.invalidators {
+		invalidator.InvalidateUser(ctx, uuid)
+	}
+	return nil
+}
+
+// blockingUsers holds lookups by UUID until release is closed.
+type blockingUsers struct {
+	auth.UsersRepository
+	release chan struct{}
+	lookups atomic.Int64
+}
+
+func (r *blockingUsers) User(ctx context.Context, uuid string) (*auth.User, error) {
+	r.lookups.Add(1)
+	<-r.release
+	return r.UsersRepository.User(ctx, uuid)
+}
+
+type recordingInvalidator struct {
+	users chan string
+}
+
+func (r *recordingInvalidator) InvalidateUser(_ context.Context, uuid string) {
+	r.users <- uuid
+}
+
+func (r *recordingInvalidator) InvalidateAllUsers(context.Context) {}
+
+type countingMetrics struct {
+	sync.Mutex
+	m map[string]int
+}
+
+func (c *countingMetrics) Inc(key string, value int) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.m == nil {
+		c.m = make(map[string]int)
+	}
+	c.m[key] += value
+}
+
+func (c *countingMetrics) value(key string) int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.m[key]
+}
@@ -0,0 +1,28 @@
+package infra
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// eventEnvelope is the published form of an outbox message. Consumers
+// deduplicate redelivered messages by ID.
+type eventEnvelope struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateUUID string          `json:"aggregateUuid"`
+	OccurredAt    time.Time       `json:"occurredAt"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func encodeEvent(m *auth.OutboxMessage) ([]byte, error) {
+	return json.Marshal(eventEnvelope{
+		ID:            m.ID,
+		Type:          string(m.Type),
+		AggregateUUID: m.AggregateUUID,
+		OccurredAt:    m.OccurredAt.UTC(),
+		Data:          m.Payload,
+	})
+}
@@ -0,0 +1,110 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// fsBlobStorage keeps blobs as files under a directory. It is only suitable
+// for a single replica or a shared volume.
+type fsBlobStorage struct {
+	dir string
+}
+
+func NewFSBlobStorage(dir string) (auth.BlobStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &fsBlobStorage{
+		dir: dir,
+	}, nil
+}
+
+func (s *fsBlobStorage) Put(ctx context.Context, key string, data []byte, _ string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Writing to a temporary file first keeps readers from seeing a partly
+	// written blob.
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (s *fsBlobStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, auth.ErrBlobNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *fsBlobStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *fsBlobStorage) path(key string) (string, error) {
+	if err := validateBlobKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+var blobKeySegmentRe = regexp.MustCompile(`^[A-Za-z0-9_\-.]+$`)
+
+// validateBlobKey rejects keys that could escape the storage root.
+func validateBlobKey(key string) error {
+	for _, segment := range strings.Split(key, "/") {
+		if !blobKeySegmentRe.MatchString(segment) || strings.Trim(segment, ".") == "" {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// httpWebhookSender posts the event in the same envelope as the other
+// publishers, signed in the X-Webhook-Signature header.
+type httpWebhookSender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewHTTPWebhookSender(client *http.Client) command.WebhookSender {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &httpWebhookSender{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (s *httpWebhookSender) Send(
+	ctx context.Context,
+	sub *auth.WebhookSubscription,
+	d *auth.WebhookDelivery,
+) (int, error) {
+	body, err := encodeEvent(&d.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "itsreg-auth-webhooks")
+	req.Header.Set("X-Webhook-ID", d.UUID)
+	req.Header.Set("X-Event-ID", strconv.FormatInt(d.Event.ID, 10))
+	req.Header.Set("X-Event-Type", string(d.Event.Type))
+	req.Header.Set("X-Webhook-Signature", auth.SignWebhook(sub.Secret, s.now(), body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
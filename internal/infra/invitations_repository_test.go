@@ -0,0 +1,122 @@
+package infra_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestPgInvitationsRepository(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	users := infra.NewPgUserRepository(db)
+	invitations := infra.NewPgInvitationsRepository(db)
+	testInvitationsRepository(t, users, invitations)
+}
+
+func TestSQLiteInvitationsRepository(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	users := infra.NewSQLiteUserRepository(db)
+	invitations := infra.NewSQLiteInvitationsRepository(db)
+	testInvitationsRepository(t, users, invitations)
+}
+
+func testInvitationsRepository(t *testing.T, users auth.UsersRepository, r auth.InvitationsRepository) {
+	t.Run("should save invitation and find it by code", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		invitation, code := fakeInvitation(t, 1)
+		require.NoError(t, r.Save(ctx, invitation))
+		require.ErrorIs(t, r.Save(ctx, invitation), auth.ErrInvitationAlreadyExists)
+
+		found, err := r.InvitationByCodeHash(ctx, auth.HashInvitationCode(code))
+		require.NoError(t, err)
+		require.Equal(t, invitation.UUID, found.UUID)
+		require.Equal(t, invitation.Roles, found.Roles)
+		require.Less(t, invitation.ExpiresAt.Sub(found.ExpiresAt).Abs(), time.Microsecond)
+
+		_, err = r.InvitationByCodeHash(ctx, auth.HashInvitationCode(gofakeit.UUID()))
+		require.ErrorIs(t, err, auth.ErrInvitationNotFound)
+	})
+
+	t.Run("should count uses up to the limit", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		invitation, code := fakeInvitation(t, 1)
+		require.NoError(t, r.Save(ctx, invitation))
+
+		use := func(_ context.Context, i *auth.Invitation) error {
+			return i.Use(time.Now())
+		}
+		require.NoError(t, r.Update(ctx, invitation.UUID, use))
+		require.ErrorAs(t, r.Update(ctx, invitation.UUID, use), &auth.RegistrationForbiddenError{})
+
+		found, err := r.InvitationByCodeHash(ctx, auth.HashInvitationCode(code))
+		require.NoError(t, err)
+		require.Equal(t, 1, found.Uses)
+
+		require.ErrorIs(t, r.Update(ctx, gofakeit.UUID(), use), auth.ErrInvitationNotFound)
+	})
+
+	t.Run("should discard changes made within a failed update", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		invitation, code := fakeInvitation(t, 1)
+		require.NoError(t, r.Save(ctx, invitation))
+
+		user := fakeUser()
+		errFailed := errors.New("failed")
+		err := r.Update(ctx, invitation.UUID, func(ctx context.Context, i *auth.Invitation) error {
+			require.NoError(t, i.Use(time.Now()))
+			require.NoError(t, users.Save(ctx, user))
+			return errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+
+		_, err = users.User(ctx, user.UUID)
+		require.ErrorAs(t, err, &auth.UserNotFound{})
+
+		found, err := r.InvitationByCodeHash(ctx, auth.HashInvitationCode(code))
+		require.NoError(t, err)
+		require.Zero(t, found.Uses)
+	})
+}
+
+func fakeInvitation(t *testing.T, maxUses int) (*auth.Invitation, string) {
+	code, err := auth.NewInvitationCode()
+	require.NoError(t, err)
+
+	invitation, err := auth.NewInvitation(
+		gofakeit.UUID(),
+		code,
+		gofakeit.UUID(),
+		[]auth.Role{auth.RoleAdmin},
+		maxUses,
+		time.Now().Add(time.Hour),
+	)
+	require.NoError(t, err)
+
+	return invitation, code
+}
@@ -0,0 +1,24 @@
+package infra
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+)
+
+// logMailer writes mails to the log instead of delivering them.
+type logMailer struct {
+	logger *slog.Logger
+}
+
+func NewLogMailer(logger *slog.Logger) command.Mailer {
+	return &logMailer{
+		logger: logger,
+	}
+}
+
+func (m *logMailer) SendAccountExists(ctx context.Context, email string) error {
+	m.logger.InfoContext(ctx, "Mail: account already exists", "to", email)
+	return nil
+}
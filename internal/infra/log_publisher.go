@@ -0,0 +1,28 @@
+package infra
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// logPublisher writes events to the log instead of delivering them.
+type logPublisher struct {
+	logger *slog.Logger
+}
+
+func NewLogPublisher(logger *slog.Logger) command.Publisher {
+	return &logPublisher{
+		logger: logger,
+	}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, m *auth.OutboxMessage) error {
+	p.logger.InfoContext(
+		ctx, "Event",
+		"id", m.ID, "type", string(m.Type), "aggregate_uuid", m.AggregateUUID, "data", string(m.Payload),
+	)
+	return nil
+}
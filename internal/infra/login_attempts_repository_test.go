@@ -0,0 +1,141 @@
+package infra_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestMemoryLoginAttemptsRepository(t *testing.T) {
+	testLoginAttemptsRepository(t, infra.NewMemoryLoginAttemptsRepository())
+}
+
+func TestPgLoginAttemptsRepository(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	testLoginAttemptsRepository(t, infra.NewPgLoginAttemptsRepository(db))
+}
+
+func testLoginAttemptsRepository(t *testing.T, r auth.LoginAttemptsRepository) {
+	t.Parallel()
+
+	t.Run("should return empty attempts for unknown key", func(t *testing.T) {
+		t.Parallel()
+
+		key := auth.LoginAttemptsKeyForEmail(auth.MustNewEmail(gofakeit.Email()))
+		a, err := r.LoginAttempts(context.Background(), key)
+		require.NoError(t, err)
+		require.Equal(t, key, a.Key)
+		require.Zero(t, a.Failures)
+		require.False(t, a.IsLocked(time.Now()))
+	})
+
+	t.Run("should register failures", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		key := auth.LoginAttemptsKeyForIP(gofakeit.IPv4Address())
+		policy := auth.LockoutPolicy{Threshold: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+
+		for range 2 {
+			err := r.Update(ctx, key, func(_ context.Context, a *auth.LoginAttempts) error {
+				policy.RegisterFailure(a, time.Now())
+				return nil
+			})
+			require.NoError(t, err)
+		}
+
+		a, err := r.LoginAttempts(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, 2, a.Failures)
+		require.True(t, a.IsLocked(time.Now()))
+	})
+
+	t.Run("should not lose concurrent failures", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		key := auth.LoginAttemptsKeyForEmail(auth.MustNewEmail(gofakeit.Email()))
+
+		const n = 10
+		var wg sync.WaitGroup
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := r.Update(ctx, key, func(_ context.Context, a *auth.LoginAttempts) error {
+					auth.DefaultAccountLockoutPolicy.RegisterFailure(a, time.Now())
+					return nil
+				})
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		a, err := r.LoginAttempts(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, n, a.Failures)
+	})
+
+	t.Run("should forget expired attempts", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		key := auth.LoginAttemptsKeyForIP(gofakeit.IPv4Address())
+		policy := auth.LockoutPolicy{Threshold: 1, BaseDelay: time.Minute, MaxDelay: time.Minute, Window: time.Hour}
+
+		// The failure was registered long enough ago to be expired by now.
+		err := r.Update(ctx, key, func(_ context.Context, a *auth.LoginAttempts) error {
+			policy.RegisterFailure(a, time.Now().Add(-2*time.Hour))
+			return nil
+		})
+		require.NoError(t, err)
+
+		a, err := r.LoginAttempts(ctx, key)
+		require.NoError(t, err)
+		require.Zero(t, a.Failures)
+
+		err = r.Update(ctx, key, func(_ context.Context, a *auth.LoginAttempts) error {
+			require.Zero(t, a.Failures)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("should reset attempts", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		key := auth.LoginAttemptsKeyForEmail(auth.MustNewEmail(gofakeit.Email()))
+
+		err := r.Update(ctx, key, func(_ context.Context, a *auth.LoginAttempts) error {
+			auth.DefaultAccountLockoutPolicy.RegisterFailure(a, time.Now())
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, r.Delete(ctx, key))
+
+		a, err := r.LoginAttempts(ctx, key)
+		require.NoError(t, err)
+		require.Zero(t, a.Failures)
+	})
+}
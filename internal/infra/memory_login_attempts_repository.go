@@ -0,0 +1,74 @@
+package infra
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// memoryLoginAttemptsRepository keeps counters in process memory. It is only
+// suitable for a single replica.
+type memoryLoginAttemptsRepository struct {
+	sync.RWMutex
+	m map[string]auth.LoginAttempts
+}
+
+func NewMemoryLoginAttemptsRepository() auth.LoginAttemptsRepository {
+	return &memoryLoginAttemptsRepository{
+		m: make(map[string]auth.LoginAttempts),
+	}
+}
+
+func (r *memoryLoginAttemptsRepository) LoginAttempts(ctx context.Context, key string) (*auth.LoginAttempts, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	a, ok := r.m[key]
+	if !ok || a.IsExpired(time.Now()) {
+		return &auth.LoginAttempts{Key: key}, nil
+	}
+
+	return &a, nil
+}
+
+// Update forgets the expired attempts first.
+func (r *memoryLoginAttemptsRepository) Update(
+	ctx context.Context,
+	key string,
+	updateFn func(ctx context.Context, a *auth.LoginAttempts) error,
+) error {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	for k, a := range r.m {
+		if a.IsExpired(now) {
+			delete(r.m, k)
+		}
+	}
+
+	a, ok := r.m[key]
+	if !ok {
+		a = auth.LoginAttempts{Key: key}
+	}
+
+	err := updateFn(ctx, &a)
+	if err != nil {
+		return err
+	}
+
+	r.m[key] = a
+
+	return nil
+}
+
+func (r *memoryLoginAttemptsRepository) Delete(ctx context.Context, key string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.m, key)
+
+	return nil
+}
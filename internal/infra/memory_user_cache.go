@@ -0,0 +1,121 @@
+package infra
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// UserCache keeps users by UUID for a while.
+type UserCache interface {
+	User(ctx context.Context, uuid string) (*auth.User, bool)
+	Set(ctx context.Context, u *auth.User)
+	Delete(ctx context.Context, uuid string)
+	Clear(ctx context.Context)
+}
+
+const (
+	DefaultUserCacheSize = 10000
+	DefaultUserCacheTTL  = 30 * time.Second
+)
+
+// memoryUserCache drops the least recently used user once it holds size
+// users, and every user ttl after it was set.
+type memoryUserCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List
+	m     map[string]*list.Element
+}
+
+type memoryUserCacheEntry struct {
+	user      auth.User
+	expiresAt time.Time
+}
+
+func NewMemoryUserCache(size int, ttl time.Duration) UserCache {
+	if size <= 0 {
+		panic("user cache size must be positive")
+	}
+
+	return &memoryUserCache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		m:     make(map[string]*list.Element),
+	}
+}
+
+func (c *memoryUserCache) User(_ context.Context, uuid string) (*auth.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.m[uuid]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*memoryUserCacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.m, uuid)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	u := cloneUser(entry.user)
+	return &u, true
+}
+
+func (c *memoryUserCache) Set(_ context.Context, u *auth.User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryUserCacheEntry{
+		user:      cloneUser(*u),
+		expiresAt: time.Now().Add(c.ttl),
+	}
+
+	if el, ok := c.m[u.UUID]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.m[u.UUID] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.m, oldest.Value.(*memoryUserCacheEntry).user.UUID)
+	}
+}
+
+func (c *memoryUserCache) Delete(_ context.Context, uuid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.m[uuid]; ok {
+		c.order.Remove(el)
+		delete(c.m, uuid)
+	}
+}
+
+func (c *memoryUserCache) Clear(_ context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.m)
+}
+
+// cloneUser copies the slices of u.
+func cloneUser(u auth.User) auth.User {
+	u.Passhash = slices.Clone(u.Passhash)
+	u.Roles = slices.Clone(u.Roles)
+	return u
+}
@@ -0,0 +1,73 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+const defaultNATSTimeout = 5 * time.Second
+
+// NATSConfig publishes events to SubjectPrefix followed by the event type,
+// for example itsreg.auth.user.registered.
+type NATSConfig struct {
+	URL           string
+	SubjectPrefix string
+	// Timeout bounds publishing of a message.
+	Timeout time.Duration
+}
+
+// natsPublisher flushes after every message.
+type natsPublisher struct {
+	conn    *nats.Conn
+	prefix  string
+	timeout time.Duration
+}
+
+func NewNATSPublisher(cfg NATSConfig) (command.Publisher, error) {
+	if cfg.SubjectPrefix == "" {
+		return nil, errors.New("expected not empty NATS subject prefix")
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultNATSTimeout
+	}
+
+	conn, err := nats.Connect(
+		cfg.URL,
+		nats.Name("itsreg-auth"),
+		nats.Timeout(cfg.Timeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsPublisher{
+		conn:    conn,
+		prefix:  cfg.SubjectPrefix,
+		timeout: cfg.Timeout,
+	}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, m *auth.OutboxMessage) error {
+	payload, err := encodeEvent(m)
+	if err != nil {
+		return err
+	}
+
+	if err = p.conn.Publish(p.prefix+"."+string(m.Type), payload); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return p.conn.FlushWithContext(ctx)
+}
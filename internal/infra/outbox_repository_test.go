@@ -0,0 +1,131 @@
+package infra_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestPgOutboxRepository(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	users := infra.NewPgUserRepository(db)
+	outbox := infra.NewPgOutboxRepository(db)
+	testOutboxRepository(t, users, outbox)
+}
+
+func TestSQLiteOutboxRepository(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	users := infra.NewSQLiteUserRepository(db)
+	outbox := infra.NewSQLiteOutboxRepository(db)
+	testOutboxRepository(t, users, outbox)
+}
+
+// testOutboxRepository checks that users stores its events to outbox.
+func testOutboxRepository(t *testing.T, users auth.UsersRepository, outbox auth.OutboxRepository) {
+	t.Run("should store events with user changes and relay them in order", func(t *testing.T) {
+		ctx := context.Background()
+
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		duplicate := fakeUser()
+		duplicate.UUID = user.UUID
+		require.ErrorIs(t, users.Save(ctx, duplicate), auth.ErrUserAlreadyExists)
+
+		err := users.Update(ctx, user.UUID, func(_ context.Context, u *auth.User) error {
+			u.GrantRole(auth.RoleAdmin)
+			return u.ChangeEmail(gofakeit.Email())
+		})
+		require.NoError(t, err)
+		require.NoError(t, users.Delete(ctx, user.UUID))
+
+		// Other tests store events concurrently, so only the events of this
+		// user are published.
+		relayUser := func(ctx context.Context, messages []*auth.OutboxMessage) []int64 {
+			var published []int64
+			for _, m := range messages {
+				if m.AggregateUUID == user.UUID {
+					published = append(published, m.ID)
+				}
+			}
+			return published
+		}
+
+		var types []auth.EventType
+		n, err := outbox.RelayOutbox(ctx, 1000, func(ctx context.Context, messages []*auth.OutboxMessage) []int64 {
+			for _, m := range messages {
+				if m.AggregateUUID == user.UUID {
+					types = append(types, m.Type)
+				}
+			}
+			return relayUser(ctx, messages)
+		})
+		require.NoError(t, err)
+		require.Equal(t, 4, n)
+		require.Equal(t, []auth.EventType{
+			auth.EventUserRegistered,
+			auth.EventUserRoleGranted,
+			auth.EventUserEmailChanged,
+			auth.EventUserDeleted,
+		}, types)
+
+		n, err = outbox.RelayOutbox(ctx, 1000, relayUser)
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("should let one relay run at a time", func(t *testing.T) {
+		ctx := context.Background()
+
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		var nested int
+		_, err := outbox.RelayOutbox(ctx, 1000, func(ctx context.Context, messages []*auth.OutboxMessage) []int64 {
+			var err error
+			nested, err = outbox.RelayOutbox(ctx, 1000, func(context.Context, []*auth.OutboxMessage) []int64 {
+				t.Error("relayed concurrently")
+				return nil
+			})
+			require.NoError(t, err)
+
+			var published []int64
+			for _, m := range messages {
+				if m.AggregateUUID == user.UUID {
+					published = append(published, m.ID)
+				}
+			}
+			return published
+		})
+		require.NoError(t, err)
+		require.Zero(t, nested)
+
+		// The lease is released, so the next relay runs.
+		require.NoError(t, users.Save(ctx, fakeUser()))
+		relayed := false
+		_, err = outbox.RelayOutbox(ctx, 1000, func(context.Context, []*auth.OutboxMessage) []int64 {
+			relayed = true
+			return nil
+		})
+		require.NoError(t, err)
+		require.True(t, relayed)
+	})
+}
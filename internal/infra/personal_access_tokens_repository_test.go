@@ -0,0 +1,150 @@
+package infra_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestPgPersonalAccessTokensRepository(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	users := infra.NewPgUserRepository(db)
+	tokens := infra.NewPgPersonalAccessTokensRepository(db)
+	testPersonalAccessTokensRepository(t, users, tokens)
+}
+
+func TestSQLitePersonalAccessTokensRepository(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	users := infra.NewSQLiteUserRepository(db)
+	tokens := infra.NewSQLitePersonalAccessTokensRepository(db)
+	testPersonalAccessTokensRepository(t, users, tokens)
+}
+
+func testPersonalAccessTokensRepository(
+	t *testing.T,
+	users auth.UsersRepository,
+	r auth.PersonalAccessTokensRepository,
+) {
+	t.Parallel()
+
+	t.Run("should save token and find it by hash", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		token, secret := fakePersonalAccessToken(t, user.UUID)
+		err := r.Save(ctx, token)
+		require.NoError(t, err)
+
+		found, err := r.PersonalAccessTokenByHash(ctx, auth.HashPersonalAccessToken(secret))
+		require.NoError(t, err)
+		require.Equal(t, token.UUID, found.UUID)
+		require.Equal(t, token.Scopes, found.Scopes)
+		require.Less(t, token.ExpiresAt.Sub(found.ExpiresAt).Abs(), time.Microsecond)
+	})
+
+	t.Run("should return error if token hash not found", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		_, err := r.PersonalAccessTokenByHash(ctx, auth.HashPersonalAccessToken(gofakeit.UUID()))
+		require.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("should list user tokens", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		for range 3 {
+			token, _ := fakePersonalAccessToken(t, user.UUID)
+			require.NoError(t, r.Save(ctx, token))
+		}
+
+		listed, err := r.UserPersonalAccessTokens(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Len(t, listed, 3)
+	})
+
+	t.Run("should record token usage", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		token, _ := fakePersonalAccessToken(t, user.UUID)
+		require.NoError(t, r.Save(ctx, token))
+
+		ip := gofakeit.IPv4Address()
+		err := r.Update(ctx, token.UUID, func(ctx context.Context, t *auth.PersonalAccessToken) error {
+			t.MarkUsed(ip, time.Now())
+			return nil
+		})
+		require.NoError(t, err)
+
+		updated, err := r.PersonalAccessToken(ctx, token.UUID)
+		require.NoError(t, err)
+		require.Equal(t, ip, updated.LastUsedIP)
+		require.False(t, updated.LastUsedAt.IsZero())
+	})
+
+	t.Run("should delete token", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		token, _ := fakePersonalAccessToken(t, user.UUID)
+		require.NoError(t, r.Save(ctx, token))
+
+		err := r.Delete(ctx, token.UUID)
+		require.NoError(t, err)
+
+		_, err = r.PersonalAccessToken(ctx, token.UUID)
+		require.EqualError(t, err, fmt.Sprintf("personal access token with UUID %s not found", token.UUID))
+	})
+}
+
+func fakePersonalAccessToken(t *testing.T, userUUID string) (*auth.PersonalAccessToken, string) {
+	secret, err := auth.NewPersonalAccessTokenSecret()
+	require.NoError(t, err)
+
+	token, err := auth.NewPersonalAccessToken(
+		gofakeit.UUID(),
+		userUUID,
+		gofakeit.Word(),
+		secret,
+		[]auth.Scope{auth.ScopeRead},
+		time.Now().Add(time.Hour),
+	)
+	require.NoError(t, err)
+
+	return token, secret
+}
@@ -0,0 +1,294 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// auditLogLockClass is the first key of the advisory locks serializing appends
+// to a chain.
+const auditLogLockClass = 0x61756469
+
+type pgAuditLogRepository struct {
+	db *sqlx.DB
+}
+
+func NewPgAuditLogRepository(db *sqlx.DB) auth.AuditLogRepository {
+	return &pgAuditLogRepository{
+		db: db,
+	}
+}
+
+func (r *pgAuditLogRepository) Append(ctx context.Context, e *auth.AuditEvent) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		chain := e.ChainKey()
+		_, err := pgutils.Exec(ctx, tx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, auditLogLockClass, chain)
+		if err != nil {
+			return err
+		}
+
+		var last auditEventRow
+		err = pgutils.Get(
+			ctx, tx, &last,
+			`SELECT
+				seq, hash
+			 FROM
+				audit_log
+			 WHERE
+				chain = $1
+			 ORDER BY
+				seq DESC
+			 LIMIT 1`,
+			chain,
+		)
+		var prev *auth.AuditEvent
+		if err == nil {
+			prev = &auth.AuditEvent{Seq: last.Seq, Hash: last.Hash}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		var seq int64
+		if err = pgutils.Get(ctx, tx, &seq, `SELECT nextval('audit_log_seq')`); err != nil {
+			return err
+		}
+		e.Chain(seq, prev)
+
+		row, err := mapAuditEventToRow(e)
+		if err != nil {
+			return err
+		}
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`INSERT INTO
+				audit_log (
+					seq, chain, action, actor_uuid, target_uuid, ip, user_agent, request_id, details,
+					created_at, prev_hash, hash
+				)
+			 VALUES
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			row.Seq, chain, row.Action, row.ActorUUID, row.TargetUUID, row.IP, row.UserAgent, row.RequestID,
+			row.Details, row.CreatedAt, row.PrevHash, row.Hash,
+		)
+		return err
+	})
+}
+
+func (r *pgAuditLogRepository) ListAuditEvents(
+	ctx context.Context,
+	l auth.ListAuditEvents,
+) (auth.AuditEventsPage, error) {
+	beforeSeq, err := l.BeforeSeq()
+	if err != nil {
+		return auth.AuditEventsPage{}, err
+	}
+
+	where, args := auditFilterToSQL(l.Filter)
+	if beforeSeq > 0 {
+		args = append(args, beforeSeq)
+		where += fmt.Sprintf(` AND seq < $%d`, len(args))
+	}
+
+	args = append(args, l.Limit+1)
+	var rows []auditEventRow
+	err = pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		fmt.Sprintf(
+			`SELECT
+				seq, action, actor_uuid, target_uuid, ip, user_agent, request_id, details,
+				created_at, prev_hash, hash
+			 FROM
+				audit_log
+			 WHERE
+				%s
+			 ORDER BY
+				seq DESC
+			 LIMIT $%d`,
+			where, len(args),
+		),
+		args...,
+	)
+	if err != nil {
+		return auth.AuditEventsPage{}, err
+	}
+
+	events, err := mapAuditEventsFromRows(rows)
+	if err != nil {
+		return auth.AuditEventsPage{}, err
+	}
+
+	page := auth.AuditEventsPage{Events: events}
+	if len(page.Events) > l.Limit {
+		page.Events = page.Events[:l.Limit]
+		page.NextCursor = l.NextCursor(page.Events[len(page.Events)-1])
+	}
+
+	return page, nil
+}
+
+// auditFilterToSQL returns the condition with numbered placeholders and
+// their arguments.
+func auditFilterToSQL(f auth.AuditFilter) (string, []any) {
+	conds := []string{"TRUE"}
+	var args []any
+
+	if f.ActorUUID != "" {
+		args = append(args, f.ActorUUID)
+		conds = append(conds, fmt.Sprintf(`actor_uuid = $%d`, len(args)))
+	}
+
+	if f.TargetUUID != "" {
+		args = append(args, f.TargetUUID)
+		conds = append(conds, fmt.Sprintf(`target_uuid = $%d`, len(args)))
+	}
+
+	if f.Action != "" {
+		args = append(args, string(f.Action))
+		conds = append(conds, fmt.Sprintf(`action = $%d`, len(args)))
+	}
+
+	if !f.From.IsZero() {
+		args = append(args, f.From.UTC())
+		conds = append(conds, fmt.Sprintf(`created_at >= $%d`, len(args)))
+	}
+
+	if !f.To.IsZero() {
+		args = append(args, f.To.UTC())
+		conds = append(conds, fmt.Sprintf(`created_at < $%d`, len(args)))
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+func (r *pgAuditLogRepository) AuditChainKeys(ctx context.Context, after string, limit int) ([]string, error) {
+	var keys []string
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &keys,
+		`SELECT DISTINCT
+			chain
+		 FROM
+			audit_log
+		 WHERE
+			chain > $1
+		 ORDER BY
+			chain
+		 LIMIT $2`,
+		after, limit,
+	)
+	return keys, err
+}
+
+func (r *pgAuditLogRepository) AuditChainEvents(
+	ctx context.Context,
+	chain string,
+	seq int64,
+	limit int,
+) ([]*auth.AuditEvent, error) {
+	var rows []auditEventRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			seq, action, actor_uuid, target_uuid, ip, user_agent, request_id, details,
+			created_at, prev_hash, hash
+		 FROM
+			audit_log
+		 WHERE
+			chain = $1 AND seq > $2
+		 ORDER BY
+			seq
+		 LIMIT $3`,
+		chain, seq, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapAuditEventsFromRows(rows)
+}
+
+type auditEventRow struct {
+	Seq        int64     `db:"seq"`
+	Action     string    `db:"action"`
+	ActorUUID  string    `db:"actor_uuid"`
+	TargetUUID string    `db:"target_uuid"`
+	IP         string    `db:"ip"`
+	UserAgent  string    `db:"user_agent"`
+	RequestID  string    `db:"request_id"`
+	Details    []byte    `db:"details"`
+	CreatedAt  time.Time `db:"created_at"`
+	PrevHash   []byte    `db:"prev_hash"`
+	Hash       []byte    `db:"hash"`
+}
+
+func mapAuditEventsFromRows(rows []auditEventRow) ([]*auth.AuditEvent, error) {
+	events := make([]*auth.AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		e, err := mapAuditEventFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func mapAuditEventFromRow(row auditEventRow) (*auth.AuditEvent, error) {
+	var details map[string]string
+	if err := json.Unmarshal(row.Details, &details); err != nil {
+		return nil, err
+	}
+	if len(details) == 0 {
+		details = nil
+	}
+
+	return &auth.AuditEvent{
+		Seq:        row.Seq,
+		Action:     auth.AuditAction(row.Action),
+		ActorUUID:  row.ActorUUID,
+		TargetUUID: row.TargetUUID,
+		IP:         row.IP,
+		UserAgent:  row.UserAgent,
+		RequestID:  row.RequestID,
+		Details:    details,
+		CreatedAt:  row.CreatedAt.Local(),
+		PrevHash:   row.PrevHash,
+		Hash:       row.Hash,
+	}, nil
+}
+
+func mapAuditEventToRow(e *auth.AuditEvent) (auditEventRow, error) {
+	details := e.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	b, err := json.Marshal(details)
+	if err != nil {
+		return auditEventRow{}, err
+	}
+
+	return auditEventRow{
+		Seq:        e.Seq,
+		Action:     string(e.Action),
+		ActorUUID:  e.ActorUUID,
+		TargetUUID: e.TargetUUID,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Details:    b,
+		CreatedAt:  e.CreatedAt.UTC(),
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}, nil
+}
@@ -0,0 +1,172 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type pgInvitationsRepository struct {
+	db *sqlx.DB
+}
+
+func NewPgInvitationsRepository(db *sqlx.DB) auth.InvitationsRepository {
+	return &pgInvitationsRepository{
+		db: db,
+	}
+}
+
+func (r *pgInvitationsRepository) Save(ctx context.Context, i *auth.Invitation) error {
+	row := mapInvitationToRow(i)
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`INSERT INTO
+			invitations (uuid, code_hash, created_by, roles, max_uses, uses, expires_at, created_at)
+		 VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8)`,
+		row.UUID, row.CodeHash, row.CreatedBy, row.Roles, row.MaxUses, row.Uses, row.ExpiresAt, row.CreatedAt,
+	)
+	if pgutils.IsUniqueViolationError(err) {
+		return auth.ErrInvitationAlreadyExists
+	} else if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return errors.New("no affected rows")
+	}
+
+	return nil
+}
+
+func (r *pgInvitationsRepository) InvitationByCodeHash(ctx context.Context, hash []byte) (*auth.Invitation, error) {
+	var row invitationRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, code_hash, created_by, roles, max_uses, uses, expires_at, created_at
+		 FROM
+			invitations
+		 WHERE
+			code_hash = $1`,
+		hash,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrInvitationNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapInvitationFromRow(row)
+}
+
+func (r *pgInvitationsRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.Invitation) error,
+) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row invitationRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				uuid, code_hash, created_by, roles, max_uses, uses, expires_at, created_at
+			 FROM
+				invitations
+			 WHERE
+				uuid = $1
+			 FOR UPDATE`,
+			uuid,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.ErrInvitationNotFound
+		} else if err != nil {
+			return err
+		}
+
+		i, err := mapInvitationFromRow(row)
+		if err != nil {
+			return err
+		}
+
+		err = updateFn(withTx(ctx, tx), i)
+		if err != nil {
+			return err
+		}
+
+		row = mapInvitationToRow(i)
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				invitations
+			 SET
+				roles = $2,
+				max_uses = $3,
+				uses = $4,
+				expires_at = $5
+			 WHERE
+				uuid = $1`,
+			row.UUID, row.Roles, row.MaxUses, row.Uses, row.ExpiresAt,
+		)
+		return err
+	})
+}
+
+type invitationRow struct {
+	UUID      string         `db:"uuid"`
+	CodeHash  []byte         `db:"code_hash"`
+	CreatedBy string         `db:"created_by"`
+	Roles     pq.StringArray `db:"roles"`
+	MaxUses   int            `db:"max_uses"`
+	Uses      int            `db:"uses"`
+	ExpiresAt sql.NullTime   `db:"expires_at"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func mapInvitationFromRow(row invitationRow) (*auth.Invitation, error) {
+	roles := make([]auth.Role, len(row.Roles))
+	for i, r := range row.Roles {
+		roles[i] = auth.Role(r)
+	}
+
+	return auth.NewInvitationFromDB(
+		row.UUID,
+		row.CodeHash,
+		row.CreatedBy,
+		roles,
+		row.MaxUses,
+		row.Uses,
+		nullTimeToLocal(row.ExpiresAt),
+		row.CreatedAt.Local(),
+	)
+}
+
+func mapInvitationToRow(i *auth.Invitation) invitationRow {
+	roles := make(pq.StringArray, len(i.Roles))
+	for j, r := range i.Roles {
+		roles[j] = string(r)
+	}
+
+	return invitationRow{
+		UUID:      i.UUID,
+		CodeHash:  i.CodeHash,
+		CreatedBy: i.CreatedBy,
+		Roles:     roles,
+		MaxUses:   i.MaxUses,
+		Uses:      i.Uses,
+		ExpiresAt: timeToNullUTC(i.ExpiresAt),
+		CreatedAt: i.CreatedAt.UTC(),
+	}
+}
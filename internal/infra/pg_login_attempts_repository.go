@@ -0,0 +1,145 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type pgLoginAttemptsRepository struct {
+	db *sqlx.DB
+}
+
+func NewPgLoginAttemptsRepository(db *sqlx.DB) auth.LoginAttemptsRepository {
+	return &pgLoginAttemptsRepository{
+		db: db,
+	}
+}
+
+func (r *pgLoginAttemptsRepository) LoginAttempts(ctx context.Context, key string) (*auth.LoginAttempts, error) {
+	var row loginAttemptsRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			key, failures, last_failure_at, locked_until, expires_at
+		 FROM
+			login_attempts
+		 WHERE
+			key = $1 AND expires_at > $2`,
+		key, time.Now().UTC(),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &auth.LoginAttempts{Key: key}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapLoginAttemptsFromRow(row), nil
+}
+
+// Update locks the counter row. It removes the expired rows first.
+func (r *pgLoginAttemptsRepository) Update(
+	ctx context.Context,
+	key string,
+	updateFn func(context.Context, *auth.LoginAttempts) error,
+) error {
+	now := time.Now().UTC()
+	_, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			login_attempts
+		 WHERE
+			expires_at <= $1`,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row loginAttemptsRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`INSERT INTO
+				login_attempts (key, failures, last_failure_at, locked_until, expires_at)
+			 VALUES
+				($1, 0, $2, $2, $3)
+			 ON CONFLICT (key) DO UPDATE SET
+				key = EXCLUDED.key
+			 RETURNING
+				key, failures, last_failure_at, locked_until, expires_at`,
+			key, time.Time{}, now,
+		)
+		if err != nil {
+			return err
+		}
+
+		a := mapLoginAttemptsFromRow(row)
+		err = updateFn(withTx(ctx, tx), a)
+		if err != nil {
+			return err
+		}
+
+		row = mapLoginAttemptsToRow(a)
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				login_attempts
+			 SET
+				failures = $2,
+				last_failure_at = $3,
+				locked_until = $4,
+				expires_at = $5
+			 WHERE
+				key = $1`,
+			row.Key, row.Failures, row.LastFailureAt, row.LockedUntil, row.ExpiresAt,
+		)
+		return err
+	})
+}
+
+func (r *pgLoginAttemptsRepository) Delete(ctx context.Context, key string) error {
+	_, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			login_attempts
+		 WHERE
+			key = $1`,
+		key,
+	)
+	return err
+}
+
+type loginAttemptsRow struct {
+	Key           string    `db:"key"`
+	Failures      int       `db:"failures"`
+	LastFailureAt time.Time `db:"last_failure_at"`
+	LockedUntil   time.Time `db:"locked_until"`
+	ExpiresAt     time.Time `db:"expires_at"`
+}
+
+func mapLoginAttemptsFromRow(row loginAttemptsRow) *auth.LoginAttempts {
+	return &auth.LoginAttempts{
+		Key:           row.Key,
+		Failures:      row.Failures,
+		LastFailureAt: row.LastFailureAt.Local(),
+		LockedUntil:   row.LockedUntil.Local(),
+		ExpiresAt:     row.ExpiresAt.Local(),
+	}
+}
+
+func mapLoginAttemptsToRow(a *auth.LoginAttempts) loginAttemptsRow {
+	return loginAttemptsRow{
+		Key:           a.Key,
+		Failures:      a.Failures,
+		LastFailureAt: a.LastFailureAt.UTC(),
+		LockedUntil:   a.LockedUntil.UTC(),
+		ExpiresAt:     a.ExpiresAt.UTC(),
+	}
+}
@@ -0,0 +1,171 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+const (
+	// outboxRelayTimeout bounds the publishing of a batch.
+	outboxRelayTimeout = 5 * time.Minute
+	outboxRelayLease   = 2 * outboxRelayTimeout
+)
+
+type pgOutboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewPgOutboxRepository(db *sqlx.DB) auth.OutboxRepository {
+	return &pgOutboxRepository{
+		db: db,
+	}
+}
+
+// RelayOutbox takes the relay lease and publishes outside of a transaction,
+// so no transaction is open while relayFn waits for the network.
+func (r *pgOutboxRepository) RelayOutbox(ctx context.Context, limit int, relayFn auth.RelayFn) (_ int, err error) {
+	holder := uuid.NewString()
+	now := time.Now().UTC()
+
+	res, err := pgutils.Exec(
+		ctx, r.db,
+		`INSERT INTO
+			outbox_relay (id, holder, leased_until)
+		 VALUES
+			(1, $1, $2)
+		 ON CONFLICT (id) DO UPDATE SET
+			holder = EXCLUDED.holder,
+			leased_until = EXCLUDED.leased_until
+		 WHERE
+			outbox_relay.leased_until <= $3`,
+		holder, now.Add(outboxRelayLease), now,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if aff == 0 {
+		return 0, nil
+	}
+
+	defer func() {
+		_, releaseErr := pgutils.Exec(
+			context.WithoutCancel(ctx), r.db,
+			`DELETE FROM outbox_relay WHERE holder = $1`,
+			holder,
+		)
+		err = errors.Join(err, releaseErr)
+	}()
+
+	var rows []outboxMessageRow
+	err = pgutils.Select(
+		ctx, r.db, &rows,
+		`SELECT
+			id, type, aggregate_uuid, payload, occurred_at
+		 FROM
+			outbox
+		 ORDER BY
+			id
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	messages := make([]*auth.OutboxMessage, len(rows))
+	for i, row := range rows {
+		messages[i] = mapOutboxMessageFromRow(row)
+	}
+
+	relayCtx, cancel := context.WithTimeout(ctx, outboxRelayTimeout)
+	defer cancel()
+
+	ids := relayFn(relayCtx, messages)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	_, err = pgutils.Exec(
+		ctx, r.db,
+		`DELETE FROM
+			outbox
+		 WHERE
+			id = ANY($1)`,
+		pq.Int64Array(ids),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+// saveEvents stores the events in the outbox within the transaction tx, which
+// also changes the user.
+func saveEvents(ctx context.Context, tx *sqlx.Tx, events []auth.Event) error {
+	for _, e := range events {
+		m, err := auth.NewOutboxMessage(e)
+		if err != nil {
+			return err
+		}
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`SELECT pg_advisory_xact_lock(hashtext('outbox:' || $1))`,
+			m.AggregateUUID,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`INSERT INTO
+				outbox (type, aggregate_uuid, payload, occurred_at)
+			 VALUES
+				($1, $2, $3, $4)`,
+			string(m.Type), m.AggregateUUID, []byte(m.Payload), m.OccurredAt.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type outboxMessageRow struct {
+	ID            int64     `db:"id"`
+	Type          string    `db:"type"`
+	AggregateUUID string    `db:"aggregate_uuid"`
+	Payload       []byte    `db:"payload"`
+	OccurredAt    time.Time `db:"occurred_at"`
+}
+
+func mapOutboxMessageFromRow(row outboxMessageRow) *auth.OutboxMessage {
+	return &auth.OutboxMessage{
+		ID:            row.ID,
+		Type:          auth.EventType(row.Type),
+		AggregateUUID: row.AggregateUUID,
+		Payload:       row.Payload,
+		OccurredAt:    row.OccurredAt.Local(),
+	}
+}
@@ -0,0 +1,269 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type pgPersonalAccessTokensRepository struct {
+	db *sqlx.DB
+}
+
+func NewPgPersonalAccessTokensRepository(db *sqlx.DB) auth.PersonalAccessTokensRepository {
+	return &pgPersonalAccessTokensRepository{
+		db: db,
+	}
+}
+
+func (r *pgPersonalAccessTokensRepository) Save(ctx context.Context, t *auth.PersonalAccessToken) error {
+	row := mapPersonalAccessTokenToRow(t)
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`INSERT INTO
+			personal_access_tokens (uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at)
+		 VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		row.UUID, row.UserUUID, row.Name, row.Scopes, row.TokenHash,
+		row.ExpiresAt, row.LastUsedAt, row.LastUsedIP, row.CreatedAt,
+	)
+	if pgutils.IsUniqueViolationError(err) {
+		return auth.ErrPersonalAccessTokenAlreadyExists
+	} else if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return errors.New("no affected rows")
+	}
+
+	return nil
+}
+
+func (r *pgPersonalAccessTokensRepository) PersonalAccessToken(
+	ctx context.Context,
+	uuid string,
+) (*auth.PersonalAccessToken, error) {
+	var row personalAccessTokenRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at
+		 FROM
+			personal_access_tokens
+		 WHERE
+			uuid = $1`,
+		uuid,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapPersonalAccessTokenFromRow(row)
+}
+
+func (r *pgPersonalAccessTokensRepository) PersonalAccessTokenByHash(
+	ctx context.Context,
+	hash []byte,
+) (*auth.PersonalAccessToken, error) {
+	var row personalAccessTokenRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at
+		 FROM
+			personal_access_tokens
+		 WHERE
+			token_hash = $1`,
+		hash,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapPersonalAccessTokenFromRow(row)
+}
+
+func (r *pgPersonalAccessTokensRepository) UserPersonalAccessTokens(
+	ctx context.Context,
+	userUUID string,
+) ([]*auth.PersonalAccessToken, error) {
+	var rows []personalAccessTokenRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at
+		 FROM
+			personal_access_tokens
+		 WHERE
+			user_uuid = $1
+		 ORDER BY
+			created_at`,
+		userUUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*auth.PersonalAccessToken, 0, len(rows))
+	for _, row := range rows {
+		t, err := mapPersonalAccessTokenFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	return tokens, nil
+}
+
+func (r *pgPersonalAccessTokensRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.PersonalAccessToken) error,
+) error {
+	t, err := r.PersonalAccessToken(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	err = updateFn(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	row := mapPersonalAccessTokenToRow(t)
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`UPDATE
+			personal_access_tokens
+		 SET
+			name = $2,
+			scopes = $3,
+			expires_at = $4,
+			last_used_at = $5,
+			last_used_ip = $6
+		 WHERE
+			uuid = $1`,
+		row.UUID, row.Name, row.Scopes, row.ExpiresAt, row.LastUsedAt, row.LastUsedIP,
+	)
+	if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+	}
+
+	return nil
+}
+
+func (r *pgPersonalAccessTokensRepository) Delete(ctx context.Context, uuid string) error {
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			personal_access_tokens
+		 WHERE
+			uuid = $1`,
+		uuid,
+	)
+	if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+	}
+
+	return nil
+}
+
+type personalAccessTokenRow struct {
+	UUID       string         `db:"uuid"`
+	UserUUID   string         `db:"user_uuid"`
+	Name       string         `db:"name"`
+	Scopes     pq.StringArray `db:"scopes"`
+	TokenHash  []byte         `db:"token_hash"`
+	ExpiresAt  sql.NullTime   `db:"expires_at"`
+	LastUsedAt sql.NullTime   `db:"last_used_at"`
+	LastUsedIP sql.NullString `db:"last_used_ip"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+func mapPersonalAccessTokenFromRow(row personalAccessTokenRow) (*auth.PersonalAccessToken, error) {
+	scopes := make([]auth.Scope, len(row.Scopes))
+	for i, s := range row.Scopes {
+		scopes[i] = auth.Scope(s)
+	}
+
+	return auth.NewPersonalAccessTokenFromDB(
+		row.UUID,
+		row.UserUUID,
+		row.Name,
+		scopes,
+		row.TokenHash,
+		nullTimeToLocal(row.ExpiresAt),
+		nullTimeToLocal(row.LastUsedAt),
+		row.LastUsedIP.String,
+		row.CreatedAt.Local(),
+	)
+}
+
+func mapPersonalAccessTokenToRow(t *auth.PersonalAccessToken) personalAccessTokenRow {
+	scopes := make(pq.StringArray, len(t.Scopes))
+	for i, s := range t.Scopes {
+		scopes[i] = string(s)
+	}
+
+	return personalAccessTokenRow{
+		UUID:       t.UUID,
+		UserUUID:   t.UserUUID,
+		Name:       t.Name,
+		Scopes:     scopes,
+		TokenHash:  t.TokenHash,
+		ExpiresAt:  timeToNullUTC(t.ExpiresAt),
+		LastUsedAt: timeToNullUTC(t.LastUsedAt),
+		LastUsedIP: sql.NullString{String: t.LastUsedIP, Valid: t.LastUsedIP != ""},
+		CreatedAt:  t.CreatedAt.UTC(),
+	}
+}
+
+func nullTimeToLocal(t sql.NullTime) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return t.Time.Local()
+}
+
+func timeToNullUTC(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
@@ -0,0 +1,213 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type pgSessionsRepository struct {
+	db *sqlx.DB
+}
+
+func NewPgSessionsRepository(db *sqlx.DB) auth.SessionsRepository {
+	return &pgSessionsRepository{
+		db: db,
+	}
+}
+
+func (r *pgSessionsRepository) Save(ctx context.Context, s *auth.Session) error {
+	row := mapSessionToRow(s)
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`INSERT INTO
+			sessions (uuid, user_uuid, user_agent, ip, created_at, last_seen_at)
+		 VALUES
+			($1, $2, $3, $4, $5, $6)`,
+		row.UUID, row.UserUUID, row.UserAgent, row.IP, row.CreatedAt, row.LastSeenAt,
+	)
+	if pgutils.IsUniqueViolationError(err) {
+		return auth.ErrSessionAlreadyExists
+	} else if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return errors.New("no affected rows")
+	}
+
+	return nil
+}
+
+func (r *pgSessionsRepository) Session(ctx context.Context, uuid string) (*auth.Session, error) {
+	var row sessionRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, user_uuid, user_agent, ip, created_at, last_seen_at
+		 FROM
+			sessions
+		 WHERE
+			uuid = $1`,
+		uuid,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.SessionNotFound{SessionUUID: uuid}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapSessionFromRow(row)
+}
+
+func (r *pgSessionsRepository) UserSessions(ctx context.Context, userUUID string) ([]*auth.Session, error) {
+	var rows []sessionRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			uuid, user_uuid, user_agent, ip, created_at, last_seen_at
+		 FROM
+			sessions
+		 WHERE
+			user_uuid = $1
+		 ORDER BY
+			last_seen_at DESC`,
+		userUUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*auth.Session, 0, len(rows))
+	for _, row := range rows {
+		s, err := mapSessionFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, nil
+}
+
+func (r *pgSessionsRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.Session) error,
+) error {
+	s, err := r.Session(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	err = updateFn(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	row := mapSessionToRow(s)
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`UPDATE
+			sessions
+		 SET
+			ip = $2,
+			last_seen_at = $3
+		 WHERE
+			uuid = $1`,
+		row.UUID, row.IP, row.LastSeenAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return auth.SessionNotFound{SessionUUID: uuid}
+	}
+
+	return nil
+}
+
+func (r *pgSessionsRepository) Delete(ctx context.Context, uuid string) error {
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			sessions
+		 WHERE
+			uuid = $1`,
+		uuid,
+	)
+	if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return auth.SessionNotFound{SessionUUID: uuid}
+	}
+
+	return nil
+}
+
+func (r *pgSessionsRepository) DeleteUserSessions(ctx context.Context, userUUID string, exceptUUID string) error {
+	_, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			sessions
+		 WHERE
+			user_uuid = $1 AND uuid <> $2`,
+		userUUID, exceptUUID,
+	)
+	return err
+}
+
+type sessionRow struct {
+	UUID       string    `db:"uuid"`
+	UserUUID   string    `db:"user_uuid"`
+	UserAgent  string    `db:"user_agent"`
+	IP         string    `db:"ip"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+}
+
+func mapSessionFromRow(row sessionRow) (*auth.Session, error) {
+	return auth.NewSessionFromDB(
+		row.UUID,
+		row.UserUUID,
+		row.UserAgent,
+		row.IP,
+		row.CreatedAt.Local(),
+		row.LastSeenAt.Local(),
+	)
+}
+
+func mapSessionToRow(s *auth.Session) sessionRow {
+	return sessionRow{
+		UUID:       s.UUID,
+		UserUUID:   s.UserUUID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt.UTC(),
+		LastSeenAt: s.LastSeenAt.UTC(),
+	}
+}
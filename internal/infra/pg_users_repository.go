@@ -3,10 +3,14 @@ package infra
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/zhikh23/pgutils"
 
 	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
@@ -23,38 +27,53 @@ func NewPgUserRepository(db *sqlx.DB) auth.UsersRepository {
 }
 
 func (r *pgUserRepository) Save(ctx context.Context, u *auth.User) error {
-	row := mapUserToRow(u)
-	res, err := pgutils.Exec(
-		ctx, r.db,
-		`INSERT INTO 
-			users (uuid, email, passhash, created_at, updated_at)
-         VALUES 
-			($1, $2, $3, $4, $5)`,
-		row.UUID, row.Email, row.Passhash, row.CreatedAt, row.UpdatedAt)
-	if pgutils.IsUniqueViolationError(err) {
-		return auth.ErrUserAlreadyExists
-	} else if err != nil {
-		return err
-	}
-
-	aff, err := res.RowsAffected()
+	row, err := mapUserToRow(u)
 	if err != nil {
 		return err
 	}
 
-	if aff == 0 {
-		return errors.New("no affected rows")
-	}
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		res, err := pgutils.Exec(
+			ctx, tx,
+			`INSERT INTO 
+				users (
+					uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+					status, suspended_until, status_reason, status_changed_by, status_changed_at,
+					created_at, updated_at, version
+				)
+			 VALUES 
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			row.UUID, row.Email, row.EmailNormalized, row.Passhash, row.Roles, row.Profile, row.AvatarKey,
+			row.Status, row.SuspendedUntil, row.StatusReason, row.StatusChangedBy, row.StatusChangedAt,
+			row.CreatedAt, row.UpdatedAt, row.Version,
+		)
+		if pgutils.IsUniqueViolationError(err) {
+			return auth.ErrUserAlreadyExists
+		} else if err != nil {
+			return err
+		}
 
-	return nil
+		aff, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if aff == 0 {
+			return errors.New("no affected rows")
+		}
+
+		return saveEvents(ctx, tx, u.PopEvents())
+	})
 }
 
 func (r *pgUserRepository) User(ctx context.Context, uuid string) (*auth.User, error) {
 	var row userRow
 	err := pgutils.Get(
-		ctx, r.db, &row,
+		ctx, conn(ctx, r.db), &row,
 		`SELECT
-			uuid, email, passhash, created_at, updated_at
+			uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+			status, suspended_until, status_reason, status_changed_by, status_changed_at,
+			created_at, updated_at, version
 	     FROM 
 			users
 		 WHERE 
@@ -70,20 +89,22 @@ func (r *pgUserRepository) User(ctx context.Context, uuid string) (*auth.User, e
 	return mapUserFromRow(row)
 }
 
-func (r *pgUserRepository) UserByEmail(ctx context.Context, email string) (*auth.User, error) {
+func (r *pgUserRepository) UserByEmail(ctx context.Context, email auth.Email) (*auth.User, error) {
 	var row userRow
 	err := pgutils.Get(
-		ctx, r.db, &row,
+		ctx, conn(ctx, r.db), &row,
 		`SELECT 
-			uuid, email, passhash, created_at, updated_at
+			uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+			status, suspended_until, status_reason, status_changed_by, status_changed_at,
+			created_at, updated_at, version
          FROM 
 			users
          WHERE 
-			email = $1`,
-		email,
+			email_normalized = $1`,
+		email.Normalized(),
 	)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, auth.UserEmailNotFound{Email: email}
+		return nil, auth.UserEmailNotFound{Email: email.String()}
 	} else if err != nil {
 		return nil, err
 	}
@@ -91,99 +112,334 @@ func (r *pgUserRepository) UserByEmail(ctx context.Context, email string) (*auth
 	return mapUserFromRow(row)
 }
 
-func (r *pgUserRepository) Update(
-	ctx context.Context,
-	uuid string,
-	updateFn func(context.Context, *auth.User) error,
-) error {
-	u, err := r.User(ctx, uuid)
+func (r *pgUserRepository) ListUsers(ctx context.Context, l auth.ListUsers) (auth.UsersPage, error) {
+	cursor, err := l.DecodeCursor()
 	if err != nil {
-		return err
+		return auth.UsersPage{}, err
 	}
 
-	err = updateFn(ctx, u)
-	if err != nil {
-		return err
+	where, args := usersFilterToSQL(l.Filter)
+
+	page := auth.UsersPage{Total: -1}
+	if l.WithTotal {
+		err = pgutils.Get(
+			ctx, conn(ctx, r.db), &page.Total,
+			`SELECT
+				COUNT(*)
+			 FROM
+				users
+			 WHERE
+				`+where,
+			args...,
+		)
+		if err != nil {
+			return auth.UsersPage{}, err
+		}
 	}
 
-	row := mapUserToRow(u)
-	res, err := pgutils.Exec(
-		ctx, r.db,
-		`UPDATE
-			users
-         SET 
-			email = $2,
-            passhash = $3,
-			updated_at = $4
-         WHERE 
-			uuid = $1`,
-		row.UUID, row.Email, row.Passhash, row.UpdatedAt,
+	// The sort column is chosen from a fixed set, never taken from input.
+	column, op, dir := "created_at", ">", "ASC"
+	if l.Sort == auth.UsersByUpdatedAt {
+		column = "updated_at"
+	}
+	if l.Descending {
+		op, dir = "<", "DESC"
+	}
+
+	if cursor != nil {
+		args = append(args, cursor.Time.UTC(), cursor.UUID)
+		where += fmt.Sprintf(` AND (%s, uuid) %s ($%d, $%d)`, column, op, len(args)-1, len(args))
+	}
+
+	args = append(args, l.Limit+1)
+	var rows []userRow
+	err = pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		fmt.Sprintf(
+			`SELECT
+				uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+				status, suspended_until, status_reason, status_changed_by, status_changed_at,
+				created_at, updated_at, version
+			 FROM
+				users
+			 WHERE
+				%s
+			 ORDER BY
+				%s %s, uuid %s
+			 LIMIT $%d`,
+			where, column, dir, dir, len(args),
+		),
+		args...,
 	)
 	if err != nil {
-		return err
+		return auth.UsersPage{}, err
 	}
 
-	aff, err := res.RowsAffected()
-	if err != nil {
-		return err
+	page.Users = make([]*auth.User, 0, len(rows))
+	for _, row := range rows {
+		u, err := mapUserFromRow(row)
+		if err != nil {
+			return auth.UsersPage{}, err
+		}
+		page.Users = append(page.Users, u)
 	}
 
-	if aff == 0 {
-		return auth.UserNotFound{UserUUID: u.UUID}
+	if len(page.Users) > l.Limit {
+		page.Users = page.Users[:l.Limit]
+		page.NextCursor = l.NextCursor(page.Users[len(page.Users)-1])
 	}
 
-	return nil
+	return page, nil
 }
 
-func (r *pgUserRepository) Delete(ctx context.Context, uuid string) error {
-	res, err := pgutils.Exec(
-		ctx, r.db,
-		`DELETE FROM
-			users
-		WHERE
-			uuid = $1`,
-		uuid,
-	)
-	if err != nil {
-		return err
+// usersFilterToSQL returns the condition with numbered placeholders and
+// their arguments.
+func usersFilterToSQL(f auth.UsersFilter) (string, []any) {
+	conds := []string{"TRUE"}
+	var args []any
+
+	if f.EmailContains != "" {
+		args = append(args, "%"+escapeLike(f.EmailContains)+"%")
+		conds = append(conds, fmt.Sprintf(`email_normalized LIKE $%d`, len(args)))
 	}
 
-	aff, err := res.RowsAffected()
-	if err != nil {
-		return nil
+	if f.Role != "" {
+		args = append(args, pq.StringArray{string(f.Role)})
+		conds = append(conds, fmt.Sprintf(`roles @> $%d::TEXT[]`, len(args)))
+	}
+
+	if !f.CreatedFrom.IsZero() {
+		args = append(args, f.CreatedFrom.UTC())
+		conds = append(conds, fmt.Sprintf(`created_at >= $%d`, len(args)))
 	}
 
-	if aff == 0 {
-		return auth.UserNotFound{UserUUID: uuid}
+	if !f.CreatedTo.IsZero() {
+		args = append(args, f.CreatedTo.UTC())
+		conds = append(conds, fmt.Sprintf(`created_at < $%d`, len(args)))
 	}
 
-	return nil
+	switch f.Status {
+	case auth.AccountActive:
+		args = append(args, f.Now.UTC())
+		conds = append(conds, fmt.Sprintf(
+			`(status = 'active' OR status = 'suspended' AND suspended_until <= $%d)`, len(args),
+		))
+	case auth.AccountSuspended:
+		args = append(args, f.Now.UTC())
+		conds = append(conds, fmt.Sprintf(`status = 'suspended' AND suspended_until > $%d`, len(args)))
+	case auth.AccountDisabled:
+		conds = append(conds, `status = 'disabled'`)
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// escapeLike makes the wildcards of LIKE match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Update locks the row of the user until updateFn returns.
+func (r *pgUserRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.User) error,
+) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row userRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+				status, suspended_until, status_reason, status_changed_by, status_changed_at,
+				created_at, updated_at, version
+			 FROM
+				users
+			 WHERE
+				uuid = $1
+			 FOR UPDATE`,
+			uuid,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.UserNotFound{UserUUID: uuid}
+		} else if err != nil {
+			return err
+		}
+
+		u, err := mapUserFromRow(row)
+		if err != nil {
+			return err
+		}
+
+		version := u.Version
+		if err = updateFn(withTx(ctx, tx), u); err != nil {
+			return err
+		}
+
+		row, err = mapUserToRow(u)
+		if err != nil {
+			return err
+		}
+
+		res, err := pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				users
+			 SET 
+				email = $2,
+				email_normalized = $3,
+				passhash = $4,
+				roles = $5,
+				profile = $6,
+				avatar_key = $7,
+				status = $8,
+				suspended_until = $9,
+				status_reason = $10,
+				status_changed_by = $11,
+				status_changed_at = $12,
+				updated_at = $13,
+				version = version + 1
+			 WHERE 
+				uuid = $1 AND version = $14`,
+			row.UUID, row.Email, row.EmailNormalized, row.Passhash, row.Roles, row.Profile, row.AvatarKey,
+			row.Status, row.SuspendedUntil, row.StatusReason, row.StatusChangedBy, row.StatusChangedAt,
+			row.UpdatedAt, version,
+		)
+		if pgutils.IsUniqueViolationError(err) {
+			return auth.ErrUserAlreadyExists
+		} else if err != nil {
+			return err
+		}
+
+		aff, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if aff == 0 {
+			return auth.ErrConcurrentModification
+		}
+		u.Version = version + 1
+
+		return saveEvents(ctx, tx, u.PopEvents())
+	})
+}
+
+func (r *pgUserRepository) Delete(ctx context.Context, uuid string) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		res, err := pgutils.Exec(
+			ctx, tx,
+			`DELETE FROM
+				users
+			WHERE
+				uuid = $1`,
+			uuid,
+		)
+		if err != nil {
+			return err
+		}
+
+		aff, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if aff == 0 {
+			return auth.UserNotFound{UserUUID: uuid}
+		}
+
+		return saveEvents(ctx, tx, []auth.Event{auth.UserDeleted{UserUUID: uuid}})
+	})
 }
 
 type userRow struct {
-	UUID      string    `db:"uuid"`
-	Email     string    `db:"email"`
-	Passhash  []byte    `db:"passhash"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	UUID            string         `db:"uuid"`
+	Email           string         `db:"email"`
+	EmailNormalized string         `db:"email_normalized"`
+	Passhash        []byte         `db:"passhash"`
+	Roles           pq.StringArray `db:"roles"`
+	Profile         []byte         `db:"profile"`
+	AvatarKey       string         `db:"avatar_key"`
+	Status          string         `db:"status"`
+	SuspendedUntil  sql.NullTime   `db:"suspended_until"`
+	StatusReason    string         `db:"status_reason"`
+	StatusChangedBy string         `db:"status_changed_by"`
+	StatusChangedAt sql.NullTime   `db:"status_changed_at"`
+	CreatedAt       time.Time      `db:"created_at"`
+	UpdatedAt       time.Time      `db:"updated_at"`
+	Version         int64          `db:"version"`
 }
 
 func mapUserFromRow(row userRow) (*auth.User, error) {
+	roles := make([]auth.Role, len(row.Roles))
+	for i, r := range row.Roles {
+		roles[i] = auth.Role(r)
+	}
+
+	var profile profileRow
+	if err := json.Unmarshal(row.Profile, &profile); err != nil {
+		return nil, err
+	}
+
 	return auth.NewUserFromDB(
 		row.UUID,
-		row.Email,
+		auth.NewEmailFromDB(row.Email, row.EmailNormalized),
 		row.Passhash,
+		roles,
+		auth.Profile(profile),
+		row.AvatarKey,
+		auth.AccountStatus{
+			State:     auth.AccountState(row.Status),
+			Until:     nullTimeToLocal(row.SuspendedUntil),
+			Reason:    row.StatusReason,
+			ChangedBy: row.StatusChangedBy,
+			ChangedAt: nullTimeToLocal(row.StatusChangedAt),
+		},
 		row.CreatedAt.Local(),
 		row.UpdatedAt.Local(),
+		row.Version,
 	)
 }
 
-func mapUserToRow(u *auth.User) userRow {
-	return userRow{
-		UUID:      u.UUID,
-		Email:     u.Email,
-		Passhash:  u.Passhash,
-		CreatedAt: u.CreatedAt.UTC(),
-		UpdatedAt: u.UpdatedAt.UTC(),
+func mapUserToRow(u *auth.User) (userRow, error) {
+	roles := make(pq.StringArray, len(u.Roles))
+	for i, r := range u.Roles {
+		roles[i] = string(r)
+	}
+
+	profile, err := json.Marshal(profileRow(u.Profile))
+	if err != nil {
+		return userRow{}, err
 	}
+
+	return userRow{
+		UUID:            u.UUID,
+		Email:           u.Email.String(),
+		EmailNormalized: u.Email.Normalized(),
+		Passhash:        u.Passhash,
+		Roles:           roles,
+		Profile:         profile,
+		AvatarKey:       u.AvatarKey,
+		Status:          string(u.Status.State),
+		SuspendedUntil:  timeToNullUTC(u.Status.Until),
+		StatusReason:    u.Status.Reason,
+		StatusChangedBy: u.Status.ChangedBy,
+		StatusChangedAt: timeToNullUTC(u.Status.ChangedAt),
+		CreatedAt:       u.CreatedAt.UTC(),
+		UpdatedAt:       u.UpdatedAt.UTC(),
+		Version:         u.Version,
+	}, nil
+}
+
+// profileRow is stored as a JSON object without the unset fields.
+type profileRow struct {
+	DisplayName string `json:"displayName,omitempty"`
+	LastName    string `json:"lastName,omitempty"`
+	FirstName   string `json:"firstName,omitempty"`
+	Patronymic  string `json:"patronymic,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Faculty     string `json:"faculty,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	Timezone    string `json:"timezone,omitempty"`
+	AvatarURL   string `json:"avatarUrl,omitempty"`
 }
@@ -0,0 +1,373 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type pgWebhooksRepository struct {
+	db *sqlx.DB
+}
+
+func NewPgWebhooksRepository(db *sqlx.DB) auth.WebhooksRepository {
+	return &pgWebhooksRepository{
+		db: db,
+	}
+}
+
+func (r *pgWebhooksRepository) SaveSubscription(ctx context.Context, s *auth.WebhookSubscription) error {
+	row := mapWebhookSubscriptionToRow(s)
+	_, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`INSERT INTO
+			webhook_subscriptions (uuid, url, event_types, secret, created_by, created_at)
+		 VALUES
+			($1, $2, $3, $4, $5, $6)`,
+		row.UUID, row.URL, row.EventTypes, row.Secret, row.CreatedBy, row.CreatedAt,
+	)
+	if pgutils.IsUniqueViolationError(err) {
+		return auth.ErrWebhookSubscriptionAlreadyExists
+	}
+	return err
+}
+
+func (r *pgWebhooksRepository) Subscriptions(ctx context.Context) ([]*auth.WebhookSubscription, error) {
+	var rows []webhookSubscriptionRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			uuid, url, event_types, secret, created_by, created_at
+		 FROM
+			webhook_subscriptions
+		 ORDER BY
+			created_at, uuid`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]*auth.WebhookSubscription, 0, len(rows))
+	for _, row := range rows {
+		s, err := mapWebhookSubscriptionFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *pgWebhooksRepository) Subscription(ctx context.Context, uuid string) (*auth.WebhookSubscription, error) {
+	var row webhookSubscriptionRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, url, event_types, secret, created_by, created_at
+		 FROM
+			webhook_subscriptions
+		 WHERE
+			uuid = $1`,
+		uuid,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrWebhookSubscriptionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapWebhookSubscriptionFromRow(row)
+}
+
+func (r *pgWebhooksRepository) DeleteSubscription(ctx context.Context, uuid string) error {
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			webhook_subscriptions
+		 WHERE
+			uuid = $1`,
+		uuid,
+	)
+	if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return auth.ErrWebhookSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (r *pgWebhooksRepository) SaveDeliveries(ctx context.Context, deliveries []*auth.WebhookDelivery) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		for _, d := range deliveries {
+			row := mapWebhookDeliveryToRow(d)
+			_, err := pgutils.Exec(
+				ctx, tx,
+				`INSERT INTO
+					webhook_deliveries (
+						uuid, subscription_uuid, event_id, event_type, aggregate_uuid, payload, occurred_at,
+						state, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+						created_at
+					)
+				 VALUES
+					($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+				 ON CONFLICT (subscription_uuid, event_id) DO NOTHING`,
+				row.UUID, row.SubscriptionUUID, row.EventID, row.EventType, row.AggregateUUID, row.Payload,
+				row.OccurredAt, row.State, row.Attempts, row.NextAttemptAt, row.LastAttemptAt,
+				row.LastStatusCode, row.LastError, row.CreatedAt,
+			)
+			if isForeignKeyViolationError(err) {
+				return auth.ErrWebhookSubscriptionNotFound
+			} else if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func isForeignKeyViolationError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+func (r *pgWebhooksRepository) ClaimDueDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*auth.WebhookDelivery, error) {
+	var rows []webhookDeliveryRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`UPDATE
+			webhook_deliveries
+		 SET
+			next_attempt_at = $2
+		 WHERE
+			uuid IN (
+				SELECT
+					uuid
+				FROM
+					webhook_deliveries
+				WHERE
+					state = 'pending' AND next_attempt_at <= $1
+				ORDER BY
+					next_attempt_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+		 RETURNING
+			uuid, subscription_uuid, event_id, event_type, aggregate_uuid, payload, occurred_at,
+			state, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+			created_at`,
+		now.UTC(), now.Add(lease).UTC(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapWebhookDeliveriesFromRows(rows), nil
+}
+
+func (r *pgWebhooksRepository) UpdateDelivery(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.WebhookDelivery) error,
+) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row webhookDeliveryRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				uuid, subscription_uuid, event_id, event_type, aggregate_uuid, payload, occurred_at,
+				state, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+				created_at
+			 FROM
+				webhook_deliveries
+			 WHERE
+				uuid = $1
+			 FOR UPDATE`,
+			uuid,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.ErrWebhookDeliveryNotFound
+		} else if err != nil {
+			return err
+		}
+
+		d := mapWebhookDeliveryFromRow(row)
+		if err = updateFn(withTx(ctx, tx), d); err != nil {
+			return err
+		}
+
+		row = mapWebhookDeliveryToRow(d)
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				webhook_deliveries
+			 SET
+				state = $2,
+				attempts = $3,
+				next_attempt_at = $4,
+				last_attempt_at = $5,
+				last_status_code = $6,
+				last_error = $7
+			 WHERE
+				uuid = $1`,
+			row.UUID, row.State, row.Attempts, row.NextAttemptAt, row.LastAttemptAt,
+			row.LastStatusCode, row.LastError,
+		)
+		return err
+	})
+}
+
+func (r *pgWebhooksRepository) Deliveries(
+	ctx context.Context,
+	subscriptionUUID string,
+	state auth.WebhookDeliveryState,
+	limit int,
+) ([]*auth.WebhookDelivery, error) {
+	var rows []webhookDeliveryRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			uuid, subscription_uuid, event_id, event_type, aggregate_uuid, payload, occurred_at,
+			state, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+			created_at
+		 FROM
+			webhook_deliveries
+		 WHERE
+			subscription_uuid = $1 AND ($2 = '' OR state = $2)
+		 ORDER BY
+			created_at DESC, event_id DESC
+		 LIMIT $3`,
+		subscriptionUUID, string(state), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapWebhookDeliveriesFromRows(rows), nil
+}
+
+type webhookSubscriptionRow struct {
+	UUID       string         `db:"uuid"`
+	URL        string         `db:"url"`
+	EventTypes pq.StringArray `db:"event_types"`
+	Secret     string         `db:"secret"`
+	CreatedBy  string         `db:"created_by"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+func mapWebhookSubscriptionFromRow(row webhookSubscriptionRow) (*auth.WebhookSubscription, error) {
+	eventTypes := make([]auth.EventType, len(row.EventTypes))
+	for i, t := range row.EventTypes {
+		eventTypes[i] = auth.EventType(t)
+	}
+
+	return auth.NewWebhookSubscriptionFromDB(
+		row.UUID,
+		row.URL,
+		eventTypes,
+		row.Secret,
+		row.CreatedBy,
+		row.CreatedAt.Local(),
+	)
+}
+
+func mapWebhookSubscriptionToRow(s *auth.WebhookSubscription) webhookSubscriptionRow {
+	eventTypes := make(pq.StringArray, len(s.EventTypes))
+	for i, t := range s.EventTypes {
+		eventTypes[i] = string(t)
+	}
+
+	return webhookSubscriptionRow{
+		UUID:       s.UUID,
+		URL:        s.URL,
+		EventTypes: eventTypes,
+		Secret:     s.Secret,
+		CreatedBy:  s.CreatedBy,
+		CreatedAt:  s.CreatedAt.UTC(),
+	}
+}
+
+type webhookDeliveryRow struct {
+	UUID             string       `db:"uuid"`
+	SubscriptionUUID string       `db:"subscription_uuid"`
+	EventID          int64        `db:"event_id"`
+	EventType        string       `db:"event_type"`
+	AggregateUUID    string       `db:"aggregate_uuid"`
+	Payload          []byte       `db:"payload"`
+	OccurredAt       time.Time    `db:"occurred_at"`
+	State            string       `db:"state"`
+	Attempts         int          `db:"attempts"`
+	NextAttemptAt    sql.NullTime `db:"next_attempt_at"`
+	LastAttemptAt    sql.NullTime `db:"last_attempt_at"`
+	LastStatusCode   int          `db:"last_status_code"`
+	LastError        string       `db:"last_error"`
+	CreatedAt        time.Time    `db:"created_at"`
+}
+
+func mapWebhookDeliveriesFromRows(rows []webhookDeliveryRow) []*auth.WebhookDelivery {
+	deliveries := make([]*auth.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = mapWebhookDeliveryFromRow(row)
+	}
+	return deliveries
+}
+
+func mapWebhookDeliveryFromRow(row webhookDeliveryRow) *auth.WebhookDelivery {
+	return &auth.WebhookDelivery{
+		UUID:             row.UUID,
+		SubscriptionUUID: row.SubscriptionUUID,
+		Event: auth.OutboxMessage{
+			ID:            row.EventID,
+			Type:          auth.EventType(row.EventType),
+			AggregateUUID: row.AggregateUUID,
+			Payload:       row.Payload,
+			OccurredAt:    row.OccurredAt.Local(),
+		},
+		State:          auth.WebhookDeliveryState(row.State),
+		Attempts:       row.Attempts,
+		NextAttemptAt:  nullTimeToLocal(row.NextAttemptAt),
+		LastAttemptAt:  nullTimeToLocal(row.LastAttemptAt),
+		LastStatusCode: row.LastStatusCode,
+		LastError:      row.LastError,
+		CreatedAt:      row.CreatedAt.Local(),
+	}
+}
+
+func mapWebhookDeliveryToRow(d *auth.WebhookDelivery) webhookDeliveryRow {
+	return webhookDeliveryRow{
+		UUID:             d.UUID,
+		SubscriptionUUID: d.SubscriptionUUID,
+		EventID:          d.Event.ID,
+		EventType:        string(d.Event.Type),
+		AggregateUUID:    d.Event.AggregateUUID,
+		Payload:          d.Event.Payload,
+		OccurredAt:       d.Event.OccurredAt.UTC(),
+		State:            string(d.State),
+		Attempts:         d.Attempts,
+		NextAttemptAt:    timeToNullUTC(d.NextAttemptAt),
+		LastAttemptAt:    timeToNullUTC(d.LastAttemptAt),
+		LastStatusCode:   d.LastStatusCode,
+		LastError:        d.LastError,
+		CreatedAt:        d.CreatedAt.UTC(),
+	}
+}
@@ -0,0 +1,188 @@
+package infra_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestWebhookPublisher(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+		if r.Header.Get("X-Event-ID") == "2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := infra.NewWebhookPublisher(srv.URL, srv.Client())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	m := fakeOutboxMessage(t, 1)
+	require.NoError(t, p.Publish(ctx, m))
+
+	r := <-received
+	require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	require.Equal(t, string(auth.EventUserRegistered), r.Header.Get("X-Event-Type"))
+	requireEventEnvelope(t, m, body)
+
+	require.Error(t, p.Publish(ctx, fakeOutboxMessage(t, 2)))
+	<-received
+
+	_, err = infra.NewWebhookPublisher("ftp://example.com", nil)
+	require.Error(t, err)
+}
+
+func TestNATSPublisher(t *testing.T) {
+	srv := newFakeNATS(t)
+
+	p, err := infra.NewNATSPublisher(infra.NATSConfig{
+		URL:           "nats://bob:secret@" + srv.addr,
+		SubjectPrefix: "itsreg.auth",
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	m := fakeOutboxMessage(t, 1)
+	require.NoError(t, p.Publish(ctx, m))
+
+	connect := <-srv.connects
+	require.Equal(t, "bob", connect["user"])
+	require.Equal(t, "secret", connect["pass"])
+
+	msg := <-srv.messages
+	require.Equal(t, "itsreg.auth.user.registered", msg.subject)
+	requireEventEnvelope(t, m, msg.payload)
+
+	srv.reject.Store(true)
+	require.Error(t, p.Publish(ctx, fakeOutboxMessage(t, 2)))
+
+	srv.reject.Store(false)
+	require.NoError(t, p.Publish(ctx, fakeOutboxMessage(t, 3)))
+	<-srv.connects
+	require.Equal(t, int64(3), envelopeID(t, (<-srv.messages).payload))
+}
+
+func fakeOutboxMessage(t *testing.T, id int64) *auth.OutboxMessage {
+	m, err := auth.NewOutboxMessage(auth.UserRegistered{
+		UserUUID: "0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b",
+		Email:    "test@test.com",
+	})
+	require.NoError(t, err)
+	m.ID = id
+	return m
+}
+
+func requireEventEnvelope(t *testing.T, m *auth.OutboxMessage, body []byte) {
+	var envelope struct {
+		ID            int64           `json:"id"`
+		Type          string          `json:"type"`
+		AggregateUUID string          `json:"aggregateUuid"`
+		Data          json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &envelope))
+	require.Equal(t, m.ID, envelope.ID)
+	require.Equal(t, string(m.Type), envelope.Type)
+	require.Equal(t, m.AggregateUUID, envelope.AggregateUUID)
+	require.JSONEq(t, string(m.Payload), string(envelope.Data))
+}
+
+func envelopeID(t *testing.T, body []byte) int64 {
+	var envelope struct {
+		ID int64 `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(body, &envelope))
+	return envelope.ID
+}
+
+type natsMessage struct {
+	subject string
+	payload []byte
+}
+
+// fakeNATS accepts connections and answers the commands the publisher sends.
+// If reject is set, it answers a PUB with -ERR and closes the connection.
+type fakeNATS struct {
+	addr     string
+	connects chan map[string]any
+	messages chan natsMessage
+	reject   atomic.Bool
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	srv := &fakeNATS{
+		addr:     l.Addr().String(),
+		connects: make(chan map[string]any, 4),
+		messages: make(chan natsMessage, 4),
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+
+	return srv
+}
+
+func (s *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+
+	_, _ = fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"max_payload\":1048576}\r\n")
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			var opts map[string]any
+			_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &opts)
+			s.connects <- opts
+		case strings.HasPrefix(line, "PUB "):
+			var subject string
+			var n int
+			_, _ = fmt.Sscanf(line, "PUB %s %d", &subject, &n)
+			payload := make([]byte, n+2)
+			if _, err = io.ReadFull(r, payload); err != nil {
+				return
+			}
+			if s.reject.Load() {
+				_, _ = fmt.Fprint(conn, "-ERR 'Permissions Violation'\r\n")
+				return
+			}
+			s.messages <- natsMessage{subject: subject, payload: payload[:n]}
+		case line == "PING":
+			_, _ = fmt.Fprint(conn, "PONG\r\n")
+		}
+	}
+}
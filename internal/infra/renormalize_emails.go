@@ -0,0 +1,45 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// RenormalizeEmails sets the normalized email of every user to the one of
+// auth.NewEmail. Addresses NewEmail refuses are left as they are.
+func RenormalizeEmails(ctx context.Context, tx *sqlx.Tx) error {
+	var rows []struct {
+		UUID            string `db:"uuid"`
+		Email           string `db:"email"`
+		EmailNormalized string `db:"email_normalized"`
+	}
+	err := pgutils.Select(ctx, tx, &rows, `SELECT uuid, email, email_normalized FROM users`)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		email, err := auth.NewEmail(row.Email)
+		if err != nil || email.Normalized() == row.EmailNormalized {
+			continue
+		}
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			tx.Rebind(`UPDATE users SET email_normalized = ? WHERE uuid = ?`),
+			email.Normalized(), row.UUID,
+		)
+		if pgutils.IsUniqueViolationError(err) || isSQLiteUniqueViolation(err) {
+			return fmt.Errorf("user %s <%s> collides with another user and must be merged first", row.UUID, row.Email)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
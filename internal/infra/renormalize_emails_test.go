@@ -0,0 +1,74 @@
+package infra_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestRenormalizeEmails(t *testing.T) {
+	db := newSQLiteDB(t)
+	r := infra.NewSQLiteUserRepository(db)
+
+	ctx := context.Background()
+
+	user := auth.MustNewUser(gofakeit.UUID(), "Ivan@XN--E1AFMKFD.xn--p1ai", fakePassword(), passhash.NewDefaultHasher())
+	require.NoError(t, r.Save(ctx, user))
+
+	// As backfilled in SQL, with the punycode domain left as is.
+	_, err := db.Exec(`UPDATE users SET email_normalized = LOWER(email) WHERE uuid = ?`, user.UUID)
+	require.NoError(t, err)
+
+	email := auth.MustNewEmail("ivan@пример.рф")
+	_, err = r.UserByEmail(ctx, email)
+	require.ErrorAs(t, err, &auth.UserEmailNotFound{})
+
+	require.NoError(t, runInTx(t, db, infra.RenormalizeEmails))
+
+	found, err := r.UserByEmail(ctx, email)
+	require.NoError(t, err)
+	require.Equal(t, user.UUID, found.UUID)
+	require.Equal(t, "Ivan@XN--E1AFMKFD.xn--p1ai", found.Email.String())
+}
+
+func TestRenormalizeEmails_Collision(t *testing.T) {
+	db := newSQLiteDB(t)
+	r := infra.NewSQLiteUserRepository(db)
+
+	ctx := context.Background()
+
+	unicode := auth.MustNewUser(gofakeit.UUID(), "ivan@пример.рф", fakePassword(), passhash.NewDefaultHasher())
+	require.NoError(t, r.Save(ctx, unicode))
+
+	punycode := auth.MustNewUser(gofakeit.UUID(), "temp@example.com", fakePassword(), passhash.NewDefaultHasher())
+	require.NoError(t, r.Save(ctx, punycode))
+
+	_, err := db.Exec(
+		`UPDATE users SET email = ?, email_normalized = ? WHERE uuid = ?`,
+		"ivan@xn--e1afmkfd.xn--p1ai", "ivan@xn--e1afmkfd.xn--p1ai", punycode.UUID,
+	)
+	require.NoError(t, err)
+
+	err = runInTx(t, db, infra.RenormalizeEmails)
+	require.ErrorContains(t, err, punycode.UUID)
+}
+
+func runInTx(t *testing.T, db *sqlx.DB, fn func(context.Context, *sqlx.Tx) error) error {
+	ctx := context.Background()
+	tx, err := db.BeginTxx(ctx, nil)
+	require.NoError(t, err)
+
+	if err = fn(ctx, tx); err != nil {
+		require.NoError(t, tx.Rollback())
+		return err
+	}
+
+	return tx.Commit()
+}
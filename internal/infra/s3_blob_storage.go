@@ -0,0 +1,111 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// S3Config describes a bucket of an S3 compatible storage. Objects are
+// addressed path-style.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+type s3BlobStorage struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3BlobStorage(cfg S3Config, client *http.Client) (auth.BlobStorage, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("expected not empty S3 bucket")
+	}
+
+	// With the region set, the client does not look up the bucket location.
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	opts := &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure:       u.Scheme == "https",
+		Region:       cfg.Region,
+		BucketLookup: minio.BucketLookupPath,
+	}
+	if client != nil {
+		opts.Transport = client.Transport
+	}
+
+	c, err := minio.New(u.Host, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3BlobStorage{
+		client: c,
+		bucket: cfg.Bucket,
+	}, nil
+}
+
+func (s *s3BlobStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := validateBlobKey(key); err != nil {
+		return err
+	}
+
+	_, err := s.client.PutObject(
+		ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType},
+	)
+	return err
+}
+
+func (s *s3BlobStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := validateBlobKey(key); err != nil {
+		return nil, err
+	}
+
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return nil, auth.ErrBlobNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *s3BlobStorage) Delete(ctx context.Context, key string) error {
+	if err := validateBlobKey(key); err != nil {
+		return err
+	}
+
+	err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package infra_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestPgSessionsRepository(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	users := infra.NewPgUserRepository(db)
+	sessions := infra.NewPgSessionsRepository(db)
+	testSessionsRepository(t, users, sessions)
+}
+
+func TestSQLiteSessionsRepository(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	users := infra.NewSQLiteUserRepository(db)
+	sessions := infra.NewSQLiteSessionsRepository(db)
+	testSessionsRepository(t, users, sessions)
+}
+
+func testSessionsRepository(t *testing.T, users auth.UsersRepository, r auth.SessionsRepository) {
+	t.Parallel()
+
+	t.Run("should save session", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		session := fakeSession(user.UUID)
+		err := r.Save(ctx, session)
+		require.NoError(t, err)
+
+		saved, err := r.Session(ctx, session.UUID)
+		require.NoError(t, err)
+		require.Equal(t, session.UserAgent, saved.UserAgent)
+		require.Equal(t, session.IP, saved.IP)
+	})
+
+	t.Run("should touch session", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		session := fakeSession(user.UUID)
+		require.NoError(t, r.Save(ctx, session))
+
+		seenAt := time.Now().Add(time.Hour)
+		err := r.Update(ctx, session.UUID, func(ctx context.Context, s *auth.Session) error {
+			s.Touch("", seenAt)
+			return nil
+		})
+		require.NoError(t, err)
+
+		touched, err := r.Session(ctx, session.UUID)
+		require.NoError(t, err)
+		require.Less(t, seenAt.Sub(touched.LastSeenAt).Abs(), time.Microsecond)
+		require.Equal(t, session.IP, touched.IP)
+	})
+
+	t.Run("should delete user sessions except one", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		user := fakeUser()
+		require.NoError(t, users.Save(ctx, user))
+
+		kept := fakeSession(user.UUID)
+		require.NoError(t, r.Save(ctx, kept))
+		for range 2 {
+			require.NoError(t, r.Save(ctx, fakeSession(user.UUID)))
+		}
+
+		err := r.DeleteUserSessions(ctx, user.UUID, kept.UUID)
+		require.NoError(t, err)
+
+		sessions, err := r.UserSessions(ctx, user.UUID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.Equal(t, kept.UUID, sessions[0].UUID)
+	})
+
+	t.Run("should return error if session not found", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		fakeUUID := gofakeit.UUID()
+		_, err := r.Session(ctx, fakeUUID)
+		require.EqualError(t, err, fmt.Sprintf("session with UUID %s not found", fakeUUID))
+	})
+}
+
+func fakeSession(userUUID string) *auth.Session {
+	session, err := auth.NewSession(
+		gofakeit.UUID(),
+		userUUID,
+		gofakeit.UserAgent(),
+		gofakeit.IPv4Address(),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return session
+}
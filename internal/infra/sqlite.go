@@ -0,0 +1,75 @@
+package infra
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// OpenSQLite opens the database at path. Transactions take the write lock
+// when they begin.
+func OpenSQLite(path string) (*sqlx.DB, error) {
+	q := url.Values{}
+	q.Set("_busy_timeout", "5000")
+	q.Set("_txlock", "immediate")
+	q.Set("_foreign_keys", "on")
+	q.Set("_journal_mode", "WAL")
+
+	db, err := sqlx.Open("sqlite3", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+
+	return db, nil
+}
+
+// sqliteTimeLayout has a fixed width to compare in order.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func timeToSQLite(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func timeFromSQLite(s string) (time.Time, error) {
+	t, err := time.Parse(sqliteTimeLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Local(), nil
+}
+
+func timeToSQLiteNull(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: timeToSQLite(t), Valid: true}
+}
+
+func timeFromSQLiteNull(s sql.NullString) (time.Time, error) {
+	if !s.Valid {
+		return time.Time{}, nil
+	}
+	return timeFromSQLite(s.String)
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var serr sqlite3.Error
+	if errors.As(err, &serr) {
+		return serr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			serr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}
+
+func isSQLiteForeignKeyViolation(err error) bool {
+	var serr sqlite3.Error
+	return errors.As(err, &serr) && serr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
@@ -0,0 +1,291 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type sqliteAuditLogRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLiteAuditLogRepository(db *sqlx.DB) auth.AuditLogRepository {
+	return &sqliteAuditLogRepository{
+		db: db,
+	}
+}
+
+// Append holds the write lock of the database while it chains the event, so
+// every event is chained to the one of its chain appended right before it.
+func (r *sqliteAuditLogRepository) Append(ctx context.Context, e *auth.AuditEvent) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var seq int64
+		err := pgutils.Get(ctx, tx, &seq, `SELECT COALESCE(MAX(seq), 0) + 1 FROM audit_log`)
+		if err != nil {
+			return err
+		}
+
+		chain := e.ChainKey()
+		var last sqliteAuditEventRow
+		err = pgutils.Get(
+			ctx, tx, &last,
+			`SELECT
+				seq, hash
+			 FROM
+				audit_log
+			 WHERE
+				chain = ?
+			 ORDER BY
+				seq DESC
+			 LIMIT 1`,
+			chain,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			e.Chain(seq, nil)
+		} else if err != nil {
+			return err
+		} else {
+			e.Chain(seq, &auth.AuditEvent{Seq: last.Seq, Hash: last.Hash})
+		}
+
+		row, err := mapAuditEventToSQLiteRow(e)
+		if err != nil {
+			return err
+		}
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`INSERT INTO
+				audit_log (
+					seq, chain, action, actor_uuid, target_uuid, ip, user_agent, request_id, details,
+					created_at, prev_hash, hash
+				)
+			 VALUES
+				(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			row.Seq, chain, row.Action, row.ActorUUID, row.TargetUUID, row.IP, row.UserAgent, row.RequestID,
+			row.Details, row.CreatedAt, row.PrevHash, row.Hash,
+		)
+		return err
+	})
+}
+
+func (r *sqliteAuditLogRepository) ListAuditEvents(
+	ctx context.Context,
+	l auth.ListAuditEvents,
+) (auth.AuditEventsPage, error) {
+	beforeSeq, err := l.BeforeSeq()
+	if err != nil {
+		return auth.AuditEventsPage{}, err
+	}
+
+	where, args := auditFilterToSQLite(l.Filter)
+	if beforeSeq > 0 {
+		args = append(args, beforeSeq)
+		where += ` AND seq < ?`
+	}
+
+	args = append(args, l.Limit+1)
+	var rows []sqliteAuditEventRow
+	err = pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		fmt.Sprintf(
+			`SELECT
+				seq, action, actor_uuid, target_uuid, ip, user_agent, request_id, details,
+				created_at, prev_hash, hash
+			 FROM
+				audit_log
+			 WHERE
+				%s
+			 ORDER BY
+				seq DESC
+			 LIMIT ?`,
+			where,
+		),
+		args...,
+	)
+	if err != nil {
+		return auth.AuditEventsPage{}, err
+	}
+
+	events, err := mapAuditEventsFromSQLiteRows(rows)
+	if err != nil {
+		return auth.AuditEventsPage{}, err
+	}
+
+	page := auth.AuditEventsPage{Events: events}
+	if len(page.Events) > l.Limit {
+		page.Events = page.Events[:l.Limit]
+		page.NextCursor = l.NextCursor(page.Events[len(page.Events)-1])
+	}
+
+	return page, nil
+}
+
+// auditFilterToSQLite is auditFilterToSQL with positional placeholders.
+func auditFilterToSQLite(f auth.AuditFilter) (string, []any) {
+	conds := []string{"TRUE"}
+	var args []any
+
+	if f.ActorUUID != "" {
+		args = append(args, f.ActorUUID)
+		conds = append(conds, `actor_uuid = ?`)
+	}
+
+	if f.TargetUUID != "" {
+		args = append(args, f.TargetUUID)
+		conds = append(conds, `target_uuid = ?`)
+	}
+
+	if f.Action != "" {
+		args = append(args, string(f.Action))
+		conds = append(conds, `action = ?`)
+	}
+
+	if !f.From.IsZero() {
+		args = append(args, timeToSQLite(f.From))
+		conds = append(conds, `created_at >= ?`)
+	}
+
+	if !f.To.IsZero() {
+		args = append(args, timeToSQLite(f.To))
+		conds = append(conds, `created_at < ?`)
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+func (r *sqliteAuditLogRepository) AuditChainKeys(ctx context.Context, after string, limit int) ([]string, error) {
+	var keys []string
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &keys,
+		`SELECT DISTINCT
+			chain
+		 FROM
+			audit_log
+		 WHERE
+			chain > ?
+		 ORDER BY
+			chain
+		 LIMIT ?`,
+		after, limit,
+	)
+	return keys, err
+}
+
+func (r *sqliteAuditLogRepository) AuditChainEvents(
+	ctx context.Context,
+	chain string,
+	seq int64,
+	limit int,
+) ([]*auth.AuditEvent, error) {
+	var rows []sqliteAuditEventRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			seq, action, actor_uuid, target_uuid, ip, user_agent, request_id, details,
+			created_at, prev_hash, hash
+		 FROM
+			audit_log
+		 WHERE
+			chain = ? AND seq > ?
+		 ORDER BY
+			seq
+		 LIMIT ?`,
+		chain, seq, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapAuditEventsFromSQLiteRows(rows)
+}
+
+type sqliteAuditEventRow struct {
+	Seq        int64  `db:"seq"`
+	Action     string `db:"action"`
+	ActorUUID  string `db:"actor_uuid"`
+	TargetUUID string `db:"target_uuid"`
+	IP         string `db:"ip"`
+	UserAgent  string `db:"user_agent"`
+	RequestID  string `db:"request_id"`
+	Details    string `db:"details"`
+	CreatedAt  string `db:"created_at"`
+	PrevHash   []byte `db:"prev_hash"`
+	Hash       []byte `db:"hash"`
+}
+
+func mapAuditEventsFromSQLiteRows(rows []sqliteAuditEventRow) ([]*auth.AuditEvent, error) {
+	events := make([]*auth.AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		e, err := mapAuditEventFromSQLiteRow(row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func mapAuditEventFromSQLiteRow(row sqliteAuditEventRow) (*auth.AuditEvent, error) {
+	var details map[string]string
+	if err := json.Unmarshal([]byte(row.Details), &details); err != nil {
+		return nil, err
+	}
+	if len(details) == 0 {
+		details = nil
+	}
+
+	createdAt, err := timeFromSQLite(row.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.AuditEvent{
+		Seq:        row.Seq,
+		Action:     auth.AuditAction(row.Action),
+		ActorUUID:  row.ActorUUID,
+		TargetUUID: row.TargetUUID,
+		IP:         row.IP,
+		UserAgent:  row.UserAgent,
+		RequestID:  row.RequestID,
+		Details:    details,
+		CreatedAt:  createdAt,
+		PrevHash:   row.PrevHash,
+		Hash:       row.Hash,
+	}, nil
+}
+
+func mapAuditEventToSQLiteRow(e *auth.AuditEvent) (sqliteAuditEventRow, error) {
+	details := e.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	b, err := json.Marshal(details)
+	if err != nil {
+		return sqliteAuditEventRow{}, err
+	}
+
+	return sqliteAuditEventRow{
+		Seq:        e.Seq,
+		Action:     string(e.Action),
+		ActorUUID:  e.ActorUUID,
+		TargetUUID: e.TargetUUID,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Details:    string(b),
+		CreatedAt:  timeToSQLite(e.CreatedAt),
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}, nil
+}
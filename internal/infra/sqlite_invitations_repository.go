@@ -0,0 +1,182 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type sqliteInvitationsRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLiteInvitationsRepository(db *sqlx.DB) auth.InvitationsRepository {
+	return &sqliteInvitationsRepository{
+		db: db,
+	}
+}
+
+func (r *sqliteInvitationsRepository) Save(ctx context.Context, i *auth.Invitation) error {
+	row, err := mapInvitationToSQLiteRow(i)
+	if err != nil {
+		return err
+	}
+
+	_, err = pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`INSERT INTO
+			invitations (uuid, code_hash, created_by, roles, max_uses, uses, expires_at, created_at)
+		 VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?)`,
+		row.UUID, row.CodeHash, row.CreatedBy, row.Roles, row.MaxUses, row.Uses, row.ExpiresAt, row.CreatedAt,
+	)
+	if isSQLiteUniqueViolation(err) {
+		return auth.ErrInvitationAlreadyExists
+	}
+	return err
+}
+
+func (r *sqliteInvitationsRepository) InvitationByCodeHash(ctx context.Context, hash []byte) (*auth.Invitation, error) {
+	var row sqliteInvitationRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, code_hash, created_by, roles, max_uses, uses, expires_at, created_at
+		 FROM
+			invitations
+		 WHERE
+			code_hash = ?`,
+		hash,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrInvitationNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapInvitationFromSQLiteRow(row)
+}
+
+// Update holds the write lock of the database until updateFn returns, so
+// concurrent registrations take turns.
+func (r *sqliteInvitationsRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.Invitation) error,
+) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row sqliteInvitationRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				uuid, code_hash, created_by, roles, max_uses, uses, expires_at, created_at
+			 FROM
+				invitations
+			 WHERE
+				uuid = ?`,
+			uuid,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.ErrInvitationNotFound
+		} else if err != nil {
+			return err
+		}
+
+		i, err := mapInvitationFromSQLiteRow(row)
+		if err != nil {
+			return err
+		}
+
+		if err = updateFn(withTx(ctx, tx), i); err != nil {
+			return err
+		}
+
+		row, err = mapInvitationToSQLiteRow(i)
+		if err != nil {
+			return err
+		}
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				invitations
+			 SET
+				roles = ?,
+				max_uses = ?,
+				uses = ?,
+				expires_at = ?
+			 WHERE
+				uuid = ?`,
+			row.Roles, row.MaxUses, row.Uses, row.ExpiresAt, row.UUID,
+		)
+		return err
+	})
+}
+
+type sqliteInvitationRow struct {
+	UUID      string         `db:"uuid"`
+	CodeHash  []byte         `db:"code_hash"`
+	CreatedBy string         `db:"created_by"`
+	Roles     string         `db:"roles"`
+	MaxUses   int            `db:"max_uses"`
+	Uses      int            `db:"uses"`
+	ExpiresAt sql.NullString `db:"expires_at"`
+	CreatedAt string         `db:"created_at"`
+}
+
+func mapInvitationFromSQLiteRow(row sqliteInvitationRow) (*auth.Invitation, error) {
+	var roles []auth.Role
+	if err := json.Unmarshal([]byte(row.Roles), &roles); err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := timeFromSQLiteNull(row.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := timeFromSQLite(row.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewInvitationFromDB(
+		row.UUID,
+		row.CodeHash,
+		row.CreatedBy,
+		roles,
+		row.MaxUses,
+		row.Uses,
+		expiresAt,
+		createdAt,
+	)
+}
+
+func mapInvitationToSQLiteRow(i *auth.Invitation) (sqliteInvitationRow, error) {
+	roles := i.Roles
+	if roles == nil {
+		roles = []auth.Role{}
+	}
+
+	rolesJSON, err := json.Marshal(roles)
+	if err != nil {
+		return sqliteInvitationRow{}, err
+	}
+
+	return sqliteInvitationRow{
+		UUID:      i.UUID,
+		CodeHash:  i.CodeHash,
+		CreatedBy: i.CreatedBy,
+		Roles:     string(rolesJSON),
+		MaxUses:   i.MaxUses,
+		Uses:      i.Uses,
+		ExpiresAt: timeToSQLiteNull(i.ExpiresAt),
+		CreatedAt: timeToSQLite(i.CreatedAt),
+	}, nil
+}
@@ -0,0 +1,158 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type sqliteOutboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLiteOutboxRepository(db *sqlx.DB) auth.OutboxRepository {
+	return &sqliteOutboxRepository{
+		db: db,
+	}
+}
+
+// RelayOutbox takes the relay lease and publishes outside of a transaction,
+// so the database is not locked while relayFn waits for the network.
+func (r *sqliteOutboxRepository) RelayOutbox(ctx context.Context, limit int, relayFn auth.RelayFn) (_ int, err error) {
+	holder := uuid.NewString()
+	now := time.Now()
+
+	res, err := pgutils.Exec(
+		ctx, r.db,
+		`INSERT INTO
+			outbox_relay (id, holder, leased_until)
+		 VALUES
+			(1, ?, ?)
+		 ON CONFLICT (id) DO UPDATE SET
+			holder = excluded.holder,
+			leased_until = excluded.leased_until
+		 WHERE
+			outbox_relay.leased_until <= ?`,
+		holder, timeToSQLite(now.Add(outboxRelayLease)), timeToSQLite(now),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if aff == 0 {
+		return 0, nil
+	}
+
+	defer func() {
+		_, releaseErr := pgutils.Exec(
+			context.WithoutCancel(ctx), r.db,
+			`DELETE FROM outbox_relay WHERE holder = ?`,
+			holder,
+		)
+		err = errors.Join(err, releaseErr)
+	}()
+
+	var rows []sqliteOutboxMessageRow
+	err = pgutils.Select(
+		ctx, r.db, &rows,
+		`SELECT
+			id, type, aggregate_uuid, payload, occurred_at
+		 FROM
+			outbox
+		 ORDER BY
+			id
+		 LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	messages := make([]*auth.OutboxMessage, len(rows))
+	for i, row := range rows {
+		messages[i], err = mapOutboxMessageFromSQLiteRow(row)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	relayCtx, cancel := context.WithTimeout(ctx, outboxRelayTimeout)
+	defer cancel()
+
+	ids := relayFn(relayCtx, messages)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	query, args, err := sqlx.In(`DELETE FROM outbox WHERE id IN (?)`, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = pgutils.Exec(ctx, r.db, query, args...); err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+func saveSQLiteEvents(ctx context.Context, tx *sqlx.Tx, events []auth.Event) error {
+	for _, e := range events {
+		m, err := auth.NewOutboxMessage(e)
+		if err != nil {
+			return err
+		}
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`INSERT INTO
+				outbox (type, aggregate_uuid, payload, occurred_at)
+			 VALUES
+				(?, ?, ?, ?)`,
+			string(m.Type), m.AggregateUUID, string(m.Payload), timeToSQLite(m.OccurredAt),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type sqliteOutboxMessageRow struct {
+	ID            int64  `db:"id"`
+	Type          string `db:"type"`
+	AggregateUUID string `db:"aggregate_uuid"`
+	Payload       string `db:"payload"`
+	OccurredAt    string `db:"occurred_at"`
+}
+
+func mapOutboxMessageFromSQLiteRow(row sqliteOutboxMessageRow) (*auth.OutboxMessage, error) {
+	occurredAt, err := timeFromSQLite(row.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.OutboxMessage{
+		ID:            row.ID,
+		Type:          auth.EventType(row.Type),
+		AggregateUUID: row.AggregateUUID,
+		Payload:       []byte(row.Payload),
+		OccurredAt:    occurredAt,
+	}, nil
+}
@@ -0,0 +1,272 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type sqlitePersonalAccessTokensRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLitePersonalAccessTokensRepository(db *sqlx.DB) auth.PersonalAccessTokensRepository {
+	return &sqlitePersonalAccessTokensRepository{
+		db: db,
+	}
+}
+
+func (r *sqlitePersonalAccessTokensRepository) Save(ctx context.Context, t *auth.PersonalAccessToken) error {
+	row, err := mapPersonalAccessTokenToSQLiteRow(t)
+	if err != nil {
+		return err
+	}
+
+	_, err = pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`INSERT INTO
+			personal_access_tokens (uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at)
+		 VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		row.UUID, row.UserUUID, row.Name, row.Scopes, row.TokenHash,
+		row.ExpiresAt, row.LastUsedAt, row.LastUsedIP, row.CreatedAt,
+	)
+	if isSQLiteUniqueViolation(err) {
+		return auth.ErrPersonalAccessTokenAlreadyExists
+	}
+	return err
+}
+
+func (r *sqlitePersonalAccessTokensRepository) PersonalAccessToken(
+	ctx context.Context,
+	uuid string,
+) (*auth.PersonalAccessToken, error) {
+	var row sqlitePersonalAccessTokenRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at
+		 FROM
+			personal_access_tokens
+		 WHERE
+			uuid = ?`,
+		uuid,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapPersonalAccessTokenFromSQLiteRow(row)
+}
+
+func (r *sqlitePersonalAccessTokensRepository) PersonalAccessTokenByHash(
+	ctx context.Context,
+	hash []byte,
+) (*auth.PersonalAccessToken, error) {
+	var row sqlitePersonalAccessTokenRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at
+		 FROM
+			personal_access_tokens
+		 WHERE
+			token_hash = ?`,
+		hash,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapPersonalAccessTokenFromSQLiteRow(row)
+}
+
+func (r *sqlitePersonalAccessTokensRepository) UserPersonalAccessTokens(
+	ctx context.Context,
+	userUUID string,
+) ([]*auth.PersonalAccessToken, error) {
+	var rows []sqlitePersonalAccessTokenRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at
+		 FROM
+			personal_access_tokens
+		 WHERE
+			user_uuid = ?
+		 ORDER BY
+			created_at`,
+		userUUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*auth.PersonalAccessToken, 0, len(rows))
+	for _, row := range rows {
+		t, err := mapPersonalAccessTokenFromSQLiteRow(row)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	return tokens, nil
+}
+
+func (r *sqlitePersonalAccessTokensRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.PersonalAccessToken) error,
+) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row sqlitePersonalAccessTokenRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				uuid, user_uuid, name, scopes, token_hash, expires_at, last_used_at, last_used_ip, created_at
+			 FROM
+				personal_access_tokens
+			 WHERE
+				uuid = ?`,
+			uuid,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+		} else if err != nil {
+			return err
+		}
+
+		t, err := mapPersonalAccessTokenFromSQLiteRow(row)
+		if err != nil {
+			return err
+		}
+
+		if err = updateFn(withTx(ctx, tx), t); err != nil {
+			return err
+		}
+
+		row, err = mapPersonalAccessTokenToSQLiteRow(t)
+		if err != nil {
+			return err
+		}
+
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				personal_access_tokens
+			 SET
+				name = ?,
+				scopes = ?,
+				expires_at = ?,
+				last_used_at = ?,
+				last_used_ip = ?
+			 WHERE
+				uuid = ?`,
+			row.Name, row.Scopes, row.ExpiresAt, row.LastUsedAt, row.LastUsedIP, row.UUID,
+		)
+		return err
+	})
+}
+
+func (r *sqlitePersonalAccessTokensRepository) Delete(ctx context.Context, uuid string) error {
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			personal_access_tokens
+		 WHERE
+			uuid = ?`,
+		uuid,
+	)
+	if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+	}
+
+	return nil
+}
+
+// sqlitePersonalAccessTokenRow keeps the scopes as a JSON array and the times
+// as text.
+type sqlitePersonalAccessTokenRow struct {
+	UUID       string         `db:"uuid"`
+	UserUUID   string         `db:"user_uuid"`
+	Name       string         `db:"name"`
+	Scopes     string         `db:"scopes"`
+	TokenHash  []byte         `db:"token_hash"`
+	ExpiresAt  sql.NullString `db:"expires_at"`
+	LastUsedAt sql.NullString `db:"last_used_at"`
+	LastUsedIP sql.NullString `db:"last_used_ip"`
+	CreatedAt  string         `db:"created_at"`
+}
+
+func mapPersonalAccessTokenFromSQLiteRow(row sqlitePersonalAccessTokenRow) (*auth.PersonalAccessToken, error) {
+	var scopes []auth.Scope
+	if err := json.Unmarshal([]byte(row.Scopes), &scopes); err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := timeFromSQLiteNull(row.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	lastUsedAt, err := timeFromSQLiteNull(row.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := timeFromSQLite(row.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewPersonalAccessTokenFromDB(
+		row.UUID,
+		row.UserUUID,
+		row.Name,
+		scopes,
+		row.TokenHash,
+		expiresAt,
+		lastUsedAt,
+		row.LastUsedIP.String,
+		createdAt,
+	)
+}
+
+func mapPersonalAccessTokenToSQLiteRow(t *auth.PersonalAccessToken) (sqlitePersonalAccessTokenRow, error) {
+	scopes, err := json.Marshal(t.Scopes)
+	if err != nil {
+		return sqlitePersonalAccessTokenRow{}, err
+	}
+
+	return sqlitePersonalAccessTokenRow{
+		UUID:       t.UUID,
+		UserUUID:   t.UserUUID,
+		Name:       t.Name,
+		Scopes:     string(scopes),
+		TokenHash:  t.TokenHash,
+		ExpiresAt:  timeToSQLiteNull(t.ExpiresAt),
+		LastUsedAt: timeToSQLiteNull(t.LastUsedAt),
+		LastUsedIP: sql.NullString{String: t.LastUsedIP, Valid: t.LastUsedIP != ""},
+		CreatedAt:  timeToSQLite(t.CreatedAt),
+	}, nil
+}
@@ -0,0 +1,215 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type sqliteSessionsRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLiteSessionsRepository(db *sqlx.DB) auth.SessionsRepository {
+	return &sqliteSessionsRepository{
+		db: db,
+	}
+}
+
+func (r *sqliteSessionsRepository) Save(ctx context.Context, s *auth.Session) error {
+	row := mapSessionToSQLiteRow(s)
+	_, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`INSERT INTO
+			sessions (uuid, user_uuid, user_agent, ip, created_at, last_seen_at)
+		 VALUES
+			(?, ?, ?, ?, ?, ?)`,
+		row.UUID, row.UserUUID, row.UserAgent, row.IP, row.CreatedAt, row.LastSeenAt,
+	)
+	if isSQLiteUniqueViolation(err) {
+		return auth.ErrSessionAlreadyExists
+	}
+	return err
+}
+
+func (r *sqliteSessionsRepository) Session(ctx context.Context, uuid string) (*auth.Session, error) {
+	var row sqliteSessionRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, user_uuid, user_agent, ip, created_at, last_seen_at
+		 FROM
+			sessions
+		 WHERE
+			uuid = ?`,
+		uuid,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.SessionNotFound{SessionUUID: uuid}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapSessionFromSQLiteRow(row)
+}
+
+func (r *sqliteSessionsRepository) UserSessions(ctx context.Context, userUUID string) ([]*auth.Session, error) {
+	var rows []sqliteSessionRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			uuid, user_uuid, user_agent, ip, created_at, last_seen_at
+		 FROM
+			sessions
+		 WHERE
+			user_uuid = ?
+		 ORDER BY
+			last_seen_at DESC`,
+		userUUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*auth.Session, 0, len(rows))
+	for _, row := range rows {
+		s, err := mapSessionFromSQLiteRow(row)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, nil
+}
+
+func (r *sqliteSessionsRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.Session) error,
+) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row sqliteSessionRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				uuid, user_uuid, user_agent, ip, created_at, last_seen_at
+			 FROM
+				sessions
+			 WHERE
+				uuid = ?`,
+			uuid,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.SessionNotFound{SessionUUID: uuid}
+		} else if err != nil {
+			return err
+		}
+
+		s, err := mapSessionFromSQLiteRow(row)
+		if err != nil {
+			return err
+		}
+
+		if err = updateFn(withTx(ctx, tx), s); err != nil {
+			return err
+		}
+
+		row = mapSessionToSQLiteRow(s)
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				sessions
+			 SET
+				ip = ?,
+				last_seen_at = ?
+			 WHERE
+				uuid = ?`,
+			row.IP, row.LastSeenAt, row.UUID,
+		)
+		return err
+	})
+}
+
+func (r *sqliteSessionsRepository) Delete(ctx context.Context, uuid string) error {
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			sessions
+		 WHERE
+			uuid = ?`,
+		uuid,
+	)
+	if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return auth.SessionNotFound{SessionUUID: uuid}
+	}
+
+	return nil
+}
+
+func (r *sqliteSessionsRepository) DeleteUserSessions(ctx context.Context, userUUID string, exceptUUID string) error {
+	_, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			sessions
+		 WHERE
+			user_uuid = ? AND uuid <> ?`,
+		userUUID, exceptUUID,
+	)
+	return err
+}
+
+type sqliteSessionRow struct {
+	UUID       string `db:"uuid"`
+	UserUUID   string `db:"user_uuid"`
+	UserAgent  string `db:"user_agent"`
+	IP         string `db:"ip"`
+	CreatedAt  string `db:"created_at"`
+	LastSeenAt string `db:"last_seen_at"`
+}
+
+func mapSessionFromSQLiteRow(row sqliteSessionRow) (*auth.Session, error) {
+	createdAt, err := timeFromSQLite(row.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	lastSeenAt, err := timeFromSQLite(row.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewSessionFromDB(
+		row.UUID,
+		row.UserUUID,
+		row.UserAgent,
+		row.IP,
+		createdAt,
+		lastSeenAt,
+	)
+}
+
+func mapSessionToSQLiteRow(s *auth.Session) sqliteSessionRow {
+	return sqliteSessionRow{
+		UUID:       s.UUID,
+		UserUUID:   s.UserUUID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  timeToSQLite(s.CreatedAt),
+		LastSeenAt: timeToSQLite(s.LastSeenAt),
+	}
+}
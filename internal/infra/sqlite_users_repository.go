@@ -0,0 +1,440 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type sqliteUserRepository struct {
+	db *sqlx.DB
+}
+
+// NewSQLiteUserRepository keeps the users in a database opened with
+// OpenSQLite, and their events in its outbox.
+func NewSQLiteUserRepository(db *sqlx.DB) auth.UsersRepository {
+	return &sqliteUserRepository{
+		db: db,
+	}
+}
+
+func (r *sqliteUserRepository) Save(ctx context.Context, u *auth.User) error {
+	row, err := mapUserToSQLiteRow(u)
+	if err != nil {
+		return err
+	}
+
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		_, err := pgutils.Exec(
+			ctx, tx,
+			`INSERT INTO
+				users (
+					uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+					status, suspended_until, status_reason, status_changed_by, status_changed_at,
+					created_at, updated_at, version
+				)
+			 VALUES
+				(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			row.UUID, row.Email, row.EmailNormalized, row.Passhash, row.Roles, row.Profile, row.AvatarKey,
+			row.Status, row.SuspendedUntil, row.StatusReason, row.StatusChangedBy, row.StatusChangedAt,
+			row.CreatedAt, row.UpdatedAt, row.Version,
+		)
+		if isSQLiteUniqueViolation(err) {
+			return auth.ErrUserAlreadyExists
+		} else if err != nil {
+			return err
+		}
+
+		return saveSQLiteEvents(ctx, tx, u.PopEvents())
+	})
+}
+
+func (r *sqliteUserRepository) User(ctx context.Context, uuid string) (*auth.User, error) {
+	var row sqliteUserRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+			status, suspended_until, status_reason, status_changed_by, status_changed_at,
+			created_at, updated_at, version
+		 FROM
+			users
+		 WHERE
+			uuid = ?`,
+		uuid,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.UserNotFound{UserUUID: uuid}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapUserFromSQLiteRow(row)
+}
+
+func (r *sqliteUserRepository) UserByEmail(ctx context.Context, email auth.Email) (*auth.User, error) {
+	var row sqliteUserRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+			status, suspended_until, status_reason, status_changed_by, status_changed_at,
+			created_at, updated_at, version
+		 FROM
+			users
+		 WHERE
+			email_normalized = ?`,
+		email.Normalized(),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.UserEmailNotFound{Email: email.String()}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapUserFromSQLiteRow(row)
+}
+
+func (r *sqliteUserRepository) ListUsers(ctx context.Context, l auth.ListUsers) (auth.UsersPage, error) {
+	cursor, err := l.DecodeCursor()
+	if err != nil {
+		return auth.UsersPage{}, err
+	}
+
+	where, args := usersFilterToSQLite(l.Filter)
+
+	page := auth.UsersPage{Total: -1}
+	if l.WithTotal {
+		err = pgutils.Get(
+			ctx, conn(ctx, r.db), &page.Total,
+			`SELECT
+				COUNT(*)
+			 FROM
+				users
+			 WHERE
+				`+where,
+			args...,
+		)
+		if err != nil {
+			return auth.UsersPage{}, err
+		}
+	}
+
+	// The sort column is chosen from a fixed set, never taken from input.
+	column, op, dir := "created_at", ">", "ASC"
+	if l.Sort == auth.UsersByUpdatedAt {
+		column = "updated_at"
+	}
+	if l.Descending {
+		op, dir = "<", "DESC"
+	}
+
+	if cursor != nil {
+		args = append(args, timeToSQLite(cursor.Time), cursor.UUID)
+		where += fmt.Sprintf(` AND (%s, uuid) %s (?, ?)`, column, op)
+	}
+
+	args = append(args, l.Limit+1)
+	var rows []sqliteUserRow
+	err = pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		fmt.Sprintf(
+			`SELECT
+				uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+				status, suspended_until, status_reason, status_changed_by, status_changed_at,
+				created_at, updated_at, version
+			 FROM
+				users
+			 WHERE
+				%s
+			 ORDER BY
+				%s %s, uuid %s
+			 LIMIT ?`,
+			where, column, dir, dir,
+		),
+		args...,
+	)
+	if err != nil {
+		return auth.UsersPage{}, err
+	}
+
+	page.Users = make([]*auth.User, 0, len(rows))
+	for _, row := range rows {
+		u, err := mapUserFromSQLiteRow(row)
+		if err != nil {
+			return auth.UsersPage{}, err
+		}
+		page.Users = append(page.Users, u)
+	}
+
+	if len(page.Users) > l.Limit {
+		page.Users = page.Users[:l.Limit]
+		page.NextCursor = l.NextCursor(page.Users[len(page.Users)-1])
+	}
+
+	return page, nil
+}
+
+// usersFilterToSQLite is usersFilterToSQL with positional placeholders.
+func usersFilterToSQLite(f auth.UsersFilter) (string, []any) {
+	conds := []string{"TRUE"}
+	var args []any
+
+	if f.EmailContains != "" {
+		args = append(args, "%"+escapeLike(f.EmailContains)+"%")
+		conds = append(conds, `email_normalized LIKE ? ESCAPE '\'`)
+	}
+
+	if f.Role != "" {
+		args = append(args, string(f.Role))
+		conds = append(conds, `EXISTS (SELECT 1 FROM json_each(roles) WHERE value = ?)`)
+	}
+
+	if !f.CreatedFrom.IsZero() {
+		args = append(args, timeToSQLite(f.CreatedFrom))
+		conds = append(conds, `created_at >= ?`)
+	}
+
+	if !f.CreatedTo.IsZero() {
+		args = append(args, timeToSQLite(f.CreatedTo))
+		conds = append(conds, `created_at < ?`)
+	}
+
+	switch f.Status {
+	case auth.AccountActive:
+		args = append(args, timeToSQLite(f.Now))
+		conds = append(conds, `(status = 'active' OR status = 'suspended' AND suspended_until <= ?)`)
+	case auth.AccountSuspended:
+		args = append(args, timeToSQLite(f.Now))
+		conds = append(conds, `status = 'suspended' AND suspended_until > ?`)
+	case auth.AccountDisabled:
+		conds = append(conds, `status = 'disabled'`)
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// Update holds the write lock of the database until updateFn returns.
+func (r *sqliteUserRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.User) error,
+) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row sqliteUserRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				uuid, email, email_normalized, passhash, roles, profile, avatar_key,
+				status, suspended_until, status_reason, status_changed_by, status_changed_at,
+				created_at, updated_at, version
+			 FROM
+				users
+			 WHERE
+				uuid = ?`,
+			uuid,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.UserNotFound{UserUUID: uuid}
+		} else if err != nil {
+			return err
+		}
+
+		u, err := mapUserFromSQLiteRow(row)
+		if err != nil {
+			return err
+		}
+
+		version := u.Version
+		if err = updateFn(withTx(ctx, tx), u); err != nil {
+			return err
+		}
+
+		row, err = mapUserToSQLiteRow(u)
+		if err != nil {
+			return err
+		}
+
+		res, err := pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				users
+			 SET
+				email = ?,
+				email_normalized = ?,
+				passhash = ?,
+				roles = ?,
+				profile = ?,
+				avatar_key = ?,
+				status = ?,
+				suspended_until = ?,
+				status_reason = ?,
+				status_changed_by = ?,
+				status_changed_at = ?,
+				updated_at = ?,
+				version = version + 1
+			 WHERE
+				uuid = ? AND version = ?`,
+			row.Email, row.EmailNormalized, row.Passhash, row.Roles, row.Profile, row.AvatarKey,
+			row.Status, row.SuspendedUntil, row.StatusReason, row.StatusChangedBy, row.StatusChangedAt,
+			row.UpdatedAt, row.UUID, version,
+		)
+		if isSQLiteUniqueViolation(err) {
+			return auth.ErrUserAlreadyExists
+		} else if err != nil {
+			return err
+		}
+
+		aff, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if aff == 0 {
+			return auth.ErrConcurrentModification
+		}
+		u.Version = version + 1
+
+		return saveSQLiteEvents(ctx, tx, u.PopEvents())
+	})
+}
+
+func (r *sqliteUserRepository) Delete(ctx context.Context, uuid string) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		res, err := pgutils.Exec(
+			ctx, tx,
+			`DELETE FROM
+				users
+			 WHERE
+				uuid = ?`,
+			uuid,
+		)
+		if err != nil {
+			return err
+		}
+
+		aff, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if aff == 0 {
+			return auth.UserNotFound{UserUUID: uuid}
+		}
+
+		return saveSQLiteEvents(ctx, tx, []auth.Event{auth.UserDeleted{UserUUID: uuid}})
+	})
+}
+
+// sqliteUserRow keeps the roles as a JSON array and the times as text.
+type sqliteUserRow struct {
+	UUID            string         `db:"uuid"`
+	Email           string         `db:"email"`
+	EmailNormalized string         `db:"email_normalized"`
+	Passhash        []byte         `db:"passhash"`
+	Roles           string         `db:"roles"`
+	Profile         string         `db:"profile"`
+	AvatarKey       string         `db:"avatar_key"`
+	Status          string         `db:"status"`
+	SuspendedUntil  sql.NullString `db:"suspended_until"`
+	StatusReason    string         `db:"status_reason"`
+	StatusChangedBy string         `db:"status_changed_by"`
+	StatusChangedAt sql.NullString `db:"status_changed_at"`
+	CreatedAt       string         `db:"created_at"`
+	UpdatedAt       string         `db:"updated_at"`
+	Version         int64          `db:"version"`
+}
+
+func mapUserFromSQLiteRow(row sqliteUserRow) (*auth.User, error) {
+	var roles []auth.Role
+	if err := json.Unmarshal([]byte(row.Roles), &roles); err != nil {
+		return nil, err
+	}
+
+	var profile profileRow
+	if err := json.Unmarshal([]byte(row.Profile), &profile); err != nil {
+		return nil, err
+	}
+
+	suspendedUntil, err := timeFromSQLiteNull(row.SuspendedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	statusChangedAt, err := timeFromSQLiteNull(row.StatusChangedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := timeFromSQLite(row.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt, err := timeFromSQLite(row.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewUserFromDB(
+		row.UUID,
+		auth.NewEmailFromDB(row.Email, row.EmailNormalized),
+		row.Passhash,
+		roles,
+		auth.Profile(profile),
+		row.AvatarKey,
+		auth.AccountStatus{
+			State:     auth.AccountState(row.Status),
+			Until:     suspendedUntil,
+			Reason:    row.StatusReason,
+			ChangedBy: row.StatusChangedBy,
+			ChangedAt: statusChangedAt,
+		},
+		createdAt,
+		updatedAt,
+		row.Version,
+	)
+}
+
+func mapUserToSQLiteRow(u *auth.User) (sqliteUserRow, error) {
+	roles := make([]string, len(u.Roles))
+	for i, r := range u.Roles {
+		roles[i] = string(r)
+	}
+
+	rolesJSON, err := json.Marshal(roles)
+	if err != nil {
+		return sqliteUserRow{}, err
+	}
+
+	profile, err := json.Marshal(profileRow(u.Profile))
+	if err != nil {
+		return sqliteUserRow{}, err
+	}
+
+	return sqliteUserRow{
+		UUID:            u.UUID,
+		Email:           u.Email.String(),
+		EmailNormalized: u.Email.Normalized(),
+		Passhash:        u.Passhash,
+		Roles:           string(rolesJSON),
+		Profile:         string(profile),
+		AvatarKey:       u.AvatarKey,
+		Status:          string(u.Status.State),
+		SuspendedUntil:  timeToSQLiteNull(u.Status.Until),
+		StatusReason:    u.Status.Reason,
+		StatusChangedBy: u.Status.ChangedBy,
+		StatusChangedAt: timeToSQLiteNull(u.Status.ChangedAt),
+		CreatedAt:       timeToSQLite(u.CreatedAt),
+		UpdatedAt:       timeToSQLite(u.UpdatedAt),
+		Version:         u.Version,
+	}, nil
+}
@@ -0,0 +1,406 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type sqliteWebhooksRepository struct {
+	db *sqlx.DB
+}
+
+func NewSQLiteWebhooksRepository(db *sqlx.DB) auth.WebhooksRepository {
+	return &sqliteWebhooksRepository{
+		db: db,
+	}
+}
+
+func (r *sqliteWebhooksRepository) SaveSubscription(ctx context.Context, s *auth.WebhookSubscription) error {
+	row, err := mapWebhookSubscriptionToSQLiteRow(s)
+	if err != nil {
+		return err
+	}
+
+	_, err = pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`INSERT INTO
+			webhook_subscriptions (uuid, url, event_types, secret, created_by, created_at)
+		 VALUES
+			(?, ?, ?, ?, ?, ?)`,
+		row.UUID, row.URL, row.EventTypes, row.Secret, row.CreatedBy, row.CreatedAt,
+	)
+	if isSQLiteUniqueViolation(err) {
+		return auth.ErrWebhookSubscriptionAlreadyExists
+	}
+	return err
+}
+
+func (r *sqliteWebhooksRepository) Subscriptions(ctx context.Context) ([]*auth.WebhookSubscription, error) {
+	var rows []sqliteWebhookSubscriptionRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			uuid, url, event_types, secret, created_by, created_at
+		 FROM
+			webhook_subscriptions
+		 ORDER BY
+			created_at, uuid`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]*auth.WebhookSubscription, 0, len(rows))
+	for _, row := range rows {
+		s, err := mapWebhookSubscriptionFromSQLiteRow(row)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *sqliteWebhooksRepository) Subscription(ctx context.Context, uuid string) (*auth.WebhookSubscription, error) {
+	var row sqliteWebhookSubscriptionRow
+	err := pgutils.Get(
+		ctx, conn(ctx, r.db), &row,
+		`SELECT
+			uuid, url, event_types, secret, created_by, created_at
+		 FROM
+			webhook_subscriptions
+		 WHERE
+			uuid = ?`,
+		uuid,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrWebhookSubscriptionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return mapWebhookSubscriptionFromSQLiteRow(row)
+}
+
+func (r *sqliteWebhooksRepository) DeleteSubscription(ctx context.Context, uuid string) error {
+	res, err := pgutils.Exec(
+		ctx, conn(ctx, r.db),
+		`DELETE FROM
+			webhook_subscriptions
+		 WHERE
+			uuid = ?`,
+		uuid,
+	)
+	if err != nil {
+		return err
+	}
+
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if aff == 0 {
+		return auth.ErrWebhookSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (r *sqliteWebhooksRepository) SaveDeliveries(ctx context.Context, deliveries []*auth.WebhookDelivery) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		for _, d := range deliveries {
+			row := mapWebhookDeliveryToSQLiteRow(d)
+			_, err := pgutils.Exec(
+				ctx, tx,
+				`INSERT INTO
+					webhook_deliveries (
+						uuid, subscription_uuid, event_id, event_type, aggregate_uuid, payload, occurred_at,
+						state, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+						created_at
+					)
+				 VALUES
+					(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				 ON CONFLICT (subscription_uuid, event_id) DO NOTHING`,
+				row.UUID, row.SubscriptionUUID, row.EventID, row.EventType, row.AggregateUUID, row.Payload,
+				row.OccurredAt, row.State, row.Attempts, row.NextAttemptAt, row.LastAttemptAt,
+				row.LastStatusCode, row.LastError, row.CreatedAt,
+			)
+			if isSQLiteForeignKeyViolation(err) {
+				return auth.ErrWebhookSubscriptionNotFound
+			} else if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimDueDeliveries needs no row locks under the write lock.
+func (r *sqliteWebhooksRepository) ClaimDueDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*auth.WebhookDelivery, error) {
+	var rows []sqliteWebhookDeliveryRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`UPDATE
+			webhook_deliveries
+		 SET
+			next_attempt_at = ?
+		 WHERE
+			uuid IN (
+				SELECT
+					uuid
+				FROM
+					webhook_deliveries
+				WHERE
+					state = 'pending' AND next_attempt_at <= ?
+				ORDER BY
+					next_attempt_at
+				LIMIT ?
+			)
+		 RETURNING
+			uuid, subscription_uuid, event_id, event_type, aggregate_uuid, payload, occurred_at,
+			state, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+			created_at`,
+		timeToSQLite(now.Add(lease)), timeToSQLite(now), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapWebhookDeliveriesFromSQLiteRows(rows)
+}
+
+func (r *sqliteWebhooksRepository) UpdateDelivery(
+	ctx context.Context,
+	uuid string,
+	updateFn func(context.Context, *auth.WebhookDelivery) error,
+) error {
+	return runTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var row sqliteWebhookDeliveryRow
+		err := pgutils.Get(
+			ctx, tx, &row,
+			`SELECT
+				uuid, subscription_uuid, event_id, event_type, aggregate_uuid, payload, occurred_at,
+				state, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+				created_at
+			 FROM
+				webhook_deliveries
+			 WHERE
+				uuid = ?`,
+			uuid,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.ErrWebhookDeliveryNotFound
+		} else if err != nil {
+			return err
+		}
+
+		d, err := mapWebhookDeliveryFromSQLiteRow(row)
+		if err != nil {
+			return err
+		}
+
+		if err = updateFn(withTx(ctx, tx), d); err != nil {
+			return err
+		}
+
+		row = mapWebhookDeliveryToSQLiteRow(d)
+		_, err = pgutils.Exec(
+			ctx, tx,
+			`UPDATE
+				webhook_deliveries
+			 SET
+				state = ?,
+				attempts = ?,
+				next_attempt_at = ?,
+				last_attempt_at = ?,
+				last_status_code = ?,
+				last_error = ?
+			 WHERE
+				uuid = ?`,
+			row.State, row.Attempts, row.NextAttemptAt, row.LastAttemptAt,
+			row.LastStatusCode, row.LastError, row.UUID,
+		)
+		return err
+	})
+}
+
+func (r *sqliteWebhooksRepository) Deliveries(
+	ctx context.Context,
+	subscriptionUUID string,
+	state auth.WebhookDeliveryState,
+	limit int,
+) ([]*auth.WebhookDelivery, error) {
+	var rows []sqliteWebhookDeliveryRow
+	err := pgutils.Select(
+		ctx, conn(ctx, r.db), &rows,
+		`SELECT
+			uuid, subscription_uuid, event_id, event_type, aggregate_uuid, payload, occurred_at,
+			state, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+			created_at
+		 FROM
+			webhook_deliveries
+		 WHERE
+			subscription_uuid = ?1 AND (?2 = '' OR state = ?2)
+		 ORDER BY
+			created_at DESC, event_id DESC
+		 LIMIT ?3`,
+		subscriptionUUID, string(state), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapWebhookDeliveriesFromSQLiteRows(rows)
+}
+
+// sqliteWebhookSubscriptionRow keeps the event types as a JSON array and the
+// times as text.
+type sqliteWebhookSubscriptionRow struct {
+	UUID       string `db:"uuid"`
+	URL        string `db:"url"`
+	EventTypes string `db:"event_types"`
+	Secret     string `db:"secret"`
+	CreatedBy  string `db:"created_by"`
+	CreatedAt  string `db:"created_at"`
+}
+
+func mapWebhookSubscriptionFromSQLiteRow(row sqliteWebhookSubscriptionRow) (*auth.WebhookSubscription, error) {
+	var eventTypes []auth.EventType
+	if err := json.Unmarshal([]byte(row.EventTypes), &eventTypes); err != nil {
+		return nil, err
+	}
+
+	createdAt, err := timeFromSQLite(row.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewWebhookSubscriptionFromDB(
+		row.UUID,
+		row.URL,
+		eventTypes,
+		row.Secret,
+		row.CreatedBy,
+		createdAt,
+	)
+}
+
+func mapWebhookSubscriptionToSQLiteRow(s *auth.WebhookSubscription) (sqliteWebhookSubscriptionRow, error) {
+	eventTypes, err := json.Marshal(s.EventTypes)
+	if err != nil {
+		return sqliteWebhookSubscriptionRow{}, err
+	}
+
+	return sqliteWebhookSubscriptionRow{
+		UUID:       s.UUID,
+		URL:        s.URL,
+		EventTypes: string(eventTypes),
+		Secret:     s.Secret,
+		CreatedBy:  s.CreatedBy,
+		CreatedAt:  timeToSQLite(s.CreatedAt),
+	}, nil
+}
+
+type sqliteWebhookDeliveryRow struct {
+	UUID             string         `db:"uuid"`
+	SubscriptionUUID string         `db:"subscription_uuid"`
+	EventID          int64          `db:"event_id"`
+	EventType        string         `db:"event_type"`
+	AggregateUUID    string         `db:"aggregate_uuid"`
+	Payload          string         `db:"payload"`
+	OccurredAt       string         `db:"occurred_at"`
+	State            string         `db:"state"`
+	Attempts         int            `db:"attempts"`
+	NextAttemptAt    sql.NullString `db:"next_attempt_at"`
+	LastAttemptAt    sql.NullString `db:"last_attempt_at"`
+	LastStatusCode   int            `db:"last_status_code"`
+	LastError        string         `db:"last_error"`
+	CreatedAt        string         `db:"created_at"`
+}
+
+func mapWebhookDeliveriesFromSQLiteRows(rows []sqliteWebhookDeliveryRow) ([]*auth.WebhookDelivery, error) {
+	deliveries := make([]*auth.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		d, err := mapWebhookDeliveryFromSQLiteRow(row)
+		if err != nil {
+			return nil, err
+		}
+		deliveries[i] = d
+	}
+	return deliveries, nil
+}
+
+func mapWebhookDeliveryFromSQLiteRow(row sqliteWebhookDeliveryRow) (*auth.WebhookDelivery, error) {
+	occurredAt, err := timeFromSQLite(row.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	nextAttemptAt, err := timeFromSQLiteNull(row.NextAttemptAt)
+	if err != nil {
+		return nil, err
+	}
+
+	lastAttemptAt, err := timeFromSQLiteNull(row.LastAttemptAt)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := timeFromSQLite(row.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.WebhookDelivery{
+		UUID:             row.UUID,
+		SubscriptionUUID: row.SubscriptionUUID,
+		Event: auth.OutboxMessage{
+			ID:            row.EventID,
+			Type:          auth.EventType(row.EventType),
+			AggregateUUID: row.AggregateUUID,
+			Payload:       []byte(row.Payload),
+			OccurredAt:    occurredAt,
+		},
+		State:          auth.WebhookDeliveryState(row.State),
+		Attempts:       row.Attempts,
+		NextAttemptAt:  nextAttemptAt,
+		LastAttemptAt:  lastAttemptAt,
+		LastStatusCode: row.LastStatusCode,
+		LastError:      row.LastError,
+		CreatedAt:      createdAt,
+	}, nil
+}
+
+func mapWebhookDeliveryToSQLiteRow(d *auth.WebhookDelivery) sqliteWebhookDeliveryRow {
+	return sqliteWebhookDeliveryRow{
+		UUID:             d.UUID,
+		SubscriptionUUID: d.SubscriptionUUID,
+		EventID:          d.Event.ID,
+		EventType:        string(d.Event.Type),
+		AggregateUUID:    d.Event.AggregateUUID,
+		Payload:          string(d.Event.Payload),
+		OccurredAt:       timeToSQLite(d.Event.OccurredAt),
+		State:            string(d.State),
+		Attempts:         d.Attempts,
+		NextAttemptAt:    timeToSQLiteNull(d.NextAttemptAt),
+		LastAttemptAt:    timeToSQLiteNull(d.LastAttemptAt),
+		LastStatusCode:   d.LastStatusCode,
+		LastError:        d.LastError,
+		CreatedAt:        timeToSQLite(d.CreatedAt),
+	}
+}
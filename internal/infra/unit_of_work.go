@@ -0,0 +1,72 @@
+package infra
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zhikh23/pgutils"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+)
+
+type txContextKey struct{}
+
+// sqlUnitOfWork keeps the transaction in the context, where the Postgres
+// and SQLite repositories pick it up.
+type sqlUnitOfWork struct {
+	db *sqlx.DB
+}
+
+func NewPgUnitOfWork(db *sqlx.DB) decorator.UnitOfWork {
+	return &sqlUnitOfWork{
+		db: db,
+	}
+}
+
+func NewSQLiteUnitOfWork(db *sqlx.DB) decorator.UnitOfWork {
+	return &sqlUnitOfWork{
+		db: db,
+	}
+}
+
+func (u *sqlUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runTx(ctx, u.db, func(tx *sqlx.Tx) error {
+		return fn(withTx(ctx, tx))
+	})
+}
+
+func withTx(ctx context.Context, tx *sqlx.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// conn is the transaction of the unit of work in ctx, if any, or db.
+func conn(ctx context.Context, db *sqlx.DB) sqlx.ExtContext {
+	if tx, ok := ctx.Value(txContextKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// runTx runs fn in a new transaction or, within a unit of work, in a
+// savepoint of its transaction.
+func runTx(ctx context.Context, db *sqlx.DB, fn pgutils.TxFunc) (err error) {
+	tx, ok := ctx.Value(txContextKey{}).(*sqlx.Tx)
+	if !ok {
+		return pgutils.RunTx(ctx, db, fn)
+	}
+
+	if _, err = pgutils.Exec(ctx, tx, `SAVEPOINT unit_of_work`); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_, rollbackErr := pgutils.Exec(ctx, tx, `ROLLBACK TO SAVEPOINT unit_of_work`)
+			err = errors.Join(err, rollbackErr)
+		} else {
+			_, err = pgutils.Exec(ctx, tx, `RELEASE SAVEPOINT unit_of_work`)
+		}
+	}()
+
+	return fn(tx)
+}
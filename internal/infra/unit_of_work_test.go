@@ -0,0 +1,117 @@
+package infra_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestPgUnitOfWork(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	users := infra.NewPgUserRepository(db)
+	uow := infra.NewPgUnitOfWork(db)
+	testUnitOfWork(t, users, uow)
+}
+
+func TestSQLiteUnitOfWork(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	users := infra.NewSQLiteUserRepository(db)
+	uow := infra.NewSQLiteUnitOfWork(db)
+	testUnitOfWork(t, users, uow)
+}
+
+func testUnitOfWork(t *testing.T, users auth.UsersRepository, uow decorator.UnitOfWork) {
+	t.Run("should roll back every change if the work fails", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		user := fakeUser()
+		other := fakeUser()
+		errFailed := errors.New("failed")
+
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Save(ctx, user))
+			require.NoError(t, users.Save(ctx, other))
+
+			// The changes are visible within the unit of work only.
+			_, err := users.User(ctx, user.UUID)
+			require.NoError(t, err)
+			_, err = users.User(context.Background(), user.UUID)
+			require.ErrorAs(t, err, &auth.UserNotFound{})
+
+			return errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+
+		for _, u := range []*auth.User{user, other} {
+			_, err = users.User(ctx, u.UUID)
+			require.ErrorAs(t, err, &auth.UserNotFound{})
+		}
+	})
+
+	t.Run("should go on after a failed repository call", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		user := fakeUser()
+		other := fakeUser()
+
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Save(ctx, user))
+			require.ErrorIs(t, users.Save(ctx, user), auth.ErrUserAlreadyExists)
+			return users.Save(ctx, other)
+		})
+		require.NoError(t, err)
+
+		for _, u := range []*auth.User{user, other} {
+			_, err = users.User(ctx, u.UUID)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("should roll back a nested unit of work on its own", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		user := fakeUser()
+		other := fakeUser()
+		errFailed := errors.New("failed")
+
+		err := uow.Do(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Save(ctx, user))
+
+			err := uow.Do(ctx, func(ctx context.Context) error {
+				require.NoError(t, users.Save(ctx, other))
+				return errFailed
+			})
+			require.ErrorIs(t, err, errFailed)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = users.User(ctx, user.UUID)
+		require.NoError(t, err)
+		_, err = users.User(ctx, other.UUID)
+		require.ErrorAs(t, err, &auth.UserNotFound{})
+	})
+}
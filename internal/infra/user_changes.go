@@ -0,0 +1,95 @@
+package infra
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/zhikh23/pgutils"
+)
+
+// UserChanges tells every replica which users changed.
+type UserChanges interface {
+	// Announce reaches the replicas once the transaction of ctx, if any,
+	// commits.
+	Announce(ctx context.Context, uuid string) error
+}
+
+type localUserChanges struct{}
+
+// NewLocalUserChanges announces nothing, for a single replica.
+func NewLocalUserChanges() UserChanges {
+	return localUserChanges{}
+}
+
+func (localUserChanges) Announce(context.Context, string) error {
+	return nil
+}
+
+const userChangesChannel = "user_changes"
+
+type pgUserChanges struct {
+	db *sqlx.DB
+}
+
+func NewPgUserChanges(db *sqlx.DB) UserChanges {
+	if db == nil {
+		panic("db is nil")
+	}
+
+	return &pgUserChanges{db: db}
+}
+
+func (c *pgUserChanges) Announce(ctx context.Context, uuid string) error {
+	_, err := pgutils.Exec(ctx, conn(ctx, c.db), `SELECT pg_notify($1, $2)`, userChangesChannel, uuid)
+	return err
+}
+
+const userChangesPingInterval = 90 * time.Second
+
+// ListenPgUserChanges invalidates the users announced by any replica until
+// stop is called, and every user on reconnect.
+func ListenPgUserChanges(
+	dsn string,
+	invalidator UserCacheInvalidator,
+	logger *slog.Logger,
+) (stop func(), err error) {
+	l := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn("User changes listener failed", "error", err.Error())
+		}
+	})
+	if err = l.Listen(userChangesChannel); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ctx := context.Background()
+		for {
+			select {
+			case n, ok := <-l.Notify:
+				if !ok {
+					return
+				}
+				if n == nil {
+					invalidator.InvalidateAllUsers(ctx)
+				} else {
+					invalidator.InvalidateUser(ctx, n.Extra)
+				}
+			case <-time.After(userChangesPingInterval):
+				go func() { _ = l.Ping() }()
+			}
+		}
+	}()
+
+	return func() {
+		_ = l.Close()
+		<-done
+	}, nil
+}
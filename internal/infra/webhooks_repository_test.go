@@ -0,0 +1,126 @@
+package infra_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+func TestPgWebhooksRepository(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	url := os.Getenv("DATABASE_URI")
+	db := sqlx.MustConnect("postgres", url)
+	t.Cleanup(func() {
+		err := db.Close()
+		require.NoError(t, err)
+	})
+
+	testWebhooksRepository(t, infra.NewPgWebhooksRepository(db))
+}
+
+func TestSQLiteWebhooksRepository(t *testing.T) {
+	testWebhooksRepository(t, infra.NewSQLiteWebhooksRepository(newSQLiteDB(t)))
+}
+
+func testWebhooksRepository(t *testing.T, webhooks auth.WebhooksRepository) {
+	newSubscription := func(t *testing.T) *auth.WebhookSubscription {
+		s, err := auth.NewWebhookSubscription(
+			gofakeit.UUID(), "https://example.com/hooks",
+			[]auth.EventType{auth.EventUserRegistered}, "whsec_test", gofakeit.UUID(),
+		)
+		require.NoError(t, err)
+		return s
+	}
+
+	newMessage := func(t *testing.T) *auth.OutboxMessage {
+		m, err := auth.NewOutboxMessage(auth.UserRegistered{UserUUID: gofakeit.UUID(), Email: gofakeit.Email()})
+		require.NoError(t, err)
+		m.ID = gofakeit.Int64()
+		return m
+	}
+
+	t.Run("should save deliveries once and claim them", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		s := newSubscription(t)
+		require.NoError(t, webhooks.SaveSubscription(ctx, s))
+		require.ErrorIs(t, webhooks.SaveSubscription(ctx, s), auth.ErrWebhookSubscriptionAlreadyExists)
+
+		m := newMessage(t)
+		d := auth.NewWebhookDelivery(gofakeit.UUID(), s, m)
+		require.NoError(t, webhooks.SaveDeliveries(ctx, []*auth.WebhookDelivery{d}))
+		again := auth.NewWebhookDelivery(gofakeit.UUID(), s, m)
+		require.NoError(t, webhooks.SaveDeliveries(ctx, []*auth.WebhookDelivery{again}))
+
+		deliveries, err := webhooks.Deliveries(ctx, s.UUID, "", auth.MaxWebhookDeliveriesLimit)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, d.UUID, deliveries[0].UUID)
+		require.JSONEq(t, string(m.Payload), string(deliveries[0].Event.Payload))
+
+		// Other tests claim deliveries concurrently, so only this one is
+		// looked for.
+		claimedBy := func(claimed []*auth.WebhookDelivery) bool {
+			for _, c := range claimed {
+				if c.UUID == d.UUID {
+					return true
+				}
+			}
+			return false
+		}
+
+		now := time.Now().Add(time.Second)
+		claimed, err := webhooks.ClaimDueDeliveries(ctx, now, time.Minute, 1000)
+		require.NoError(t, err)
+		require.True(t, claimedBy(claimed))
+
+		claimed, err = webhooks.ClaimDueDeliveries(ctx, now, time.Minute, 1000)
+		require.NoError(t, err)
+		require.False(t, claimedBy(claimed))
+
+		err = webhooks.UpdateDelivery(ctx, d.UUID, func(_ context.Context, d *auth.WebhookDelivery) error {
+			d.Succeed(204, now)
+			return nil
+		})
+		require.NoError(t, err)
+
+		deliveries, err = webhooks.Deliveries(ctx, s.UUID, auth.WebhookDeliverySucceeded, auth.MaxWebhookDeliveriesLimit)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, 204, deliveries[0].LastStatusCode)
+	})
+
+	t.Run("should delete deliveries with the subscription", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		s := newSubscription(t)
+		require.NoError(t, webhooks.SaveSubscription(ctx, s))
+		d := auth.NewWebhookDelivery(gofakeit.UUID(), s, newMessage(t))
+		require.NoError(t, webhooks.SaveDeliveries(ctx, []*auth.WebhookDelivery{d}))
+
+		require.NoError(t, webhooks.DeleteSubscription(ctx, s.UUID))
+		require.ErrorIs(t, webhooks.DeleteSubscription(ctx, s.UUID), auth.ErrWebhookSubscriptionNotFound)
+
+		err := webhooks.UpdateDelivery(ctx, d.UUID, func(context.Context, *auth.WebhookDelivery) error {
+			return errors.New("unexpected call")
+		})
+		require.ErrorIs(t, err, auth.ErrWebhookDeliveryNotFound)
+
+		err = webhooks.SaveDeliveries(ctx, []*auth.WebhookDelivery{auth.NewWebhookDelivery(gofakeit.UUID(), s, newMessage(t))})
+		require.ErrorIs(t, err, auth.ErrWebhookSubscriptionNotFound)
+	})
+}
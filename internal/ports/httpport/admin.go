@@ -0,0 +1,329 @@
+package httpport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/ports/userimport"
+)
+
+var errAdminRequired = errors.New("admin role is required")
+
+// requireAdmin writes 403 and returns false if the principal is not an admin.
+func requireAdmin(w http.ResponseWriter, r *http.Request) (query.Principal, bool) {
+	principal := principalFromContext(r.Context())
+	if !principal.IsAdmin() {
+		httpError(w, r, errAdminRequired, http.StatusForbidden)
+		return query.Principal{}, false
+	}
+	return principal, true
+}
+
+func (s Server) ListUsers(w http.ResponseWriter, r *http.Request, params ListUsersParams) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	q := query.ListUsers{
+		EmailContains: stringFromAPI(params.Email),
+		Role:          stringFromAPI(params.Role),
+		Cursor:        stringFromAPI(params.Cursor),
+	}
+	if params.CreatedFrom != nil {
+		q.CreatedFrom = *params.CreatedFrom
+	}
+	if params.CreatedTo != nil {
+		q.CreatedTo = *params.CreatedTo
+	}
+	if params.Status != nil {
+		q.Status = string(*params.Status)
+	}
+	if params.Sort != nil {
+		q.Sort = string(*params.Sort)
+	}
+	if params.Order != nil {
+		q.Descending = *params.Order == Desc
+	}
+	if params.Limit != nil {
+		q.Limit = *params.Limit
+	}
+	if params.WithTotal != nil {
+		q.WithTotal = *params.WithTotal
+	}
+
+	page, err := s.app.Queries.ListUsers.Handle(r.Context(), q)
+	if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, mapUsersPageToAPI(page))
+}
+
+func mapUsersPageToAPI(page query.UsersPage) UsersPage {
+	users := make([]User, len(page.Users))
+	for i, u := range page.Users {
+		users[i] = mapUserToAPI(u)
+	}
+
+	res := UsersPage{
+		Users:      users,
+		NextCursor: stringToAPI(page.NextCursor),
+	}
+	if page.Total >= 0 {
+		res.Total = &page.Total
+	}
+
+	return res
+}
+
+func (s Server) GetUserStatus(w http.ResponseWriter, r *http.Request, uuid string) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	s.renderUserStatus(w, r, uuid)
+}
+
+func (s Server) ChangeUserStatus(w http.ResponseWriter, r *http.Request, uuid string, params ChangeUserStatusParams) {
+	principal, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	expectedVersion, err := versionFromIfMatch(params.IfMatch)
+	if err != nil {
+		httpError(w, r, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	var putStatus PutAccountStatus
+	if err = render.Decode(r, &putStatus); err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	var until time.Time
+	if putStatus.Until != nil {
+		until = *putStatus.Until
+	}
+
+	err = s.app.Commands.ChangeUserStatus.Handle(r.Context(), command.ChangeUserStatus{
+		UserUUID:        uuid,
+		Status:          string(putStatus.Status),
+		Until:           until,
+		Reason:          putStatus.Reason,
+		ChangedBy:       principal.UserUUID,
+		ExpectedVersion: expectedVersion,
+	})
+	if errors.As(err, &auth.UserNotFound{}) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if errors.Is(err, auth.ErrConcurrentModification) {
+		httpError(w, r, err, http.StatusPreconditionFailed)
+		return
+	} else if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.renderUserStatus(w, r, uuid)
+}
+
+func (s Server) renderUserStatus(w http.ResponseWriter, r *http.Request, uuid string) {
+	user, err := s.app.Queries.GetUser.Handle(r.Context(), query.GetUser{
+		UserUUID: uuid,
+	})
+	if errors.As(err, &auth.UserNotFound{}) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", versionToETag(user.Version))
+	render.JSON(w, r, mapAccountStatusToAPI(user.Status))
+}
+
+func mapAccountStatusToAPI(s query.AccountStatus) AccountStatus {
+	return AccountStatus{
+		Status:    AccountState(s.State),
+		Until:     timeToAPI(s.Until),
+		Reason:    stringToAPI(s.Reason),
+		ChangedBy: stringToAPI(s.ChangedBy),
+		ChangedAt: timeToAPI(s.ChangedAt),
+	}
+}
+
+func (s Server) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var postInvitation PostInvitation
+	if err := render.Decode(r, &postInvitation); err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	code, err := auth.NewInvitationCode()
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	var expiresAt time.Time
+	if postInvitation.ExpiresAt != nil {
+		expiresAt = *postInvitation.ExpiresAt
+	}
+
+	var roles []string
+	if postInvitation.Roles != nil {
+		roles = *postInvitation.Roles
+	}
+
+	invitationUUID := uuid.NewString()
+	err = s.app.Commands.CreateInvitation.Handle(r.Context(), command.CreateInvitation{
+		UUID:      invitationUUID,
+		Code:      code,
+		CreatedBy: principal.UserUUID,
+		Roles:     roles,
+		MaxUses:   postInvitation.MaxUses,
+		ExpiresAt: expiresAt,
+	})
+	if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, CreatedInvitation{
+		Uuid: invitationUUID,
+		Code: code,
+	})
+}
+
+// maxImportSize bounds the import file. Larger imports are split into
+// several files.
+const maxImportSize = 32 << 20
+
+func (s Server) ImportUsers(w http.ResponseWriter, r *http.Request, params ImportUsersParams) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	format, err := userimport.ParseFormat(params.Format)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	dryRun := params.DryRun != nil && *params.DryRun
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	report, err := userimport.Run(r.Context(), s.app.Commands.ImportUser, body, format, dryRun)
+	if err != nil {
+		err = fmt.Errorf("%w: stopped after %d imported rows", err, report.Imported)
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	render.JSON(w, r, mapImportReportToAPI(report))
+}
+
+func (s Server) ListUserSessions(w http.ResponseWriter, r *http.Request, uuid string) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	sessions, err := s.app.Queries.Sessions.Handle(r.Context(), query.Sessions{
+		UserUUID: uuid,
+	})
+	if errors.As(err, &auth.UserNotFound{}) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, mapSessionsToAPI(sessions, ""))
+}
+
+func (s Server) RevokeUserSessions(w http.ResponseWriter, r *http.Request, uuid string) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	err := s.app.Commands.RevokeUserSessions.Handle(r.Context(), command.RevokeUserSessions{
+		UserUUID: uuid,
+	})
+	if errors.As(err, &auth.UserNotFound{}) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) RevokeUserSession(w http.ResponseWriter, r *http.Request, uuid string, sessionUuid string) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	err := s.app.Commands.RevokeSession.Handle(r.Context(), command.RevokeSession{
+		UserUUID:    uuid,
+		SessionUUID: sessionUuid,
+	})
+	if errors.As(err, &auth.SessionNotFound{}) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func mapImportReportToAPI(report userimport.Report) ImportReport {
+	rows := make([]ImportRow, len(report.Rows))
+	for i, row := range report.Rows {
+		rows[i] = ImportRow{
+			Line:  row.Line,
+			Uuid:  stringToAPI(row.UUID),
+			Email: stringToAPI(row.Email),
+			Error: stringToAPI(row.Error),
+		}
+	}
+
+	return ImportReport{
+		DryRun:   report.DryRun,
+		Imported: report.Imported,
+		Failed:   report.Failed,
+		Rows:     rows,
+	}
+}
@@ -0,0 +1,130 @@
+package httpport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+)
+
+func (s Server) ListAuditEvents(w http.ResponseWriter, r *http.Request, params ListAuditEventsParams) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	q := query.ListAuditEvents{
+		ActorUUID:  stringFromAPI(params.Actor),
+		TargetUUID: stringFromAPI(params.Target),
+		Action:     stringFromAPI(params.Action),
+		Cursor:     stringFromAPI(params.Cursor),
+	}
+	if params.From != nil {
+		q.From = *params.From
+	}
+	if params.To != nil {
+		q.To = *params.To
+	}
+	if params.Limit != nil {
+		q.Limit = *params.Limit
+	}
+
+	page, err := s.app.Queries.ListAuditEvents.Handle(r.Context(), q)
+	if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	events := make([]AuditEvent, len(page.Events))
+	for i, e := range page.Events {
+		events[i] = mapAuditEventToAPI(e)
+	}
+
+	render.JSON(w, r, AuditEventsPage{
+		Events:     events,
+		NextCursor: stringToAPI(page.NextCursor),
+	})
+}
+
+func (s Server) VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	v, err := s.app.Queries.VerifyAuditLog.Handle(r.Context(), query.VerifyAuditLog{})
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	res := AuditLogVerification{
+		Intact:  v.BrokenAt == 0,
+		Checked: v.Checked,
+	}
+	if v.BrokenAt != 0 {
+		res.BrokenAt = &v.BrokenAt
+	}
+
+	render.JSON(w, r, res)
+}
+
+func (s Server) ListSecurityEvents(w http.ResponseWriter, r *http.Request, params ListSecurityEventsParams) {
+	principal := principalFromContext(r.Context())
+
+	q := query.ListAuditEvents{
+		TargetUUID: principal.UserUUID,
+		Cursor:     stringFromAPI(params.Cursor),
+	}
+	if params.Limit != nil {
+		q.Limit = *params.Limit
+	}
+
+	page, err := s.app.Queries.ListAuditEvents.Handle(r.Context(), q)
+	if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	events := make([]SecurityEvent, len(page.Events))
+	for i, e := range page.Events {
+		events[i] = SecurityEvent{
+			Action:    e.Action,
+			Ip:        stringToAPI(e.IP),
+			UserAgent: stringToAPI(e.UserAgent),
+			CreatedAt: e.CreatedAt,
+		}
+	}
+
+	render.JSON(w, r, SecurityEventsPage{
+		Events:     events,
+		NextCursor: stringToAPI(page.NextCursor),
+	})
+}
+
+func mapAuditEventToAPI(e query.AuditEvent) AuditEvent {
+	details := e.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	return AuditEvent{
+		Seq:        e.Seq,
+		Action:     e.Action,
+		ActorUuid:  stringToAPI(e.ActorUUID),
+		TargetUuid: stringToAPI(e.TargetUUID),
+		Ip:         stringToAPI(e.IP),
+		UserAgent:  stringToAPI(e.UserAgent),
+		RequestId:  stringToAPI(e.RequestID),
+		Details:    details,
+		CreatedAt:  e.CreatedAt,
+		Hash:       e.Hash,
+	}
+}
@@ -0,0 +1,146 @@
+package httpport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type ctxKey int
+
+const (
+	principalCtxKey ctxKey = iota
+)
+
+var (
+	errMissingBearerToken = errors.New("missing bearer token")
+	errInsufficientScope  = errors.New("insufficient token scope")
+)
+
+// authMiddleware authenticates requests to operations secured by bearerAuth
+// and leaves the other operations untouched.
+func (s Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(BearerAuthScopes).([]string); !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			httpError(w, r, errMissingBearerToken, http.StatusUnauthorized)
+			return
+		}
+
+		principal, ok := s.authenticate(w, r, token)
+		if !ok {
+			return
+		}
+
+		info := auth.RequestInfoFromContext(r.Context())
+		info.ActorUUID = principal.UserUUID
+
+		ctx := context.WithValue(r.Context(), principalCtxKey, principal)
+		ctx = auth.ContextWithRequestInfo(ctx, info)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// optionalPrincipal authenticates the bearer token of an operation which
+// does not require one. Without a token, the principal is zero.
+func (s Server) optionalPrincipal(w http.ResponseWriter, r *http.Request) (query.Principal, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return query.Principal{}, true
+	}
+	return s.authenticate(w, r, token)
+}
+
+// authenticate writes the error response and returns false if token is not
+// accepted for the request.
+func (s Server) authenticate(w http.ResponseWriter, r *http.Request, token string) (query.Principal, bool) {
+	principal, err := s.app.Queries.AuthenticateToken.Handle(r.Context(), query.AuthenticateToken{
+		Token: token,
+		IP:    clientIP(r),
+	})
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenExpired) {
+		httpError(w, r, err, http.StatusUnauthorized)
+		return query.Principal{}, false
+	} else if errors.As(err, &auth.AccountInactiveError{}) {
+		httpError(w, r, err, http.StatusForbidden)
+		return query.Principal{}, false
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return query.Principal{}, false
+	}
+
+	if !principal.HasScope(requiredScope(r)) {
+		httpError(w, r, errInsufficientScope, http.StatusForbidden)
+		return query.Principal{}, false
+	}
+
+	return principal, true
+}
+
+// requestInfoMiddleware describes the client for the audit log. The actor is
+// added by authMiddleware.
+func requestInfoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := auth.ContextWithRequestInfo(r.Context(), auth.RequestInfo{
+			IP:        clientIP(r),
+			UserAgent: r.UserAgent(),
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func principalFromContext(ctx context.Context) query.Principal {
+	p, ok := ctx.Value(principalCtxKey).(query.Principal)
+	if !ok {
+		panic("principal is missing in context: operation is not secured")
+	}
+	return p
+}
+
+// principalUserUUID identifies the user for per-user rate limits.
+func principalUserUUID(r *http.Request) (string, bool) {
+	p, ok := r.Context().Value(principalCtxKey).(query.Principal)
+	return p.UserUUID, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func requiredScope(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return string(auth.ScopeRead)
+	default:
+		return string(auth.ScopeWrite)
+	}
+}
+
+// clientIP returns the address set by middleware.RealIP or the peer address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
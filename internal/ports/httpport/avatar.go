@@ -0,0 +1,144 @@
+package httpport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+const (
+	avatarFormField = "image"
+	// maxAvatarFormOverhead leaves room for the multipart headers and
+	// boundaries around the image.
+	maxAvatarFormOverhead = 64 << 10
+)
+
+var (
+	errAvatarMissing  = errors.New("expected image form field")
+	errAvatarTooLarge = fmt.Errorf("expected image of at most %d bytes", auth.MaxAvatarUploadBytes)
+)
+
+func (s Server) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	principal := principalFromContext(r.Context())
+
+	image, err := readAvatarForm(w, r)
+	if errors.Is(err, errAvatarTooLarge) {
+		httpError(w, r, err, http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.app.Commands.UploadAvatar.Handle(r.Context(), command.UploadAvatar{
+		UserUUID: principal.UserUUID,
+		Image:    image,
+	})
+	if errors.As(err, &auth.UserNotFound{}) {
+		httpError(w, r, auth.ErrInvalidToken, http.StatusUnauthorized)
+		return
+	} else if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.GetCurrentUser(w, r)
+}
+
+// readAvatarForm streams the multipart form instead of parsing it.
+func readAvatarForm(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, auth.MaxAvatarUploadBytes+maxAvatarFormOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, commonerrs.NewInvalidInputError("expected multipart/form-data body")
+	}
+
+	for {
+		part, err := mr.NextPart()
+		var maxBytesErr *http.MaxBytesError
+		if errors.Is(err, io.EOF) {
+			return nil, errAvatarMissing
+		} else if errors.As(err, &maxBytesErr) {
+			return nil, errAvatarTooLarge
+		} else if err != nil {
+			return nil, commonerrs.NewInvalidInputError("malformed multipart/form-data body")
+		}
+
+		if part.FormName() != avatarFormField {
+			continue
+		}
+
+		image, err := io.ReadAll(io.LimitReader(part, auth.MaxAvatarUploadBytes+1))
+		if errors.As(err, &maxBytesErr) || len(image) > auth.MaxAvatarUploadBytes {
+			return nil, errAvatarTooLarge
+		} else if err != nil {
+			return nil, commonerrs.NewInvalidInputError("malformed multipart/form-data body")
+		}
+
+		return image, nil
+	}
+}
+
+// GetUserAvatar lets the versioned path be cached for good.
+func (s Server) GetUserAvatar(w http.ResponseWriter, r *http.Request, uuid string, params GetUserAvatarParams) {
+	avatar, err := s.app.Queries.GetAvatar.Handle(r.Context(), query.GetAvatar{
+		UserUUID: uuid,
+	})
+	if errors.As(err, &auth.UserNotFound{}) || errors.Is(err, auth.ErrAvatarNotFound) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	version := avatarVersion(avatar.Key)
+	if stringFromAPI(params.V) == version {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "public, no-cache")
+	}
+	w.Header().Set("ETag", `"`+version+`"`)
+	w.Header().Set("Content-Type", avatar.ContentType)
+
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(avatar.Data))
+}
+
+// avatarVersion is derived from the key, which changes with the content.
+func avatarVersion(key string) string {
+	return strings.TrimSuffix(path.Base(key), path.Ext(key))
+}
+
+func avatarToAPI(userUUID string, key string) *string {
+	return stringToAPI(avatarPath(userUUID, key))
+}
+
+func avatarPath(userUUID string, key string) string {
+	if key == "" {
+		return ""
+	}
+	return fmt.Sprintf("/users/%s/avatar?v=%s", userUUID, avatarVersion(key))
+}
+
+// userPicture is the uploaded avatar, which takes precedence over the avatar
+// URL of the profile.
+func userPicture(user query.User) string {
+	if path := avatarPath(user.UUID, user.AvatarKey); path != "" {
+		return path
+	}
+	return user.Profile.AvatarURL
+}
@@ -0,0 +1,26 @@
+package httpport
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+)
+
+func TestUserPicture(t *testing.T) {
+	user := query.User{
+		UUID:    "0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b",
+		Profile: query.Profile{AvatarURL: "https://example.com/avatar.png"},
+	}
+	require.Equal(t, "https://example.com/avatar.png", userPicture(user))
+	require.Equal(t, "https://example.com/avatar.png", mapUserToClaims(user).Picture)
+
+	user.AvatarKey = "avatars/0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b/5d41402abc4b2a76.png"
+	want := "/users/0190a5c2-7b3e-7d4f-9a1b-2c3d4e5f6a7b/avatar?v=5d41402abc4b2a76"
+	require.Equal(t, want, userPicture(user))
+	require.Equal(t, want, mapUserToClaims(user).Picture)
+
+	user.Profile.AvatarURL = ""
+	require.Equal(t, want, userPicture(user))
+}
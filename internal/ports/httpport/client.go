@@ -1,8 +1,14 @@
 package httpport
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/render"
 
@@ -32,13 +38,56 @@ func MustNewHTTPAuthClient(addr string) *HTTPAuthClient {
 }
 
 func (c *HTTPAuthClient) RegisterUser(ctx context.Context, uuid string, email string, password string) (*http.Response, error) {
-	return c.client.RegisterUser(ctx, auth.RegisterUserJSONRequestBody{
-		Uuid:     uuid,
+	return c.client.RegisterUser(ctx, &auth.RegisterUserParams{}, auth.RegisterUserJSONRequestBody{
+		Uuid:     &uuid,
 		Email:    email,
 		Password: password,
 	})
 }
 
+// RegisterUserIdempotently lets the server generate the UUID, which is
+// returned.
+func (c *HTTPAuthClient) RegisterUserIdempotently(
+	ctx context.Context,
+	idempotencyKey string,
+	email string,
+	password string,
+) (auth.RegisteredUser, *http.Response, error) {
+	res, err := c.client.RegisterUser(ctx, &auth.RegisterUserParams{
+		IdempotencyKey: &idempotencyKey,
+	}, auth.RegisterUserJSONRequestBody{
+		Email:    email,
+		Password: password,
+	})
+	if err != nil {
+		return auth.RegisteredUser{}, res, err
+	}
+
+	var registered auth.RegisteredUser
+	if res.StatusCode == http.StatusCreated {
+		if err = render.DecodeJSON(res.Body, &registered); err != nil {
+			return auth.RegisteredUser{}, res, err
+		}
+	}
+
+	return registered, res, nil
+}
+
+func (c *HTTPAuthClient) RegisterInvitedUser(
+	ctx context.Context,
+	uuid string,
+	email string,
+	password string,
+	invitationCode string,
+) (*http.Response, error) {
+	return c.client.RegisterUser(ctx, &auth.RegisterUserParams{}, auth.RegisterUserJSONRequestBody{
+		Uuid:           &uuid,
+		Email:          email,
+		Password:       password,
+		InvitationCode: &invitationCode,
+	})
+}
+
 func (c *HTTPAuthClient) LoginUser(ctx context.Context, email string, password string) (auth.Authenticated, *http.Response, error) {
 	res, err := c.client.LoginUser(ctx, auth.LoginUserJSONRequestBody{
 		Email:    email,
@@ -57,7 +106,16 @@ func (c *HTTPAuthClient) LoginUser(ctx context.Context, email string, password s
 }
 
 func (c *HTTPAuthClient) GetUser(ctx context.Context, uuid string) (auth.User, *http.Response, error) {
-	res, err := c.client.GetUser(ctx, uuid)
+	return c.getUser(ctx, uuid)
+}
+
+// GetUserWithToken gets the user as the owner of token, who may see more.
+func (c *HTTPAuthClient) GetUserWithToken(ctx context.Context, token string, uuid string) (auth.User, *http.Response, error) {
+	return c.getUser(ctx, uuid, withBearerToken(token))
+}
+
+func (c *HTTPAuthClient) getUser(ctx context.Context, uuid string, editors ...auth.RequestEditorFn) (auth.User, *http.Response, error) {
+	res, err := c.client.GetUser(ctx, uuid, editors...)
 	if err != nil {
 		return auth.User{}, res, err
 	}
@@ -69,3 +127,415 @@ func (c *HTTPAuthClient) GetUser(ctx context.Context, uuid string) (auth.User, *
 
 	return user, res, nil
 }
+
+func (c *HTTPAuthClient) GetCurrentUser(ctx context.Context, token string) (auth.User, *http.Response, error) {
+	res, err := c.client.GetCurrentUser(ctx, withBearerToken(token))
+	if err != nil {
+		return auth.User{}, res, err
+	}
+
+	var user auth.User
+	if err = render.DecodeJSON(res.Body, &user); err != nil {
+		return auth.User{}, res, err
+	}
+
+	return user, res, nil
+}
+
+// UpdateCurrentUser sends patch, a JSON Merge Patch of the profile, as is.
+func (c *HTTPAuthClient) UpdateCurrentUser(ctx context.Context, token string, patch string) (auth.User, *http.Response, error) {
+	return c.UpdateCurrentUserIfMatch(ctx, token, "", patch)
+}
+
+// UpdateCurrentUserIfMatch sends If-Match unless ifMatch is empty.
+func (c *HTTPAuthClient) UpdateCurrentUserIfMatch(
+	ctx context.Context,
+	token string,
+	ifMatch string,
+	patch string,
+) (auth.User, *http.Response, error) {
+	var params auth.UpdateCurrentUserParams
+	if ifMatch != "" {
+		params.IfMatch = &ifMatch
+	}
+
+	res, err := c.client.UpdateCurrentUserWithBody(
+		ctx, &params, mergePatchContentType, strings.NewReader(patch), withBearerToken(token),
+	)
+	if err != nil {
+		return auth.User{}, res, err
+	}
+
+	var user auth.User
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &user); err != nil {
+			return auth.User{}, res, err
+		}
+	}
+
+	return user, res, nil
+}
+
+func (c *HTTPAuthClient) UploadAvatar(ctx context.Context, token string, image []byte) (auth.User, *http.Response, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	part, err := mw.CreateFormFile("image", "avatar")
+	if err != nil {
+		return auth.User{}, nil, err
+	}
+
+	if _, err = part.Write(image); err != nil {
+		return auth.User{}, nil, err
+	}
+
+	if err = mw.Close(); err != nil {
+		return auth.User{}, nil, err
+	}
+
+	res, err := c.client.UploadAvatarWithBody(ctx, mw.FormDataContentType(), &body, withBearerToken(token))
+	if err != nil {
+		return auth.User{}, res, err
+	}
+
+	var user auth.User
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &user); err != nil {
+			return auth.User{}, res, err
+		}
+	}
+
+	return user, res, nil
+}
+
+// GetUserAvatar sends etag in If-None-Match, if it is not empty.
+func (c *HTTPAuthClient) GetUserAvatar(ctx context.Context, uuid string, etag string) ([]byte, *http.Response, error) {
+	res, err := c.client.GetUserAvatar(ctx, uuid, &auth.GetUserAvatarParams{}, func(ctx context.Context, req *http.Request) error {
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, res, err
+	}
+	defer res.Body.Close()
+
+	image, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, res, err
+	}
+
+	return image, res, nil
+}
+
+func (c *HTTPAuthClient) CreatePersonalAccessToken(
+	ctx context.Context,
+	token string,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (auth.CreatedPersonalAccessToken, *http.Response, error) {
+	res, err := c.client.CreatePersonalAccessToken(ctx, auth.CreatePersonalAccessTokenJSONRequestBody{
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}, withBearerToken(token))
+	if err != nil {
+		return auth.CreatedPersonalAccessToken{}, res, err
+	}
+
+	var created auth.CreatedPersonalAccessToken
+	if res.StatusCode == http.StatusCreated {
+		if err = render.DecodeJSON(res.Body, &created); err != nil {
+			return auth.CreatedPersonalAccessToken{}, res, err
+		}
+	}
+
+	return created, res, nil
+}
+
+func (c *HTTPAuthClient) ListPersonalAccessTokens(
+	ctx context.Context,
+	token string,
+) ([]auth.PersonalAccessToken, *http.Response, error) {
+	res, err := c.client.ListPersonalAccessTokens(ctx, withBearerToken(token))
+	if err != nil {
+		return nil, res, err
+	}
+
+	var tokens []auth.PersonalAccessToken
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &tokens); err != nil {
+			return nil, res, err
+		}
+	}
+
+	return tokens, res, nil
+}
+
+func (c *HTTPAuthClient) RevokePersonalAccessToken(ctx context.Context, token string, uuid string) (*http.Response, error) {
+	return c.client.RevokePersonalAccessToken(ctx, uuid, withBearerToken(token))
+}
+
+func withBearerToken(token string) auth.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		return nil
+	}
+}
+
+func (c *HTTPAuthClient) ListSessions(ctx context.Context, token string) ([]auth.Session, *http.Response, error) {
+	res, err := c.client.ListSessions(ctx, withBearerToken(token))
+	if err != nil {
+		return nil, res, err
+	}
+
+	var sessions []auth.Session
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &sessions); err != nil {
+			return nil, res, err
+		}
+	}
+
+	return sessions, res, nil
+}
+
+func (c *HTTPAuthClient) RevokeOtherSessions(ctx context.Context, token string) (*http.Response, error) {
+	return c.client.RevokeOtherSessions(ctx, withBearerToken(token))
+}
+
+func (c *HTTPAuthClient) RevokeSession(ctx context.Context, token string, uuid string) (*http.Response, error) {
+	return c.client.RevokeSession(ctx, uuid, withBearerToken(token))
+}
+
+func (c *HTTPAuthClient) ListUsers(
+	ctx context.Context,
+	token string,
+	params *auth.ListUsersParams,
+) (auth.UsersPage, *http.Response, error) {
+	res, err := c.client.ListUsers(ctx, params, withBearerToken(token))
+	if err != nil {
+		return auth.UsersPage{}, res, err
+	}
+
+	var page auth.UsersPage
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &page); err != nil {
+			return auth.UsersPage{}, res, err
+		}
+	}
+
+	return page, res, nil
+}
+
+func (c *HTTPAuthClient) ChangeUserStatus(
+	ctx context.Context,
+	token string,
+	userUUID string,
+	status auth.PutAccountStatus,
+) (auth.AccountStatus, *http.Response, error) {
+	res, err := c.client.ChangeUserStatus(ctx, userUUID, &auth.ChangeUserStatusParams{}, status, withBearerToken(token))
+	if err != nil {
+		return auth.AccountStatus{}, res, err
+	}
+
+	var s auth.AccountStatus
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &s); err != nil {
+			return auth.AccountStatus{}, res, err
+		}
+	}
+
+	return s, res, nil
+}
+
+func (c *HTTPAuthClient) ListUserSessions(
+	ctx context.Context,
+	token string,
+	userUUID string,
+) ([]auth.Session, *http.Response, error) {
+	res, err := c.client.ListUserSessions(ctx, userUUID, withBearerToken(token))
+	if err != nil {
+		return nil, res, err
+	}
+
+	var sessions []auth.Session
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &sessions); err != nil {
+			return nil, res, err
+		}
+	}
+
+	return sessions, res, nil
+}
+
+func (c *HTTPAuthClient) ImportUsers(
+	ctx context.Context,
+	token string,
+	format string,
+	dryRun bool,
+	body io.Reader,
+) (auth.ImportReport, *http.Response, error) {
+	params := &auth.ImportUsersParams{
+		Format: format,
+		DryRun: &dryRun,
+	}
+
+	res, err := c.client.ImportUsersWithBody(ctx, params, "application/octet-stream", body, withBearerToken(token))
+	if err != nil {
+		return auth.ImportReport{}, res, err
+	}
+
+	var report auth.ImportReport
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &report); err != nil {
+			return auth.ImportReport{}, res, err
+		}
+	}
+
+	return report, res, nil
+}
+
+func (c *HTTPAuthClient) CreateInvitation(
+	ctx context.Context,
+	token string,
+	maxUses int,
+) (auth.CreatedInvitation, *http.Response, error) {
+	res, err := c.client.CreateInvitation(ctx, auth.CreateInvitationJSONRequestBody{
+		MaxUses: maxUses,
+	}, withBearerToken(token))
+	if err != nil {
+		return auth.CreatedInvitation{}, res, err
+	}
+
+	var created auth.CreatedInvitation
+	if res.StatusCode == http.StatusCreated {
+		if err = render.DecodeJSON(res.Body, &created); err != nil {
+			return auth.CreatedInvitation{}, res, err
+		}
+	}
+
+	return created, res, nil
+}
+
+func (c *HTTPAuthClient) ListSecurityEvents(
+	ctx context.Context,
+	token string,
+	params *auth.ListSecurityEventsParams,
+) (auth.SecurityEventsPage, *http.Response, error) {
+	res, err := c.client.ListSecurityEvents(ctx, params, withBearerToken(token))
+	if err != nil {
+		return auth.SecurityEventsPage{}, res, err
+	}
+
+	var page auth.SecurityEventsPage
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &page); err != nil {
+			return auth.SecurityEventsPage{}, res, err
+		}
+	}
+
+	return page, res, nil
+}
+
+func (c *HTTPAuthClient) ListAuditEvents(
+	ctx context.Context,
+	token string,
+	params *auth.ListAuditEventsParams,
+) (auth.AuditEventsPage, *http.Response, error) {
+	res, err := c.client.ListAuditEvents(ctx, params, withBearerToken(token))
+	if err != nil {
+		return auth.AuditEventsPage{}, res, err
+	}
+
+	var page auth.AuditEventsPage
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &page); err != nil {
+			return auth.AuditEventsPage{}, res, err
+		}
+	}
+
+	return page, res, nil
+}
+
+func (c *HTTPAuthClient) VerifyAuditLog(ctx context.Context, token string) (*http.Response, error) {
+	return c.client.VerifyAuditLog(ctx, withBearerToken(token))
+}
+
+func (c *HTTPAuthClient) ListWebhookSubscriptions(
+	ctx context.Context,
+	token string,
+) ([]auth.WebhookSubscription, *http.Response, error) {
+	res, err := c.client.ListWebhookSubscriptions(ctx, withBearerToken(token))
+	if err != nil {
+		return nil, res, err
+	}
+
+	var subscriptions []auth.WebhookSubscription
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &subscriptions); err != nil {
+			return nil, res, err
+		}
+	}
+
+	return subscriptions, res, nil
+}
+
+func (c *HTTPAuthClient) CreateWebhookSubscription(
+	ctx context.Context,
+	token string,
+	url string,
+	eventTypes []string,
+) (auth.CreatedWebhookSubscription, *http.Response, error) {
+	res, err := c.client.CreateWebhookSubscription(ctx, auth.PostWebhookSubscription{
+		Url:        url,
+		EventTypes: eventTypes,
+	}, withBearerToken(token))
+	if err != nil {
+		return auth.CreatedWebhookSubscription{}, res, err
+	}
+
+	var created auth.CreatedWebhookSubscription
+	if res.StatusCode == http.StatusCreated {
+		if err = render.DecodeJSON(res.Body, &created); err != nil {
+			return auth.CreatedWebhookSubscription{}, res, err
+		}
+	}
+
+	return created, res, nil
+}
+
+func (c *HTTPAuthClient) DeleteWebhookSubscription(ctx context.Context, token string, uuid string) (*http.Response, error) {
+	return c.client.DeleteWebhookSubscription(ctx, uuid, withBearerToken(token))
+}
+
+func (c *HTTPAuthClient) ListWebhookDeliveries(
+	ctx context.Context,
+	token string,
+	uuid string,
+	params *auth.ListWebhookDeliveriesParams,
+) ([]auth.WebhookDelivery, *http.Response, error) {
+	res, err := c.client.ListWebhookDeliveries(ctx, uuid, params, withBearerToken(token))
+	if err != nil {
+		return nil, res, err
+	}
+
+	var deliveries []auth.WebhookDelivery
+	if res.StatusCode == http.StatusOK {
+		if err = render.DecodeJSON(res.Body, &deliveries); err != nil {
+			return nil, res, err
+		}
+	}
+
+	return deliveries, res, nil
+}
+
+func (c *HTTPAuthClient) RedeliverWebhook(
+	ctx context.Context,
+	token string,
+	uuid string,
+	deliveryUUID string,
+) (*http.Response, error) {
+	return c.client.RedeliverWebhook(ctx, uuid, deliveryUUID, withBearerToken(token))
+}
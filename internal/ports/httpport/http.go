@@ -3,16 +3,24 @@ package httpport
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/google/uuid"
 
 	"github.com/bmstu-itstech/itsreg-auth/internal/app"
 	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
 	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/executor"
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/jwtauth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/server"
 	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
 )
 
@@ -22,37 +30,96 @@ const (
 
 type Server struct {
 	app *app.Application
+	// idempotent wraps the operations whose responses carry no secrets.
+	idempotent            func(http.Handler) http.Handler
+	concealExistingEmails bool
 }
 
 func NewHTTPServer(app *app.Application) *Server {
 	return &Server{app: app}
 }
 
-func (s Server) RegisterUser(w http.ResponseWriter, r *http.Request) {
+func NewHTTPHandler(
+	app *app.Application,
+	router chi.Router,
+	limiter *server.RateLimiter,
+	idempotency *server.Idempotency,
+	concealExistingEmails bool,
+) http.Handler {
+	s := NewHTTPServer(app)
+	s.concealExistingEmails = concealExistingEmails
+	s.idempotent = idempotency.Middleware(principalUserUUID, httpError)
+	return HandlerWithOptions(s, ChiServerOptions{
+		BaseRouter: router,
+		// The last middleware is the outermost one.
+		Middlewares: []MiddlewareFunc{
+			limiter.Middleware(principalUserUUID, httpError),
+			s.authMiddleware,
+			limiter.AuthFailuresMiddleware(httpError),
+			requestInfoMiddleware,
+		},
+	})
+}
+
+// RegisterUser leaves the Idempotency-Key header to the idempotency
+// middleware.
+func (s Server) RegisterUser(w http.ResponseWriter, r *http.Request, _ RegisterUserParams) {
+	if s.idempotent != nil {
+		s.idempotent(http.HandlerFunc(s.registerUser)).ServeHTTP(w, r)
+		return
+	}
+	s.registerUser(w, r)
+}
+
+func (s Server) registerUser(w http.ResponseWriter, r *http.Request) {
 	var postRegister PostRegister
 	if err := render.Decode(r, &postRegister); err != nil {
 		httpError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
+	userUUID := stringFromAPI(postRegister.Uuid)
+	if userUUID == "" {
+		generated, err := uuid.NewV7()
+		if err != nil {
+			httpError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		userUUID = generated.String()
+	}
+
 	err := s.app.Commands.RegisterUser.Handle(r.Context(), command.RegisterUser{
-		UUID:     postRegister.Uuid,
+		UUID:     userUUID,
 		Email:    postRegister.Email,
 		Password: postRegister.Password,
+
+		InvitationCode: stringFromAPI(postRegister.InvitationCode),
 	})
 	if errors.As(err, &commonerrs.InvalidInputError{}) {
 		httpError(w, r, err, http.StatusBadRequest)
 		return
+	} else if errors.As(err, &auth.RegistrationForbiddenError{}) {
+		httpError(w, r, err, http.StatusForbidden)
+		return
 	} else if errors.Is(err, auth.ErrUserAlreadyExists) {
 		httpError(w, r, err, http.StatusBadRequest)
 		return
+	} else if errors.Is(err, executor.ErrQueueFull) {
+		busyError(w, r, err)
+		return
 	} else if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("content-location", fmt.Sprintf("/users/%s", postRegister.Uuid))
+	if s.concealExistingEmails {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("content-location", fmt.Sprintf("/users/%s", userUUID))
 	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, RegisteredUser{Uuid: userUUID})
 }
 
 func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
@@ -62,19 +129,34 @@ func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sessionUUID := uuid.NewString()
 	user, err := s.app.Queries.LoginUser.Handle(r.Context(), query.LoginUser{
-		Email:    postLogin.Email,
-		Password: postLogin.Password,
+		Email:       postLogin.Email,
+		Password:    postLogin.Password,
+		SessionUUID: sessionUUID,
+		UserAgent:   r.UserAgent(),
+		IP:          clientIP(r),
 	})
+	var tooManyErr auth.TooManyLoginAttemptsError
 	if errors.Is(err, auth.ErrInvalidCredentials) {
 		httpError(w, r, err, http.StatusUnauthorized)
 		return
+	} else if errors.As(err, &auth.AccountInactiveError{}) {
+		httpError(w, r, err, http.StatusForbidden)
+		return
+	} else if errors.As(err, &tooManyErr) {
+		w.Header().Set("Retry-After", retryAfterToAPI(tooManyErr.RetryAfter))
+		httpError(w, r, err, http.StatusTooManyRequests)
+		return
+	} else if errors.Is(err, executor.ErrQueueFull) {
+		busyError(w, r, err)
+		return
 	} else if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	at, err := jwtauth.NewAccessToken(user.UUID, accessTTL)
+	at, err := jwtauth.NewAccessToken(user.UUID, sessionUUID, mapUserToClaims(user), accessTTL)
 	if err != nil {
 		httpError(w, r, err, http.StatusInternalServerError)
 		return
@@ -88,6 +170,11 @@ func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s Server) GetUser(w http.ResponseWriter, r *http.Request, uuid string) {
+	principal, ok := s.optionalPrincipal(w, r)
+	if !ok {
+		return
+	}
+
 	user, err := s.app.Queries.GetUser.Handle(r.Context(), query.GetUser{
 		UserUUID: uuid,
 	})
@@ -99,19 +186,377 @@ func (s Server) GetUser(w http.ResponseWriter, r *http.Request, uuid string) {
 		return
 	}
 
+	u := mapUserToAPI(user)
+	if !principal.IsAdmin() && principal.UserUUID != user.UUID {
+		u.Roles, u.Status, u.SuspendedUntil = nil, nil, nil
+	}
+
+	render.JSON(w, r, u)
+}
+
+func (s Server) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	principal := principalFromContext(r.Context())
+
+	user, err := s.app.Queries.GetUser.Handle(r.Context(), query.GetUser{
+		UserUUID: principal.UserUUID,
+	})
+	if errors.As(err, &auth.UserNotFound{}) {
+		httpError(w, r, auth.ErrInvalidToken, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", versionToETag(user.Version))
 	render.JSON(w, r, mapUserToAPI(user))
 }
 
+func (s Server) UpdateCurrentUser(w http.ResponseWriter, r *http.Request, params UpdateCurrentUserParams) {
+	principal := principalFromContext(r.Context())
+
+	expectedVersion, err := versionFromIfMatch(params.IfMatch)
+	if err != nil {
+		httpError(w, r, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	update, err := decodeProfileMergePatch(w, r)
+	if err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.app.Commands.UpdateProfile.Handle(r.Context(), command.UpdateProfile{
+		UserUUID:        principal.UserUUID,
+		Update:          update,
+		ExpectedVersion: expectedVersion,
+	})
+	if errors.As(err, &auth.UserNotFound{}) {
+		httpError(w, r, auth.ErrInvalidToken, http.StatusUnauthorized)
+		return
+	} else if errors.Is(err, auth.ErrConcurrentModification) {
+		httpError(w, r, err, http.StatusPreconditionFailed)
+		return
+	} else if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.GetCurrentUser(w, r)
+}
+
+var errPersonalAccessTokenNotAllowed = errors.New("personal access tokens can not manage tokens and sessions")
+
+func (s Server) ListPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	principal := principalFromContext(r.Context())
+	if principal.IsPersonalAccessToken() {
+		httpError(w, r, errPersonalAccessTokenNotAllowed, http.StatusForbidden)
+		return
+	}
+
+	tokens, err := s.app.Queries.PersonalAccessTokens.Handle(r.Context(), query.PersonalAccessTokens{
+		UserUUID: principal.UserUUID,
+	})
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, mapPersonalAccessTokensToAPI(tokens))
+}
+
+func (s Server) CreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	principal := principalFromContext(r.Context())
+	if principal.IsPersonalAccessToken() {
+		httpError(w, r, errPersonalAccessTokenNotAllowed, http.StatusForbidden)
+		return
+	}
+
+	var postToken PostPersonalAccessToken
+	if err := render.Decode(r, &postToken); err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	secret, err := auth.NewPersonalAccessTokenSecret()
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	var expiresAt time.Time
+	if postToken.ExpiresAt != nil {
+		expiresAt = *postToken.ExpiresAt
+	}
+
+	tokenUUID := uuid.NewString()
+	err = s.app.Commands.CreatePersonalAccessToken.Handle(r.Context(), command.CreatePersonalAccessToken{
+		UUID:      tokenUUID,
+		UserUUID:  principal.UserUUID,
+		Name:      postToken.Name,
+		Secret:    secret,
+		Scopes:    postToken.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-location", "/me/tokens")
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, CreatedPersonalAccessToken{
+		Uuid:  tokenUUID,
+		Token: secret,
+	})
+}
+
+func (s Server) RevokePersonalAccessToken(w http.ResponseWriter, r *http.Request, uuid string) {
+	principal := principalFromContext(r.Context())
+	if principal.IsPersonalAccessToken() {
+		httpError(w, r, errPersonalAccessTokenNotAllowed, http.StatusForbidden)
+		return
+	}
+
+	err := s.app.Commands.RevokePersonalAccessToken.Handle(r.Context(), command.RevokePersonalAccessToken{
+		UserUUID:  principal.UserUUID,
+		TokenUUID: uuid,
+	})
+	if errors.As(err, &auth.PersonalAccessTokenNotFound{}) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) ListSessions(w http.ResponseWriter, r *http.Request) {
+	principal := principalFromContext(r.Context())
+	if principal.IsPersonalAccessToken() {
+		httpError(w, r, errPersonalAccessTokenNotAllowed, http.StatusForbidden)
+		return
+	}
+
+	sessions, err := s.app.Queries.Sessions.Handle(r.Context(), query.Sessions{
+		UserUUID: principal.UserUUID,
+	})
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, mapSessionsToAPI(sessions, principal.SessionUUID))
+}
+
+func (s Server) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	principal := principalFromContext(r.Context())
+	if principal.IsPersonalAccessToken() {
+		httpError(w, r, errPersonalAccessTokenNotAllowed, http.StatusForbidden)
+		return
+	}
+
+	err := s.app.Commands.RevokeUserSessions.Handle(r.Context(), command.RevokeUserSessions{
+		UserUUID:          principal.UserUUID,
+		ExceptSessionUUID: principal.SessionUUID,
+	})
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) RevokeSession(w http.ResponseWriter, r *http.Request, uuid string) {
+	principal := principalFromContext(r.Context())
+	if principal.IsPersonalAccessToken() {
+		httpError(w, r, errPersonalAccessTokenNotAllowed, http.StatusForbidden)
+		return
+	}
+
+	err := s.app.Commands.RevokeSession.Handle(r.Context(), command.RevokeSession{
+		UserUUID:    principal.UserUUID,
+		SessionUUID: uuid,
+	})
+	if errors.As(err, &auth.SessionNotFound{}) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func httpError(w http.ResponseWriter, r *http.Request, err error, code int) {
+	res := Error{Message: err.Error()}
+
+	var invalidInputErr commonerrs.InvalidInputError
+	if errors.As(err, &invalidInputErr) && len(invalidInputErr.Violations) > 0 {
+		details := make([]ErrorDetail, len(invalidInputErr.Violations))
+		for i, v := range invalidInputErr.Violations {
+			details[i] = ErrorDetail{Rule: v.Rule, Message: v.Message}
+		}
+		res.Details = &details
+	}
+
+	var forbiddenErr auth.RegistrationForbiddenError
+	if errors.As(err, &forbiddenErr) {
+		res.Code = &forbiddenErr.Code
+	}
+
+	var inactiveErr auth.AccountInactiveError
+	if errors.As(err, &inactiveErr) {
+		code := inactiveErr.Code()
+		res.Code = &code
+	}
+
 	w.WriteHeader(code)
-	render.JSON(w, r, Error{Message: err.Error()})
+	render.JSON(w, r, res)
+}
+
+const busyRetryAfter = time.Second
+
+func busyError(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Retry-After", retryAfterToAPI(busyRetryAfter))
+	httpError(w, r, err, http.StatusServiceUnavailable)
+}
+
+var errInvalidIfMatch = errors.New("If-Match must be an ETag of the user")
+
+func versionToETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// versionFromIfMatch returns nil for no If-Match or "*".
+func versionFromIfMatch(ifMatch *string) (*int64, error) {
+	if ifMatch == nil || *ifMatch == "*" {
+		return nil, nil
+	}
+
+	unquoted, err := strconv.Unquote(*ifMatch)
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+
+	return &version, nil
+}
+
+// retryAfterToAPI rounds up.
+func retryAfterToAPI(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
 }
 
 func mapUserToAPI(user query.User) User {
 	return User{
-		CreatedAt: user.CreatedAt,
-		Email:     user.Email,
-		UpdatedAt: user.UpdatedAt,
-		Uuid:      user.UUID,
+		Avatar:         avatarToAPI(user.UUID, user.AvatarKey),
+		CreatedAt:      user.CreatedAt,
+		Email:          user.Email,
+		Profile:        mapProfileToAPI(user.Profile),
+		Roles:          &user.Roles,
+		Status:         (*AccountState)(&user.Status.State),
+		SuspendedUntil: timeToAPI(user.Status.Until),
+		UpdatedAt:      user.UpdatedAt,
+		Uuid:           user.UUID,
+	}
+}
+
+func mapProfileToAPI(p query.Profile) Profile {
+	return Profile{
+		AvatarUrl:   stringToAPI(p.AvatarURL),
+		DisplayName: stringToAPI(p.DisplayName),
+		Faculty:     stringToAPI(p.Faculty),
+		FirstName:   stringToAPI(p.FirstName),
+		Group:       stringToAPI(p.Group),
+		LastName:    stringToAPI(p.LastName),
+		Locale:      stringToAPI(p.Locale),
+		Patronymic:  stringToAPI(p.Patronymic),
+		Timezone:    stringToAPI(p.Timezone),
+	}
+}
+
+func mapUserToClaims(user query.User) jwtauth.ProfileClaims {
+	p := user.Profile
+	return jwtauth.ProfileClaims{
+		Name:              strings.Join(slices.DeleteFunc([]string{p.LastName, p.FirstName, p.Patronymic}, isEmpty), " "),
+		GivenName:         p.FirstName,
+		FamilyName:        p.LastName,
+		MiddleName:        p.Patronymic,
+		PreferredUsername: p.DisplayName,
+		Locale:            p.Locale,
+		Zoneinfo:          p.Timezone,
+		Picture:           userPicture(user),
+	}
+}
+
+func isEmpty(s string) bool {
+	return s == ""
+}
+
+func mapPersonalAccessTokensToAPI(tokens []query.PersonalAccessToken) []PersonalAccessToken {
+	res := make([]PersonalAccessToken, len(tokens))
+	for i, t := range tokens {
+		res[i] = PersonalAccessToken{
+			CreatedAt:  t.CreatedAt,
+			ExpiresAt:  timeToAPI(t.ExpiresAt),
+			LastUsedAt: timeToAPI(t.LastUsedAt),
+			LastUsedIp: stringToAPI(t.LastUsedIP),
+			Name:       t.Name,
+			Scopes:     t.Scopes,
+			Uuid:       t.UUID,
+		}
+	}
+	return res
+}
+
+func timeToAPI(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func stringFromAPI(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func stringToAPI(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func mapSessionsToAPI(sessions []query.Session, currentSessionUUID string) []Session {
+	res := make([]Session, len(sessions))
+	for i, s := range sessions {
+		res[i] = Session{
+			CreatedAt:  s.CreatedAt,
+			Current:    s.UUID == currentSessionUUID,
+			Ip:         s.IP,
+			LastSeenAt: s.LastSeenAt,
+			UserAgent:  s.UserAgent,
+			Uuid:       s.UUID,
+		}
 	}
+	return res
 }
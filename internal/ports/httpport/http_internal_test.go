@@ -0,0 +1,39 @@
+package httpport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app"
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+)
+
+type registerUserFunc func(ctx context.Context, cmd command.RegisterUser) error
+
+func (f registerUserFunc) Handle(ctx context.Context, cmd command.RegisterUser) error {
+	return f(ctx, cmd)
+}
+
+func TestRegisterUser_ConcealExistingEmails(t *testing.T) {
+	s := Server{
+		app: &app.Application{Commands: app.Commands{
+			RegisterUser: registerUserFunc(func(context.Context, command.RegisterUser) error { return nil }),
+		}},
+		concealExistingEmails: true,
+	}
+
+	body := `{"email":"test@test.com","password":"correct horse battery staple"}`
+	r := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.registerUser(w, r)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Empty(t, w.Header().Get("Content-Location"))
+	require.Empty(t, w.Body.String())
+}
@@ -1,21 +1,30 @@
 package httpport_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
+	authclient "github.com/bmstu-itstech/itsreg-auth/api/openapi/clients/auth"
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/jwtauth"
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/server"
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/tests"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
 	"github.com/bmstu-itstech/itsreg-auth/internal/ports/httpport"
 	"github.com/bmstu-itstech/itsreg-auth/internal/service"
 )
@@ -54,6 +63,44 @@ func TestAuthHTTP(t *testing.T) {
 		require.Less(t, time.Now().Sub(user.UpdatedAt), time.Second)
 	})
 
+	t.Run("should register user idempotently with generated uuid", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		key := gofakeit.UUID()
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		registered, res, err := client.RegisterUserIdempotently(ctx, key, email, password)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		parsed, err := uuid.Parse(registered.Uuid)
+		require.NoError(t, err)
+		require.Equal(t, uuid.Version(7), parsed.Version())
+
+		replayed, res, err := client.RegisterUserIdempotently(ctx, key, email, password)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		require.Equal(t, "true", res.Header.Get(server.IdempotentReplayedHeader))
+		require.Equal(t, registered.Uuid, replayed.Uuid)
+
+		_, res, err = client.RegisterUserIdempotently(ctx, key, gofakeit.Email(), password)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	})
+
+	t.Run("should reject malformed user uuid", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		res, err := client.RegisterUser(ctx, "1234", gofakeit.Email(), fakePassword())
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
 	t.Run("should login user", func(t *testing.T) {
 		t.Parallel()
 
@@ -73,6 +120,55 @@ func TestAuthHTTP(t *testing.T) {
 		parsed, err := jwtauth.ParseAccessToken(tokens.AccessToken)
 		require.NoError(t, err)
 		require.Equal(t, uuid, parsed.UserUUID)
+		require.NotEmpty(t, parsed.SessionUUID)
+	})
+
+	t.Run("should not store login responses with idempotency key", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, gofakeit.UUID(), email, password)
+		require.NoError(t, err)
+
+		key := gofakeit.UUID()
+		body := fmt.Sprintf(`{"email":%q,"password":%q}`, email, password)
+		for range 2 {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr+"/login", strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(server.IdempotencyKeyHeader, key)
+
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			require.Empty(t, res.Header.Get(server.IdempotentReplayedHeader))
+		}
+	})
+
+	t.Run("should login user with email in another case", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		email := strings.ToLower(gofakeit.Email())
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, uuid, email, password)
+		require.NoError(t, err)
+
+		_, res, err := client.LoginUser(ctx, strings.ToUpper(email), password)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, err = client.RegisterUser(ctx, gofakeit.UUID(), strings.ToUpper(email), password)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
 	})
 
 	t.Run("should return error if password mismatch", func(t *testing.T) {
@@ -91,6 +187,29 @@ func TestAuthHTTP(t *testing.T) {
 		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
 	})
 
+	t.Run("should lock account out after failed attempts", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, uuid, email, password)
+		require.NoError(t, err)
+
+		for range auth.DefaultAccountLockoutPolicy.Threshold {
+			_, res, err := client.LoginUser(ctx, email, fakePassword())
+			require.NoError(t, err)
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		}
+
+		_, res, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		require.NotEmpty(t, res.Header.Get("Retry-After"))
+	})
+
 	t.Run("should return error if user is invalid", func(t *testing.T) {
 		t.Parallel()
 
@@ -105,6 +224,462 @@ func TestAuthHTTP(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, res.StatusCode)
 	})
 
+	t.Run("should reject weak password", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		res, err := client.RegisterUser(ctx, gofakeit.UUID(), gofakeit.Email(), "password")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		var body authclient.Error
+		require.NoError(t, render.DecodeJSON(res.Body, &body))
+		require.NotNil(t, body.Details)
+
+		rules := make([]string, 0, len(*body.Details))
+		for _, d := range *body.Details {
+			rules = append(rules, d.Rule)
+		}
+		require.Contains(t, rules, auth.PasswordRuleCharClasses)
+		require.Contains(t, rules, auth.PasswordRuleBreached)
+	})
+
+	t.Run("should reject unknown invitation code", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		res, err := client.RegisterInvitedUser(ctx, gofakeit.UUID(), gofakeit.Email(), fakePassword(), "itsreg_inv_unknown")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		var body authclient.Error
+		require.NoError(t, render.DecodeJSON(res.Body, &body))
+		require.NotNil(t, body.Code)
+		require.Equal(t, auth.RegistrationForbiddenInvitationInvalid, *body.Code)
+	})
+
+	t.Run("should authenticate with personal access token", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, uuid, email, password)
+		require.NoError(t, err)
+
+		tokens, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		created, res, err := client.CreatePersonalAccessToken(ctx, tokens.AccessToken, "ci", []string{"read"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		user, res, err := client.GetCurrentUser(ctx, created.Token)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, uuid, user.Uuid)
+
+		_, res, err = client.CreatePersonalAccessToken(ctx, created.Token, "another", []string{"read"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		listed, res, err := client.ListPersonalAccessTokens(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, listed, 1)
+		require.Equal(t, created.Uuid, listed[0].Uuid)
+		require.NotNil(t, listed[0].LastUsedAt)
+		require.NotNil(t, listed[0].LastUsedIp)
+
+		res, err = client.RevokePersonalAccessToken(ctx, tokens.AccessToken, created.Uuid)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		_, res, err = client.GetCurrentUser(ctx, created.Token)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("should manage sessions", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, uuid, email, password)
+		require.NoError(t, err)
+
+		first, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		second, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		sessions, res, err := client.ListSessions(ctx, second.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, sessions, 2)
+
+		res, err = client.RevokeOtherSessions(ctx, second.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		_, res, err = client.GetCurrentUser(ctx, first.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		sessions, _, err = client.ListSessions(ctx, second.AccessToken)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.True(t, sessions[0].Current)
+
+		res, err = client.RevokeSession(ctx, second.AccessToken, sessions[0].Uuid)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		_, res, err = client.GetCurrentUser(ctx, second.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("should update profile with merge patch", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, gofakeit.UUID(), email, password)
+		require.NoError(t, err)
+
+		tokens, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		user, res, err := client.UpdateCurrentUser(ctx, tokens.AccessToken,
+			`{"lastName": "Иванов", "firstName": "Иван", "group": "иу7-64б", "locale": "ru-ru"}`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "Иванов", *user.Profile.LastName)
+		require.Equal(t, "ИУ7-64Б", *user.Profile.Group)
+		require.Equal(t, "ru-RU", *user.Profile.Locale)
+
+		user, res, err = client.UpdateCurrentUser(ctx, tokens.AccessToken, `{"group": null, "timezone": "Europe/Moscow"}`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Nil(t, user.Profile.Group)
+		require.Equal(t, "Иван", *user.Profile.FirstName)
+		require.Equal(t, "Europe/Moscow", *user.Profile.Timezone)
+
+		_, res, err = client.UpdateCurrentUser(ctx, tokens.AccessToken, `{"firstName": "Ivan"}`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		_, res, err = client.UpdateCurrentUser(ctx, tokens.AccessToken, `{"email": "other@test.com"}`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		user, _, err = client.GetCurrentUser(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, "Иван", *user.Profile.FirstName)
+		require.Equal(t, email, user.Email)
+	})
+
+	t.Run("should refuse update with stale If-Match", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, gofakeit.UUID(), email, password)
+		require.NoError(t, err)
+
+		tokens, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		_, res, err := client.GetCurrentUser(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		etag := res.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+
+		_, res, err = client.UpdateCurrentUserIfMatch(ctx, tokens.AccessToken, etag, `{"firstName": "Иван"}`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotEqual(t, etag, res.Header.Get("ETag"))
+
+		_, res, err = client.UpdateCurrentUserIfMatch(ctx, tokens.AccessToken, etag, `{"firstName": "Пётр"}`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+
+		user, _, err := client.GetCurrentUser(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, "Иван", *user.Profile.FirstName)
+	})
+
+	t.Run("should upload and serve avatar", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, uuid, email, password)
+		require.NoError(t, err)
+
+		tokens, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		_, res, err := client.GetUserAvatar(ctx, uuid, "")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		_, res, err = client.UploadAvatar(ctx, tokens.AccessToken, []byte("not an image"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		user, res, err := client.UploadAvatar(ctx, tokens.AccessToken, fakePNG(t, 300, 300))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotNil(t, user.Avatar)
+		first := *user.Avatar
+
+		avatar, res, err := client.GetUserAvatar(ctx, uuid, "")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "image/png", res.Header.Get("Content-Type"))
+		cfg, err := png.DecodeConfig(bytes.NewReader(avatar))
+		require.NoError(t, err)
+		require.Equal(t, auth.AvatarSize, cfg.Width)
+
+		etag := res.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+
+		_, res, err = client.GetUserAvatar(ctx, uuid, etag)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotModified, res.StatusCode)
+
+		user, res, err = client.UploadAvatar(ctx, tokens.AccessToken, fakePNG(t, 100, 50))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotEqual(t, first, *user.Avatar)
+
+		_, res, err = client.GetUserAvatar(ctx, uuid, etag)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should rate limit requests per user", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, gofakeit.UUID(), email, password)
+		require.NoError(t, err)
+
+		tokens, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		for i := range currentUserRateLimit {
+			_, res, err := client.GetCurrentUser(ctx, tokens.AccessToken)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			require.Equal(t, fmt.Sprint(currentUserRateLimit), res.Header.Get("RateLimit-Limit"))
+			require.Equal(t, fmt.Sprint(currentUserRateLimit-i-1), res.Header.Get("RateLimit-Remaining"))
+		}
+
+		_, res, err := client.GetCurrentUser(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		require.NotEmpty(t, res.Header.Get("Retry-After"))
+	})
+
+	t.Run("should forbid admin endpoints for regular users", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, uuid, email, password)
+		require.NoError(t, err)
+
+		tokens, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		_, res, err := client.ListUserSessions(ctx, tokens.AccessToken, uuid)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, res, err = client.ImportUsers(ctx, tokens.AccessToken, "jsonl", true, strings.NewReader("{}"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, res, err = client.CreateInvitation(ctx, tokens.AccessToken, 1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, res, err = client.ListUsers(ctx, tokens.AccessToken, &authclient.ListUsersParams{})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, res, err = client.ChangeUserStatus(ctx, tokens.AccessToken, uuid, authclient.PutAccountStatus{
+			Status: authclient.Disabled,
+			Reason: "self-service",
+		})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, res, err = client.ListAuditEvents(ctx, tokens.AccessToken, &authclient.ListAuditEventsParams{})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res, err = client.VerifyAuditLog(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, res, err = client.ListWebhookSubscriptions(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, res, err = client.CreateWebhookSubscription(
+			ctx, tokens.AccessToken, "https://example.com/hooks", []string{"user.registered"},
+		)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res, err = client.DeleteWebhookSubscription(ctx, tokens.AccessToken, uuid)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, res, err = client.ListWebhookDeliveries(ctx, tokens.AccessToken, uuid, &authclient.ListWebhookDeliveriesParams{})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		res, err = client.RedeliverWebhook(ctx, tokens.AccessToken, uuid, uuid)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("should list own security events", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, uuid, email, password)
+		require.NoError(t, err)
+
+		_, _, err = client.LoginUser(ctx, email, "wrong "+password)
+		require.NoError(t, err)
+
+		tokens, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		page, res, err := client.ListSecurityEvents(ctx, tokens.AccessToken, &authclient.ListSecurityEventsParams{})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		actions := make([]string, len(page.Events))
+		for i, e := range page.Events {
+			actions[i] = e.Action
+		}
+		require.Equal(t, []string{"login.succeeded", "login.failed", "user.registered"}, actions)
+	})
+
+	t.Run("should return error if token is invalid", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		_, res, err := client.GetCurrentUser(ctx, "invalid")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("should accept access token without session until it expires", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		_, err := client.RegisterUser(ctx, uuid, gofakeit.Email(), fakePassword())
+		require.NoError(t, err)
+
+		token, err := jwtauth.NewAccessToken(uuid, "", jwtauth.ProfileClaims{}, time.Hour)
+		require.NoError(t, err)
+
+		_, res, err := client.GetCurrentUser(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		token, err = jwtauth.NewAccessToken(uuid, "", jwtauth.ProfileClaims{}, -time.Minute)
+		require.NoError(t, err)
+
+		_, res, err = client.GetCurrentUser(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("should return account status to the user only", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		uuid := gofakeit.UUID()
+		email := gofakeit.Email()
+		password := fakePassword()
+
+		_, err := client.RegisterUser(ctx, uuid, email, password)
+		require.NoError(t, err)
+
+		user, res, err := client.GetUser(ctx, uuid)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Nil(t, user.Roles)
+		require.Nil(t, user.Status)
+
+		tokens, _, err := client.LoginUser(ctx, email, password)
+		require.NoError(t, err)
+
+		user, res, err = client.GetUserWithToken(ctx, tokens.AccessToken, uuid)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotNil(t, user.Roles)
+		require.Equal(t, authclient.Active, *user.Status)
+
+		other := gofakeit.UUID()
+		_, err = client.RegisterUser(ctx, other, gofakeit.Email(), fakePassword())
+		require.NoError(t, err)
+
+		user, _, err = client.GetUserWithToken(ctx, tokens.AccessToken, other)
+		require.NoError(t, err)
+		require.Nil(t, user.Status)
+
+		_, res, err = client.GetUserWithToken(ctx, "invalid", uuid)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
 	t.Run("should return error if user not found", func(t *testing.T) {
 		t.Parallel()
 
@@ -116,17 +691,36 @@ func TestAuthHTTP(t *testing.T) {
 	})
 }
 
+func fakePNG(t *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x*height/width, color.RGBA{R: 255, A: 255})
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
 func fakePassword() string {
 	return gofakeit.Password(true, true, true, true, false, 8)
 }
 
+const currentUserRateLimit = 5
+
 func startService() bool {
 	app := service.NewComponentTestApplication()
+	rules, err := server.ParseRateLimitRules(fmt.Sprintf("GET /api/me user %d/1h", currentUserRateLimit))
+	if err != nil {
+		panic(err)
+	}
+	limiter := service.NewComponentTestRateLimiter(rules)
+	idempotency := service.NewComponentTestIdempotency()
 
 	port := os.Getenv("PORT")
 	addr := fmt.Sprintf(":%s", port)
 	go server.RunHTTPServerOnAddr(addr, func(router chi.Router) http.Handler {
-		return httpport.HandlerFromMux(httpport.NewHTTPServer(app), router)
+		return httpport.NewHTTPHandler(app, router, limiter, idempotency, false)
 	})
 
 	ok := tests.WaitForPort(addr)
@@ -0,0 +1,73 @@
+package httpport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	maxMergePatchBytes    = 64 << 10
+)
+
+// decodeProfileMergePatch reads a JSON Merge Patch (RFC 7396) of the profile,
+// or plain application/json.
+func decodeProfileMergePatch(w http.ResponseWriter, r *http.Request) (auth.ProfileUpdate, error) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != "application/json") {
+			return auth.ProfileUpdate{}, commonerrs.NewInvalidInputError(
+				fmt.Sprintf("expected %s content type", mergePatchContentType),
+			)
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMergePatchBytes))
+	if err != nil {
+		return auth.ProfileUpdate{}, commonerrs.NewInvalidInputError("unable to read request body")
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return auth.ProfileUpdate{}, commonerrs.NewInvalidInputError("expected JSON object")
+	}
+
+	var update auth.ProfileUpdate
+	fields := map[string]**string{
+		auth.ProfileFieldDisplayName: &update.DisplayName,
+		auth.ProfileFieldLastName:    &update.LastName,
+		auth.ProfileFieldFirstName:   &update.FirstName,
+		auth.ProfileFieldPatronymic:  &update.Patronymic,
+		auth.ProfileFieldGroup:       &update.Group,
+		auth.ProfileFieldFaculty:     &update.Faculty,
+		auth.ProfileFieldLocale:      &update.Locale,
+		auth.ProfileFieldTimezone:    &update.Timezone,
+		auth.ProfileFieldAvatarURL:   &update.AvatarURL,
+	}
+
+	for name, raw := range patch {
+		dst, ok := fields[name]
+		if !ok {
+			return auth.ProfileUpdate{}, commonerrs.NewInvalidInputError(fmt.Sprintf("unknown profile field %q", name))
+		}
+
+		var v string
+		if !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return auth.ProfileUpdate{}, commonerrs.NewInvalidInputError(
+					fmt.Sprintf("expected string or null for profile field %q", name),
+				)
+			}
+		}
+		*dst = &v
+	}
+
+	return update, nil
+}
@@ -5,16 +5,157 @@ package httpport
 
 import (
 	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const (
+	BearerAuthScopes = "bearerAuth.Scopes"
+)
+
+// Defines values for AccountState.
+const (
+	Active    AccountState = "active"
+	Suspended AccountState = "suspended"
+	Disabled  AccountState = "disabled"
+)
+
+// Defines values for ListUsersParamsOrder.
+const (
+	Asc  ListUsersParamsOrder = "asc"
+	Desc ListUsersParamsOrder = "desc"
+)
+
+// Defines values for ListUsersParamsSort.
+const (
+	CreatedAt ListUsersParamsSort = "createdAt"
+	UpdatedAt ListUsersParamsSort = "updatedAt"
 )
 
+// Defines values for WebhookDeliveryState.
+const (
+	Pending   WebhookDeliveryState = "pending"
+	Succeeded WebhookDeliveryState = "succeeded"
+	Dead      WebhookDeliveryState = "dead"
+)
+
+// AccountState defines model for AccountState.
+type AccountState string
+
+// AccountStatus defines model for AccountStatus.
+type AccountStatus struct {
+	ChangedAt *time.Time `json:"changedAt,omitempty"`
+	// ChangedBy UUID of the admin who made the last change.
+	ChangedBy *string `json:"changedBy,omitempty"`
+	// Reason Reason given for the last change.
+	Reason *string      `json:"reason,omitempty"`
+	Status AccountState `json:"status"`
+	// Until End of the suspension, set for suspended users only.
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// AuditEvent defines model for AuditEvent.
+type AuditEvent struct {
+	Action    string            `json:"action"`
+	ActorUuid *string           `json:"actorUuid,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Details   map[string]string `json:"details"`
+	// Hash Hex encoded SHA-256 chaining the event to the previous one.
+	Hash       string  `json:"hash"`
+	Ip         *string `json:"ip,omitempty"`
+	RequestId  *string `json:"requestId,omitempty"`
+	Seq        int64   `json:"seq"`
+	TargetUuid *string `json:"targetUuid,omitempty"`
+	UserAgent  *string `json:"userAgent,omitempty"`
+}
+
+// AuditEventsPage defines model for AuditEventsPage.
+type AuditEventsPage struct {
+	Events []AuditEvent `json:"events"`
+	// NextCursor Cursor of the next page, missing on the last page.
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// AuditLogVerification defines model for AuditLogVerification.
+type AuditLogVerification struct {
+	// BrokenAt Number of the first event of a chain that does not match.
+	BrokenAt *int64 `json:"brokenAt,omitempty"`
+	// Checked Number of events that match their chains.
+	Checked int64 `json:"checked"`
+	Intact  bool  `json:"intact"`
+}
+
 // Authenticated defines model for Authenticated.
 type Authenticated struct {
 	AccessToken string `json:"accessToken"`
 }
 
+// CreatedInvitation defines model for CreatedInvitation.
+type CreatedInvitation struct {
+	Code string `json:"code"`
+	Uuid string `json:"uuid"`
+}
+
+// CreatedPersonalAccessToken defines model for CreatedPersonalAccessToken.
+type CreatedPersonalAccessToken struct {
+	Token string `json:"token"`
+	Uuid  string `json:"uuid"`
+}
+
+// CreatedWebhookSubscription defines model for CreatedWebhookSubscription.
+type CreatedWebhookSubscription struct {
+	Secret string `json:"secret"`
+	Uuid   string `json:"uuid"`
+}
+
 // Error defines model for Error.
 type Error struct {
+	// Code Machine-readable reason, set for some errors.
+	Code    *string        `json:"code,omitempty"`
+	Details *[]ErrorDetail `json:"details,omitempty"`
+	Message string         `json:"message"`
+}
+
+// ErrorDetail defines model for ErrorDetail.
+type ErrorDetail struct {
 	Message string `json:"message"`
+	Rule    string `json:"rule"`
+}
+
+// ImportReport defines model for ImportReport.
+type ImportReport struct {
+	DryRun   bool        `json:"dryRun"`
+	Failed   int         `json:"failed"`
+	Imported int         `json:"imported"`
+	Rows     []ImportRow `json:"rows"`
+}
+
+// ImportRow defines model for ImportRow.
+type ImportRow struct {
+	Email *string `json:"email,omitempty"`
+	// Error Why the row is not imported.
+	Error *string `json:"error,omitempty"`
+	Line  int     `json:"line"`
+	Uuid  *string `json:"uuid,omitempty"`
+}
+
+// PersonalAccessToken defines model for PersonalAccessToken.
+type PersonalAccessToken struct {
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	LastUsedIp *string    `json:"lastUsedIp,omitempty"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Uuid       string     `json:"uuid"`
+}
+
+// PostInvitation defines model for PostInvitation.
+type PostInvitation struct {
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	MaxUses   int        `json:"maxUses"`
+	// Roles Roles granted to the invited users.
+	Roles *[]string `json:"roles,omitempty"`
 }
 
 // PostLogin defines model for PostLogin.
@@ -23,23 +164,250 @@ type PostLogin struct {
 	Password string `json:"password"`
 }
 
+// PostPersonalAccessToken defines model for PostPersonalAccessToken.
+type PostPersonalAccessToken struct {
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Name      string     `json:"name"`
+	// Scopes Any of "read" and "write".
+	Scopes []string `json:"scopes"`
+}
+
 // PostRegister defines model for PostRegister.
 type PostRegister struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Uuid     string `json:"uuid"`
+	Email          string  `json:"email"`
+	InvitationCode *string `json:"invitationCode,omitempty"`
+	Password       string  `json:"password"`
+	// Uuid Lower case UUID in canonical form. Generated by the server, as
+	// UUIDv7, if omitted.
+	Uuid *string `json:"uuid,omitempty"`
+}
+
+// PostWebhookSubscription defines model for PostWebhookSubscription.
+type PostWebhookSubscription struct {
+	EventTypes []string `json:"eventTypes"`
+	Url        string   `json:"url"`
+}
+
+// Profile Optional user attributes. Unset fields are omitted.
+type Profile struct {
+	// AvatarUrl Absolute https URL, shown unless an avatar is uploaded.
+	AvatarUrl   *string `json:"avatarUrl,omitempty"`
+	DisplayName *string `json:"displayName,omitempty"`
+	Faculty     *string `json:"faculty,omitempty"`
+	FirstName   *string `json:"firstName,omitempty"`
+	Group       *string `json:"group,omitempty"`
+	// LastName Cyrillic letters, hyphens and spaces.
+	LastName *string `json:"lastName,omitempty"`
+	// Locale BCP 47 language tag.
+	Locale     *string `json:"locale,omitempty"`
+	Patronymic *string `json:"patronymic,omitempty"`
+	// Timezone IANA time zone name.
+	Timezone *string `json:"timezone,omitempty"`
+}
+
+// PutAccountStatus defines model for PutAccountStatus.
+type PutAccountStatus struct {
+	Reason string       `json:"reason"`
+	Status AccountState `json:"status"`
+	// Until End of the suspension, required for suspensions only.
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// RegisteredUser defines model for RegisteredUser.
+type RegisteredUser struct {
+	Uuid string `json:"uuid"`
+}
+
+// SecurityEvent defines model for SecurityEvent.
+type SecurityEvent struct {
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"createdAt"`
+	Ip        *string   `json:"ip,omitempty"`
+	UserAgent *string   `json:"userAgent,omitempty"`
+}
+
+// SecurityEventsPage defines model for SecurityEventsPage.
+type SecurityEventsPage struct {
+	Events []SecurityEvent `json:"events"`
+	// NextCursor Cursor of the next page, missing on the last page.
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// Session defines model for Session.
+type Session struct {
+	CreatedAt  time.Time `json:"createdAt"`
+	Current    bool      `json:"current"`
+	Ip         string    `json:"ip"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	UserAgent  string    `json:"userAgent"`
+	Uuid       string    `json:"uuid"`
 }
 
 // User defines model for User.
 type User struct {
+	// Avatar Path of the uploaded avatar relative to the API root. It changes
+	// whenever the avatar does. It takes precedence over
+	// profile.avatarUrl, and so does the picture claim of access tokens.
+	Avatar    *string   `json:"avatar,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
 	Email     string    `json:"email"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Uuid      string    `json:"uuid"`
+	Profile   Profile   `json:"profile"`
+	// Roles Returned to admins and to the user only.
+	Roles *[]string `json:"roles,omitempty"`
+	// Status Returned to admins and to the user only.
+	Status *AccountState `json:"status,omitempty"`
+	// SuspendedUntil End of the suspension, set for suspended users only.
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	Uuid           string     `json:"uuid"`
+}
+
+// UsersPage defines model for UsersPage.
+type UsersPage struct {
+	// NextCursor Cursor of the next page, missing on the last page.
+	NextCursor *string `json:"nextCursor,omitempty"`
+	// Total Number of users matching the filters, if requested.
+	Total *int   `json:"total,omitempty"`
+	Users []User `json:"users"`
+}
+
+// WebhookDelivery defines model for WebhookDelivery.
+type WebhookDelivery struct {
+	Attempts      int        `json:"attempts"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	EventId       int64      `json:"eventId"`
+	EventType     string     `json:"eventType"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty"`
+	LastError     *string    `json:"lastError,omitempty"`
+	// LastStatusCode Status of the last response, absent if there was none.
+	LastStatusCode *int                 `json:"lastStatusCode,omitempty"`
+	NextAttemptAt  *time.Time           `json:"nextAttemptAt,omitempty"`
+	State          WebhookDeliveryState `json:"state"`
+	Uuid           string               `json:"uuid"`
+}
+
+// WebhookDeliveryState defines model for WebhookDeliveryState.
+type WebhookDeliveryState string
+
+// WebhookSubscription defines model for WebhookSubscription.
+type WebhookSubscription struct {
+	CreatedAt  time.Time `json:"createdAt"`
+	CreatedBy  string    `json:"createdBy"`
+	EventTypes []string  `json:"eventTypes"`
+	Url        string    `json:"url"`
+	Uuid       string    `json:"uuid"`
+}
+
+// ListAuditEventsParams defines parameters for ListAuditEvents.
+type ListAuditEventsParams struct {
+	// Actor UUID of the user who acted.
+	Actor *string `form:"actor,omitempty" json:"actor,omitempty"`
+	// Target UUID of the user acted upon.
+	Target *string `form:"target,omitempty" json:"target,omitempty"`
+	Action *string `form:"action,omitempty" json:"action,omitempty"`
+	// From Inclusive start of the time range.
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+	// To Exclusive end of the time range.
+	To     *time.Time `form:"to,omitempty" json:"to,omitempty"`
+	Cursor *string    `form:"cursor,omitempty" json:"cursor,omitempty"`
+	Limit  *int       `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ListUsersParams defines parameters for ListUsers.
+type ListUsersParams struct {
+	// Email Part of the email, matched ignoring case.
+	Email *string `form:"email,omitempty" json:"email,omitempty"`
+	Role  *string `form:"role,omitempty" json:"role,omitempty"`
+	// CreatedFrom Inclusive start of the registration time range.
+	CreatedFrom *time.Time `form:"createdFrom,omitempty" json:"createdFrom,omitempty"`
+	// CreatedTo Exclusive end of the registration time range.
+	CreatedTo *time.Time `form:"createdTo,omitempty" json:"createdTo,omitempty"`
+	// Status Status in effect now, so lifted suspensions are active.
+	Status *AccountState         `form:"status,omitempty" json:"status,omitempty"`
+	Sort   *ListUsersParamsSort  `form:"sort,omitempty" json:"sort,omitempty"`
+	Order  *ListUsersParamsOrder `form:"order,omitempty" json:"order,omitempty"`
+	Cursor *string               `form:"cursor,omitempty" json:"cursor,omitempty"`
+	Limit  *int                  `form:"limit,omitempty" json:"limit,omitempty"`
+	// WithTotal Count the users matching the filters.
+	WithTotal *bool `form:"withTotal,omitempty" json:"withTotal,omitempty"`
+}
+
+// ImportUsersParams defines parameters for ImportUsers.
+type ImportUsersParams struct {
+	// Format File format, jsonl or csv.
+	Format string `form:"format" json:"format"`
+	// DryRun Check the rows without creating users.
+	DryRun *bool `form:"dryRun,omitempty" json:"dryRun,omitempty"`
+}
+
+// ChangeUserStatusParams defines parameters for ChangeUserStatus.
+type ChangeUserStatusParams struct {
+	// IfMatch ETag of the user as last read. The change is refused with 412 if
+	// the user has changed since.
+	IfMatch *string `json:"If-Match,omitempty"`
+}
+
+// ListWebhookDeliveriesParams defines parameters for ListWebhookDeliveries.
+type ListWebhookDeliveriesParams struct {
+	State *WebhookDeliveryState `form:"state,omitempty" json:"state,omitempty"`
+	Limit *int                  `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// UpdateCurrentUserParams defines parameters for UpdateCurrentUser.
+type UpdateCurrentUserParams struct {
+	// IfMatch ETag of the user as last read. The change is refused with 412 if
+	// the user has changed since.
+	IfMatch *string `json:"If-Match,omitempty"`
+}
+
+// ListSecurityEventsParams defines parameters for ListSecurityEvents.
+type ListSecurityEventsParams struct {
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+	Limit  *int    `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// RegisterUserParams defines parameters for RegisterUser.
+type RegisterUserParams struct {
+	// IdempotencyKey Unique key of the request. A retry with the same key and body gets
+	// the original response with the Idempotent-Replayed header set.
+	// Keys expire after a day by default.
+	IdempotencyKey *string `json:"Idempotency-Key,omitempty"`
 }
 
+// GetUserAvatarParams defines parameters for GetUserAvatar.
+type GetUserAvatarParams struct {
+	// V Version of the avatar, only used to bust caches.
+	V *string `form:"v,omitempty" json:"v,omitempty"`
+}
+
+// ListUsersParamsOrder defines parameters for ListUsers.
+type ListUsersParamsOrder string
+
+// ListUsersParamsSort defines parameters for ListUsers.
+type ListUsersParamsSort string
+
+// UploadAvatarMultipartBody defines parameters for UploadAvatar.
+type UploadAvatarMultipartBody struct {
+	Image openapi_types.File `json:"image"`
+}
+
+// CreateInvitationJSONRequestBody defines body for CreateInvitation for application/json ContentType.
+type CreateInvitationJSONRequestBody = PostInvitation
+
+// ChangeUserStatusJSONRequestBody defines body for ChangeUserStatus for application/json ContentType.
+type ChangeUserStatusJSONRequestBody = PutAccountStatus
+
+// CreateWebhookSubscriptionJSONRequestBody defines body for CreateWebhookSubscription for application/json ContentType.
+type CreateWebhookSubscriptionJSONRequestBody = PostWebhookSubscription
+
 // LoginUserJSONRequestBody defines body for LoginUser for application/json ContentType.
 type LoginUserJSONRequestBody = PostLogin
 
+// UploadAvatarMultipartRequestBody defines body for UploadAvatar for multipart/form-data ContentType.
+type UploadAvatarMultipartRequestBody = UploadAvatarMultipartBody
+
+// CreatePersonalAccessTokenJSONRequestBody defines body for CreatePersonalAccessToken for application/json ContentType.
+type CreatePersonalAccessTokenJSONRequestBody = PostPersonalAccessToken
+
 // RegisterUserJSONRequestBody defines body for RegisterUser for application/json ContentType.
 type RegisterUserJSONRequestBody = PostRegister
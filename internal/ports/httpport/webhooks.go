@@ -0,0 +1,178 @@
+package httpport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/commonerrs"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+func (s Server) ListWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	subscriptions, err := s.app.Queries.ListWebhookSubscriptions.Handle(r.Context(), query.ListWebhookSubscriptions{})
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]WebhookSubscription, len(subscriptions))
+	for i, sub := range subscriptions {
+		res[i] = WebhookSubscription{
+			Uuid:       sub.UUID,
+			Url:        sub.URL,
+			EventTypes: sub.EventTypes,
+			CreatedBy:  sub.CreatedBy,
+			CreatedAt:  sub.CreatedAt,
+		}
+	}
+
+	render.JSON(w, r, res)
+}
+
+func (s Server) CreateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var postSubscription PostWebhookSubscription
+	if err := render.Decode(r, &postSubscription); err != nil {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	secret, err := auth.NewWebhookSecret()
+	if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	subscriptionUUID := uuid.NewString()
+	err = s.app.Commands.CreateWebhookSubscription.Handle(r.Context(), command.CreateWebhookSubscription{
+		UUID:       subscriptionUUID,
+		URL:        postSubscription.Url,
+		EventTypes: postSubscription.EventTypes,
+		Secret:     secret,
+		CreatedBy:  principal.UserUUID,
+	})
+	if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, CreatedWebhookSubscription{
+		Uuid:   subscriptionUUID,
+		Secret: secret,
+	})
+}
+
+func (s Server) DeleteWebhookSubscription(w http.ResponseWriter, r *http.Request, uuid string) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	err := s.app.Commands.DeleteWebhookSubscription.Handle(r.Context(), command.DeleteWebhookSubscription{
+		UUID: uuid,
+	})
+	if errors.Is(err, auth.ErrWebhookSubscriptionNotFound) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) ListWebhookDeliveries(
+	w http.ResponseWriter,
+	r *http.Request,
+	uuid string,
+	params ListWebhookDeliveriesParams,
+) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	q := query.ListWebhookDeliveries{
+		SubscriptionUUID: uuid,
+	}
+	if params.State != nil {
+		q.State = string(*params.State)
+	}
+	if params.Limit != nil {
+		q.Limit = *params.Limit
+	}
+
+	deliveries, err := s.app.Queries.ListWebhookDeliveries.Handle(r.Context(), q)
+	if errors.Is(err, auth.ErrWebhookSubscriptionNotFound) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if errors.As(err, &commonerrs.InvalidInputError{}) {
+		httpError(w, r, err, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		res[i] = mapWebhookDeliveryToAPI(d)
+	}
+
+	render.JSON(w, r, res)
+}
+
+func mapWebhookDeliveryToAPI(d query.WebhookDelivery) WebhookDelivery {
+	res := WebhookDelivery{
+		Uuid:          d.UUID,
+		EventId:       d.EventID,
+		EventType:     d.EventType,
+		State:         WebhookDeliveryState(d.State),
+		Attempts:      d.Attempts,
+		NextAttemptAt: timeToAPI(d.NextAttemptAt),
+		LastAttemptAt: timeToAPI(d.LastAttemptAt),
+		LastError:     stringToAPI(d.LastError),
+		CreatedAt:     d.CreatedAt,
+	}
+	if d.LastStatusCode != 0 {
+		res.LastStatusCode = &d.LastStatusCode
+	}
+
+	return res
+}
+
+func (s Server) RedeliverWebhook(w http.ResponseWriter, r *http.Request, uuid string, deliveryUuid string) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	err := s.app.Commands.RedeliverWebhook.Handle(r.Context(), command.RedeliverWebhook{
+		SubscriptionUUID: uuid,
+		DeliveryUUID:     deliveryUuid,
+	})
+	if errors.Is(err, auth.ErrWebhookDeliveryNotFound) {
+		httpError(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
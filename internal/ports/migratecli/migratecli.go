@@ -0,0 +1,83 @@
+// Package migratecli is the "migrate" subcommand of the service binary:
+//
+//	migrate up          apply the pending migrations
+//	migrate down [n]    revert the last n applied migrations, 1 by default
+//	migrate status      list the migrations and whether they are applied
+package migratecli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/migrate"
+)
+
+var ErrUsage = errors.New("usage: migrate up | down [n] | status")
+
+func Run(ctx context.Context, m *migrate.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		applied, err := m.Up(ctx)
+		for _, v := range applied {
+			fmt.Fprintf(out, "applied %d\n", v)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return ErrUsage
+			}
+		} else if len(args) > 2 {
+			return ErrUsage
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, v := range reverted {
+			fmt.Fprintf(out, "reverted %d\n", v)
+		}
+		return err
+
+	case "status":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return writeStatus(out, statuses)
+
+	default:
+		return ErrUsage
+	}
+}
+
+func writeStatus(out io.Writer, statuses []migrate.Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := ""
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,47 @@
+// Package outboxrelay publishes the events stored in the outbox in the
+// background.
+package outboxrelay
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+)
+
+const DefaultInterval = 5 * time.Second
+
+// Run relays the outbox every interval until ctx is done. Failures are logged
+// by the command decorators and retried on the next tick.
+func Run(ctx context.Context, relay command.RelayOutboxHandler, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = relay.Handle(ctx, command.RelayOutbox{BatchSize: batchSize})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunFromEnv takes the interval from OUTBOX_RELAY_INTERVAL, such as 10s, and
+// the batch size from OUTBOX_RELAY_BATCH_SIZE.
+func RunFromEnv(ctx context.Context, relay command.RelayOutboxHandler) {
+	interval, err := time.ParseDuration(os.Getenv("OUTBOX_RELAY_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	batchSize, err := strconv.Atoi(os.Getenv("OUTBOX_RELAY_BATCH_SIZE"))
+	if err != nil || batchSize <= 0 {
+		batchSize = command.DefaultRelayBatchSize
+	}
+
+	Run(ctx, relay, interval, batchSize)
+}
@@ -0,0 +1,264 @@
+// Package userimport reads users with their password hashes from JSONL or CSV
+// files and imports them row by row, for both the CLI and the admin endpoint.
+//
+// Every row has the fields uuid, email, passhash and created_at. A missing
+// uuid is generated, a missing created_at means now. CSV files start with a
+// header naming the columns in any order.
+package userimport
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+)
+
+var ErrUnknownFormat = errors.New("unknown import format, expected jsonl or csv")
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatJSONL, FormatCSV:
+		return f, nil
+	case "ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// FormatFromFilename guesses the format by the file extension.
+func FormatFromFilename(name string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+type Result struct {
+	Line  int    `json:"line"`
+	UUID  string `json:"uuid,omitempty"`
+	Email string `json:"email,omitempty"`
+	// Error is empty if the row is imported, or could be with a dry run.
+	Error string `json:"error,omitempty"`
+}
+
+type Report struct {
+	DryRun   bool     `json:"dryRun"`
+	Imported int      `json:"imported"`
+	Failed   int      `json:"failed"`
+	Rows     []Result `json:"rows"`
+}
+
+type row struct {
+	UUID      string `json:"uuid"`
+	Email     string `json:"email"`
+	Passhash  string `json:"passhash"`
+	CreatedAt string `json:"created_at"`
+}
+
+var errDuplicateRow = errors.New("uuid or email is already used by an earlier row")
+
+// Run imports every row of r. A row that can not be parsed or imported is
+// reported and does not stop the import; only an unreadable file does.
+func Run(
+	ctx context.Context,
+	handler command.ImportUserHandler,
+	r io.Reader,
+	format Format,
+	dryRun bool,
+) (Report, error) {
+	report := Report{DryRun: dryRun, Rows: make([]Result, 0)}
+
+	// The repository only catches duplicates within the file once they are
+	// saved, which a dry run never does.
+	seen := make(map[string]struct{})
+
+	err := readRows(r, format, func(line int, row row, parseErr error) error {
+		if parseErr == nil && row.UUID == "" {
+			generated, err := uuid.NewV7()
+			if err != nil {
+				return err
+			}
+			row.UUID = generated.String()
+		}
+		res := Result{Line: line, UUID: row.UUID, Email: row.Email}
+
+		err := parseErr
+		if err == nil {
+			err = importRow(ctx, handler, row, seen, dryRun)
+		}
+
+		if err != nil {
+			res.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Imported++
+		}
+		report.Rows = append(report.Rows, res)
+
+		return ctx.Err()
+	})
+
+	return report, err
+}
+
+func importRow(
+	ctx context.Context,
+	handler command.ImportUserHandler,
+	row row,
+	seen map[string]struct{},
+	dryRun bool,
+) error {
+	email, err := auth.NewEmail(row.Email)
+	if err != nil {
+		return err
+	}
+
+	uuidKey, emailKey := "uuid:"+row.UUID, "email:"+email.Normalized()
+	if _, ok := seen[uuidKey]; ok {
+		return errDuplicateRow
+	}
+	if _, ok := seen[emailKey]; ok {
+		return errDuplicateRow
+	}
+
+	var createdAt time.Time
+	if row.CreatedAt != "" {
+		createdAt, err = time.Parse(time.RFC3339, row.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("invalid created_at: %w", err)
+		}
+	}
+
+	err = handler.Handle(ctx, command.ImportUser{
+		UUID:      row.UUID,
+		Email:     row.Email,
+		Passhash:  []byte(row.Passhash),
+		CreatedAt: createdAt,
+		DryRun:    dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	seen[uuidKey] = struct{}{}
+	seen[emailKey] = struct{}{}
+
+	return nil
+}
+
+type rowFunc func(line int, row row, parseErr error) error
+
+func readRows(r io.Reader, format Format, fn rowFunc) error {
+	switch format {
+	case FormatJSONL:
+		return readJSONL(r, fn)
+	case FormatCSV:
+		return readCSV(r, fn)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// maxJSONLLineSize bounds the memory a single malformed line can take.
+const maxJSONLLineSize = 64 * 1024
+
+func readJSONL(r io.Reader, fn rowFunc) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), maxJSONLLineSize)
+
+	line := 0
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+
+		var row row
+		err := json.Unmarshal([]byte(text), &row)
+		if err != nil {
+			err = fmt.Errorf("invalid JSON: %w", err)
+		}
+
+		if err = fn(line, row, err); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+func readCSV(r io.Reader, fn rowFunc) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"email", "passhash"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("csv header has no %q column", required)
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err = fn(parseErr.Line, row{}, err); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		err = fn(line, row{
+			UUID:      field("uuid"),
+			Email:     field("email"),
+			Passhash:  field("passhash"),
+			CreatedAt: field("created_at"),
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+}
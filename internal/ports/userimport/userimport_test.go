@@ -0,0 +1,104 @@
+package userimport_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs/handlers/slogdiscard"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/ports/userimport"
+	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+)
+
+const (
+	djangoHash = "pbkdf2_sha256$1000$seasalt$YIWkt6M1JFXrHg5s0jZjBSc7C2Cz6QvchSJ0h8Y+i7c="
+	bcryptHash = "$2a$04$OvJYA8.6GB/EQK9R9YQmBulpRTefPK9LQv3DqoQ65up0vTRy9GoAy"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		format userimport.Format
+		input  string
+	}{
+		{
+			name:   "jsonl",
+			format: userimport.FormatJSONL,
+			input: strings.Join([]string{
+				`{"uuid":"a8098c1a-f86e-11da-bd1a-00112444be1e","email":"django@example.com","passhash":"` + djangoHash + `"}`,
+				`{"email":"itsreg@example.com","passhash":"` + bcryptHash + `","created_at":"2020-01-02T03:04:05Z"}`,
+				`{"email":"md5@example.com","passhash":"md5$salt$hash"}`,
+				`{"email":"DJANGO@example.com","passhash":"` + djangoHash + `"}`,
+				`not json`,
+			}, "\n"),
+		},
+		{
+			name:   "csv",
+			format: userimport.FormatCSV,
+			input: strings.Join([]string{
+				`email,passhash,uuid,created_at`,
+				`django@example.com,` + djangoHash + `,a8098c1a-f86e-11da-bd1a-00112444be1e,`,
+				`itsreg@example.com,` + bcryptHash + `,,2020-01-02T03:04:05Z`,
+				`md5@example.com,md5$salt$hash,,`,
+				`DJANGO@example.com,` + djangoHash + `,,`,
+				`broken,"unterminated`,
+			}, "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		for _, dryRun := range []bool{true, false} {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				users := mocks.NewMockUserRepository()
+				audit := mocks.NewMockAuditLogRepository()
+				h := command.NewImportUserHandler(
+					users, audit, passhash.NewDefaultHasher(), mocks.NewMockUnitOfWork(users, audit),
+					slogdiscard.NewDiscardLogger(), metrics.NoOp{},
+				)
+
+				report, err := userimport.Run(ctx, h, strings.NewReader(tt.input), tt.format, dryRun)
+				require.NoError(t, err)
+				require.Equal(t, dryRun, report.DryRun)
+				require.Equal(t, 2, report.Imported)
+				require.Equal(t, 3, report.Failed)
+				require.Len(t, report.Rows, 5)
+
+				require.Empty(t, report.Rows[0].Error)
+				require.Equal(t, "a8098c1a-f86e-11da-bd1a-00112444be1e", report.Rows[0].UUID)
+				require.Empty(t, report.Rows[1].Error)
+				require.Equal(t, uuid.Version(7), uuid.MustParse(report.Rows[1].UUID).Version())
+				require.Contains(t, report.Rows[2].Error, "unsupported password hash format")
+				require.NotEmpty(t, report.Rows[3].Error)
+				require.NotEmpty(t, report.Rows[4].Error)
+
+				_, err = users.UserByEmail(ctx, auth.MustNewEmail("django@example.com"))
+				if dryRun {
+					require.Error(t, err)
+				} else {
+					require.NoError(t, err)
+				}
+			})
+		}
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	f, err := userimport.FormatFromFilename("users.csv")
+	require.NoError(t, err)
+	require.Equal(t, userimport.FormatCSV, f)
+
+	f, err = userimport.FormatFromFilename("users.ndjson")
+	require.NoError(t, err)
+	require.Equal(t, userimport.FormatJSONL, f)
+
+	_, err = userimport.FormatFromFilename("users.xml")
+	require.ErrorIs(t, err, userimport.ErrUnknownFormat)
+}
@@ -0,0 +1,40 @@
+// Package webhookdelivery sends the queued webhook deliveries in the
+// background.
+package webhookdelivery
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+)
+
+const DefaultInterval = 5 * time.Second
+
+// Run sends the due deliveries every interval until ctx is done. Failures
+// are logged by the command decorators.
+func Run(ctx context.Context, deliver command.DeliverWebhooksHandler, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = deliver.Handle(ctx, command.DeliverWebhooks{})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunFromEnv takes the interval from WEBHOOK_DELIVERY_INTERVAL, such as 10s.
+func RunFromEnv(ctx context.Context, deliver command.DeliverWebhooksHandler) {
+	interval, err := time.ParseDuration(os.Getenv("WEBHOOK_DELIVERY_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	Run(ctx, deliver, interval)
+}
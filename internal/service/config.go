@@ -0,0 +1,275 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/passhash"
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
+)
+
+type config struct {
+	concealExistingEmails bool
+	registrationPolicy    auth.RegistrationPolicy
+	passwordPolicy        auth.PasswordPolicy
+	passwordHasher        auth.PasswordHasher
+
+	hashingConcurrency int
+	hashingQueueSize   int
+
+	webhookRetryPolicy auth.WebhookRetryPolicy
+}
+
+// configFromEnv falls back to the defaults for unset or malformed variables.
+func configFromEnv() config {
+	policy := auth.DefaultPasswordPolicy
+	policy.MinLength = intFromEnv("PASSWORD_MIN_LENGTH", policy.MinLength)
+	policy.MinCharClasses = intFromEnv("PASSWORD_MIN_CHAR_CLASSES", policy.MinCharClasses)
+	policy.CheckBreached = boolFromEnv("PASSWORD_CHECK_BREACHED", policy.CheckBreached)
+	if words := os.Getenv("PASSWORD_BANNED_WORDS"); words != "" {
+		policy.BannedWords = strings.Split(words, ";")
+	}
+
+	return config{
+		concealExistingEmails: ConcealExistingEmails(),
+		registrationPolicy:    registrationPolicyFromEnv(),
+		passwordPolicy:        policy,
+		passwordHasher:        passwordHasherFromEnv(),
+
+		hashingConcurrency: intFromEnv("HASHING_CONCURRENCY", defaultHashingConcurrency()),
+		hashingQueueSize:   intFromEnv("HASHING_QUEUE_SIZE", defaultHashingQueueSize),
+
+		webhookRetryPolicy: webhookRetryPolicyFromEnv(),
+	}
+}
+
+func webhookRetryPolicyFromEnv() auth.WebhookRetryPolicy {
+	policy := auth.DefaultWebhookRetryPolicy
+	policy.MaxAttempts = max(1, intFromEnv("WEBHOOK_MAX_ATTEMPTS", policy.MaxAttempts))
+	if d, err := time.ParseDuration(os.Getenv("WEBHOOK_RETRY_BASE_DELAY")); err == nil && d > 0 {
+		policy.BaseDelay = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("WEBHOOK_RETRY_MAX_DELAY")); err == nil && d > 0 {
+		policy.MaxDelay = d
+	}
+	return policy
+}
+
+// newWebhookClient does not follow redirects.
+func newWebhookClient() *http.Client {
+	return &http.Client{
+		Timeout: defaultEventsTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func registrationPolicyFromEnv() auth.RegistrationPolicy {
+	mode := os.Getenv("REGISTRATION_MODE")
+	if mode == "" {
+		return auth.DefaultRegistrationPolicy
+	}
+
+	registrationMode, err := auth.NewRegistrationMode(mode)
+	if err != nil {
+		panic(err)
+	}
+
+	var domains []string
+	if v := os.Getenv("REGISTRATION_ALLOWED_DOMAINS"); v != "" {
+		domains = strings.Split(v, ",")
+	}
+
+	policy, err := auth.NewRegistrationPolicy(registrationMode, domains)
+	if err != nil {
+		panic(err)
+	}
+
+	return policy
+}
+
+// passwordHasherFromEnv hashes with PASSWORD_HASH_ALGORITHM and verifies the
+// other algorithms too.
+func passwordHasherFromEnv() auth.PasswordHasher {
+	bcrypt := passhash.DefaultBcrypt
+	bcrypt.Cost = intFromEnv("BCRYPT_COST", bcrypt.Cost)
+
+	argon2id := passhash.DefaultArgon2id
+	argon2id.Memory = uint32(intFromEnv("ARGON2ID_MEMORY_KIB", int(argon2id.Memory)))
+	argon2id.Iterations = uint32(intFromEnv("ARGON2ID_ITERATIONS", int(argon2id.Iterations)))
+	argon2id.Parallelism = uint8(intFromEnv("ARGON2ID_PARALLELISM", int(argon2id.Parallelism)))
+
+	if os.Getenv("PASSWORD_HASH_ALGORITHM") == "bcrypt" {
+		return passhash.NewHasher(bcrypt, argon2id, passhash.DefaultPBKDF2SHA256)
+	}
+	return passhash.NewHasher(argon2id, bcrypt, passhash.DefaultPBKDF2SHA256)
+}
+
+func sqlitePathFromEnv() (string, bool) {
+	return strings.CutPrefix(os.Getenv("DATABASE_URI"), "sqlite://")
+}
+
+// databaseFromEnv connects to DATABASE_URI, a Postgres URL or sqlite://<path>.
+func databaseFromEnv() *sqlx.DB {
+	path, ok := sqlitePathFromEnv()
+	if !ok {
+		return sqlx.MustConnect("postgres", os.Getenv("DATABASE_URI"))
+	}
+
+	db, err := infra.OpenSQLite(path)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// cachedUsersFromEnv returns a nil invalidator if USER_CACHE_SIZE is 0.
+func cachedUsersFromEnv(
+	users auth.UsersRepository,
+	changes infra.UserChanges,
+	publisher command.Publisher,
+	metricsClient decorator.MetricsClient,
+) (auth.UsersRepository, command.Publisher, infra.UserCacheInvalidator) {
+	size := intFromEnv("USER_CACHE_SIZE", infra.DefaultUserCacheSize)
+	if size <= 0 {
+		return users, publisher, nil
+	}
+
+	ttl, err := time.ParseDuration(os.Getenv("USER_CACHE_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = infra.DefaultUserCacheTTL
+	}
+
+	users, invalidator := infra.NewCachedUserRepository(users, infra.NewMemoryUserCache(size, ttl), changes, metricsClient)
+	return users, infra.NewUserCacheInvalidatingPublisher(publisher, invalidator), invalidator
+}
+
+func listenUserChangesFromEnv(invalidator infra.UserCacheInvalidator, logger *slog.Logger) Cleanup {
+	if invalidator == nil {
+		return func() {}
+	}
+
+	stop, err := infra.ListenPgUserChanges(os.Getenv("DATABASE_URI"), invalidator, logger)
+	if err != nil {
+		panic(err)
+	}
+	return Cleanup(stop)
+}
+
+const defaultAvatarStorageDir = "data/avatars"
+
+func blobStorageFromEnv() auth.BlobStorage {
+	var (
+		storage auth.BlobStorage
+		err     error
+	)
+
+	switch os.Getenv("AVATAR_STORAGE") {
+	case "", "fs":
+		dir := os.Getenv("AVATAR_STORAGE_DIR")
+		if dir == "" {
+			dir = defaultAvatarStorageDir
+		}
+		storage, err = infra.NewFSBlobStorage(dir)
+	case "s3":
+		storage, err = infra.NewS3BlobStorage(infra.S3Config{
+			Endpoint:        os.Getenv("AVATAR_S3_ENDPOINT"),
+			Region:          os.Getenv("AVATAR_S3_REGION"),
+			Bucket:          os.Getenv("AVATAR_S3_BUCKET"),
+			AccessKeyID:     os.Getenv("AVATAR_S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AVATAR_S3_SECRET_ACCESS_KEY"),
+		}, nil)
+	default:
+		err = fmt.Errorf("unknown AVATAR_STORAGE %q", os.Getenv("AVATAR_STORAGE"))
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return storage
+}
+
+func publisherFromEnv(logger *slog.Logger) command.Publisher {
+	var (
+		publisher command.Publisher
+		err       error
+	)
+
+	switch os.Getenv("EVENTS_PUBLISHER") {
+	case "", "log":
+		publisher = infra.NewLogPublisher(logger)
+	case "webhook":
+		publisher, err = infra.NewWebhookPublisher(os.Getenv("EVENTS_WEBHOOK_URL"), &http.Client{
+			Timeout: defaultEventsTimeout,
+		})
+	case "nats":
+		prefix := os.Getenv("EVENTS_NATS_SUBJECT_PREFIX")
+		if prefix == "" {
+			prefix = defaultEventsNATSSubjectPrefix
+		}
+		publisher, err = infra.NewNATSPublisher(infra.NATSConfig{
+			URL:           os.Getenv("EVENTS_NATS_URL"),
+			SubjectPrefix: prefix,
+			Timeout:       defaultEventsTimeout,
+		})
+	default:
+		err = fmt.Errorf("unknown EVENTS_PUBLISHER %q", os.Getenv("EVENTS_PUBLISHER"))
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return publisher
+}
+
+const (
+	defaultEventsTimeout           = 10 * time.Second
+	defaultEventsNATSSubjectPrefix = "itsreg.auth"
+)
+
+func componentTestConfig() config {
+	return config{
+		registrationPolicy: auth.DefaultRegistrationPolicy,
+		passwordPolicy:     auth.DefaultPasswordPolicy,
+		passwordHasher:     passhash.NewDefaultHasher(),
+
+		hashingConcurrency: defaultHashingConcurrency(),
+		hashingQueueSize:   defaultHashingQueueSize,
+
+		webhookRetryPolicy: auth.DefaultWebhookRetryPolicy,
+	}
+}
+
+const defaultHashingQueueSize = 64
+
+func defaultHashingConcurrency() int {
+	return max(1, runtime.NumCPU()-1)
+}
+
+func intFromEnv(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func boolFromEnv(key string, fallback bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+// FakePublisher keeps the published messages in memory.
+type FakePublisher struct {
+	mu       sync.Mutex
+	messages []*auth.OutboxMessage
+	// Fail, if set, decides which messages fail to publish.
+	Fail func(m *auth.OutboxMessage) error
+}
+
+func NewFakePublisher() *FakePublisher {
+	return &FakePublisher{}
+}
+
+func (p *FakePublisher) Publish(_ context.Context, m *auth.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Fail != nil {
+		if err := p.Fail(m); err != nil {
+			return err
+		}
+	}
+
+	p.messages = append(p.messages, m)
+
+	return nil
+}
+
+func (p *FakePublisher) Messages() []*auth.OutboxMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*auth.OutboxMessage(nil), p.messages...)
+}
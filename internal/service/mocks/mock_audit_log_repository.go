@@ -0,0 +1,118 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type mockAuditLogRepository struct {
+	sync.RWMutex
+	events []auth.AuditEvent
+}
+
+func NewMockAuditLogRepository() auth.AuditLogRepository {
+	return &mockAuditLogRepository{}
+}
+
+func (r *mockAuditLogRepository) Append(ctx context.Context, e *auth.AuditEvent) error {
+	r.Lock()
+	defer r.Unlock()
+
+	var prev *auth.AuditEvent
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if r.events[i].ChainKey() == e.ChainKey() {
+			prev = &r.events[i]
+			break
+		}
+	}
+	e.Chain(int64(len(r.events)+1), prev)
+
+	r.events = append(r.events, *e)
+
+	return nil
+}
+
+func (r *mockAuditLogRepository) ListAuditEvents(
+	ctx context.Context,
+	l auth.ListAuditEvents,
+) (auth.AuditEventsPage, error) {
+	beforeSeq, err := l.BeforeSeq()
+	if err != nil {
+		return auth.AuditEventsPage{}, err
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+
+	var page auth.AuditEventsPage
+	for i := len(r.events) - 1; i >= 0; i-- {
+		e := r.events[i]
+		if beforeSeq > 0 && e.Seq >= beforeSeq || !l.Filter.Matches(&e) {
+			continue
+		}
+
+		if len(page.Events) == l.Limit {
+			page.NextCursor = l.NextCursor(page.Events[len(page.Events)-1])
+			break
+		}
+		page.Events = append(page.Events, &e)
+	}
+
+	return page, nil
+}
+
+func (r *mockAuditLogRepository) AuditChainKeys(ctx context.Context, after string, limit int) ([]string, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var keys []string
+	for i := range r.events {
+		key := r.events[i].ChainKey()
+		if key > after && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys[:min(limit, len(keys))], nil
+}
+
+func (r *mockAuditLogRepository) AuditChainEvents(
+	ctx context.Context,
+	chain string,
+	seq int64,
+	limit int,
+) ([]*auth.AuditEvent, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var events []*auth.AuditEvent
+	for i := range r.events {
+		if r.events[i].Seq <= seq || r.events[i].ChainKey() != chain {
+			continue
+		}
+		if len(events) == limit {
+			break
+		}
+		e := r.events[i]
+		events = append(events, &e)
+	}
+
+	return events, nil
+}
+
+func (r *mockAuditLogRepository) snapshot() func() {
+	r.RLock()
+	n := len(r.events)
+	r.RUnlock()
+
+	return func() {
+		r.Lock()
+		defer r.Unlock()
+
+		r.events = r.events[:n]
+	}
+}
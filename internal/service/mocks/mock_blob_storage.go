@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type mockBlobStorage struct {
+	sync.RWMutex
+	m map[string][]byte
+}
+
+func NewMockBlobStorage() auth.BlobStorage {
+	return &mockBlobStorage{
+		m: make(map[string][]byte),
+	}
+}
+
+func (s *mockBlobStorage) Put(ctx context.Context, key string, data []byte, _ string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.m[key] = bytes.Clone(data)
+
+	return nil
+}
+
+func (s *mockBlobStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	data, ok := s.m[key]
+	if !ok {
+		return nil, auth.ErrBlobNotFound
+	}
+
+	return bytes.Clone(data), nil
+}
+
+func (s *mockBlobStorage) Delete(ctx context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.m, key)
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type mockInvitationsRepository struct {
+	sync.RWMutex
+	m map[string]auth.Invitation
+}
+
+func NewMockInvitationsRepository() auth.InvitationsRepository {
+	return &mockInvitationsRepository{
+		m: make(map[string]auth.Invitation),
+	}
+}
+
+func (r *mockInvitationsRepository) Save(ctx context.Context, i *auth.Invitation) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.m[i.UUID]; ok {
+		return auth.ErrInvitationAlreadyExists
+	}
+
+	for _, invitation := range r.m {
+		if bytes.Equal(invitation.CodeHash, i.CodeHash) {
+			return auth.ErrInvitationAlreadyExists
+		}
+	}
+
+	r.m[i.UUID] = *i
+
+	return nil
+}
+
+func (r *mockInvitationsRepository) InvitationByCodeHash(ctx context.Context, hash []byte) (*auth.Invitation, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, i := range r.m {
+		if bytes.Equal(i.CodeHash, hash) {
+			return &i, nil
+		}
+	}
+
+	return nil, auth.ErrInvitationNotFound
+}
+
+func (r *mockInvitationsRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(ctx context.Context, i *auth.Invitation) error,
+) error {
+	r.Lock()
+	defer r.Unlock()
+
+	i, ok := r.m[uuid]
+	if !ok {
+		return auth.ErrInvitationNotFound
+	}
+
+	err := updateFn(ctx, &i)
+	if err != nil {
+		return err
+	}
+
+	r.m[uuid] = i
+
+	return nil
+}
+
+func (r *mockInvitationsRepository) snapshot() func() {
+	r.RLock()
+	m := maps.Clone(r.m)
+	r.RUnlock()
+
+	return func() {
+		r.Lock()
+		defer r.Unlock()
+
+		r.m = m
+	}
+}
@@ -0,0 +1,77 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type mockOutboxRepository struct {
+	sync.Mutex
+	messages []*auth.OutboxMessage
+	lastID   int64
+	relaying bool
+}
+
+func (r *mockOutboxRepository) save(events []auth.Event) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, e := range events {
+		m, err := auth.NewOutboxMessage(e)
+		if err != nil {
+			return err
+		}
+
+		r.lastID++
+		m.ID = r.lastID
+		r.messages = append(r.messages, m)
+	}
+
+	return nil
+}
+
+func (r *mockOutboxRepository) RelayOutbox(ctx context.Context, limit int, relayFn auth.RelayFn) (int, error) {
+	r.Lock()
+	if r.relaying {
+		r.Unlock()
+		return 0, nil
+	}
+	r.relaying = true
+	messages := slices.Clone(r.messages[:min(limit, len(r.messages))])
+	r.Unlock()
+
+	var ids []int64
+	if len(messages) > 0 {
+		ids = relayFn(ctx, messages)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.relaying = false
+	r.messages = slices.DeleteFunc(r.messages, func(m *auth.OutboxMessage) bool {
+		return slices.Contains(ids, m.ID)
+	})
+
+	return len(ids), nil
+}
+
+// snapshot forgets the messages stored after it on restore. Messages relayed
+// meanwhile stay relayed.
+func (r *mockOutboxRepository) snapshot() func() {
+	r.Lock()
+	lastID := r.lastID
+	r.Unlock()
+
+	return func() {
+		r.Lock()
+		defer r.Unlock()
+
+		r.messages = slices.DeleteFunc(r.messages, func(m *auth.OutboxMessage) bool {
+			return m.ID > lastID
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type mockPersonalAccessTokensRepository struct {
+	sync.RWMutex
+	m map[string]auth.PersonalAccessToken
+}
+
+func NewMockPersonalAccessTokensRepository() auth.PersonalAccessTokensRepository {
+	return &mockPersonalAccessTokensRepository{
+		m: make(map[string]auth.PersonalAccessToken),
+	}
+}
+
+func (r *mockPersonalAccessTokensRepository) Save(ctx context.Context, t *auth.PersonalAccessToken) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.m[t.UUID]; ok {
+		return auth.ErrPersonalAccessTokenAlreadyExists
+	}
+
+	for _, token := range r.m {
+		if bytes.Equal(token.TokenHash, t.TokenHash) {
+			return auth.ErrPersonalAccessTokenAlreadyExists
+		}
+	}
+
+	r.m[t.UUID] = *t
+
+	return nil
+}
+
+func (r *mockPersonalAccessTokensRepository) PersonalAccessToken(
+	ctx context.Context,
+	uuid string,
+) (*auth.PersonalAccessToken, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	t, ok := r.m[uuid]
+	if !ok {
+		return nil, auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+	}
+
+	return &t, nil
+}
+
+func (r *mockPersonalAccessTokensRepository) PersonalAccessTokenByHash(
+	ctx context.Context,
+	hash []byte,
+) (*auth.PersonalAccessToken, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, t := range r.m {
+		if bytes.Equal(t.TokenHash, hash) {
+			return &t, nil
+		}
+	}
+
+	return nil, auth.ErrInvalidToken
+}
+
+func (r *mockPersonalAccessTokensRepository) UserPersonalAccessTokens(
+	ctx context.Context,
+	userUUID string,
+) ([]*auth.PersonalAccessToken, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	tokens := make([]*auth.PersonalAccessToken, 0)
+	for _, t := range r.m {
+		if t.UserUUID == userUUID {
+			tokens = append(tokens, &t)
+		}
+	}
+
+	slices.SortFunc(tokens, func(a, b *auth.PersonalAccessToken) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return tokens, nil
+}
+
+func (r *mockPersonalAccessTokensRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(ctx context.Context, t *auth.PersonalAccessToken) error,
+) error {
+	r.Lock()
+	defer r.Unlock()
+
+	t, ok := r.m[uuid]
+	if !ok {
+		return auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+	}
+
+	err := updateFn(ctx, &t)
+	if err != nil {
+		return err
+	}
+
+	r.m[uuid] = t
+
+	return nil
+}
+
+func (r *mockPersonalAccessTokensRepository) Delete(ctx context.Context, uuid string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	_, ok := r.m[uuid]
+	if !ok {
+		return auth.PersonalAccessTokenNotFound{TokenUUID: uuid}
+	}
+
+	delete(r.m, uuid)
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package mocks
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type mockSessionsRepository struct {
+	sync.RWMutex
+	m map[string]auth.Session
+}
+
+func NewMockSessionsRepository() auth.SessionsRepository {
+	return &mockSessionsRepository{
+		m: make(map[string]auth.Session),
+	}
+}
+
+func (r *mockSessionsRepository) Save(ctx context.Context, s *auth.Session) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.m[s.UUID]; ok {
+		return auth.ErrSessionAlreadyExists
+	}
+
+	r.m[s.UUID] = *s
+
+	return nil
+}
+
+func (r *mockSessionsRepository) Session(ctx context.Context, uuid string) (*auth.Session, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	s, ok := r.m[uuid]
+	if !ok {
+		return nil, auth.SessionNotFound{SessionUUID: uuid}
+	}
+
+	return &s, nil
+}
+
+func (r *mockSessionsRepository) UserSessions(ctx context.Context, userUUID string) ([]*auth.Session, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	sessions := make([]*auth.Session, 0)
+	for _, s := range r.m {
+		if s.UserUUID == userUUID {
+			sessions = append(sessions, &s)
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b *auth.Session) int {
+		return b.LastSeenAt.Compare(a.LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+func (r *mockSessionsRepository) Update(
+	ctx context.Context,
+	uuid string,
+	updateFn func(ctx context.Context, s *auth.Session) error,
+) error {
+	r.Lock()
+	defer r.Unlock()
+
+	s, ok := r.m[uuid]
+	if !ok {
+		return auth.SessionNotFound{SessionUUID: uuid}
+	}
+
+	err := updateFn(ctx, &s)
+	if err != nil {
+		return err
+	}
+
+	r.m[uuid] = s
+
+	return nil
+}
+
+func (r *mockSessionsRepository) Delete(ctx context.Context, uuid string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	_, ok := r.m[uuid]
+	if !ok {
+		return auth.SessionNotFound{SessionUUID: uuid}
+	}
+
+	delete(r.m, uuid)
+
+	return nil
+}
+
+func (r *mockSessionsRepository) DeleteUserSessions(ctx context.Context, userUUID string, exceptUUID string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for uuid, s := range r.m {
+		if s.UserUUID == userUUID && uuid != exceptUUID {
+			delete(r.m, uuid)
+		}
+	}
+
+	return nil
+}
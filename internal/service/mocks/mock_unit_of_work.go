@@ -0,0 +1,60 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+)
+
+// snapshotter is a mock repository which can undo its changes.
+type snapshotter interface {
+	// snapshot returns a function restoring the current state.
+	snapshot() (restore func())
+}
+
+type unitOfWorkContextKey struct{}
+
+// mockUnitOfWork restores the repositories if the work fails. Units of work
+// take turns, but changes made outside them meanwhile are undone as well.
+type mockUnitOfWork struct {
+	mu    sync.Mutex
+	repos []snapshotter
+}
+
+// NewMockUnitOfWork covers the given mock repositories. It panics if one of
+// them does not support rollback.
+func NewMockUnitOfWork(repos ...any) decorator.UnitOfWork {
+	u := &mockUnitOfWork{}
+	for _, r := range repos {
+		s, ok := r.(snapshotter)
+		if !ok {
+			panic(fmt.Sprintf("%T does not support units of work", r))
+		}
+		u.repos = append(u.repos, s)
+	}
+	return u
+}
+
+func (u *mockUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(unitOfWorkContextKey{}) == nil {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		ctx = context.WithValue(ctx, unitOfWorkContextKey{}, true)
+	}
+
+	restores := make([]func(), len(u.repos))
+	for i, r := range u.repos {
+		restores[i] = r.snapshot()
+	}
+
+	if err := fn(ctx); err != nil {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+		return err
+	}
+
+	return nil
+}
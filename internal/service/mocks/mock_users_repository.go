@@ -2,6 +2,9 @@ package mocks
 
 import (
 	"context"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
 
 	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
@@ -9,15 +12,27 @@ import (
 
 type mockUserRepository struct {
 	sync.RWMutex
-	m map[string]auth.User
+	m      map[string]auth.User
+	outbox *mockOutboxRepository
 }
 
+// NewMockUserRepository drops the events of the users.
 func NewMockUserRepository() auth.UsersRepository {
 	return &mockUserRepository{
 		m: make(map[string]auth.User),
 	}
 }
 
+// NewMockUserRepositoryWithOutbox stores the events of the users to the
+// returned outbox.
+func NewMockUserRepositoryWithOutbox() (auth.UsersRepository, auth.OutboxRepository) {
+	outbox := &mockOutboxRepository{}
+	return &mockUserRepository{
+		m:      make(map[string]auth.User),
+		outbox: outbox,
+	}, outbox
+}
+
 func (r *mockUserRepository) Save(ctx context.Context, u *auth.User) error {
 	r.Lock()
 	defer r.Unlock()
@@ -27,12 +42,16 @@ func (r *mockUserRepository) Save(ctx context.Context, u *auth.User) error {
 	}
 
 	for _, user := range r.m {
-		if user.Email == u.Email {
+		if user.Email.Equal(u.Email) {
 			return auth.ErrUserAlreadyExists
 		}
 	}
 
-	r.m[u.UUID] = *u
+	if err := r.saveEvents(u.PopEvents()); err != nil {
+		return err
+	}
+
+	r.m[u.UUID] = cloneUser(*u)
 
 	return nil
 }
@@ -46,20 +65,63 @@ func (r *mockUserRepository) User(ctx context.Context, uuid string) (*auth.User,
 		return nil, auth.UserNotFound{UserUUID: uuid}
 	}
 
+	u = cloneUser(u)
 	return &u, nil
 }
 
-func (r *mockUserRepository) UserByEmail(ctx context.Context, email string) (*auth.User, error) {
+func (r *mockUserRepository) UserByEmail(ctx context.Context, email auth.Email) (*auth.User, error) {
 	r.RLock()
 	defer r.RUnlock()
 
 	for _, u := range r.m {
-		if u.Email == email {
+		if u.Email.Equal(email) {
+			u = cloneUser(u)
 			return &u, nil
 		}
 	}
 
-	return nil, auth.UserEmailNotFound{Email: email}
+	return nil, auth.UserEmailNotFound{Email: email.String()}
+}
+
+func (r *mockUserRepository) ListUsers(ctx context.Context, l auth.ListUsers) (auth.UsersPage, error) {
+	cursor, err := l.DecodeCursor()
+	if err != nil {
+		return auth.UsersPage{}, err
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+
+	var matched []*auth.User
+	total := 0
+	for _, u := range r.m {
+		if !l.Filter.Matches(&u) {
+			continue
+		}
+		total++
+
+		if l.After(&u, cursor) {
+			u = cloneUser(u)
+			matched = append(matched, &u)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return l.Less(matched[i], matched[j])
+	})
+
+	page := auth.UsersPage{Total: -1}
+	if l.WithTotal {
+		page.Total = total
+	}
+
+	if len(matched) > l.Limit {
+		matched = matched[:l.Limit]
+		page.NextCursor = l.NextCursor(matched[len(matched)-1])
+	}
+	page.Users = matched
+
+	return page, nil
 }
 
 func (r *mockUserRepository) Update(
@@ -74,12 +136,29 @@ func (r *mockUserRepository) Update(
 	if !ok {
 		return auth.UserNotFound{UserUUID: uuid}
 	}
+	user = cloneUser(user)
 
+	version := user.Version
 	err := updateFn(ctx, &user)
 	if err != nil {
 		return err
 	}
 
+	if r.m[uuid].Version != version {
+		return auth.ErrConcurrentModification
+	}
+
+	for _, other := range r.m {
+		if other.UUID != uuid && other.Email.Equal(user.Email) {
+			return auth.ErrUserAlreadyExists
+		}
+	}
+	user.Version = version + 1
+
+	if err = r.saveEvents(user.PopEvents()); err != nil {
+		return err
+	}
+
 	r.m[uuid] = user
 
 	return nil
@@ -94,7 +173,46 @@ func (r *mockUserRepository) Delete(ctx context.Context, uuid string) error {
 		return auth.UserNotFound{UserUUID: uuid}
 	}
 
+	if err := r.saveEvents([]auth.Event{auth.UserDeleted{UserUUID: uuid}}); err != nil {
+		return err
+	}
+
 	delete(r.m, uuid)
 
 	return nil
 }
+
+// cloneUser copies the slices of u.
+func cloneUser(u auth.User) auth.User {
+	u.Passhash = slices.Clone(u.Passhash)
+	u.Roles = slices.Clone(u.Roles)
+	return u
+}
+
+func (r *mockUserRepository) saveEvents(events []auth.Event) error {
+	if r.outbox == nil {
+		return nil
+	}
+	return r.outbox.save(events)
+}
+
+func (r *mockUserRepository) snapshot() func() {
+	r.RLock()
+	m := maps.Clone(r.m)
+	r.RUnlock()
+
+	var restoreOutbox func()
+	if r.outbox != nil {
+		restoreOutbox = r.outbox.snapshot()
+	}
+
+	return func() {
+		r.Lock()
+		r.m = m
+		r.Unlock()
+
+		if restoreOutbox != nil {
+			restoreOutbox()
+		}
+	}
+}
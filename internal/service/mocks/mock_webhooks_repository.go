@@ -0,0 +1,209 @@
+package mocks
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
+)
+
+type mockWebhooksRepository struct {
+	sync.RWMutex
+	subscriptions map[string]auth.WebhookSubscription
+	deliveries    map[string]auth.WebhookDelivery
+}
+
+func NewMockWebhooksRepository() auth.WebhooksRepository {
+	return &mockWebhooksRepository{
+		subscriptions: make(map[string]auth.WebhookSubscription),
+		deliveries:    make(map[string]auth.WebhookDelivery),
+	}
+}
+
+func (r *mockWebhooksRepository) SaveSubscription(_ context.Context, s *auth.WebhookSubscription) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.subscriptions[s.UUID]; ok {
+		return auth.ErrWebhookSubscriptionAlreadyExists
+	}
+
+	r.subscriptions[s.UUID] = *s
+
+	return nil
+}
+
+func (r *mockWebhooksRepository) Subscriptions(_ context.Context) ([]*auth.WebhookSubscription, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	subscriptions := make([]*auth.WebhookSubscription, 0, len(r.subscriptions))
+	for _, s := range r.subscriptions {
+		subscriptions = append(subscriptions, &s)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if !subscriptions[i].CreatedAt.Equal(subscriptions[j].CreatedAt) {
+			return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+		}
+		return subscriptions[i].UUID < subscriptions[j].UUID
+	})
+
+	return subscriptions, nil
+}
+
+func (r *mockWebhooksRepository) Subscription(_ context.Context, uuid string) (*auth.WebhookSubscription, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	s, ok := r.subscriptions[uuid]
+	if !ok {
+		return nil, auth.ErrWebhookSubscriptionNotFound
+	}
+
+	return &s, nil
+}
+
+func (r *mockWebhooksRepository) DeleteSubscription(_ context.Context, uuid string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.subscriptions[uuid]; !ok {
+		return auth.ErrWebhookSubscriptionNotFound
+	}
+
+	delete(r.subscriptions, uuid)
+	for k, d := range r.deliveries {
+		if d.SubscriptionUUID == uuid {
+			delete(r.deliveries, k)
+		}
+	}
+
+	return nil
+}
+
+func (r *mockWebhooksRepository) SaveDeliveries(_ context.Context, deliveries []*auth.WebhookDelivery) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, d := range deliveries {
+		if _, ok := r.subscriptions[d.SubscriptionUUID]; !ok {
+			return auth.ErrWebhookSubscriptionNotFound
+		}
+
+		if r.hasDelivery(d.SubscriptionUUID, d.Event.ID) {
+			continue
+		}
+
+		r.deliveries[d.UUID] = *d
+	}
+
+	return nil
+}
+
+func (r *mockWebhooksRepository) hasDelivery(subscriptionUUID string, eventID int64) bool {
+	for _, d := range r.deliveries {
+		if d.SubscriptionUUID == subscriptionUUID && d.Event.ID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *mockWebhooksRepository) ClaimDueDeliveries(
+	_ context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*auth.WebhookDelivery, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	var due []auth.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.State == auth.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+
+	claimed := make([]*auth.WebhookDelivery, 0, min(limit, len(due)))
+	for _, d := range due[:min(limit, len(due))] {
+		d.NextAttemptAt = now.Add(lease)
+		r.deliveries[d.UUID] = d
+		claimed = append(claimed, &d)
+	}
+
+	return claimed, nil
+}
+
+func (r *mockWebhooksRepository) UpdateDelivery(
+	ctx context.Context,
+	uuid string,
+	updateFn func(ctx context.Context, d *auth.WebhookDelivery) error,
+) error {
+	r.Lock()
+	defer r.Unlock()
+
+	d, ok := r.deliveries[uuid]
+	if !ok {
+		return auth.ErrWebhookDeliveryNotFound
+	}
+
+	if err := updateFn(ctx, &d); err != nil {
+		return err
+	}
+
+	r.deliveries[uuid] = d
+
+	return nil
+}
+
+func (r *mockWebhooksRepository) Deliveries(
+	_ context.Context,
+	subscriptionUUID string,
+	state auth.WebhookDeliveryState,
+	limit int,
+) ([]*auth.WebhookDelivery, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var deliveries []*auth.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.SubscriptionUUID == subscriptionUUID && (state == "" || d.State == state) {
+			deliveries = append(deliveries, &d)
+		}
+	}
+
+	slices.SortFunc(deliveries, func(a, b *auth.WebhookDelivery) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Event.ID, a.Event.ID)
+	})
+
+	return deliveries[:min(limit, len(deliveries))], nil
+}
+
+func (r *mockWebhooksRepository) snapshot() func() {
+	r.RLock()
+	subscriptions := maps.Clone(r.subscriptions)
+	deliveries := maps.Clone(r.deliveries)
+	r.RUnlock()
+
+	return func() {
+		r.Lock()
+		defer r.Unlock()
+
+		r.subscriptions = subscriptions
+		r.deliveries = deliveries
+	}
+}
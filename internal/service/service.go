@@ -1,8 +1,8 @@
 package service
 
 import (
+	"io/fs"
 	"log/slog"
-	"os"
 
 	"github.com/jmoiron/sqlx"
 
@@ -10,50 +10,235 @@ import (
 	"github.com/bmstu-itstech/itsreg-auth/internal/app/command"
 	"github.com/bmstu-itstech/itsreg-auth/internal/app/query"
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/decorator"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/executor"
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/logs"
 	"github.com/bmstu-itstech/itsreg-auth/internal/common/metrics"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/migrate"
+	"github.com/bmstu-itstech/itsreg-auth/internal/common/server"
 	"github.com/bmstu-itstech/itsreg-auth/internal/domain/auth"
 	"github.com/bmstu-itstech/itsreg-auth/internal/infra"
 	"github.com/bmstu-itstech/itsreg-auth/internal/service/mocks"
+	"github.com/bmstu-itstech/itsreg-auth/migrations"
 )
 
 type Cleanup func()
 
-func NewApplication() (*app.Application, Cleanup) {
+func NewDatabase() (*sqlx.DB, Cleanup) {
+	db := databaseFromEnv()
+	return db, func() {
+		_ = db.Close()
+	}
+}
+
+func NewApplication(db *sqlx.DB) (*app.Application, Cleanup) {
 	logger := logs.DefaultLogger()
 	metricsClient := metrics.NoOp{}
 
-	url := os.Getenv("DATABASE_URI")
-	db := sqlx.MustConnect("postgres", url)
+	if _, ok := sqlitePathFromEnv(); ok {
+		return newSQLiteApplication(db, logger, metricsClient)
+	}
 
 	users := infra.NewPgUserRepository(db)
+	tokens := infra.NewPgPersonalAccessTokensRepository(db)
+	sessions := infra.NewPgSessionsRepository(db)
+	attempts := infra.NewPgLoginAttemptsRepository(db)
+	invitations := infra.NewPgInvitationsRepository(db)
+	audit := infra.NewPgAuditLogRepository(db)
+	outbox := infra.NewPgOutboxRepository(db)
+	webhooks := infra.NewPgWebhooksRepository(db)
+	blobs := blobStorageFromEnv()
+	mailer := infra.NewLogMailer(logger)
+	publisher := publisherFromEnv(logger)
+	sender := infra.NewHTTPWebhookSender(newWebhookClient())
+	uow := infra.NewPgUnitOfWork(db)
+	users, publisher, invalidator := cachedUsersFromEnv(users, infra.NewPgUserChanges(db), publisher, metricsClient)
 
-	return newApplication(logger, metricsClient, users), func() {
-		_ = db.Close()
-	}
+	return newApplication(
+		configFromEnv(),
+		logger, metricsClient,
+		users, tokens, sessions, attempts, invitations, audit, outbox, webhooks, blobs, uow,
+		mailer, publisher, sender,
+	), listenUserChangesFromEnv(invalidator, logger)
+}
+
+// newSQLiteApplication is for a single node. Login attempts are kept in memory.
+func newSQLiteApplication(
+	db *sqlx.DB,
+	logger *slog.Logger,
+	metricsClient decorator.MetricsClient,
+) (*app.Application, Cleanup) {
+	users := infra.NewSQLiteUserRepository(db)
+	tokens := infra.NewSQLitePersonalAccessTokensRepository(db)
+	sessions := infra.NewSQLiteSessionsRepository(db)
+	attempts := infra.NewMemoryLoginAttemptsRepository()
+	invitations := infra.NewSQLiteInvitationsRepository(db)
+	audit := infra.NewSQLiteAuditLogRepository(db)
+	outbox := infra.NewSQLiteOutboxRepository(db)
+	webhooks := infra.NewSQLiteWebhooksRepository(db)
+	blobs := blobStorageFromEnv()
+	mailer := infra.NewLogMailer(logger)
+	publisher := publisherFromEnv(logger)
+	sender := infra.NewHTTPWebhookSender(newWebhookClient())
+	uow := infra.NewSQLiteUnitOfWork(db)
+	users, publisher, _ = cachedUsersFromEnv(users, infra.NewLocalUserChanges(), publisher, metricsClient)
+
+	return newApplication(
+		configFromEnv(),
+		logger, metricsClient,
+		users, tokens, sessions, attempts, invitations, audit, outbox, webhooks, blobs, uow,
+		mailer, publisher, sender,
+	), func() {}
 }
 
 func NewComponentTestApplication() *app.Application {
 	logger := logs.DefaultLogger()
 	metricsClient := metrics.NoOp{}
 
-	users := mocks.NewMockUserRepository()
+	users, outbox := mocks.NewMockUserRepositoryWithOutbox()
+	tokens := mocks.NewMockPersonalAccessTokensRepository()
+	sessions := mocks.NewMockSessionsRepository()
+	attempts := infra.NewMemoryLoginAttemptsRepository()
+	invitations := mocks.NewMockInvitationsRepository()
+	audit := mocks.NewMockAuditLogRepository()
+	webhooks := mocks.NewMockWebhooksRepository()
+	blobs := mocks.NewMockBlobStorage()
+	mailer := infra.NewLogMailer(logger)
+	publisher := mocks.NewFakePublisher()
+	sender := infra.NewHTTPWebhookSender(newWebhookClient())
+	uow := mocks.NewMockUnitOfWork(users, invitations, audit, webhooks)
+
+	return newApplication(
+		componentTestConfig(),
+		logger, metricsClient,
+		users, tokens, sessions, attempts, invitations, audit, outbox, webhooks, blobs, uow,
+		mailer, publisher, sender,
+	)
+}
+
+func NewRateLimiter(db *sqlx.DB) *server.RateLimiter {
+	if _, ok := sqlitePathFromEnv(); ok {
+		return server.NewRateLimiterFromEnv(server.NewMemoryRateLimitStore())
+	}
+
+	return server.NewRateLimiterFromEnv(server.NewPgRateLimitStore(db))
+}
+
+// NewComponentTestRateLimiter does not limit authentication failures.
+func NewComponentTestRateLimiter(rules []server.RateLimitRule) *server.RateLimiter {
+	return server.NewRateLimiter(server.NewMemoryRateLimitStore(), rules, server.RateLimit{})
+}
+
+func NewIdempotency(db *sqlx.DB) *server.Idempotency {
+	if _, ok := sqlitePathFromEnv(); ok {
+		return server.NewIdempotencyFromEnv(server.NewMemoryIdempotencyStore())
+	}
+
+	return server.NewIdempotencyFromEnv(server.NewPgIdempotencyStore(db))
+}
+
+func NewComponentTestIdempotency() *server.Idempotency {
+	return server.NewIdempotency(server.NewMemoryIdempotencyStore(), server.DefaultIdempotencyTTL)
+}
+
+func NewMigrator() (*migrate.Migrator, Cleanup) {
+	db := databaseFromEnv()
+
+	var fsys fs.FS = migrations.FS
+	// 018 renormalizes the emails backfilled in SQL by 007.
+	steps := []migrate.Step{{Version: 18, Up: infra.RenormalizeEmails}}
+	if _, ok := sqlitePathFromEnv(); ok {
+		fsys, steps = migrations.SQLiteFS, nil
+	}
+
+	m, err := migrate.New(db, fsys, steps...)
+	if err != nil {
+		panic(err)
+	}
+
+	return m, func() {
+		_ = db.Close()
+	}
+}
+
+func MigrateOnStartup() bool {
+	return boolFromEnv("MIGRATE_ON_STARTUP", false)
+}
 
-	return newApplication(logger, metricsClient, users)
+func ConcealExistingEmails() bool {
+	return boolFromEnv("REGISTER_CONCEAL_EXISTING_EMAILS", false)
 }
 
 func newApplication(
+	cfg config,
 	logger *slog.Logger,
 	metricsClients decorator.MetricsClient,
 	users auth.UsersRepository,
+	tokens auth.PersonalAccessTokensRepository,
+	sessions auth.SessionsRepository,
+	attempts auth.LoginAttemptsRepository,
+	invitations auth.InvitationsRepository,
+	audit auth.AuditLogRepository,
+	outbox auth.OutboxRepository,
+	webhooks auth.WebhooksRepository,
+	blobs auth.BlobStorage,
+	uow decorator.UnitOfWork,
+	mailer command.Mailer,
+	publisher command.Publisher,
+	sender command.WebhookSender,
 ) *app.Application {
+	hashing := executor.New("password_hashing", cfg.hashingConcurrency, cfg.hashingQueueSize, metricsClients)
+
 	return &app.Application{
 		Commands: app.Commands{
-			RegisterUser: command.NewRegisterUserHandler(users, logger, metricsClients),
+			RegisterUser: command.NewRegisterUserHandler(
+				users, invitations, audit, uow, mailer, cfg.passwordHasher, hashing,
+				cfg.passwordPolicy, cfg.registrationPolicy, cfg.concealExistingEmails,
+				logger, metricsClients,
+			),
+			ImportUser: command.NewImportUserHandler(users, audit, cfg.passwordHasher, uow, logger, metricsClients),
+
+			UpdateProfile: command.NewUpdateProfileHandler(users, logger, metricsClients),
+			UploadAvatar:  command.NewUploadAvatarHandler(users, blobs, logger, metricsClients),
+
+			ChangeUserStatus: command.NewChangeUserStatusHandler(users, audit, uow, logger, metricsClients),
+			CreateInvitation: command.NewCreateInvitationHandler(invitations, audit, logger, metricsClients),
+
+			CreatePersonalAccessToken: command.NewCreatePersonalAccessTokenHandler(users, tokens, audit, logger, metricsClients),
+			RevokePersonalAccessToken: command.NewRevokePersonalAccessTokenHandler(tokens, audit, logger, metricsClients),
+
+			RevokeSession:      command.NewRevokeSessionHandler(sessions, audit, logger, metricsClients),
+			RevokeUserSessions: command.NewRevokeUserSessionsHandler(users, sessions, audit, logger, metricsClients),
+
+			RelayOutbox: command.NewRelayOutboxHandler(outbox, webhooks, publisher, logger, metricsClients),
+
+			CreateWebhookSubscription: command.NewCreateWebhookSubscriptionHandler(webhooks, audit, uow, logger, metricsClients),
+			DeleteWebhookSubscription: command.NewDeleteWebhookSubscriptionHandler(webhooks, audit, uow, logger, metricsClients),
+			RedeliverWebhook:          command.NewRedeliverWebhookHandler(webhooks, logger, metricsClients),
+			DeliverWebhooks: command.NewDeliverWebhooksHandler(
+				webhooks, sender, cfg.webhookRetryPolicy,
+				logger, metricsClients,
+			),
 		},
 		Queries: app.Queries{
 			GetUser:   query.NewGetUserHandler(users, logger, metricsClients),
-			LoginUser: query.NewLoginUserHandler(users, logger, metricsClients),
+			GetAvatar: query.NewGetAvatarHandler(users, blobs, logger, metricsClients),
+			ListUsers: query.NewListUsersHandler(users, logger, metricsClients),
+
+			LoginUser: query.NewLoginUserHandler(
+				users, sessions, attempts, audit, cfg.passwordHasher, hashing,
+				logger, metricsClients,
+			),
+
+			AuthenticateToken:    query.NewAuthenticateTokenHandler(users, sessions, tokens, logger, metricsClients),
+			PersonalAccessTokens: query.NewPersonalAccessTokensHandler(tokens, logger, metricsClients),
+
+			Sessions: query.NewSessionsHandler(users, sessions, logger, metricsClients),
+
+			ListAuditEvents: query.NewListAuditEventsHandler(audit, logger, metricsClients),
+			VerifyAuditLog:  query.NewVerifyAuditLogHandler(audit, logger, metricsClients),
+
+			ListWebhookSubscriptions: query.NewListWebhookSubscriptionsHandler(webhooks, logger, metricsClients),
+			ListWebhookDeliveries:    query.NewListWebhookDeliveriesHandler(webhooks, logger, metricsClients),
 		},
 	}
 }
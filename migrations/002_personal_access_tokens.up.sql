@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    uuid         VARCHAR(36)  PRIMARY KEY,
+    user_uuid    VARCHAR(36)  NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    name         VARCHAR(64)  NOT NULL,
+    scopes       TEXT[]       NOT NULL,
+    token_hash   BYTEA        UNIQUE NOT NULL,
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(45),
+    created_at   TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_uuid_idx ON personal_access_tokens (user_uuid);
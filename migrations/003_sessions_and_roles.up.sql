@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS sessions (
+    uuid         VARCHAR(36)  PRIMARY KEY,
+    user_uuid    VARCHAR(36)  NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    user_agent   VARCHAR(512) NOT NULL,
+    ip           VARCHAR(45)  NOT NULL,
+    created_at   TIMESTAMP    NOT NULL,
+    last_seen_at TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_uuid_idx ON sessions (user_uuid);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key             VARCHAR(300) PRIMARY KEY,
+    failures        INTEGER      NOT NULL,
+    last_failure_at TIMESTAMP    NOT NULL,
+    locked_until    TIMESTAMP    NOT NULL
+);
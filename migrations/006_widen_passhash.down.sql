@@ -0,0 +1,3 @@
+-- Hashes longer than 72 characters, such as argon2id ones, can not be narrowed
+-- back and make this migration fail.
+ALTER TABLE users ALTER COLUMN passhash TYPE VARCHAR(72);
@@ -0,0 +1 @@
+ALTER TABLE users ALTER COLUMN passhash TYPE VARCHAR(255);
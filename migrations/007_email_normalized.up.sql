@@ -0,0 +1,31 @@
+-- The application normalizes emails to NFC and lower case, with the domain in
+-- Unicode form. Existing rows are backfilled with the closest SQL equivalent;
+-- punycode domains are renormalized when the user is next updated.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_normalized VARCHAR(256);
+
+UPDATE users SET email_normalized = LOWER(NORMALIZE(TRIM(email), NFC));
+
+-- Accounts that differ only in case can not get a unique index and have to be
+-- merged by hand first. The whole migration is rolled back in that case.
+DO $$
+DECLARE
+    collisions TEXT;
+BEGIN
+    SELECT string_agg(email_normalized || ': ' || accounts, E'\n')
+    INTO collisions
+    FROM (
+        SELECT email_normalized, string_agg(uuid || ' <' || email || '>', ', ' ORDER BY created_at) AS accounts
+        FROM users
+        GROUP BY email_normalized
+        HAVING COUNT(*) > 1
+    ) AS c;
+
+    IF collisions IS NOT NULL THEN
+        RAISE EXCEPTION E'users with colliding emails must be merged first:\n%', collisions;
+    END IF;
+END
+$$;
+
+ALTER TABLE users ALTER COLUMN email_normalized SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_normalized_idx ON users (email_normalized);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    uuid       VARCHAR(36) PRIMARY KEY,
+    code_hash  BYTEA       UNIQUE NOT NULL,
+    created_by VARCHAR(36) NOT NULL,
+    roles      TEXT[]      NOT NULL DEFAULT '{}',
+    max_uses   INTEGER     NOT NULL,
+    uses       INTEGER     NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP   NOT NULL
+);
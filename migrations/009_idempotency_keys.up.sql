@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key         VARCHAR(600) PRIMARY KEY,
+    fingerprint BYTEA        NOT NULL,
+    completed   BOOLEAN      NOT NULL,
+    status_code INTEGER      NOT NULL,
+    header      JSONB        NOT NULL,
+    body        BYTEA        NOT NULL,
+    expires_at  TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
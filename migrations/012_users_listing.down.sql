@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_roles_idx;
+DROP INDEX IF EXISTS users_email_normalized_trgm_idx;
+DROP INDEX IF EXISTS users_updated_at_uuid_idx;
+DROP INDEX IF EXISTS users_created_at_uuid_idx;
@@ -0,0 +1,9 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Keyset pagination of the admin listing, in both directions.
+CREATE INDEX IF NOT EXISTS users_created_at_uuid_idx ON users (created_at, uuid);
+CREATE INDEX IF NOT EXISTS users_updated_at_uuid_idx ON users (updated_at, uuid);
+
+-- Substring search by email and filtering by role.
+CREATE INDEX IF NOT EXISTS users_email_normalized_trgm_idx ON users USING GIN (email_normalized gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS users_roles_idx ON users USING GIN (roles);
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status            VARCHAR(16)  NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until   TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason     VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_by VARCHAR(36)  NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+
+-- Few accounts are ever blocked, so only they are indexed.
+CREATE INDEX IF NOT EXISTS users_status_idx ON users (status, suspended_until) WHERE status <> 'active';
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    seq         BIGINT       PRIMARY KEY,
+    action      VARCHAR(64)  NOT NULL,
+    actor_uuid  VARCHAR(36)  NOT NULL DEFAULT '',
+    target_uuid VARCHAR(36)  NOT NULL DEFAULT '',
+    ip          VARCHAR(45)  NOT NULL DEFAULT '',
+    user_agent  VARCHAR(512) NOT NULL DEFAULT '',
+    request_id  VARCHAR(128) NOT NULL DEFAULT '',
+    details     JSONB        NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMP    NOT NULL,
+    prev_hash   BYTEA        NOT NULL,
+    hash        BYTEA        NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_uuid_idx ON audit_log (actor_uuid, seq);
+CREATE INDEX IF NOT EXISTS audit_log_target_uuid_idx ON audit_log (target_uuid, seq);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log (action, seq);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at);
+
+-- The log is append-only. The hash chain detects changes made around this,
+-- for example by the table owner.
+CREATE OR REPLACE FUNCTION audit_log_forbid_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_forbid_change();
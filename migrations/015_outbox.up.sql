@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id             BIGSERIAL   PRIMARY KEY,
+    type           VARCHAR(64) NOT NULL,
+    aggregate_uuid VARCHAR(36) NOT NULL,
+    payload        JSONB       NOT NULL,
+    occurred_at    TIMESTAMP   NOT NULL
+);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    uuid        VARCHAR(36)   PRIMARY KEY,
+    url         VARCHAR(2048) NOT NULL,
+    event_types TEXT[]        NOT NULL,
+    secret      VARCHAR(64)   NOT NULL,
+    created_by  VARCHAR(36)   NOT NULL,
+    created_at  TIMESTAMP     NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    uuid              VARCHAR(36)  PRIMARY KEY,
+    subscription_uuid VARCHAR(36)  NOT NULL REFERENCES webhook_subscriptions (uuid) ON DELETE CASCADE,
+    event_id          BIGINT       NOT NULL,
+    event_type        VARCHAR(64)  NOT NULL,
+    aggregate_uuid    VARCHAR(36)  NOT NULL,
+    payload           JSONB        NOT NULL,
+    occurred_at       TIMESTAMP    NOT NULL,
+    state             VARCHAR(16)  NOT NULL,
+    attempts          INT          NOT NULL DEFAULT 0,
+    next_attempt_at   TIMESTAMP,
+    last_attempt_at   TIMESTAMP,
+    last_status_code  INT          NOT NULL DEFAULT 0,
+    last_error        VARCHAR(512) NOT NULL DEFAULT '',
+    created_at        TIMESTAMP    NOT NULL,
+    UNIQUE (subscription_uuid, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (next_attempt_at) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx
+    ON webhook_deliveries (subscription_uuid, created_at);
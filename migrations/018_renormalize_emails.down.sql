@@ -0,0 +1,2 @@
+-- The renormalized emails are kept: the application normalizes them the same
+-- way.
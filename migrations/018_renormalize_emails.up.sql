@@ -0,0 +1,3 @@
+-- The emails backfilled by 007 are renormalized in Go by a step of this
+-- migration, as SQL can not convert punycode domains to Unicode the way the
+-- application does.
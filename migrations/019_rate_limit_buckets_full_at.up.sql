@@ -0,0 +1,5 @@
+-- Buckets are removed once they are full again, which is the same as having
+-- no bucket. Existing buckets are taken as full.
+ALTER TABLE rate_limit_buckets ADD COLUMN IF NOT EXISTS full_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC');
+
+CREATE INDEX IF NOT EXISTS rate_limit_buckets_full_at_idx ON rate_limit_buckets (full_at);
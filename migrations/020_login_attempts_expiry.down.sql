@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS login_attempts_expires_at_idx;
+
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,9 @@
+-- Attempts are removed once they expire. Keys are now hashes, so the rows
+-- with the former keys are only kept until then.
+ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+
+UPDATE login_attempts SET expires_at = GREATEST(locked_until, last_failure_at + INTERVAL '1 hour');
+
+ALTER TABLE login_attempts ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS login_attempts_expires_at_idx ON login_attempts (expires_at);
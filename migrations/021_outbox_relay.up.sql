@@ -0,0 +1,7 @@
+-- The replica relaying the outbox holds the lease until it has published and
+-- removed its batch, or the lease runs out.
+CREATE TABLE IF NOT EXISTS outbox_relay (
+    id           SMALLINT    PRIMARY KEY CHECK (id = 1),
+    holder       VARCHAR(36) NOT NULL,
+    leased_until TIMESTAMP   NOT NULL
+);
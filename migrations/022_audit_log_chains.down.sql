@@ -0,0 +1,3 @@
+DROP SEQUENCE IF EXISTS audit_log_seq;
+DROP INDEX IF EXISTS audit_log_chain_idx;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS chain;
@@ -0,0 +1,8 @@
+-- Events are chained per target from now on. The events appended before are
+-- chained in the order of seq, so they stay in the chain keyed ''.
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS chain VARCHAR(36) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS audit_log_chain_idx ON audit_log (chain, seq);
+
+CREATE SEQUENCE IF NOT EXISTS audit_log_seq;
+SELECT setval('audit_log_seq', COALESCE((SELECT MAX(seq) FROM audit_log), 0) + 1, false);
@@ -0,0 +1,27 @@
+// Package migrations embeds the SQL migrations of the service, so the binary
+// can apply them itself.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS holds the migrations of Postgres.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqlite embed.FS
+
+// SQLiteFS holds the migrations of SQLite.
+var SQLiteFS = mustSub(sqlite, "sqlite")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,29 @@
+-- Times are kept as fixed-width UTC text, so they compare in order.
+CREATE TABLE IF NOT EXISTS users (
+    uuid              TEXT    PRIMARY KEY,
+    email             TEXT    NOT NULL,
+    email_normalized  TEXT    NOT NULL UNIQUE,
+    passhash          BLOB    NOT NULL,
+    roles             TEXT    NOT NULL DEFAULT '[]',
+    profile           TEXT    NOT NULL DEFAULT '{}',
+    avatar_key        TEXT    NOT NULL DEFAULT '',
+    status            TEXT    NOT NULL DEFAULT 'active',
+    suspended_until   TEXT,
+    status_reason     TEXT    NOT NULL DEFAULT '',
+    status_changed_by TEXT    NOT NULL DEFAULT '',
+    status_changed_at TEXT,
+    created_at        TEXT    NOT NULL,
+    updated_at        TEXT    NOT NULL,
+    version           INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS users_created_at_uuid_idx ON users (created_at, uuid);
+CREATE INDEX IF NOT EXISTS users_updated_at_uuid_idx ON users (updated_at, uuid);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    type           TEXT    NOT NULL,
+    aggregate_uuid TEXT    NOT NULL,
+    payload        TEXT    NOT NULL,
+    occurred_at    TEXT    NOT NULL
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS personal_access_tokens;
@@ -0,0 +1,97 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    uuid         TEXT PRIMARY KEY,
+    user_uuid    TEXT NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    scopes       TEXT NOT NULL,
+    token_hash   BLOB NOT NULL UNIQUE,
+    expires_at   TEXT,
+    last_used_at TEXT,
+    last_used_ip TEXT,
+    created_at   TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_uuid_idx ON personal_access_tokens (user_uuid);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    uuid         TEXT PRIMARY KEY,
+    user_uuid    TEXT NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    user_agent   TEXT NOT NULL,
+    ip           TEXT NOT NULL,
+    created_at   TEXT NOT NULL,
+    last_seen_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_uuid_idx ON sessions (user_uuid);
+
+CREATE TABLE IF NOT EXISTS invitations (
+    uuid       TEXT    PRIMARY KEY,
+    code_hash  BLOB    NOT NULL UNIQUE,
+    created_by TEXT    NOT NULL,
+    roles      TEXT    NOT NULL DEFAULT '[]',
+    max_uses   INTEGER NOT NULL,
+    uses       INTEGER NOT NULL DEFAULT 0,
+    expires_at TEXT,
+    created_at TEXT    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    seq         INTEGER PRIMARY KEY,
+    action      TEXT    NOT NULL,
+    actor_uuid  TEXT    NOT NULL DEFAULT '',
+    target_uuid TEXT    NOT NULL DEFAULT '',
+    ip          TEXT    NOT NULL DEFAULT '',
+    user_agent  TEXT    NOT NULL DEFAULT '',
+    request_id  TEXT    NOT NULL DEFAULT '',
+    details     TEXT    NOT NULL DEFAULT '{}',
+    created_at  TEXT    NOT NULL,
+    prev_hash   BLOB    NOT NULL,
+    hash        BLOB    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_uuid_idx ON audit_log (actor_uuid, seq);
+CREATE INDEX IF NOT EXISTS audit_log_target_uuid_idx ON audit_log (target_uuid, seq);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log (action, seq);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at);
+
+-- The log is append-only. The hash chain detects changes made around this.
+CREATE TRIGGER IF NOT EXISTS audit_log_forbid_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_forbid_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    uuid        TEXT PRIMARY KEY,
+    url         TEXT NOT NULL,
+    event_types TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    created_by  TEXT NOT NULL,
+    created_at  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    uuid              TEXT    PRIMARY KEY,
+    subscription_uuid TEXT    NOT NULL REFERENCES webhook_subscriptions (uuid) ON DELETE CASCADE,
+    event_id          INTEGER NOT NULL,
+    event_type        TEXT    NOT NULL,
+    aggregate_uuid    TEXT    NOT NULL,
+    payload           TEXT    NOT NULL,
+    occurred_at       TEXT    NOT NULL,
+    state             TEXT    NOT NULL,
+    attempts          INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at   TEXT,
+    last_attempt_at   TEXT,
+    last_status_code  INTEGER NOT NULL DEFAULT 0,
+    last_error        TEXT    NOT NULL DEFAULT '',
+    created_at        TEXT    NOT NULL,
+    UNIQUE (subscription_uuid, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (next_attempt_at) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx
+    ON webhook_deliveries (subscription_uuid, created_at);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS outbox_relay (
+    id           INTEGER PRIMARY KEY CHECK (id = 1),
+    holder       TEXT    NOT NULL,
+    leased_until TEXT    NOT NULL
+);
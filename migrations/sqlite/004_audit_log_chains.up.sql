@@ -0,0 +1,5 @@
+-- Events are chained per target from now on. The events appended before are
+-- chained in the order of seq, so they stay in the chain keyed ''.
+ALTER TABLE audit_log ADD COLUMN chain TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS audit_log_chain_idx ON audit_log (chain, seq);